@@ -361,6 +361,15 @@ type batchInternal struct {
 
 	commitErr error
 
+	// txn is set when the batch holds the writes of a Txn being committed. The
+	// commit pipeline validates the transaction's read set before assigning
+	// the batch a sequence number.
+	txn *Txn
+
+	// locks is set while the batch holds key locks acquired by DB.LockKeys. The
+	// locks are released when the batch is committed, reset or closed.
+	locks *lockTable
+
 	// Position bools together to reduce the sizeof the struct.
 
 	// ingestedSSTBatch indicates that the batch contains one or more key kinds
@@ -676,6 +685,24 @@ func (b *Batch) Get(key []byte) ([]byte, io.Closer, error) {
 	return b.db.getInternal(key, b, nil /* snapshot */)
 }
 
+// ttlEnabled returns true if the batch belongs to a DB with TTL enabled, in
+// which case the values of sets and merges are prefixed with a TTL header.
+func (b *Batch) ttlEnabled() bool {
+	return b.db != nil && b.db.opts.TTL != nil
+}
+
+// prepareDeferredTTLRecord is like prepareDeferredKeyValueRecord, but prefixes
+// the value with the TTL header for the given expiry. The deferred op's Value
+// excludes the header.
+func (b *Batch) prepareDeferredTTLRecord(
+	keyLen, valueLen int, kind InternalKeyKind, expiry uint64,
+) {
+	n := ttlHeaderLen(expiry)
+	b.prepareDeferredKeyValueRecord(keyLen, n+valueLen, kind)
+	putTTLHeader(b.deferredOp.Value[:n], expiry)
+	b.deferredOp.Value = b.deferredOp.Value[n:]
+}
+
 func (b *Batch) prepareDeferredKeyValueRecord(keyLen, valueLen int, kind InternalKeyKind) {
 	if b.committing {
 		panic("pebble: batch already committing")
@@ -807,10 +834,14 @@ func (b *Batch) AddInternalKey(key *base.InternalKey, value []byte, _ *WriteOpti
 	return nil
 }
 
-// Set adds an action to the batch that sets the key to map to the value.
+// Set adds an action to the batch that sets the key to map to the value. If
+// opts.TTL is positive, the value expires after the TTL; see SetWithTTL.
 //
 // It is safe to modify the contents of the arguments after Set returns.
-func (b *Batch) Set(key, value []byte, _ *WriteOptions) error {
+func (b *Batch) Set(key, value []byte, opts *WriteOptions) error {
+	if opts != nil && opts.TTL > 0 {
+		return b.SetWithTTL(key, value, opts.TTL, opts)
+	}
 	deferredOp := b.SetDeferred(len(key), len(value))
 	copy(deferredOp.Key, key)
 	copy(deferredOp.Value, value)
@@ -829,11 +860,41 @@ func (b *Batch) Set(key, value []byte, _ *WriteOptions) error {
 // letting the caller encode into those objects and then call Finish() on the
 // returned object.
 func (b *Batch) SetDeferred(keyLen, valueLen int) *DeferredBatchOp {
-	b.prepareDeferredKeyValueRecord(keyLen, valueLen, InternalKeyKindSet)
+	if b.ttlEnabled() {
+		b.prepareDeferredTTLRecord(keyLen, valueLen, InternalKeyKindSet, 0)
+	} else {
+		b.prepareDeferredKeyValueRecord(keyLen, valueLen, InternalKeyKindSet)
+	}
 	b.deferredOp.index = b.index
 	return &b.deferredOp
 }
 
+// SetWithTTL adds an action to the batch that sets the key to map to the value
+// until ttl has elapsed. Once the value has expired, reads no longer observe
+// the key and compactions drop it. Merges applied to the value expire along
+// with it. SetWithTTL returns an error if TTL is not enabled through
+// Options.TTL.
+//
+// It is safe to modify the contents of the arguments after SetWithTTL returns.
+func (b *Batch) SetWithTTL(key, value []byte, ttl time.Duration, _ *WriteOptions) error {
+	if !b.ttlEnabled() {
+		return errTTLDisabled
+	}
+	if ttl <= 0 {
+		return errors.Errorf("pebble: invalid TTL %s", ttl)
+	}
+	expiry := uint64(b.db.opts.TTL.Now().Add(ttl).UnixNano())
+	b.prepareDeferredTTLRecord(len(key), len(value), InternalKeyKindSet, expiry)
+	copy(b.deferredOp.Key, key)
+	copy(b.deferredOp.Value, value)
+	if b.index != nil {
+		if err := b.index.Add(b.deferredOp.offset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Merge adds an action to the batch that merges the value at key with the new
 // value. The details of the merge are dependent upon the configured merge
 // operator.
@@ -858,7 +919,11 @@ func (b *Batch) Merge(key, value []byte, _ *WriteOptions) error {
 // letting the caller encode into those objects and then call Finish() on the
 // returned object.
 func (b *Batch) MergeDeferred(keyLen, valueLen int) *DeferredBatchOp {
-	b.prepareDeferredKeyValueRecord(keyLen, valueLen, InternalKeyKindMerge)
+	if b.ttlEnabled() {
+		b.prepareDeferredTTLRecord(keyLen, valueLen, InternalKeyKindMerge, 0)
+	} else {
+		b.prepareDeferredKeyValueRecord(keyLen, valueLen, InternalKeyKindMerge)
+	}
 	b.deferredOp.index = b.index
 	return &b.deferredOp
 }
@@ -1505,6 +1570,7 @@ func (b *Batch) Close() error {
 	// We still want to recycle these batches. The b.lifecycle atomic negotiates
 	// the batch's lifecycle. If the commit pipeline still might read b.data,
 	// b.lifecycle will be nonzeroed [the low bits hold a ref count].
+	b.releaseLocks()
 	for {
 		v := b.lifecycle.Load()
 		switch {
@@ -1569,6 +1635,7 @@ func (b *Batch) Reset() {
 	if v := b.lifecycle.Load(); v > 0 {
 		b.data = nil
 	}
+	b.releaseLocks()
 	b.reset()
 }
 
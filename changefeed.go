@@ -0,0 +1,458 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/chris124567/pebble/batchrepr"
+	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/internal/rangekey"
+	"github.com/chris124567/pebble/record"
+	"github.com/chris124567/pebble/wal"
+)
+
+// ErrChangesUnavailable is returned by DB.Subscribe when the changes from the
+// requested sequence number are no longer retained in the WAL.
+var ErrChangesUnavailable = errors.New("pebble: changes are no longer retained")
+
+// ErrSubscriptionLagged is returned by Subscription.Next when the subscriber
+// fell so far behind the commit pipeline that its buffered changes exceeded
+// maxSubscriptionBufferBytes. The subscriber may resubscribe from the
+// sequence number following the last change it observed.
+var ErrSubscriptionLagged = errors.New("pebble: subscription fell too far behind")
+
+// maxSubscriptionBufferBytes bounds the size of the committed batches buffered
+// for a subscription that is not keeping up with the commit pipeline.
+const maxSubscriptionBufferBytes = 64 << 20
+
+// Change is a single mutation committed to the DB, as returned by
+// Subscription.Next.
+type Change struct {
+	// Kind is the kind of the mutation: a set, delete, single delete, merge,
+	// range deletion or range key operation.
+	Kind InternalKeyKind
+	// SeqNum is the sequence number at which the mutation was committed.
+	SeqNum base.SeqNum
+	// Key is the key written, or the start key of the span written by range
+	// deletions and range keys.
+	Key []byte
+	// EndKey is the exclusive end key of the span written by range deletions
+	// and range keys.
+	EndKey []byte
+	// Suffix is the suffix of a RangeKeySet or RangeKeyUnset.
+	Suffix []byte
+	// Value is the value of a set, merge or RangeKeySet.
+	Value []byte
+	// Expiry is the time at which the value of a set expires, or the zero time
+	// if it does not expire. See Batch.SetWithTTL.
+	Expiry time.Time
+}
+
+// Subscribe returns a Subscription to the mutations committed to the DB that
+// overlap span, starting at the sequence number fromSeqNum. A nil span.Start
+// or span.End leaves the span unbounded on that side.
+//
+// Changes committed before the call are read back from the WAL, which retains
+// only the changes that have not yet been flushed. If fromSeqNum precedes the
+// retained changes (or the WAL is disabled), Subscribe returns
+// ErrChangesUnavailable. While the retained changes are being read, the DB
+// does not delete or recycle obsolete WAL files. Subsequent changes are
+// delivered from the commit pipeline once they become visible.
+//
+// Changes are delivered in sequence number order and include sets, deletes,
+// merges, range deletions and range keys. Ingested and excised spans are not
+// reported. The Subscription must be closed before the DB is closed.
+func (d *DB) Subscribe(span KeyRange, fromSeqNum base.SeqNum) (*Subscription, error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	s := &Subscription{
+		d:      d,
+		span:   span,
+		from:   fromSeqNum,
+		notify: make(chan struct{}, 1),
+	}
+	// Register the subscription while holding commitPipeline.mu, so that every
+	// batch sequenced at or after liveFrom is captured for it.
+	d.commit.mu.Lock()
+	liveFrom := d.mu.versions.logSeqNum.Load()
+	d.changes.register(s)
+	d.commit.mu.Unlock()
+	if fromSeqNum >= liveFrom {
+		return s, nil
+	}
+	if err := s.startCatchUp(liveFrom); err != nil {
+		_ = s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Subscription is a stream of committed mutations. See DB.Subscribe.
+//
+// A Subscription is not safe for concurrent use.
+type Subscription struct {
+	d      *DB
+	span   KeyRange
+	from   base.SeqNum
+	closed bool
+
+	// catchUp holds the state for reading the changes that were committed
+	// before the subscription was registered back from the WAL.
+	catchUp struct {
+		active bool
+		logs   wal.Logs
+		r      wal.Reader
+		// until is the sequence number at which live delivery begins.
+		until base.SeqNum
+	}
+
+	// The following fields are protected by changeFeed.mu.
+	queue       []capturedBatch
+	queuedBytes int
+	err         error
+	// notify is signaled when a batch is queued or err is set.
+	notify chan struct{}
+
+	// batch is the batch whose changes are being returned.
+	batch struct {
+		reader batchrepr.Reader
+		seqNum base.SeqNum
+	}
+	// pending holds changes decoded from a single batch entry but not yet
+	// returned.
+	pending []Change
+}
+
+// startCatchUp prepares to read the changes in [s.from, liveFrom) from the
+// WAL.
+func (s *Subscription) startCatchUp(liveFrom base.SeqNum) error {
+	d := s.d
+	if d.opts.DisableWAL || d.opts.ReadOnly {
+		return errors.Wrapf(ErrChangesUnavailable, "changes from %s requested, WAL starts at %s", s.from, liveFrom)
+	}
+	// Write an empty log-data record to flush and sync the WAL, so that every
+	// batch sequenced before liveFrom can be read from the WAL files.
+	if err := d.LogData(nil /* data */, Sync); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.disableFileDeletions()
+	// The oldest unflushed memtable was created when its WAL was, so every
+	// batch from its logSeqNum onwards is in a WAL that has not been deleted.
+	availableFrom := d.mu.mem.queue[0].logSeqNum
+	s.catchUp.logs = d.mu.log.manager.List()
+	d.mu.Unlock()
+	s.catchUp.active = true
+	s.catchUp.until = liveFrom
+	// No batch precedes SeqNumStart, so a DB that has never flushed retains
+	// every change.
+	if s.from < availableFrom && availableFrom > base.SeqNumStart {
+		return errors.Wrapf(ErrChangesUnavailable, "changes from %s requested, WAL starts at %s", s.from, availableFrom)
+	}
+	return nil
+}
+
+// finishCatchUp releases the resources used to read the WAL.
+func (s *Subscription) finishCatchUp() error {
+	if !s.catchUp.active {
+		return nil
+	}
+	s.catchUp.active = false
+	var err error
+	if s.catchUp.r != nil {
+		err = s.catchUp.r.Close()
+		s.catchUp.r = nil
+	}
+	s.catchUp.logs = nil
+	if s.d.closed.Load() == nil {
+		s.d.mu.Lock()
+		s.d.enableFileDeletions()
+		s.d.mu.Unlock()
+	}
+	return err
+}
+
+// Next returns the next change, blocking until one is committed. Next returns
+// an error if ctx is done, the DB is closed or the subscription lagged. The
+// slices in the returned Change must not be modified.
+func (s *Subscription) Next(ctx context.Context) (Change, error) {
+	if s.closed {
+		return Change{}, ErrClosed
+	}
+	for {
+		if len(s.pending) > 0 {
+			c := s.pending[0]
+			s.pending = s.pending[1:]
+			return c, nil
+		}
+		kind, ukey, value, ok, err := s.batch.reader.Next()
+		if err != nil {
+			return Change{}, err
+		}
+		if ok {
+			s.decode(kind, ukey, value)
+			continue
+		}
+		repr, err := s.nextBatch(ctx)
+		if err != nil {
+			return Change{}, err
+		}
+		s.batch.reader = batchrepr.Read(repr)
+		s.batch.seqNum = batchrepr.ReadSeqNum(repr)
+	}
+}
+
+// decode appends the changes for a batch entry that fall within the
+// subscription to s.pending.
+func (s *Subscription) decode(kind InternalKeyKind, ukey, value []byte) {
+	if kind == InternalKeyKindLogData {
+		// Log data does not consume a sequence number.
+		return
+	}
+	seqNum := s.batch.seqNum
+	s.batch.seqNum++
+	if seqNum < s.from {
+		return
+	}
+	c := Change{Kind: kind, SeqNum: seqNum, Key: ukey}
+	switch kind {
+	case InternalKeyKindSet, InternalKeyKindSetWithDelete, InternalKeyKindMerge:
+		c.Value = value
+		if s.d.opts.TTL != nil {
+			expiry, v, err := decodeTTLValue(value)
+			if err != nil {
+				return
+			}
+			c.Value = v
+			if expiry != 0 {
+				c.Expiry = time.Unix(0, int64(expiry))
+			}
+		}
+	case InternalKeyKindDelete, InternalKeyKindSingleDelete:
+	case InternalKeyKindDeleteSized:
+		// The value holds the size of the deleted value, which is internal.
+		c.Kind = InternalKeyKindDelete
+	case InternalKeyKindRangeDelete:
+		c.EndKey = value
+	case InternalKeyKindRangeKeySet, InternalKeyKindRangeKeyUnset, InternalKeyKindRangeKeyDelete:
+		span, err := rangekey.Decode(base.MakeInternalKey(ukey, seqNum, kind), value, nil)
+		if err != nil || !s.overlaps(span.Start, span.End) {
+			return
+		}
+		for _, k := range span.Keys {
+			s.pending = append(s.pending, Change{
+				Kind: kind, SeqNum: seqNum, Key: span.Start, EndKey: span.End,
+				Suffix: k.Suffix, Value: k.Value,
+			})
+		}
+		return
+	default:
+		// Ingestions and excisions are not reported.
+		return
+	}
+	if c.EndKey == nil && !s.contains(c.Key) || c.EndKey != nil && !s.overlaps(c.Key, c.EndKey) {
+		return
+	}
+	s.pending = append(s.pending, c)
+}
+
+func (s *Subscription) contains(key []byte) bool {
+	cmp := s.d.cmp
+	return (s.span.Start == nil || cmp(key, s.span.Start) >= 0) &&
+		(s.span.End == nil || cmp(key, s.span.End) < 0)
+}
+
+func (s *Subscription) overlaps(start, end []byte) bool {
+	cmp := s.d.cmp
+	return (s.span.Start == nil || cmp(end, s.span.Start) > 0) &&
+		(s.span.End == nil || cmp(start, s.span.End) < 0)
+}
+
+// nextBatch returns the representation of the next committed batch.
+func (s *Subscription) nextBatch(ctx context.Context) ([]byte, error) {
+	for s.catchUp.active {
+		repr, err := s.nextWALBatch()
+		if err != nil || repr != nil {
+			return repr, err
+		}
+	}
+	for {
+		s.d.changes.mu.Lock()
+		if len(s.queue) > 0 {
+			b := s.queue[0]
+			s.queue[0] = capturedBatch{}
+			s.queue = s.queue[1:]
+			s.queuedBytes -= len(b.repr)
+			s.d.changes.mu.Unlock()
+			return b.repr, nil
+		}
+		err := s.err
+		s.d.changes.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		select {
+		case <-s.notify:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-s.d.closedCh:
+			return nil, ErrClosed
+		}
+	}
+}
+
+// nextWALBatch returns the next batch read from the WAL that may contain
+// changes at or after s.from. It returns nil once the catch-up is complete.
+func (s *Subscription) nextWALBatch() ([]byte, error) {
+	for {
+		if s.catchUp.r == nil {
+			if len(s.catchUp.logs) == 0 {
+				// The WAL must contain the log-data record written by startCatchUp.
+				return nil, base.CorruptionErrorf("pebble: WAL ended before seqnum %s", s.catchUp.until)
+			}
+			s.catchUp.r = s.catchUp.logs[0].OpenEncryptedForRead(s.d.opts.keyProvider())
+			s.catchUp.logs = s.catchUp.logs[1:]
+		}
+		rr, _, err := s.catchUp.r.NextRecord()
+		if err == nil {
+			var repr []byte
+			repr, err = io.ReadAll(rr)
+			if err == nil {
+				h, ok := batchrepr.ReadHeader(repr)
+				if !ok {
+					return nil, base.CorruptionErrorf("pebble: corrupt WAL record")
+				}
+				if h.SeqNum >= s.catchUp.until {
+					return nil, s.finishCatchUp()
+				}
+				if h.SeqNum+base.SeqNum(h.Count) <= s.from {
+					continue
+				}
+				return repr, nil
+			}
+		}
+		if !errors.Is(err, io.EOF) && !errors.Is(err, record.ErrUnexpectedEOF) {
+			return nil, errors.Wrap(err, "pebble: reading WAL")
+		}
+		// Continue with the next WAL.
+		err = s.catchUp.r.Close()
+		s.catchUp.r = nil
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Close releases the subscription's resources.
+func (s *Subscription) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	s.d.changes.unregister(s)
+	return s.finishCatchUp()
+}
+
+// capturedBatch is a committed batch awaiting delivery to subscriptions.
+type capturedBatch struct {
+	seqNum base.SeqNum
+	count  uint32
+	repr   []byte
+}
+
+// changeFeed delivers committed batches to subscriptions. Batches are captured
+// in sequence number order while the commit pipeline assigns their sequence
+// numbers, and delivered once they are visible.
+type changeFeed struct {
+	// numSubs is the number of subscriptions, used to skip capturing batches
+	// when there are none.
+	numSubs atomic.Int32
+	mu      sync.Mutex
+	subs    map[*Subscription]struct{}
+	// captured holds the batches that have been sequenced but are not yet
+	// visible, in sequence number order.
+	captured []capturedBatch
+}
+
+// register registers a subscription. commitPipeline.mu must be held.
+func (f *changeFeed) register(s *Subscription) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.subs == nil {
+		f.subs = make(map[*Subscription]struct{})
+	}
+	f.subs[s] = struct{}{}
+	f.numSubs.Add(1)
+}
+
+func (f *changeFeed) unregister(s *Subscription) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.subs, s)
+	f.numSubs.Add(-1)
+	if len(f.subs) == 0 {
+		clear(f.captured)
+		f.captured = f.captured[:0]
+	}
+}
+
+// capture records a batch that has been assigned a sequence number.
+// commitPipeline.mu must be held.
+func (f *changeFeed) capture(b *Batch) {
+	if f.numSubs.Load() == 0 || b.Count() == 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.captured = append(f.captured, capturedBatch{
+		seqNum: b.SeqNum(),
+		count:  b.Count(),
+		repr:   append([]byte(nil), b.Repr()...),
+	})
+}
+
+// deliver queues the captured batches that are visible at visibleSeqNum for
+// each subscription.
+func (f *changeFeed) deliver(visibleSeqNum base.SeqNum) {
+	if f.numSubs.Load() == 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := 0
+	for ; i < len(f.captured); i++ {
+		b := f.captured[i]
+		if b.seqNum+base.SeqNum(b.count) > visibleSeqNum {
+			break
+		}
+		for s := range f.subs {
+			if s.err != nil {
+				continue
+			}
+			s.queue = append(s.queue, b)
+			s.queuedBytes += len(b.repr)
+			if s.queuedBytes > maxSubscriptionBufferBytes {
+				s.err = ErrSubscriptionLagged
+				s.queue = nil
+				s.queuedBytes = 0
+			}
+			select {
+			case s.notify <- struct{}{}:
+			default:
+			}
+		}
+	}
+	if i > 0 {
+		clear(f.captured[:i])
+		f.captured = append(f.captured[:0], f.captured[i:]...)
+	}
+}
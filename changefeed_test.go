@@ -0,0 +1,160 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func formatChange(c Change) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "%s:%s", c.Kind, c.Key)
+	if c.EndKey != nil {
+		fmt.Fprintf(&buf, "-%s", c.EndKey)
+	}
+	if c.Suffix != nil {
+		fmt.Fprintf(&buf, "@%s", c.Suffix)
+	}
+	if c.Value != nil {
+		fmt.Fprintf(&buf, "=%s", c.Value)
+	}
+	return buf.String()
+}
+
+// nextChanges reads n changes from the subscription, checking that their
+// sequence numbers are non-decreasing.
+func nextChanges(t *testing.T, s *Subscription, n int) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var res []string
+	var last base.SeqNum
+	for i := 0; i < n; i++ {
+		c, err := s.Next(ctx)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, c.SeqNum, last)
+		last = c.SeqNum
+		res = append(res, formatChange(c))
+	}
+	return strings.Join(res, " ")
+}
+
+func expectNoChange(t *testing.T, s *Subscription) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	c, err := s.Next(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded, "unexpected change %s", formatChange(c))
+}
+
+func TestSubscribe(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem(), Merger: DefaultMerger})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// Changes committed before the subscription are read from the WAL.
+	require.NoError(t, d.Set([]byte("a"), []byte("1"), nil))
+	start := d.mu.versions.visibleSeqNum.Load()
+	require.NoError(t, d.Set([]byte("b"), []byte("2"), nil))
+
+	all, err := d.Subscribe(KeyRange{}, start)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, all.Close()) }()
+	bounded, err := d.Subscribe(KeyRange{Start: []byte("c"), End: []byte("m")}, 0)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, bounded.Close()) }()
+
+	b := d.NewBatch()
+	require.NoError(t, b.Set([]byte("c"), []byte("3"), nil))
+	require.NoError(t, b.Delete([]byte("b"), nil))
+	require.NoError(t, b.Merge([]byte("d"), []byte("4"), nil))
+	require.NoError(t, b.LogData([]byte("ignored"), nil))
+	require.NoError(t, b.DeleteRange([]byte("a"), []byte("e"), nil))
+	require.NoError(t, b.RangeKeySet([]byte("k"), []byte("p"), []byte("5"), []byte("v"), nil))
+	require.NoError(t, b.RangeKeyUnset([]byte("q"), []byte("r"), []byte("6"), nil))
+	require.NoError(t, b.RangeKeyDelete([]byte("s"), []byte("t"), nil))
+	require.NoError(t, b.Commit(nil))
+	require.NoError(t, d.Set([]byte("z"), []byte("7"), nil))
+
+	require.Equal(t,
+		"SET:b=2 SET:c=3 DEL:b MERGE:d=4 RANGEDEL:a-e RANGEKEYSET:k-p@5=v RANGEKEYUNSET:q-r@6 RANGEKEYDEL:s-t SET:z=7",
+		nextChanges(t, all, 9))
+	expectNoChange(t, all)
+	require.Equal(t, "SET:c=3 MERGE:d=4 RANGEDEL:a-e RANGEKEYSET:k-p@5=v", nextChanges(t, bounded, 4))
+	expectNoChange(t, bounded)
+
+	// Changes committed while a subscriber waits wake it up.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.Equal(t, "SET:y=8", nextChanges(t, all, 1))
+	}()
+	require.NoError(t, d.Set([]byte("y"), []byte("8"), nil))
+	wg.Wait()
+
+	// Once flushed, the changes are no longer retained.
+	require.NoError(t, d.Flush())
+	_, err = d.Subscribe(KeyRange{}, start)
+	require.ErrorIs(t, err, ErrChangesUnavailable)
+	s, err := d.Subscribe(KeyRange{}, d.mu.versions.visibleSeqNum.Load())
+	require.NoError(t, err)
+	require.NoError(t, d.Set([]byte("x"), []byte("9"), nil))
+	require.Equal(t, "SET:x=9", nextChanges(t, s, 1))
+	require.NoError(t, s.Close())
+	_, err = s.Next(context.Background())
+	require.ErrorIs(t, err, ErrClosed)
+}
+
+// TestSubscribeConcurrent subscribes while batches are concurrently committed
+// and verifies that every change is delivered exactly once, in order.
+func TestSubscribeConcurrent(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	const writers, writes = 4, 200
+	start := d.mu.versions.visibleSeqNum.Load()
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < writes; i++ {
+				require.NoError(t, d.Set([]byte(fmt.Sprintf("%d-%03d", w, i)), nil, nil))
+			}
+		}()
+	}
+	// Subscribe midway through the writes, from the start.
+	time.Sleep(time.Millisecond)
+	s, err := d.Subscribe(KeyRange{}, start)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, s.Close()) }()
+	wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	next := make([]int, writers)
+	seqNum := start
+	for i := 0; i < writers*writes; i++ {
+		c, err := s.Next(ctx)
+		require.NoError(t, err)
+		require.Equal(t, seqNum, c.SeqNum)
+		seqNum++
+		var w, n int
+		_, err = fmt.Sscanf(string(c.Key), "%d-%d", &w, &n)
+		require.NoError(t, err)
+		require.Equal(t, next[w], n)
+		next[w]++
+	}
+	expectNoChange(t, s)
+}
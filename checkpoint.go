@@ -12,6 +12,7 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/errors/oserror"
 	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/internal/encryption"
 	"github.com/chris124567/pebble/internal/manifest"
 	"github.com/chris124567/pebble/record"
 	"github.com/chris124567/pebble/vfs"
@@ -482,18 +483,25 @@ func (d *DB) writeCheckpointManifest(
 			return err
 		}
 		defer src.Close()
+		srcReader, err := encryption.OpenFile(src, d.opts.keyProvider())
+		if err != nil {
+			return err
+		}
 
 		dst, err := fs.Create(destPath, vfs.WriteCategoryUnspecified)
 		if err != nil {
 			return err
 		}
+		if dst, err = createEncryptedFile(dst, d.opts.keyProvider()); err != nil {
+			return err
+		}
 		defer dst.Close()
 
 		// Copy all existing records. We need to copy at the record level in case we
 		// need to append another record with the excluded files (we cannot simply
 		// append a record after a raw data copy; see
 		// https://github.com/cockroachdb/cockroach/issues/100935).
-		r := record.NewReader(&io.LimitedReader{R: src, N: manifestSize}, manifestFileNum)
+		r := record.NewReader(&io.LimitedReader{R: srcReader, N: manifestSize}, manifestFileNum)
 		w := record.NewWriter(dst)
 		for {
 			rr, err := r.Next()
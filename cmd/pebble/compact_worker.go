@@ -0,0 +1,144 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+
+	"github.com/chris124567/pebble"
+	"github.com/chris124567/pebble/cockroachkvs"
+	"github.com/chris124567/pebble/objstorage/remote"
+	"github.com/chris124567/pebble/vfs"
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+var compactWorkerConfig struct {
+	options       string
+	sharedStorage string
+	locator       string
+	s3            remote.S3Options
+}
+
+var compactWorkerCmd = &cobra.Command{
+	Use:   "compact-worker",
+	Short: "run a compaction offloaded by a DB",
+	Long: `
+Run a compaction offloaded by a DB through Options.Experimental.RemoteCompactor.
+The compaction job is read from stdin, and the description of its outputs is
+written to stdout.
+
+The input sstables are read from, and the output sstables written to, the
+shared storage given by --shared-storage or by the --s3-* flags, which must be
+the storage that the DB registers under --locator. S3 credentials are read from
+the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment
+variables.
+
+The options of the compaction, such as the comparer, merger and per-level
+writer options, are read from the DB's OPTIONS file given by --options.
+`,
+	Args: cobra.NoArgs,
+	Run:  runCompactWorker,
+}
+
+func init() {
+	compactWorkerCmd.Flags().StringVar(
+		&compactWorkerConfig.options, "options", "", "path to the OPTIONS file of the DB")
+	compactWorkerCmd.Flags().StringVar(
+		&compactWorkerConfig.sharedStorage, "shared-storage", "", "path to local shared storage")
+	compactWorkerCmd.Flags().StringVar(
+		&compactWorkerConfig.locator, "locator", "", "locator of the shared storage")
+	compactWorkerCmd.Flags().StringVar(
+		&compactWorkerConfig.s3.Endpoint, "s3-endpoint", "", "endpoint of the S3 shared storage")
+	compactWorkerCmd.Flags().StringVar(
+		&compactWorkerConfig.s3.Region, "s3-region", "", "region of the S3 shared storage")
+	compactWorkerCmd.Flags().StringVar(
+		&compactWorkerConfig.s3.Bucket, "s3-bucket", "", "bucket of the S3 shared storage")
+	compactWorkerCmd.Flags().StringVar(
+		&compactWorkerConfig.s3.Prefix, "s3-prefix", "", "object name prefix of the S3 shared storage")
+	compactWorkerCmd.Flags().BoolVar(
+		&compactWorkerConfig.s3.UsePathStyle, "s3-path-style", false, "use path-style S3 addressing")
+}
+
+func runCompactWorker(cmd *cobra.Command, args []string) {
+	opts, err := compactWorkerOptions()
+	if err != nil {
+		log.Fatal(err)
+	}
+	job, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		log.Fatal(err)
+	}
+	result, err := pebble.RunRemoteCompaction(context.Background(), opts, job)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := os.Stdout.Write(result); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func compactWorkerOptions() (*pebble.Options, error) {
+	cfg := &compactWorkerConfig
+	opts := &pebble.Options{}
+	if cfg.options != "" {
+		data, err := os.ReadFile(cfg.options)
+		if err != nil {
+			return nil, err
+		}
+		hooks := &pebble.ParseHooks{
+			NewComparer: func(name string) (*pebble.Comparer, error) {
+				if name == cockroachkvs.Comparer.Name {
+					return &cockroachkvs.Comparer, nil
+				}
+				return nil, errors.Errorf("unknown comparer %q", name)
+			},
+			NewMerger: func(name string) (*pebble.Merger, error) {
+				if name == fauxMVCCMerger.Name {
+					return fauxMVCCMerger, nil
+				}
+				return nil, errors.Errorf("unknown merger %q", name)
+			},
+			NewKeySchema: func(name string) (pebble.KeySchema, error) {
+				for _, s := range []*pebble.KeySchema{&cockroachkvs.KeySchema, &testKeysSchema, &defaultSchema} {
+					if s.Name == name {
+						return *s, nil
+					}
+				}
+				return pebble.KeySchema{}, errors.Errorf("unknown key schema %q", name)
+			},
+			SkipUnknown: func(name, value string) bool { return true },
+		}
+		if err := opts.Parse(string(data), hooks); err != nil {
+			return nil, err
+		}
+	}
+
+	var storage remote.Storage
+	switch {
+	case cfg.sharedStorage != "" && cfg.s3.Bucket != "":
+		return nil, errors.New("only one of --shared-storage and --s3-bucket can be set")
+	case cfg.sharedStorage != "":
+		storage = remote.NewLocalFS(cfg.sharedStorage, vfs.Default)
+	case cfg.s3.Bucket != "":
+		s3 := cfg.s3
+		s3.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		s3.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		s3.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+		var err error
+		if storage, err = remote.NewS3(s3); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("one of --shared-storage and --s3-bucket must be set")
+	}
+	opts.Experimental.RemoteStorage = remote.MakeSimpleFactory(map[remote.Locator]remote.Storage{
+		remote.Locator(cfg.locator): storage,
+	})
+	return opts, nil
+}
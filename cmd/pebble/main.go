@@ -77,7 +77,7 @@ func main() {
 	rootCmd.SetVersionTemplate(`{{printf "%s" .Short}}
 {{printf "%s" .Version}}
 `)
-	rootCmd.AddCommand(benchCmd)
+	rootCmd.AddCommand(benchCmd, compactWorkerCmd)
 
 	t := tool.New(
 		tool.Comparers(&cockroachkvs.Comparer, testkeys.Comparer),
@@ -22,6 +22,7 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/chris124567/pebble"
 	"github.com/chris124567/pebble/bloom"
+	"github.com/chris124567/pebble/fusefilter"
 	"github.com/chris124567/pebble/cockroachkvs"
 	"github.com/chris124567/pebble/internal/base"
 	"github.com/chris124567/pebble/replay"
@@ -303,6 +304,8 @@ func (c *replayConfig) parseHooks() *pebble.ParseHooks {
 				return nil, nil
 			case "rocksdb.BuiltinBloomFilter":
 				return bloom.FilterPolicy(10), nil
+			case "pebble.BinaryFuseFilter":
+				return fusefilter.FilterPolicy(8), nil
 			default:
 				return nil, errors.Errorf("invalid filter policy name %q", name)
 			}
@@ -140,6 +140,19 @@ type commitEnv struct {
 	// the memtable the batch should be applied to. Serial execution enforced by
 	// commitPipeline.mu.
 	write func(b *Batch, wg *sync.WaitGroup, err *error) (*memTable, error)
+	// Optional. Validate the read set of the transaction whose writes are held
+	// in the batch. A batch failing validation is not committed. Serial
+	// execution enforced by commitPipeline.mu.
+	validateTxn func(t *Txn) error
+	// Optional. Record the writes of a batch that has been assigned a
+	// sequence number. If opaque is true, the batch only allocated sequence
+	// numbers on behalf of an operation such as ingestion. Serial execution
+	// enforced by commitPipeline.mu.
+	trackWrites func(b *Batch, opaque bool)
+	// Optional. Capture the mutations of a batch that has been assigned a
+	// sequence number for delivery to change subscribers. Serial execution
+	// enforced by commitPipeline.mu.
+	captureChanges func(b *Batch)
 }
 
 // A commitPipeline manages the stages of committing a set of mutations
@@ -410,6 +423,10 @@ func (p *commitPipeline) AllocateSeqNum(
 		runtime.Gosched()
 	}
 
+	if p.env.trackWrites != nil {
+		p.env.trackWrites(b, true /* opaque */)
+	}
+
 	// Invoke the prepare callback. Note the lack of error reporting. Even if the
 	// callback internally fails, the sequence number needs to be published in
 	// order to allow the commit pipeline to proceed.
@@ -433,6 +450,25 @@ func (p *commitPipeline) prepare(b *Batch, syncWAL bool, noSyncWait bool) (*memT
 	}
 	var syncWG *sync.WaitGroup
 	var syncErr *error
+
+	p.mu.Lock()
+
+	// Validate a transaction's read set while holding commitPipeline.mu, so
+	// that no other batch can be sequenced between validation and the
+	// assignment of the batch's sequence number.
+	if b.txn != nil && p.env.validateTxn != nil {
+		if err := p.env.validateTxn(b.txn); err != nil {
+			p.mu.Unlock()
+			// The batch was never enqueued, so release the semaphores acquired
+			// by Commit.
+			<-p.commitQueueSem
+			if syncWAL {
+				<-p.logSyncQSem
+			}
+			return nil, err
+		}
+	}
+
 	switch {
 	case !syncWAL:
 		// Only need to wait for the publish.
@@ -452,8 +488,6 @@ func (p *commitPipeline) prepare(b *Batch, syncWAL bool, noSyncWait bool) (*memT
 		b.commit.Add(2)
 	}
 
-	p.mu.Lock()
-
 	// Enqueue the batch in the pending queue. Note that while the pending queue
 	// is lock-free, we want the order of batches to be the same as the sequence
 	// number order.
@@ -463,6 +497,12 @@ func (p *commitPipeline) prepare(b *Batch, syncWAL bool, noSyncWait bool) (*memT
 	// here to handle concurrent reads of logSeqNum. commitPipeline.mu provides
 	// mutual exclusion for other goroutines writing to logSeqNum.
 	b.setSeqNum(p.env.logSeqNum.Add(base.SeqNum(n)) - base.SeqNum(n))
+	if p.env.trackWrites != nil {
+		p.env.trackWrites(b, false /* opaque */)
+	}
+	if p.env.captureChanges != nil {
+		p.env.captureChanges(b)
+	}
 
 	// Write the data to the WAL.
 	mem, err := p.env.write(b, syncWG, syncErr)
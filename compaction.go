@@ -21,13 +21,13 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/chris124567/pebble/internal/base"
 	"github.com/chris124567/pebble/internal/compact"
+	"github.com/chris124567/pebble/internal/encryption"
 	"github.com/chris124567/pebble/internal/keyspan"
 	"github.com/chris124567/pebble/internal/keyspan/keyspanimpl"
 	"github.com/chris124567/pebble/internal/manifest"
 	"github.com/chris124567/pebble/internal/sstableinternal"
 	"github.com/chris124567/pebble/objstorage"
 	"github.com/chris124567/pebble/objstorage/objstorageprovider/objiotracing"
-	"github.com/chris124567/pebble/objstorage/remote"
 	"github.com/chris124567/pebble/sstable"
 	"github.com/chris124567/pebble/sstable/blob"
 	"github.com/chris124567/pebble/sstable/block"
@@ -145,8 +145,24 @@ const (
 	compactionKindElisionOnly
 	compactionKindRead
 	compactionKindTombstoneDensity
+	// compactionKindExpired denotes a compaction of a table with a large
+	// fraction of expired values. See Options.TTL.
+	compactionKindExpired
 	compactionKindRewrite
 	compactionKindIngestedFlushable
+	// compactionKindTiering denotes a copy compaction that moves a cold local
+	// table to the remote storage configured by Options.Experimental.Tiering,
+	// within the same level. See TieringOptions.
+	compactionKindTiering
+	// compactionKindUniversal denotes a compaction of sorted runs picked by the
+	// universal compaction picker. See UniversalCompactionOptions.
+	compactionKindUniversal
+	// compactionKindFIFO denotes a compaction that drops its input tables, picked
+	// by the FIFO compaction picker. See FIFOCompactionOptions.
+	compactionKindFIFO
+	// compactionKindPolicy denotes a compaction picked by the CompactionPolicy
+	// configured by Options.Experimental.CompactionPolicy.
+	compactionKindPolicy
 )
 
 func (k compactionKind) String() string {
@@ -165,12 +181,22 @@ func (k compactionKind) String() string {
 		return "read"
 	case compactionKindTombstoneDensity:
 		return "tombstone-density"
+	case compactionKindExpired:
+		return "expired"
 	case compactionKindRewrite:
 		return "rewrite"
 	case compactionKindIngestedFlushable:
 		return "ingested-flushable"
 	case compactionKindCopy:
 		return "copy"
+	case compactionKindTiering:
+		return "tiering"
+	case compactionKindUniversal:
+		return "universal"
+	case compactionKindFIFO:
+		return "fifo"
+	case compactionKindPolicy:
+		return "policy"
 	}
 	return "?"
 }
@@ -255,6 +281,9 @@ type compaction struct {
 	// maxOverlapBytes is the maximum number of bytes of overlap allowed for a
 	// single output table with the tables in the grandparent level.
 	maxOverlapBytes uint64
+	// inheritCreationTime is true if the output tables are considered created
+	// at the creation time of the oldest input table.
+	inheritCreationTime bool
 
 	// flushing contains the flushables (aka memtables) that are being flushed.
 	flushing flushableList
@@ -302,6 +331,10 @@ type compaction struct {
 	pickerMetrics pickedCompactionMetrics
 
 	grantHandle CompactionGrantHandle
+
+	// remoteOutputs is set when the compaction is run by a remote compaction
+	// worker on behalf of a DB; see RunRemoteCompaction.
+	remoteOutputs *remoteCompactionOutputs
 }
 
 // inputLargestSeqNumAbsolute returns the maximum LargestSeqNumAbsolute of any
@@ -316,6 +349,17 @@ func (c *compaction) inputLargestSeqNumAbsolute() base.SeqNum {
 	return seqNum
 }
 
+// inputCreationTime returns the creation time of the oldest input table.
+func (c *compaction) inputCreationTime() int64 {
+	creationTime := int64(math.MaxInt64)
+	for _, cl := range c.inputs {
+		for m := range cl.files.All() {
+			creationTime = min(creationTime, m.CreationTime)
+		}
+	}
+	return creationTime
+}
+
 func (c *compaction) makeInfo(jobID JobID) CompactionInfo {
 	info := CompactionInfo{
 		JobID:       int(jobID),
@@ -411,6 +455,7 @@ func newCompaction(
 		c.cmp, c.version, pc.l0Organizer, c.outputLevel.level, base.UserKeyBoundsFromInternal(c.smallest, c.largest),
 	)
 	c.kind = pc.kind
+	c.inheritCreationTime = pc.inheritCreationTime
 
 	// In addition to the default compaction, we also check whether a tombstone density compaction can be optimized into
 	// a move compaction. However, we want to avoid performing a move compaction into the lowest level, since the goal
@@ -418,7 +463,7 @@ func newCompaction(
 	//
 	// Tombstone density compaction is meant to address cases where tombstones don't reclaim much space but are still
 	// expensive to scan over. We can only remove the tombstones once there's nothing at all underneath them.
-	if (c.kind == compactionKindDefault || (c.kind == compactionKindTombstoneDensity && c.outputLevel.level != numLevels-1)) &&
+	if (c.kind == compactionKindDefault || c.kind == compactionKindUniversal || c.kind == compactionKindPolicy || (c.kind == compactionKindTombstoneDensity && c.outputLevel.level != numLevels-1)) &&
 		c.outputLevel.files.Empty() && !c.hasExtraLevelData() &&
 		c.startLevel.files.Len() == 1 && c.grandparents.AggregateSizeSum() <= c.maxOverlapBytes {
 		// This compaction can be converted into a move or copy from one level
@@ -437,15 +482,26 @@ func newCompaction(
 		//
 		// 1) The source file is a virtual sstable
 		// 2) The existing file `meta` is on non-remote storage
-		// 3) The output level prefers shared storage
-		mustCopy := !isRemote && remote.ShouldCreateShared(opts.Experimental.CreateOnShared, c.outputLevel.level)
-		if mustCopy {
+		// 3) The span policies of the file prefer shared storage in the
+		//    output level
+		//
+		// The file is also rewritten if its span policies disagree on its
+		// placement (or can't be determined), so that the outputs are split
+		// at the span boundaries.
+		preferShared, ok, err := opts.spanPreferSharedStorage(meta.UserKeyBounds(), c.outputLevel.level)
+		switch {
+		case err != nil:
+			// Rewriting the file consults the span policies again, and fails
+			// the compaction if the error persists.
+			opts.Logger.Errorf("pebble: span policies of %s: %v", meta.TableNum, err)
+		case !ok:
+		case !isRemote && preferShared:
 			// If the source is virtual, it's best to just rewrite the file as all
 			// conditions in the above comment are met.
 			if !meta.Virtual {
 				c.kind = compactionKindCopy
 			}
-		} else {
+		default:
 			c.kind = compactionKindMove
 		}
 	}
@@ -672,16 +728,16 @@ func newFlush(
 }
 
 func (c *compaction) hasExtraLevelData() bool {
-	if len(c.extraLevels) == 0 {
-		// not a multi level compaction
-		return false
-	} else if c.extraLevels[0].files.Empty() {
-		// a multi level compaction without data in the intermediate input level;
-		// e.g. for a multi level compaction with levels 4,5, and 6, this could
-		// occur if there is no files to compact in 5, or in 5 and 6 (i.e. a move).
-		return false
+	// A multi level compaction may have no data in the intermediate input
+	// levels; e.g. for a multi level compaction with levels 4,5, and 6, this
+	// could occur if there is no files to compact in 5, or in 5 and 6 (i.e. a
+	// move).
+	for _, cl := range c.extraLevels {
+		if !cl.files.Empty() {
+			return true
+		}
 	}
-	return true
+	return false
 }
 
 // errorOnUserKeyOverlap returns an error if the last two written sstables in
@@ -752,11 +808,7 @@ func (c *compaction) newInputIters(
 				}
 			}
 		}
-		if len(c.extraLevels) > 0 {
-			if len(c.extraLevels) > 1 {
-				panic("n>2 multi level compaction not implemented yet")
-			}
-			interLevel := c.extraLevels[0]
+		for _, interLevel := range c.extraLevels {
 			err := manifest.CheckOrdering(c.cmp, c.formatKey,
 				manifest.Level(interLevel.level), interLevel.files.Iter())
 			if err != nil {
@@ -1594,6 +1646,7 @@ func (d *DB) flush1() (bytesFlushed uint64, err error) {
 		d.mu.snapshots.cumulativePinnedCount += stats.CumulativePinnedKeys
 		d.mu.snapshots.cumulativePinnedSize += stats.CumulativePinnedSize
 		d.mu.versions.metrics.Keys.MissizedTombstonesCount += stats.CountMissizedDels
+		d.mu.versions.metrics.Keys.ExpiredValuesCount += stats.CountExpired
 	}
 
 	d.clearCompactingState(c, err != nil)
@@ -1702,6 +1755,28 @@ func (d *DB) maybeTransitionSnapshotsToFileOnlyLocked() {
 	}
 }
 
+// runCompactionChecks periodically scans for cold tables to move to remote
+// storage (see TieringOptions) and schedules compactions, so that such tables
+// are moved while the DB is otherwise idle, and tables which grow too old are
+// dropped (see FIFOCompactionOptions). It runs until the DB is closed.
+func (d *DB) runCompactionChecks(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.closedCh:
+			return
+		case <-ticker.C:
+			if d.opts.Experimental.Tiering != nil && !d.opts.DisableAutomaticCompactions {
+				d.scanTieringCandidates(tieringScanBatch)
+			}
+			d.mu.Lock()
+			d.maybeScheduleCompaction()
+			d.mu.Unlock()
+		}
+	}
+}
+
 // maybeScheduleCompactionAsync should be used when
 // we want to possibly schedule a compaction, but don't
 // want to eat the cost of running maybeScheduleCompaction.
@@ -1828,7 +1903,12 @@ func (d *DB) pickAnyCompaction(env compactionEnv) (pc *pickedCompaction) {
 		return pc
 	}
 	if !d.opts.DisableAutomaticCompactions {
-		return d.mu.versions.picker.pickAutoNonScore(env)
+		if pc = d.mu.versions.picker.pickAutoNonScore(env); pc != nil {
+			return pc
+		}
+		// Tiering compactions have the lowest priority: they do not affect the
+		// shape of the LSM.
+		return d.pickTieringCompaction(env)
 	}
 	return nil
 }
@@ -1918,7 +1998,8 @@ func (d *DB) GetWaitingCompaction() (bool, WaitingCompaction) {
 		}
 	}
 	// INVARIANT: pc != nil and is in the cache.
-	return true, makeWaitingCompaction(pc.manualID > 0, pc.kind, pc.score)
+	priority := spanPriority(d.cmp, d.opts.Experimental.SpanPriorities, pc.userKeyBounds())
+	return true, makeWaitingCompaction(pc.manualID > 0, pc.kind, priority, pc.score)
 }
 
 // GetAllowedWithoutPermission implements DBForCompaction (it is called by the
@@ -2528,9 +2609,12 @@ func (d *DB) compact1(c *compaction, errChannel chan error) (err error) {
 			e := &ve.NewTables[i]
 			info.Output.Tables = append(info.Output.Tables, e.Meta.TableInfo())
 		}
+		info.FilterRemovedKeys = stats.CountFilterRemoved
+		info.FilterChangedValues = stats.CountFilterChanged
 		d.mu.snapshots.cumulativePinnedCount += stats.CumulativePinnedKeys
 		d.mu.snapshots.cumulativePinnedSize += stats.CumulativePinnedSize
 		d.mu.versions.metrics.Keys.MissizedTombstonesCount += stats.CountMissizedDels
+		d.mu.versions.metrics.Keys.ExpiredValuesCount += stats.CountExpired
 	}
 
 	// NB: clearing compacting state must occur before updating the read state;
@@ -2558,10 +2642,11 @@ func (d *DB) compact1(c *compaction, errChannel chan error) (err error) {
 // runCopyCompaction runs a copy compaction where a new FileNum is created that
 // is a byte-for-byte copy of the input file or span thereof in some cases. This
 // is used in lieu of a move compaction when a file is being moved across the
-// local/remote storage boundary. It could also be used in lieu of a rewrite
-// compaction as part of a Download() call, which allows copying only a span of
-// the external file, provided the file does not contain range keys or value
-// blocks (see sstable.CopySpan).
+// local/remote storage boundary, including by tiering (compactionKindTiering)
+// and when a Download() call recalls a tiered file to local storage. It could
+// also be used in lieu of a rewrite compaction as part of a Download() call,
+// which allows copying only a span of the external file, provided the file
+// does not contain range keys or value blocks (see sstable.CopySpan).
 //
 // d.mu must be held when calling this method. The mutex will be released when
 // doing IO.
@@ -2587,8 +2672,21 @@ func (d *DB) runCopyCompaction(
 		return nil, compact.Stats{}, err
 	}
 	if !objMeta.IsExternal() {
-		if objMeta.IsRemote() || !remote.ShouldCreateShared(d.opts.Experimental.CreateOnShared, c.outputLevel.level) {
-			panic("pebble: scheduled a copy compaction that is not actually moving files to shared storage")
+		switch {
+		case c.kind == compactionKindTiering:
+			if objMeta.IsRemote() {
+				panic("pebble: scheduled a tiering compaction of a remote file")
+			}
+		case objMeta.IsRemote():
+			if !c.isDownload {
+				panic("pebble: scheduled a copy compaction of a remote file that is not a download")
+			}
+		default:
+			if preferShared, _, err := d.opts.spanPreferSharedStorage(inputMeta.UserKeyBounds(), c.outputLevel.level); err != nil {
+				return nil, compact.Stats{}, err
+			} else if !preferShared {
+				panic("pebble: scheduled a copy compaction that is not actually moving files to shared storage")
+			}
 		}
 		// Note that based on logic in the compaction picker, we're guaranteed
 		// inputMeta.Virtual is nil.
@@ -2625,9 +2723,15 @@ func (d *DB) runCopyCompaction(
 		// We will update this size later after we produce the new backing file.
 		newMeta.InitVirtualBacking(base.DiskFileNum(newMeta.TableNum), inputMeta.FileBacking.Size)
 	} else {
-		// local -> shared copy. New file is guaranteed to not be virtual.
+		// local -> shared or shared -> local copy. New file is guaranteed to not
+		// be virtual.
 		newMeta.InitPhysicalBacking()
 	}
+	if objMeta.IsRemote() && !objMeta.IsExternal() {
+		// The file is recalled to local storage by a download; restart its age
+		// so that tiering does not immediately move it back.
+		newMeta.CreationTime = d.opts.Experimental.Tiering.Now().Unix()
+	}
 
 	// Before dropping the db mutex, grab a ref to the current version. This
 	// prevents any concurrent excises from deleting files that this compaction
@@ -2663,16 +2767,29 @@ func (d *DB) runCopyCompaction(
 			}
 		}()
 
+		// The copy can't be split at span boundaries, so if the span policies
+		// of the table disagree, it is placed according to its level.
+		preferShared, _, err := d.opts.spanPreferSharedStorage(newMeta.UserKeyBounds(), c.outputLevel.level)
+		if err != nil {
+			return nil, compact.Stats{}, err
+		}
 		w, _, err := d.objProvider.Create(
 			ctx, base.FileTypeTable, newMeta.FileBacking.DiskFileNum,
 			objstorage.CreateOptions{
-				PreferSharedStorage: remote.ShouldCreateShared(d.opts.Experimental.CreateOnShared, c.outputLevel.level),
+				PreferSharedStorage: preferShared,
 			},
 		)
 		if err != nil {
 			return nil, compact.Stats{}, err
 		}
 		deleteOnExit = true
+		if cipher, err := d.fileKeys.create(newMeta.FileBacking.DiskFileNum); err != nil {
+			w.Abort()
+			return nil, compact.Stats{}, err
+		} else if cipher != nil {
+			w = encryption.NewWritable(w, cipher)
+			newMeta.FileBacking.EncryptionKey = d.fileKeys.encodedKey(newMeta.FileBacking.DiskFileNum)
+		}
 
 		start, end := newMeta.Smallest(), newMeta.Largest()
 		if newMeta.SyntheticPrefixAndSuffix.HasPrefix() {
@@ -2718,13 +2835,29 @@ func (d *DB) runCopyCompaction(
 		newMeta.FileBacking.Size = wrote
 		newMeta.Size = wrote
 	} else {
-		_, err := d.objProvider.LinkOrCopyFromLocal(context.TODO(), d.opts.FS,
-			d.objProvider.Path(objMeta), base.FileTypeTable, newMeta.FileBacking.DiskFileNum,
-			objstorage.CreateOptions{PreferSharedStorage: true})
+		var err error
+		if objMeta.IsRemote() {
+			// shared -> local copy.
+			err = d.copyRemoteTableToLocal(inputMeta.FileBacking.DiskFileNum, newMeta.FileBacking.DiskFileNum)
+		} else {
+			createOpts := objstorage.CreateOptions{PreferSharedStorage: true}
+			if c.kind == compactionKindTiering {
+				createOpts.ForceSharedStorage = true
+				createOpts.SharedLocator = d.opts.Experimental.Tiering.Locator
+			}
+			_, err = d.objProvider.LinkOrCopyFromLocal(context.TODO(), d.opts.FS,
+				d.objProvider.Path(objMeta), base.FileTypeTable, newMeta.FileBacking.DiskFileNum,
+				createOpts)
+		}
 		if err != nil {
 			return nil, compact.Stats{}, err
 		}
 		deleteOnExit = true
+		// The copy is encrypted with the same data key as the original.
+		if err := d.fileKeys.add(newMeta.FileBacking.DiskFileNum, inputMeta.FileBacking.EncryptionKey); err != nil {
+			return nil, compact.Stats{}, err
+		}
+		newMeta.FileBacking.EncryptionKey = inputMeta.FileBacking.EncryptionKey
 	}
 	ve.NewTables = []newTableEntry{{
 		Level: c.outputLevel.level,
@@ -3008,7 +3141,9 @@ func (d *DB) runCompaction(
 		return d.runDeleteOnlyCompaction(jobID, c, snapshots)
 	case compactionKindMove:
 		return d.runMoveCompaction(jobID, c)
-	case compactionKindCopy:
+	case compactionKindFIFO:
+		return d.runFIFOCompaction(jobID, c)
+	case compactionKindCopy, compactionKindTiering:
 		return d.runCopyCompaction(jobID, c)
 	case compactionKindIngestedFlushable:
 		panic("pebble: runCompaction cannot handle compactionKindIngestedFlushable.")
@@ -3045,10 +3180,16 @@ func (d *DB) runCompaction(
 	// Options and the compaction inputs.
 	valueSeparation := c.getValueSeparation(jobID, c, tableFormat)
 
-	result := d.compactAndWrite(jobID, c, snapshots, tableFormat, valueSeparation)
+	result, ok := d.maybeRunRemoteCompaction(jobID, c, snapshots, tableFormat, valueSeparation)
+	if !ok {
+		result = d.compactAndWrite(jobID, c, snapshots, tableFormat, valueSeparation)
+	}
 	if result.Err == nil {
 		ve, result.Err = c.makeVersionEdit(result)
 	}
+	if result.Err == nil {
+		d.fileKeys.setEncryptionKeys(ve)
+	}
 	if result.Err != nil {
 		// Delete any created tables or blob files.
 		obsoleteFiles := manifest.ObsoleteFiles{
@@ -3145,7 +3286,7 @@ func (d *DB) compactAndWrite(
 	c.allowedZeroSeqNum = c.allowZeroSeqNum()
 	cfg := compact.IterConfig{
 		Comparer:         c.comparer,
-		Merge:            d.merge,
+		Merge:            d.storedMerge(),
 		TombstoneElision: c.delElision,
 		RangeKeyElision:  c.rangeKeyElision,
 		Snapshots:        snapshots,
@@ -3170,6 +3311,13 @@ func (d *DB) compactAndWrite(
 			})
 		},
 	}
+	if d.opts.TTL != nil {
+		cfg.ValueExpiry = ttlValueExpiry
+		cfg.Now = d.ttlNow()
+	}
+	if c.kind != compactionKindFlush && c.outputLevel != nil {
+		cfg.Filter = d.compactionFilterFunc(c.outputLevel.level)
+	}
 	iter := compact.NewIter(cfg, pointIter, rangeDelIter, rangeKeyIter)
 
 	runnerCfg := compact.RunnerConfig{
@@ -3200,18 +3348,33 @@ func (d *DB) compactAndWrite(
 			if err != nil {
 				return runner.Finish().WithError(err)
 			}
+			if ks := d.opts.private.keyspaces; ks != nil {
+				// Never write an sstable spanning multiple keyspaces.
+				spanPolicyEndKey = ks.clampSpanPolicyEnd(d.cmp, firstKey, spanPolicyEndKey)
+			}
 			spanPolicyValid = true
 		}
 
 		writerOpts := d.opts.MakeWriterOptions(c.outputLevel.level, tableFormat)
-		if spanPolicy.DisableValueSeparationBySuffix {
-			writerOpts.DisableValueBlocks = true
+		if ks := d.opts.private.keyspaces; ks != nil {
+			ks.applyLevelOptions(d.opts, &writerOpts, c.outputLevel.level, firstKey)
+		}
+		spanPolicy.applyWriterOptions(&writerOpts)
+		if writerOpts.Compression == AdaptiveCompression {
+			writerOpts.AdaptiveCompressionModel = d.compressionModels[c.outputLevel.level]
 		}
 		vSep := valueSeparation
 		if spanPolicy.ValueStoragePolicy == ValueStorageLowReadLatency {
 			vSep = compact.NeverSeparateValues{}
 		}
-		objMeta, tw, err := d.newCompactionOutputTable(jobID, c, writerOpts)
+		preferShared := spanPolicy.preferSharedStorage(d.opts.Experimental.CreateOnShared, c.outputLevel.level)
+		if c.remoteOutputs != nil {
+			// The DB decided to place the outputs of the remote compaction on
+			// shared storage, whatever the CreateOnShared of the worker; only
+			// the span policies can still object.
+			preferShared = spanPolicy.TableStoragePolicy != TableStorageLocal
+		}
+		objMeta, tw, err := d.newCompactionOutputTable(jobID, c, writerOpts, preferShared)
 		if err != nil {
 			return runner.Finish().WithError(err)
 		}
@@ -3255,8 +3418,8 @@ func (c *compaction) makeVersionEdit(result compact.Result) (*versionEdit, error
 	if c.flushing != nil {
 		outputMetrics.BlobBytesFlushed = result.Stats.CumulativeBlobFileSize
 	}
-	if len(c.extraLevels) > 0 {
-		outputMetrics.TableBytesIn += c.extraLevels[0].files.TableSizeSum()
+	for _, cl := range c.extraLevels {
+		outputMetrics.TableBytesIn += cl.files.TableSizeSum()
 	}
 	outputMetrics.TableBytesRead += outputMetrics.TableBytesIn
 
@@ -3264,14 +3427,20 @@ func (c *compaction) makeVersionEdit(result compact.Result) (*versionEdit, error
 	if len(c.flushing) == 0 && c.metrics[c.startLevel.level] == nil {
 		c.metrics[c.startLevel.level] = &LevelMetrics{}
 	}
+	for _, cl := range c.extraLevels {
+		c.metrics[cl.level] = &LevelMetrics{}
+	}
 	if len(c.extraLevels) > 0 {
-		c.metrics[c.extraLevels[0].level] = &LevelMetrics{}
 		outputMetrics.MultiLevel.TableBytesInTop = startLevelBytes
 		outputMetrics.MultiLevel.TableBytesIn = outputMetrics.TableBytesIn
 		outputMetrics.MultiLevel.TableBytesRead = outputMetrics.TableBytesRead
 	}
 
 	inputLargestSeqNumAbsolute := c.inputLargestSeqNumAbsolute()
+	var inputCreationTime int64
+	if c.inheritCreationTime {
+		inputCreationTime = c.inputCreationTime()
+	}
 	ve.NewTables = make([]newTableEntry, len(result.Tables))
 	for i := range result.Tables {
 		t := &result.Tables[i]
@@ -3285,6 +3454,9 @@ func (c *compaction) makeVersionEdit(result compact.Result) (*versionEdit, error
 			BlobReferences:     t.BlobReferences,
 			BlobReferenceDepth: t.BlobReferenceDepth,
 		}
+		if c.inheritCreationTime {
+			fileMeta.CreationTime = min(fileMeta.CreationTime, inputCreationTime)
+		}
 		if c.flushing == nil {
 			// Set the file's LargestSeqNumAbsolute to be the maximum value of any
 			// of the compaction's input sstables.
@@ -3299,7 +3471,7 @@ func (c *compaction) makeVersionEdit(result compact.Result) (*versionEdit, error
 		// If the file didn't contain any range deletions, we can fill its
 		// table stats now, avoiding unnecessarily loading the table later.
 		maybeSetStatsFromProperties(
-			fileMeta.PhysicalMeta(), &t.WriterMeta.Properties.CommonProperties, c.logger,
+			fileMeta.PhysicalMeta(), &t.WriterMeta.Properties, c.logger,
 		)
 
 		if t.WriterMeta.HasPointKeys {
@@ -3331,6 +3503,7 @@ func (c *compaction) makeVersionEdit(result compact.Result) (*versionEdit, error
 		outputMetrics.TablesCount++
 		outputMetrics.Additional.BytesWrittenDataBlocks += t.WriterMeta.Properties.DataSize
 		outputMetrics.Additional.BytesWrittenValueBlocks += t.WriterMeta.Properties.ValueBlocksSize
+		outputMetrics.Compression.Add(&t.WriterMeta.CompressionStats)
 	}
 
 	// Sanity check that the tables are ordered and don't overlap.
@@ -3347,11 +3520,12 @@ func (c *compaction) makeVersionEdit(result compact.Result) (*versionEdit, error
 }
 
 // newCompactionOutputTable creates an object for a new table produced by a
-// compaction or flush.
+// compaction or flush, preferably on shared storage if preferSharedStorage is
+// set.
 func (d *DB) newCompactionOutputTable(
-	jobID JobID, c *compaction, writerOpts sstable.WriterOptions,
+	jobID JobID, c *compaction, writerOpts sstable.WriterOptions, preferSharedStorage bool,
 ) (objstorage.ObjectMetadata, sstable.RawWriter, error) {
-	writable, objMeta, err := d.newCompactionOutputObj(c, base.FileTypeTable)
+	writable, objMeta, err := d.newCompactionOutputObj(c, base.FileTypeTable, preferSharedStorage)
 	if err != nil {
 		return objstorage.ObjectMetadata{}, nil, err
 	}
@@ -3376,7 +3550,14 @@ func (d *DB) newCompactionOutputTable(
 func (d *DB) newCompactionOutputBlob(
 	jobID JobID, c *compaction,
 ) (objstorage.Writable, objstorage.ObjectMetadata, error) {
-	writable, objMeta, err := d.newCompactionOutputObj(c, base.FileTypeBlob)
+	// A blob file holds values of all the keys of the compaction. If the span
+	// policies of the compaction disagree, it is placed according to its
+	// level.
+	preferShared, _, err := d.opts.spanPreferSharedStorage(c.userKeyBounds(), c.outputLevel.level)
+	if err != nil {
+		return nil, objstorage.ObjectMetadata{}, err
+	}
+	writable, objMeta, err := d.newCompactionOutputObj(c, base.FileTypeBlob, preferShared)
 	if err != nil {
 		return nil, objstorage.ObjectMetadata{}, err
 	}
@@ -3391,7 +3572,7 @@ func (d *DB) newCompactionOutputBlob(
 
 // newCompactionOutputObj creates an object produced by a compaction or flush.
 func (d *DB) newCompactionOutputObj(
-	c *compaction, typ base.FileType,
+	c *compaction, typ base.FileType, preferSharedStorage bool,
 ) (objstorage.Writable, objstorage.ObjectMetadata, error) {
 	diskFileNum := d.mu.versions.getNextDiskFileNum()
 
@@ -3417,15 +3598,38 @@ func (d *DB) newCompactionOutputObj(
 		}
 	}
 
-	// Prefer shared storage if present.
 	createOpts := objstorage.CreateOptions{
-		PreferSharedStorage: remote.ShouldCreateShared(d.opts.Experimental.CreateOnShared, c.outputLevel.level),
+		PreferSharedStorage: preferSharedStorage,
 		WriteCategory:       writeCategory,
 	}
+	if r := c.remoteOutputs; r != nil {
+		// The outputs of a remote compaction are always placed on shared
+		// storage, under file numbers reserved by the DB. The DB doesn't
+		// offload compactions whose outputs its span policies place on local
+		// storage, but the span policies of the worker may disagree.
+		if !preferSharedStorage {
+			return nil, objstorage.ObjectMetadata{}, errors.Errorf(
+				"pebble: remote compaction output %s must be placed on local storage", diskFileNum)
+		}
+		if diskFileNum >= r.fileNumLimit {
+			return nil, objstorage.ObjectMetadata{}, errors.Errorf(
+				"pebble: remote compaction ran out of reserved file numbers (limit %s)", r.fileNumLimit)
+		}
+		createOpts.ForceSharedStorage = true
+		createOpts.SharedLocator = r.locator
+	}
 	writable, objMeta, err := d.objProvider.Create(ctx, typ, diskFileNum, createOpts)
 	if err != nil {
 		return nil, objstorage.ObjectMetadata{}, err
 	}
+	// The data key is recorded in the file's metadata once the compaction's
+	// version edit is built.
+	if cipher, err := d.fileKeys.create(diskFileNum); err != nil {
+		writable.Abort()
+		return nil, objstorage.ObjectMetadata{}, err
+	} else if cipher != nil {
+		writable = encryption.NewWritable(writable, cipher)
+	}
 
 	if c.kind != compactionKindFlush {
 		writable = &compactionWritable{
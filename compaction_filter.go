@@ -0,0 +1,88 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import "github.com/chris124567/pebble/internal/compact"
+
+// CompactionFilterDecision is the outcome of CompactionFilter.Filter for a
+// key.
+type CompactionFilterDecision = compact.FilterDecision
+
+const (
+	// CompactionFilterKeep retains the key and its value unchanged.
+	CompactionFilterKeep = compact.FilterKeep
+	// CompactionFilterRemove deletes the key. The key is written as a point
+	// tombstone, so that older versions of the key in lower levels are not
+	// resurrected, unless the tombstone can be elided immediately.
+	CompactionFilterRemove = compact.FilterRemove
+	// CompactionFilterChangeValue retains the key, replacing its value with
+	// the value returned by Filter.
+	CompactionFilterChangeValue = compact.FilterChangeValue
+)
+
+// CompactionFilter allows an application to drop keys or rewrite their values
+// as they are compacted, for example to garbage collect application-level
+// versions that are no longer needed.
+//
+// Filter is called by compactions (but not flushes) for the latest version of
+// every point key that is set, including values that are the result of merging
+// onto a base value. Versions of a key that are visible to an open Snapshot or
+// EventuallyFileOnlySnapshot are never passed to Filter, so a filter cannot
+// alter what a snapshot observes. Unmerged MERGE operands, tombstones and
+// range keys are never filtered.
+//
+// A key is filtered each time it is compacted, and keys that have not yet
+// been compacted are observed by reads regardless of the filter's decision.
+// Filter must therefore be deterministic and idempotent: changing a value it
+// returned earlier must not be required for correctness.
+//
+// When keyspaces are configured, keys are passed to Filter with their
+// keyspace's one-byte ID prefix. When TTL is enabled, values are passed to
+// Filter without their TTL header, and a changed value retains the expiry of
+// the original value.
+//
+// Filter may be called concurrently by multiple compactions.
+type CompactionFilter interface {
+	// Filter decides the fate of a key, passed with its value, that is being
+	// written to the given output level. When the decision is
+	// CompactionFilterChangeValue, newValue is the key's new value. The key and
+	// value are only valid for the duration of the call, and newValue is
+	// copied before Filter is called again.
+	Filter(level int, key, value []byte) (decision CompactionFilterDecision, newValue []byte)
+}
+
+// compactionFilterFunc returns the compact.IterConfig.Filter with which a
+// compaction writing to outputLevel applies d.opts.CompactionFilter, or nil if
+// no filter is configured.
+func (d *DB) compactionFilterFunc(
+	outputLevel int,
+) func(key, value []byte) (compact.FilterDecision, []byte, error) {
+	filter := d.opts.CompactionFilter
+	if filter == nil {
+		return nil
+	}
+	if d.opts.TTL == nil {
+		return func(key, value []byte) (compact.FilterDecision, []byte, error) {
+			decision, newValue := filter.Filter(outputLevel, key, value)
+			return decision, newValue, nil
+		}
+	}
+	var buf []byte
+	return func(key, value []byte) (compact.FilterDecision, []byte, error) {
+		expiry, value, err := decodeTTLValue(value)
+		if err != nil {
+			return compact.FilterKeep, nil, err
+		}
+		decision, newValue := filter.Filter(outputLevel, key, value)
+		if decision != compact.FilterChangeValue {
+			return decision, nil, nil
+		}
+		n := ttlHeaderLen(expiry)
+		buf = append(buf[:0], make([]byte, n)...)
+		putTTLHeader(buf, expiry)
+		buf = append(buf, newValue...)
+		return decision, buf, nil
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chris124567/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+// testCompactionFilter removes keys whose values begin with "rm" and upper
+// cases the values of keys whose values begin with "up".
+type testCompactionFilter struct{}
+
+func (testCompactionFilter) Filter(
+	level int, key, value []byte,
+) (CompactionFilterDecision, []byte) {
+	switch {
+	case bytes.HasPrefix(value, []byte("rm")):
+		return CompactionFilterRemove, nil
+	case bytes.HasPrefix(value, []byte("up")):
+		return CompactionFilterChangeValue, bytes.ToUpper(value)
+	}
+	return CompactionFilterKeep, nil
+}
+
+func TestCompactionFilter(t *testing.T) {
+	for _, ttl := range []bool{false, true} {
+		t.Run(map[bool]string{false: "plain", true: "ttl"}[ttl], func(t *testing.T) {
+			var mu sync.Mutex
+			var infos []CompactionInfo
+			opts := &Options{
+				FS:                          vfs.NewMem(),
+				CompactionFilter:            testCompactionFilter{},
+				DisableAutomaticCompactions: true,
+				EventListener: &EventListener{
+					CompactionEnd: func(info CompactionInfo) {
+						mu.Lock()
+						defer mu.Unlock()
+						infos = append(infos, info)
+					},
+				},
+			}
+			var writeOpts *WriteOptions
+			if ttl {
+				opts.TTL = &TTLOptions{}
+				writeOpts = &WriteOptions{TTL: time.Hour}
+			}
+			d, err := Open("", opts)
+			require.NoError(t, err)
+			defer func() { require.NoError(t, d.Close()) }()
+
+			require.NoError(t, d.Set([]byte("a"), []byte("keep"), writeOpts))
+			require.NoError(t, d.Set([]byte("b"), []byte("old"), writeOpts))
+			require.NoError(t, d.Flush())
+			require.NoError(t, d.Compact(context.Background(), []byte("a"), []byte("z"), false))
+
+			// The snapshot observes b=old, which must not be filtered.
+			snap := d.NewSnapshot()
+			require.NoError(t, d.Set([]byte("b"), []byte("rm"), writeOpts))
+			require.NoError(t, d.Set([]byte("c"), []byte("rm"), writeOpts))
+			require.NoError(t, d.Set([]byte("d"), []byte("up"), writeOpts))
+			require.NoError(t, d.Merge([]byte("e"), []byte("up"), nil))
+			require.NoError(t, d.Flush())
+			require.NoError(t, d.Compact(context.Background(), []byte("a"), []byte("z"), false))
+
+			scan := func(r Reader) string {
+				iter, err := r.NewIter(nil)
+				require.NoError(t, err)
+				var buf bytes.Buffer
+				for valid := iter.First(); valid; valid = iter.Next() {
+					buf.WriteString(string(iter.Key()) + "=" + string(iter.Value()) + " ")
+				}
+				require.NoError(t, iter.Close())
+				return buf.String()
+			}
+			// Flushes are not filtered, so the snapshot observes b=old and
+			// nothing written after it, and the DB observes the filtered keys.
+			// The MERGE onto e has no base and so is not filtered.
+			require.Equal(t, "a=keep b=old ", scan(snap))
+			require.Equal(t, "a=keep d=UP e=up ", scan(d))
+
+			mu.Lock()
+			last := infos[len(infos)-1]
+			mu.Unlock()
+			require.Equal(t, uint64(2), last.FilterRemovedKeys)
+			require.Equal(t, uint64(1), last.FilterChangedValues)
+			require.Contains(t, last.String(), "filtered 2 removed, 1 changed")
+
+			// Once the snapshot is closed, the older version of b is dropped
+			// along with the tombstone shadowing it.
+			require.NoError(t, snap.Close())
+			require.NoError(t, d.Compact(context.Background(), []byte("a"), []byte("z"), false))
+			require.Equal(t, "a=keep d=UP e=up ", scan(d))
+		})
+	}
+}
@@ -54,11 +54,17 @@ type compactionPickerMetrics struct {
 		fillFactor            float64
 		compensatedFillFactor float64
 	}
+	// universal is only populated by the universal compaction picker.
+	universal struct {
+		sortedRuns        int
+		sizeAmplification float64
+	}
 }
 
 type compactionPicker interface {
 	getMetrics([]compactionInfo) compactionPickerMetrics
 	getBaseLevel() int
+	getCompactionConcurrency() int
 	estimatedCompactionDebt() uint64
 	pickAutoScore(env compactionEnv) (pc *pickedCompaction)
 	pickAutoNonScore(env compactionEnv) (pc *pickedCompaction)
@@ -200,6 +206,10 @@ type pickedCompaction struct {
 	// overlap in its output level with. If the overlap is greater than
 	// maxReadCompaction bytes, then we don't proceed with the compaction.
 	maxReadCompactionBytes uint64
+	// inheritCreationTime is true if the output tables are considered created
+	// at the creation time of the oldest input table, rather than at the time
+	// they are written.
+	inheritCreationTime bool
 
 	// The boundaries of the input data.
 	smallest      InternalKey
@@ -329,6 +339,7 @@ func (pc *pickedCompaction) clone() *pickedCompaction {
 		maxOutputFileSize:      pc.maxOutputFileSize,
 		maxOverlapBytes:        pc.maxOverlapBytes,
 		maxReadCompactionBytes: pc.maxReadCompactionBytes,
+		inheritCreationTime:    pc.inheritCreationTime,
 		smallest:               pc.smallest.Clone(),
 		largest:                pc.largest.Clone(),
 
@@ -592,6 +603,13 @@ func newCompactionPickerByScore(
 		virtualBackings: virtualBackings,
 	}
 	p.initLevelMaxBytes(inProgressCompactions)
+	for i := range opts.Experimental.SpanPriorities {
+		s := &opts.Experimental.SpanPriorities[i]
+		if s.overReadAmp(opts.Comparer.Compare, v) && !s.overlapsInProgress(opts.Comparer.Compare, inProgressCompactions) {
+			p.spanCompactionSlot = true
+			break
+		}
+	}
 	return p
 }
 
@@ -692,6 +710,10 @@ type compactionPickerByScore struct {
 	// level.
 	levelMaxBytes [numLevels]int64
 	dbSizeBytes   uint64
+	// spanCompactionSlot is true if a span of
+	// Options.Experimental.SpanPriorities exceeds its read amplification
+	// target, and no in-progress compaction overlaps it.
+	spanCompactionSlot bool
 }
 
 var _ compactionPicker = &compactionPickerByScore{}
@@ -1276,7 +1298,17 @@ func (p *compactionPickerByScore) getCompactionConcurrency() int {
 			int((garbageFraction / garbageFractionLimit) * float64(upper-lower))
 	}
 
-	extraCompactions := max(l0ReadAmpCompactions, compactionDebtCompactions, compactableGarbageCompactions, 0)
+	// A span of Options.Experimental.SpanPriorities exceeding its read
+	// amplification target gets a compaction of its own, on top of the others.
+	// The slot is withdrawn once a compaction overlaps the span, so that at
+	// most one extra compaction runs on behalf of the spans, and the other
+	// compactions only get to use it while the span can't be compacted.
+	spanCompactions := 0
+	if p.spanCompactionSlot {
+		spanCompactions = 1
+	}
+
+	extraCompactions := max(l0ReadAmpCompactions, compactionDebtCompactions, compactableGarbageCompactions, 0) + spanCompactions
 
 	return min(lower+extraCompactions, upper)
 }
@@ -1344,6 +1376,12 @@ func (p *compactionPickerByScore) logCompactionForTesting(
 // If a score-based compaction cannot be found, pickAuto falls back to looking
 // for an elision-only compaction to remove obsolete keys.
 func (p *compactionPickerByScore) pickAutoScore(env compactionEnv) (pc *pickedCompaction) {
+	// Spans of Options.Experimental.SpanPriorities that exceed their read
+	// amplification target are compacted first.
+	if pc := p.pickSpanPriority(env); pc != nil {
+		return pc
+	}
+
 	scores := p.calculateLevelScores(env.inProgressCompactions)
 
 	// Check for a score-based compaction. candidateLevelInfos are first sorted
@@ -1403,6 +1441,12 @@ func (p *compactionPickerByScore) pickAutoNonScore(env compactionEnv) (pc *picke
 		return pc
 	}
 
+	// Check for files whose values have largely expired. Like tombstone
+	// density compactions, these compactions may select a file at any level.
+	if pc := p.pickExpiredCompaction(env); pc != nil {
+		return pc
+	}
+
 	// Check for L6 files with tombstones that may be elided. These files may
 	// exist if a snapshot prevented the elision of a tombstone or because of
 	// a move compaction. These are low-priority compactions because they
@@ -1673,6 +1717,33 @@ func (p *compactionPickerByScore) pickTombstoneDensityCompaction(
 	return p.pickedCompactionFromCandidateFile(candidate, env, level, defaultOutputLevel(level, p.baseLevel), compactionKindTombstoneDensity)
 }
 
+// pickExpiredCompaction looks for a compaction that drops expired values (see
+// Options.TTL). It picks the file with the highest estimated fraction of
+// expired bytes, provided it is at least TTLOptions.CompactionThreshold.
+func (p *compactionPickerByScore) pickExpiredCompaction(env compactionEnv) (pc *pickedCompaction) {
+	if p.opts.TTL == nil || p.opts.TTL.CompactionThreshold < 0 {
+		return nil
+	}
+	now := uint64(p.opts.TTL.Now().UnixNano())
+	var candidate *tableMetadata
+	var level int
+	var candidateRatio float64
+	for l := 0; l < numLevels; l++ {
+		iter := p.vers.Levels[l].Iter()
+		for f := iter.First(); f != nil; f = iter.Next() {
+			if f.IsCompacting() || !f.StatsValid() || f.Size == 0 || f.Stats.ExpiringValueSize == 0 {
+				continue
+			}
+			ratio := float64(f.Stats.ExpiredBytesEstimate(now)) / float64(f.Size+f.EstimatedReferenceSize())
+			if ratio < p.opts.TTL.CompactionThreshold || ratio <= candidateRatio {
+				continue
+			}
+			candidate, level, candidateRatio = f, l, ratio
+		}
+	}
+	return p.pickedCompactionFromCandidateFile(candidate, env, level, defaultOutputLevel(level, p.baseLevel), compactionKindExpired)
+}
+
 // pickAutoLPositive picks an automatic compaction for the candidate
 // file in a positive-numbered level. This function must not be used for
 // L0.
@@ -1943,7 +2014,8 @@ func newPickedManualCompaction(
 
 // pickDownloadCompaction picks a download compaction for the downloadSpan,
 // which could be specified as being performed either by a copy compaction of
-// the backing file or a rewrite compaction.
+// the backing file or a rewrite compaction. It is also used to pick tiering
+// compactions, which copy a single file within its level as well.
 func pickDownloadCompaction(
 	vers *version,
 	l0Organizer *manifest.L0Organizer,
@@ -1958,7 +2030,7 @@ func pickDownloadCompaction(
 	if file.CompactionState == manifest.CompactionStateCompacting {
 		return nil
 	}
-	if kind != compactionKindCopy && kind != compactionKindRewrite {
+	if kind != compactionKindCopy && kind != compactionKindRewrite && kind != compactionKindTiering {
 		panic("invalid download/rewrite compaction kind")
 	}
 	pc = newPickedCompaction(opts, vers, l0Organizer, level, level, baseLevel)
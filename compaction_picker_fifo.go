@@ -0,0 +1,282 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"slices"
+	"time"
+
+	"github.com/chris124567/pebble/internal/compact"
+	"github.com/chris124567/pebble/internal/manifest"
+)
+
+// FIFOCompactionOptions configures FIFO compactions. See
+// Options.Experimental.FIFOCompaction.
+//
+// Under FIFO compaction, data is never merged into lower levels. Instead, the
+// oldest tables (those with the smallest largest sequence numbers) are dropped
+// once the total size of the tables exceeds MaxSize, or once they are older
+// than TTL. Tables are dropped by FIFO compactions, which, like delete-only
+// compactions, delete their input tables without reading them. The deletion of
+// the underlying files is paced like that of any other obsolete file (see
+// Options.TargetByteDeletionRate).
+//
+// FIFO compaction is meant for DBs that hold a bounded window of time-ordered
+// data, such as a buffer of metrics or logs, whose keys are written in
+// increasing order. Flushes of such keys don't overlap, so L0 doesn't grow in
+// sublevels. Dropped tables are not protected by open snapshots, and keys
+// overwritten by a newer table that is dropped reappear with their older
+// values.
+type FIFOCompactionOptions struct {
+	// MaxSize is the total size of the tables beyond which the oldest tables
+	// are dropped. Zero means no limit.
+	MaxSize uint64
+
+	// TTL is the age beyond which tables are dropped, as measured from the time
+	// they were created. Zero means no limit.
+	TTL time.Duration
+
+	// IntraL0, if true, enables intra-L0 compactions that merge the newest L0
+	// tables together once L0 holds at least Options.L0CompactionFileThreshold
+	// tables. Only tables smaller than the target file size of L0 are merged,
+	// and the merged tables are considered created at the creation time of the
+	// oldest of them by TTL.
+	IntraL0 bool
+
+	// CheckInterval is the interval at which the DB looks for tables older than
+	// TTL, in addition to whenever compactions are picked. The default is one
+	// minute.
+	CheckInterval time.Duration
+
+	// Now returns the current time, against which the age of tables is
+	// evaluated. The default is time.Now.
+	Now func() time.Time
+}
+
+// EnsureDefaults ensures that the default values for all of the options have
+// been initialized.
+func (o *FIFOCompactionOptions) EnsureDefaults() {
+	if o.CheckInterval <= 0 {
+		o.CheckInterval = time.Minute
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+}
+
+// compactionPickerFIFO picks FIFO compactions that drop the oldest tables, and
+// optionally intra-L0 compactions. It never picks compactions that move data
+// to lower levels, so it doesn't delegate to the leveled picker beyond the
+// base level and the compaction concurrency.
+type compactionPickerFIFO struct {
+	*compactionPickerByScore
+	fopts *FIFOCompactionOptions
+}
+
+var _ compactionPicker = &compactionPickerFIFO{}
+
+func newCompactionPickerFIFO(
+	v *version,
+	l0Organizer *manifest.L0Organizer,
+	virtualBackings *manifest.VirtualBackings,
+	opts *Options,
+	inProgressCompactions []compactionInfo,
+) *compactionPickerFIFO {
+	return &compactionPickerFIFO{
+		compactionPickerByScore: newCompactionPickerByScore(
+			v, l0Organizer, virtualBackings, opts, inProgressCompactions),
+		fopts: opts.Experimental.FIFOCompaction,
+	}
+}
+
+func (p *compactionPickerFIFO) getMetrics(inProgress []compactionInfo) compactionPickerMetrics {
+	var m compactionPickerMetrics
+	m.levels[0].score = float64(p.vers.Levels[0].Len()) / float64(p.opts.L0CompactionFileThreshold)
+	return m
+}
+
+// estimatedCompactionDebt returns zero, since FIFO compactions don't rewrite
+// data, and intra-L0 compactions only rewrite small tables.
+func (p *compactionPickerFIFO) estimatedCompactionDebt() uint64 {
+	return 0
+}
+
+// pickAutoScore picks a FIFO compaction dropping the oldest tables if the size
+// or age limits are exceeded, or else an intra-L0 compaction if enabled.
+func (p *compactionPickerFIFO) pickAutoScore(env compactionEnv) (pc *pickedCompaction) {
+	if pc := p.pickDrop(); pc != nil {
+		return pc
+	}
+	if p.fopts.IntraL0 {
+		return p.pickIntraL0(env)
+	}
+	return nil
+}
+
+// pickAutoNonScore returns nil: the compactions picked by the leveled picker
+// would move data into lower levels.
+func (p *compactionPickerFIFO) pickAutoNonScore(env compactionEnv) (pc *pickedCompaction) {
+	return nil
+}
+
+// pickDrop picks a FIFO compaction of the oldest tables, if the total size of
+// the tables exceeds MaxSize or if the oldest tables are older than TTL.
+//
+// The tables of each level are visited in the order of the level: L0 by
+// sequence number, and the other levels by key, which for the time-ordered
+// keys FIFO compaction is meant for is also the order in which they were
+// written. The levels are merged by picking the table with the smallest
+// largest sequence number among the next table of each level. Tables are
+// dropped in this order, so the compaction stops short of the oldest table
+// that is already compacting.
+func (p *compactionPickerFIFO) pickDrop() *pickedCompaction {
+	var total uint64
+	var iters [numLevels]manifest.LevelIterator
+	var next [numLevels]*tableMetadata
+	for level := range p.vers.Levels {
+		total += p.vers.Levels[level].TableSize()
+		iters[level] = p.vers.Levels[level].Iter()
+		next[level] = iters[level].First()
+	}
+
+	var expiredBefore int64
+	if p.fopts.TTL > 0 {
+		expiredBefore = p.fopts.Now().Add(-p.fopts.TTL).Unix()
+	}
+	var dropped [numLevels][]*tableMetadata
+	var n int
+	for {
+		level := -1
+		for l, f := range next {
+			if f != nil && (level < 0 || f.LargestSeqNum < next[level].LargestSeqNum) {
+				level = l
+			}
+		}
+		if level < 0 {
+			break
+		}
+		f := next[level]
+		tooLarge := p.fopts.MaxSize > 0 && total > p.fopts.MaxSize
+		expired := p.fopts.TTL > 0 && f.CreationTime <= expiredBefore
+		if (!tooLarge && !expired) || f.IsCompacting() {
+			break
+		}
+		dropped[level] = append(dropped[level], f)
+		total -= f.Size
+		n++
+		next[level] = iters[level].Next()
+	}
+	if n == 0 {
+		return nil
+	}
+
+	var inputs []compactionLevel
+	for level := range dropped {
+		if len(dropped[level]) == 0 {
+			continue
+		}
+		files := manifest.NewLevelSliceKeySorted(p.opts.Comparer.Compare, dropped[level])
+		if level == 0 {
+			files = manifest.NewLevelSliceSeqSorted(dropped[level])
+		}
+		inputs = append(inputs, compactionLevel{level: level, files: files})
+	}
+	if len(inputs) == 1 {
+		inputs = append(inputs, compactionLevel{level: inputs[0].level})
+	}
+	pc := newPickedCompaction(p.opts, p.vers, p.l0Organizer, inputs[0].level, inputs[len(inputs)-1].level, p.baseLevel)
+	pc.kind = compactionKindFIFO
+	pc.inputs = inputs
+	pc.startLevel = &pc.inputs[0]
+	pc.outputLevel = &pc.inputs[len(pc.inputs)-1]
+	for i := 1; i < len(pc.inputs)-1; i++ {
+		pc.extraLevels = append(pc.extraLevels, &pc.inputs[i])
+	}
+	for i := range pc.inputs {
+		pc.maybeExpandBounds(manifest.KeyRange(pc.cmp, pc.inputs[i].files.All()))
+	}
+	return pc
+}
+
+// pickIntraL0 picks an intra-L0 compaction of the newest small L0 tables, if
+// L0 holds at least Options.L0CompactionFileThreshold tables.
+//
+// The picked tables are the newest tables that are smaller than the target
+// file size of L0, stopping at the first table that isn't. No compaction is
+// picked if the sequence numbers of the picked tables interleave with those of
+// any other L0 table, since the output would then be misordered in L0.
+func (p *compactionPickerFIFO) pickIntraL0(env compactionEnv) *pickedCompaction {
+	if p.vers.Levels[0].Len() < p.opts.L0CompactionFileThreshold {
+		return nil
+	}
+	maxSize := uint64(p.opts.Level(0).TargetFileSize)
+	var files []*tableMetadata
+	iter := p.vers.Levels[0].Iter()
+	for f := iter.Last(); f != nil; f = iter.Prev() {
+		if f.LargestSeqNum >= env.earliestUnflushedSeqNum {
+			// The table was ingested as a flushable, and older data may still
+			// be in the memtables.
+			if len(files) > 0 {
+				break
+			}
+			continue
+		}
+		if f.IsCompacting() || f.Size >= maxSize ||
+			!canCompactTables(manifest.NewLevelSliceSeqSorted([]*tableMetadata{f}), 0, env.problemSpans) {
+			break
+		}
+		files = append(files, f)
+	}
+	if len(files) < minIntraL0Count {
+		return nil
+	}
+	slices.Reverse(files)
+	// The sequence numbers of the other tables must not overlap those of the
+	// picked tables.
+	smallest, largest := files[0].SmallestSeqNum, files[len(files)-1].LargestSeqNum
+	for _, f := range files {
+		smallest = min(smallest, f.SmallestSeqNum)
+	}
+	for f := range p.vers.Levels[0].All() {
+		if f.LargestSeqNum < smallest || f.SmallestSeqNum > largest {
+			continue
+		}
+		if !slices.Contains(files, f) {
+			return nil
+		}
+	}
+
+	pc := newPickedCompaction(p.opts, p.vers, p.l0Organizer, 0, 0, p.baseLevel)
+	pc.inheritCreationTime = true
+	pc.startLevel.files = manifest.NewLevelSliceSeqSorted(files)
+	pc.startLevel.l0SublevelInfo = generateSublevelInfo(pc.cmp, pc.startLevel.files)
+	pc.smallest, pc.largest = manifest.KeyRange(pc.cmp, pc.startLevel.files.All())
+	pc.score = p.getMetrics(nil).levels[0].score
+	return pc
+}
+
+// runFIFOCompaction runs a FIFO compaction, which deletes all of its input
+// tables.
+//
+// d.mu must be held when calling this.
+func (d *DB) runFIFOCompaction(
+	jobID JobID, c *compaction,
+) (ve *versionEdit, stats compact.Stats, _ error) {
+	ve = &versionEdit{
+		DeletedTables: map[manifest.DeletedTableEntry]*tableMetadata{},
+	}
+	for _, cl := range c.inputs {
+		if cl.files.Empty() {
+			continue
+		}
+		levelMetrics := &LevelMetrics{}
+		for f := range cl.files.All() {
+			ve.DeletedTables[manifest.DeletedTableEntry{Level: cl.level, FileNum: f.TableNum}] = f
+			levelMetrics.TablesDeleted++
+		}
+		c.metrics[cl.level] = levelMetrics
+	}
+	return ve, stats, nil
+}
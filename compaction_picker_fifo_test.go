@@ -0,0 +1,156 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/crlib/crstrings"
+	"github.com/cockroachdb/datadriven"
+	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/internal/compact"
+	"github.com/chris124567/pebble/internal/manifest"
+	"github.com/chris124567/pebble/objstorage"
+	"github.com/chris124567/pebble/sstable"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFIFOCompactionOptions(t *testing.T) {
+	opts := &Options{}
+	opts.Experimental.CompactionStyle = CompactionStyleFIFO
+	opts.Experimental.FIFOCompaction = &FIFOCompactionOptions{
+		MaxSize: 32 << 10,
+		TTL:     time.Hour,
+		IntraL0: true,
+	}
+	opts.EnsureDefaults()
+
+	// The options round trip through the OPTIONS file.
+	var parsed Options
+	require.NoError(t, parsed.Parse(opts.String(), nil))
+	require.Equal(t, CompactionStyleFIFO, parsed.Experimental.CompactionStyle)
+	require.Equal(t, opts.Experimental.FIFOCompaction.MaxSize, parsed.Experimental.FIFOCompaction.MaxSize)
+	require.Equal(t, opts.Experimental.FIFOCompaction.TTL, parsed.Experimental.FIFOCompaction.TTL)
+	require.True(t, parsed.Experimental.FIFOCompaction.IntraL0)
+}
+
+func TestCompactionPickerFIFO(t *testing.T) {
+	var opts *Options
+	var picker *compactionPickerFIFO
+	var inProgress []compactionInfo
+	var pc *pickedCompaction
+	var now int64
+
+	datadriven.RunTest(t, "testdata/compaction_picker_fifo", func(t *testing.T, td *datadriven.TestData) string {
+		switch td.Cmd {
+		case "define":
+			opts = DefaultOptions()
+			opts.Experimental.CompactionStyle = CompactionStyleFIFO
+			opts.Experimental.FIFOCompaction = &FIFOCompactionOptions{
+				Now: func() time.Time { return time.Unix(now, 0) },
+			}
+			fopts := opts.Experimental.FIFOCompaction
+			td.MaybeScanArgs(t, "max_size", &fopts.MaxSize)
+			if td.HasArg("ttl") {
+				var ttl string
+				td.ScanArgs(t, "ttl", &ttl)
+				var err error
+				if fopts.TTL, err = time.ParseDuration(ttl); err != nil {
+					td.Fatalf(t, "%v", err)
+				}
+			}
+			fopts.IntraL0 = td.HasArg("intra_l0")
+			td.MaybeScanArgs(t, "l0_compaction_file_threshold", &opts.L0CompactionFileThreshold)
+			if td.HasArg("target_file_size") {
+				opts.Levels = make([]LevelOptions, numLevels)
+				td.ScanArgs(t, "target_file_size", &opts.Levels[0].TargetFileSize)
+			}
+			opts.EnsureDefaults()
+
+			var v *version
+			var l0Organizer *manifest.L0Organizer
+			v, l0Organizer, inProgress = parsePickerVersion(t, td, opts)
+			vb := manifest.MakeVirtualBackings()
+			picker = newCompactionPickerFIFO(v, l0Organizer, &vb, opts, inProgress)
+			pc = nil
+			return v.String()
+
+		case "creation-times":
+			// Each line is of the form <table>: <creation time in seconds>.
+			for _, line := range crstrings.Lines(td.Input) {
+				table, creationTime, ok := strings.Cut(line, ":")
+				if !ok {
+					td.Fatalf(t, "malformed creation time %q", line)
+				}
+				secs, err := strconv.ParseInt(strings.TrimSpace(creationTime), 10, 64)
+				if err != nil {
+					td.Fatalf(t, "%v", err)
+				}
+				var found bool
+				for _, level := range picker.vers.Levels {
+					for f := range level.All() {
+						if f.TableNum.String() == strings.TrimSpace(table) {
+							f.CreationTime, found = secs, true
+						}
+					}
+				}
+				if !found {
+					td.Fatalf(t, "cannot find table %s", table)
+				}
+			}
+			return ""
+
+		case "pick-auto":
+			td.MaybeScanArgs(t, "now", &now)
+			env := compactionEnv{
+				diskAvailBytes:          math.MaxUint64,
+				earliestUnflushedSeqNum: base.SeqNumMax,
+				inProgressCompactions:   inProgress,
+			}
+			if td.HasArg("earliest_unflushed_seqnum") {
+				var seqNum uint64
+				td.ScanArgs(t, "earliest_unflushed_seqnum", &seqNum)
+				env.earliestUnflushedSeqNum = base.SeqNum(seqNum)
+			}
+			pc = picker.pickAutoScore(env)
+			if pc != nil {
+				checkClone(t, pc)
+			}
+			return pickedCompactionInputs(pc)
+
+		case "output-creation-time":
+			// Print the creation time of an output table of the last picked
+			// compaction, if the table is written now.
+			if pc == nil {
+				return "no compaction"
+			}
+			td.MaybeScanArgs(t, "now", &now)
+			c := newCompaction(pc, opts, time.Unix(now, 0), nil /* provider */, noopGrantHandle{}, neverSeparateValues)
+			ve, err := c.makeVersionEdit(compact.Result{
+				Tables: []compact.OutputTable{{
+					CreationTime: time.Unix(now, 0),
+					ObjMeta:      objstorage.ObjectMetadata{DiskFileNum: 1000},
+					WriterMeta: sstable.WriterMetadata{
+						HasPointKeys:  true,
+						SmallestPoint: pc.smallest,
+						LargestPoint:  pc.largest,
+					},
+				}},
+			})
+			if err != nil {
+				return err.Error()
+			}
+			return fmt.Sprint(ve.NewTables[0].Meta.CreationTime)
+
+		default:
+			return fmt.Sprintf("unknown command: %s", td.Cmd)
+		}
+	})
+}
@@ -1716,6 +1716,103 @@ func fileNums(files manifest.LevelSlice) string {
 	return strings.Join(ss, ",")
 }
 
+// parsePickerVersion parses the input of a datadriven define command: a version
+// in the format of its DebugString, optionally followed by the in-progress
+// compactions, in the form of:
+//
+//	compactions
+//	  L0 000001 000002 -> L6 000005
+//
+// The input tables of the in-progress compactions are marked as compacting.
+func parsePickerVersion(
+	t *testing.T, td *datadriven.TestData, opts *Options,
+) (*version, *manifest.L0Organizer, []compactionInfo) {
+	versionInput, compactionsInput, _ := strings.Cut(td.Input, "compactions\n")
+	l0Organizer := manifest.NewL0Organizer(opts.Comparer, opts.FlushSplitBytes)
+	v, err := manifest.ParseVersionDebug(opts.Comparer, l0Organizer, versionInput)
+	if err != nil {
+		td.Fatalf(t, "%v", err)
+	}
+
+	var inProgress []compactionInfo
+	for _, line := range crstrings.Lines(compactionsInput) {
+		var info compactionInfo
+		var files [][]*tableMetadata
+		for _, field := range strings.Fields(line) {
+			switch {
+			case field == "->":
+			case strings.HasPrefix(field, "L"):
+				level, err := strconv.Atoi(field[1:])
+				if err != nil {
+					td.Fatalf(t, "malformed compaction %q", line)
+				}
+				info.outputLevel = level
+				if len(info.inputs) > 0 && info.inputs[len(info.inputs)-1].level == level {
+					// eg, L0 -> L0 compaction or L6 -> L6 compaction
+					continue
+				}
+				info.inputs = append(info.inputs, compactionLevel{level: level})
+				files = append(files, nil)
+			default:
+				if len(info.inputs) == 0 {
+					td.Fatalf(t, "malformed compaction %q", line)
+				}
+				level := info.inputs[len(info.inputs)-1].level
+				var compactFile *tableMetadata
+				for f := range v.Levels[level].All() {
+					if f.TableNum.String() == field {
+						compactFile = f
+					}
+				}
+				if compactFile == nil {
+					td.Fatalf(t, "cannot find compaction file L%d.%s", level, field)
+				}
+				compactFile.CompactionState = manifest.CompactionStateCompacting
+				files[len(files)-1] = append(files[len(files)-1], compactFile)
+			}
+		}
+		for i := range info.inputs {
+			if info.inputs[i].level == 0 {
+				info.inputs[i].files = manifest.NewLevelSliceSeqSorted(files[i])
+				for _, f := range files[i] {
+					f.IsIntraL0Compacting = info.outputLevel == 0
+				}
+			} else {
+				info.inputs[i].files = manifest.NewLevelSliceKeySorted(opts.Comparer.Compare, files[i])
+			}
+			if len(files[i]) == 0 {
+				continue
+			}
+			smallest, largest := manifest.KeyRange(opts.Comparer.Compare, info.inputs[i].files.All())
+			if info.smallest.UserKey == nil || base.InternalCompare(opts.Comparer.Compare, smallest, info.smallest) < 0 {
+				info.smallest = smallest
+			}
+			if info.largest.UserKey == nil || base.InternalCompare(opts.Comparer.Compare, largest, info.largest) > 0 {
+				info.largest = largest
+			}
+		}
+		inProgress = append(inProgress, info)
+	}
+	l0Organizer.InitCompactingFileInfo(inProgressL0Compactions(inProgress))
+	return v, l0Organizer, inProgress
+}
+
+// pickedCompactionInputs formats the kind, levels and input tables of a picked
+// compaction.
+func pickedCompactionInputs(pc *pickedCompaction) string {
+	if pc == nil {
+		return "nil"
+	}
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "%s: L%d -> L%d\n", pc.kind, pc.startLevel.level, pc.outputLevel.level)
+	for _, cl := range pc.inputs {
+		if !cl.files.Empty() {
+			fmt.Fprintf(&buf, "L%d: %s\n", cl.level, fileNums(cl.files))
+		}
+	}
+	return buf.String()
+}
+
 func checkClone(t *testing.T, pc *pickedCompaction) {
 	pcClone := pc.clone()
 	require.Equal(t, pc.String(), pcClone.String())
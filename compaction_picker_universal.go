@@ -0,0 +1,347 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+
+	"github.com/chris124567/pebble/internal/manifest"
+)
+
+// CompactionStyle selects the compaction picker, which determines the shape of
+// the LSM. See Options.Experimental.CompactionStyle.
+type CompactionStyle int8
+
+const (
+	// CompactionStyleLeveled is the default compaction style. Each level below
+	// L0 is a single sorted run that is a fixed multiple larger than the level
+	// above it, and data is compacted one level at a time.
+	CompactionStyleLeveled CompactionStyle = iota
+	// CompactionStyleUniversal is a tiered compaction style that lowers write
+	// amplification at the cost of space and read amplification. Sorted runs
+	// are only merged with runs of a similar size, and the LSM is fully
+	// compacted when the runs above the oldest one grow too large compared to
+	// it. See UniversalCompactionOptions.
+	CompactionStyleUniversal
+	// CompactionStyleFIFO never merges data into lower levels, and instead
+	// drops the oldest tables once the DB grows too large or the tables grow
+	// too old. See FIFOCompactionOptions.
+	CompactionStyleFIFO
+)
+
+// String implements fmt.Stringer.
+func (s CompactionStyle) String() string {
+	switch s {
+	case CompactionStyleLeveled:
+		return "leveled"
+	case CompactionStyleUniversal:
+		return "universal"
+	case CompactionStyleFIFO:
+		return "fifo"
+	default:
+		return fmt.Sprintf("CompactionStyle(%d)", int8(s))
+	}
+}
+
+// UniversalCompactionOptions configures universal compactions. See
+// Options.Experimental.UniversalCompaction.
+//
+// Under universal compaction, the LSM is viewed as a list of sorted runs,
+// ordered from newest to oldest: each L0 sublevel is a sorted run, followed by
+// each non-empty level below L0. Once there are at least
+// Options.L0CompactionThreshold sorted runs, the picker considers, in order:
+//
+//  1. A full compaction of all the sorted runs into the bottommost level, if
+//     the size of the runs above the oldest one exceeds
+//     MaxSizeAmplificationPercent of the size of the oldest one.
+//  2. A merge of consecutive sorted runs of similar size (see SizeRatio).
+//  3. A merge of the newest sorted runs that brings the number of sorted runs
+//     back below Options.L0CompactionThreshold.
+//
+// A compaction that includes an L0 sublevel always includes all older L0
+// sublevels, and the output of a compaction is written to the deepest level
+// above the next older sorted run. This is what allows universal compactions
+// to reuse the L0 sublevel machinery of leveled compactions.
+type UniversalCompactionOptions struct {
+	// SizeRatio is the percentage by which the size of a sorted run may exceed
+	// the total size of the newer runs picked so far for it to still be merged
+	// with them. The default is 1.
+	SizeRatio int
+
+	// MinMergeWidth is the minimum number of sorted runs merged by a size ratio
+	// compaction. The default (and minimum) is 2.
+	MinMergeWidth int
+
+	// MaxMergeWidth is the maximum number of sorted runs merged by a size ratio
+	// or run count compaction, not counting the L0 sublevels and the level
+	// that may be added to satisfy the constraints described above. Zero means
+	// no limit.
+	MaxMergeWidth int
+
+	// MaxSizeAmplificationPercent is the size of the sorted runs above the
+	// oldest one, as a percentage of the size of the oldest one, beyond which
+	// all the sorted runs are compacted together. The default is 200.
+	MaxSizeAmplificationPercent int
+}
+
+// EnsureDefaults ensures that the default values for all of the options have
+// been initialized.
+func (o *UniversalCompactionOptions) EnsureDefaults() {
+	if o.SizeRatio <= 0 {
+		o.SizeRatio = 1
+	}
+	if o.MinMergeWidth < 2 {
+		o.MinMergeWidth = 2
+	}
+	if o.MaxMergeWidth < 0 {
+		o.MaxMergeWidth = 0
+	}
+	if o.MaxSizeAmplificationPercent <= 0 {
+		o.MaxSizeAmplificationPercent = 200
+	}
+}
+
+// sortedRun is a sorted run of the universal compaction picker: an L0 sublevel
+// or a non-empty level below L0.
+type sortedRun struct {
+	level      int
+	files      manifest.LevelSlice
+	size       uint64
+	compacting bool
+}
+
+// compactionPickerUniversal implements universal compactions on top of the
+// leveled picker, to which it delegates everything but score-based
+// compactions. Its base level is always L1, since the levels between L0 and
+// the deepest sorted run are not necessarily empty.
+type compactionPickerUniversal struct {
+	*compactionPickerByScore
+	uopts *UniversalCompactionOptions
+	// runs are the sorted runs of vers, from newest to oldest.
+	runs []sortedRun
+}
+
+var _ compactionPicker = &compactionPickerUniversal{}
+
+func newCompactionPickerUniversal(
+	v *version,
+	l0Organizer *manifest.L0Organizer,
+	virtualBackings *manifest.VirtualBackings,
+	opts *Options,
+	inProgressCompactions []compactionInfo,
+) *compactionPickerUniversal {
+	p := &compactionPickerUniversal{
+		compactionPickerByScore: newCompactionPickerByScore(
+			v, l0Organizer, virtualBackings, opts, inProgressCompactions),
+		uopts: opts.Experimental.UniversalCompaction,
+	}
+	p.compactionPickerByScore.forceBaseLevel1()
+	for sublevel := len(v.L0SublevelFiles) - 1; sublevel >= 0; sublevel-- {
+		p.runs = append(p.runs, makeSortedRun(0, v.L0SublevelFiles[sublevel]))
+	}
+	for level := 1; level < numLevels; level++ {
+		if !v.Levels[level].Empty() {
+			p.runs = append(p.runs, makeSortedRun(level, v.Levels[level].Slice()))
+		}
+	}
+	return p
+}
+
+func makeSortedRun(level int, files manifest.LevelSlice) sortedRun {
+	r := sortedRun{level: level, files: files, size: files.AggregateSizeSum()}
+	for f := range files.All() {
+		if f.IsCompacting() {
+			r.compacting = true
+			break
+		}
+	}
+	return r
+}
+
+// score returns the ratio between the number of sorted runs and the number of
+// sorted runs that triggers a compaction.
+func (p *compactionPickerUniversal) score() float64 {
+	return float64(len(p.runs)) / float64(p.opts.L0CompactionThreshold)
+}
+
+// sizeAmplification returns the size of the sorted runs above the oldest one
+// as a fraction of the size of the oldest one.
+func (p *compactionPickerUniversal) sizeAmplification() float64 {
+	if len(p.runs) < 2 {
+		return 0
+	}
+	var newer uint64
+	for _, r := range p.runs[:len(p.runs)-1] {
+		newer += r.size
+	}
+	oldest := p.runs[len(p.runs)-1].size
+	if oldest == 0 {
+		return 0
+	}
+	return float64(newer) / float64(oldest)
+}
+
+func (p *compactionPickerUniversal) getMetrics(inProgress []compactionInfo) compactionPickerMetrics {
+	var m compactionPickerMetrics
+	m.levels[0].score = p.score()
+	m.universal.sortedRuns = len(p.runs)
+	m.universal.sizeAmplification = p.sizeAmplification()
+	return m
+}
+
+// estimatedCompactionDebt estimates the number of bytes which need to be
+// compacted before the number of sorted runs drops below the compaction
+// threshold, or before the LSM is fully compacted if its size amplification is
+// too high.
+func (p *compactionPickerUniversal) estimatedCompactionDebt() uint64 {
+	if p == nil || len(p.runs) < 2 {
+		return 0
+	}
+	if p.sizeAmplification()*100 >= float64(p.uopts.MaxSizeAmplificationPercent) {
+		var debt uint64
+		for _, r := range p.runs {
+			debt += r.size
+		}
+		return debt
+	}
+	if len(p.runs) < p.opts.L0CompactionThreshold {
+		return 0
+	}
+	var debt uint64
+	for _, r := range p.runs[:min(len(p.runs)-p.opts.L0CompactionThreshold+2, len(p.runs))] {
+		debt += r.size
+	}
+	return debt
+}
+
+// forceBaseLevel1 is a no-op, as the base level is always L1.
+func (p *compactionPickerUniversal) forceBaseLevel1() {}
+
+// pickAutoScore picks the best universal compaction, if any.
+func (p *compactionPickerUniversal) pickAutoScore(env compactionEnv) (pc *pickedCompaction) {
+	n := len(p.runs)
+	if n < 2 || n < p.opts.L0CompactionThreshold {
+		return nil
+	}
+
+	// Reduce the size amplification by compacting all the sorted runs
+	// together.
+	if p.sizeAmplification()*100 >= float64(p.uopts.MaxSizeAmplificationPercent) {
+		if pc := p.pickRuns(env, 0, n-1); pc != nil {
+			return pc
+		}
+	}
+
+	// Merge consecutive sorted runs of similar size. A run is added to the
+	// candidate as long as it's not much larger than the runs picked so far,
+	// so that each byte is rewritten a logarithmic number of times.
+	for i := 0; i < n; i++ {
+		if p.runs[i].compacting {
+			continue
+		}
+		j, size := i, p.runs[i].size
+		for j+1 < n && (p.uopts.MaxMergeWidth == 0 || j+1-i < p.uopts.MaxMergeWidth) {
+			next := &p.runs[j+1]
+			if next.compacting || next.size*100 > size*uint64(100+p.uopts.SizeRatio) {
+				break
+			}
+			size += next.size
+			j++
+		}
+		if j-i+1 >= p.uopts.MinMergeWidth {
+			if pc := p.pickRuns(env, i, j); pc != nil {
+				return pc
+			}
+		}
+	}
+
+	// Merge the newest sorted runs to bring the number of sorted runs back
+	// below the compaction threshold.
+	width := max(n-p.opts.L0CompactionThreshold+1, p.uopts.MinMergeWidth)
+	if p.uopts.MaxMergeWidth > 0 {
+		width = min(width, p.uopts.MaxMergeWidth)
+	}
+	return p.pickRuns(env, 0, min(width, n)-1)
+}
+
+// pickRuns returns a compaction of the sorted runs i through j, or nil if the
+// compaction conflicts with an in-progress compaction. The range of sorted
+// runs is extended to include all the L0 sublevels older than run j, and, if
+// necessary, the level below L0 that the output would otherwise overlap.
+func (p *compactionPickerUniversal) pickRuns(env compactionEnv, i, j int) *pickedCompaction {
+	n := len(p.runs)
+	for j+1 < n && p.runs[j+1].level == 0 {
+		j++
+	}
+	var outputLevel int
+	switch {
+	case p.runs[j].level > 0:
+		outputLevel = p.runs[j].level
+	case j == n-1:
+		outputLevel = numLevels - 1
+	default:
+		// Write the output to the deepest level above the next older sorted
+		// run, and if that's L0, merge that run too.
+		outputLevel = p.runs[j+1].level - 1
+		if outputLevel == 0 {
+			j++
+			outputLevel = p.runs[j].level
+		}
+	}
+	for k := i; k <= j; k++ {
+		if p.runs[k].compacting || !canCompactTables(p.runs[k].files, p.runs[k].level, env.problemSpans) {
+			return nil
+		}
+	}
+	startLevel := p.runs[i].level
+	// A compaction that is in progress and writing into one of the levels
+	// spanned by this compaction may be producing data that is older than
+	// the output of this compaction.
+	for _, c := range env.inProgressCompactions {
+		if c.outputLevel > startLevel && c.outputLevel <= outputLevel {
+			return nil
+		}
+	}
+
+	pc := newPickedCompaction(p.opts, p.vers, p.l0Organizer, startLevel, outputLevel, p.baseLevel)
+	pc.kind = compactionKindUniversal
+	pc.score = p.score()
+	pc.inputs = pc.inputs[:0]
+	var l0Files []*tableMetadata
+	for k := i; k <= j; k++ {
+		r := &p.runs[k]
+		if r.level == 0 {
+			for f := range r.files.All() {
+				l0Files = append(l0Files, f)
+			}
+			continue
+		}
+		pc.inputs = append(pc.inputs, compactionLevel{level: r.level, files: r.files})
+	}
+	if l0Files != nil {
+		files := manifest.NewLevelSliceSeqSorted(l0Files)
+		pc.inputs = append([]compactionLevel{{
+			level:          0,
+			files:          files,
+			l0SublevelInfo: generateSublevelInfo(pc.cmp, files),
+		}}, pc.inputs...)
+	}
+	if pc.inputs[len(pc.inputs)-1].level != outputLevel {
+		pc.inputs = append(pc.inputs, compactionLevel{level: outputLevel})
+	}
+	pc.startLevel = &pc.inputs[0]
+	pc.outputLevel = &pc.inputs[len(pc.inputs)-1]
+	for k := 1; k < len(pc.inputs)-1; k++ {
+		pc.extraLevels = append(pc.extraLevels, &pc.inputs[k])
+	}
+	for k := range pc.inputs {
+		pc.maybeExpandBounds(manifest.KeyRange(pc.cmp, pc.inputs[k].files.All()))
+	}
+	// Fail-safe to protect against compacting the same sstable concurrently.
+	if inputRangeAlreadyCompacting(env, pc) {
+		return nil
+	}
+	return pc
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/datadriven"
+	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/internal/manifest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUniversalCompactionOptions(t *testing.T) {
+	opts := &Options{}
+	opts.Experimental.CompactionStyle = CompactionStyleUniversal
+	opts.Experimental.UniversalCompaction = &UniversalCompactionOptions{
+		MaxSizeAmplificationPercent: 300,
+	}
+	opts.EnsureDefaults()
+
+	// The options round trip through the OPTIONS file.
+	var parsed Options
+	require.NoError(t, parsed.Parse(opts.String(), nil))
+	require.Equal(t, CompactionStyleUniversal, parsed.Experimental.CompactionStyle)
+	require.Equal(t, *opts.Experimental.UniversalCompaction, *parsed.Experimental.UniversalCompaction)
+}
+
+func TestCompactionPickerUniversal(t *testing.T) {
+	var picker *compactionPickerUniversal
+	var inProgress []compactionInfo
+
+	datadriven.RunTest(t, "testdata/compaction_picker_universal", func(t *testing.T, td *datadriven.TestData) string {
+		switch td.Cmd {
+		case "define":
+			opts := DefaultOptions()
+			opts.Experimental.CompactionStyle = CompactionStyleUniversal
+			opts.Experimental.UniversalCompaction = &UniversalCompactionOptions{}
+			uopts := opts.Experimental.UniversalCompaction
+			td.MaybeScanArgs(t, "l0_compaction_threshold", &opts.L0CompactionThreshold)
+			td.MaybeScanArgs(t, "size_ratio", &uopts.SizeRatio)
+			td.MaybeScanArgs(t, "min_merge_width", &uopts.MinMergeWidth)
+			td.MaybeScanArgs(t, "max_merge_width", &uopts.MaxMergeWidth)
+			td.MaybeScanArgs(t, "max_size_amp", &uopts.MaxSizeAmplificationPercent)
+			opts.EnsureDefaults()
+
+			var v *version
+			var l0Organizer *manifest.L0Organizer
+			v, l0Organizer, inProgress = parsePickerVersion(t, td, opts)
+			vb := manifest.MakeVirtualBackings()
+			picker = newCompactionPickerUniversal(v, l0Organizer, &vb, opts, inProgress)
+
+			var buf strings.Builder
+			buf.WriteString(v.String())
+			for _, r := range picker.runs {
+				fmt.Fprintf(&buf, "run: L%d %s size=%d", r.level, fileNums(r.files), r.size)
+				if r.compacting {
+					buf.WriteString(" compacting")
+				}
+				buf.WriteString("\n")
+			}
+			m := picker.getMetrics(inProgress)
+			fmt.Fprintf(&buf, "size amplification: %.2f\n", m.universal.sizeAmplification)
+			fmt.Fprintf(&buf, "estimated debt: %d\n", picker.estimatedCompactionDebt())
+			return buf.String()
+
+		case "pick-auto":
+			env := compactionEnv{
+				diskAvailBytes:          math.MaxUint64,
+				earliestUnflushedSeqNum: base.SeqNumMax,
+				inProgressCompactions:   inProgress,
+			}
+			pc := picker.pickAutoScore(env)
+			if pc != nil {
+				checkClone(t, pc)
+			}
+			return pickedCompactionInputs(pc)
+
+		default:
+			return fmt.Sprintf("unknown command: %s", td.Cmd)
+		}
+	})
+}
@@ -0,0 +1,281 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"iter"
+	"slices"
+
+	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/internal/manifest"
+)
+
+// CompactionPolicy picks automatic compactions ahead of the built-in picker
+// selected by Options.Experimental.CompactionStyle. See
+// Options.Experimental.CompactionPolicy.
+//
+// PickCompactions is called with the DB mutex held whenever the DB looks for a
+// compaction to schedule, so it must be fast and must not call into the DB.
+type CompactionPolicy interface {
+	// PickCompactions returns candidate compactions in order of preference.
+	// The first candidate that is valid against the current LSM is
+	// scheduled; the others are discarded, and the policy is consulted again
+	// when the next compaction is picked. If none of the candidates are
+	// valid, the built-in picker picks the compaction instead.
+	//
+	// A candidate is valid if all of its inputs are tables of the levels
+	// [StartLevel, OutputLevel], at least one is in StartLevel, StartLevel <
+	// OutputLevel, and none of the tables are compacting once the inputs are
+	// expanded as described in CompactionCandidate.
+	PickCompactions(state CompactionPolicyState) []CompactionCandidate
+}
+
+// CompactionPolicyState is the state of the LSM passed to
+// CompactionPolicy.PickCompactions. It is only valid for the duration of the
+// call.
+type CompactionPolicyState struct {
+	// Version is a read-only view of the current version of the LSM.
+	Version CompactionPolicyVersion
+	// InProgress describes the compactions that are in progress.
+	InProgress []CompactionPolicyInProgress
+	// Levels holds the scores computed by the built-in picker for each level.
+	// They are only meaningful for CompactionStyleLeveled.
+	Levels [numLevels]CompactionPolicyLevelScore
+	// BaseLevel is the level into which L0 is compacted by the built-in
+	// picker. Levels in [1, BaseLevel) are empty.
+	BaseLevel int
+}
+
+// CompactionPolicyLevelScore holds the compaction scores of a level. See
+// LevelMetrics for the meaning of each score.
+type CompactionPolicyLevelScore struct {
+	Score                 float64
+	FillFactor            float64
+	CompensatedFillFactor float64
+}
+
+// CompactionPolicyInProgress describes a compaction that is in progress.
+type CompactionPolicyInProgress struct {
+	// Inputs holds the input tables of the compaction, per level.
+	Inputs []LevelInfo
+	// OutputLevel is the level into which the compaction writes its output.
+	OutputLevel int
+	// Smallest and Largest bound the keys of the compaction.
+	Smallest InternalKey
+	Largest  InternalKey
+}
+
+// CompactionCandidate is a compaction returned by a CompactionPolicy.
+//
+// Before it is scheduled, the candidate is expanded so that the compaction
+// preserves the ordering of the LSM: all the tables of the levels [StartLevel,
+// OutputLevel] that overlap the key range of the inputs are added to the
+// inputs, until the key range no longer grows. In L0, the tables that overlap
+// the added L0 tables are added as well.
+type CompactionCandidate struct {
+	// StartLevel is the shallowest level of the inputs.
+	StartLevel int
+	// OutputLevel is the level into which the compaction writes its output.
+	OutputLevel int
+	// Inputs are the input tables of the compaction.
+	Inputs []FileNum
+}
+
+// CompactionPolicyVersion is a read-only view of a version of the LSM.
+type CompactionPolicyVersion struct {
+	v *version
+}
+
+// Tables returns the tables of the level, ordered by key, or by sequence
+// number in L0.
+func (v CompactionPolicyVersion) Tables(level int) iter.Seq[TableInfo] {
+	return tableInfos(v.v.Levels[level].All())
+}
+
+// Size returns the total size of the tables of the level.
+func (v CompactionPolicyVersion) Size(level int) uint64 {
+	return v.v.Levels[level].TableSize()
+}
+
+// L0Sublevels returns the number of L0 sublevels.
+func (v CompactionPolicyVersion) L0Sublevels() int {
+	return len(v.v.L0SublevelFiles)
+}
+
+// L0Sublevel returns the tables of the L0 sublevel, ordered by key. Sublevel 0
+// holds the oldest tables.
+func (v CompactionPolicyVersion) L0Sublevel(sublevel int) iter.Seq[TableInfo] {
+	return tableInfos(v.v.L0SublevelFiles[sublevel].All())
+}
+
+// Overlaps returns the tables of the level that overlap the user key range
+// [start, end]. In L0, the tables that overlap the returned tables are
+// returned as well.
+func (v CompactionPolicyVersion) Overlaps(level int, start, end []byte) iter.Seq[TableInfo] {
+	return tableInfos(v.v.Overlaps(level, base.UserKeyBoundsInclusive(start, end)).All())
+}
+
+func tableInfos(files iter.Seq[*tableMetadata]) iter.Seq[TableInfo] {
+	return func(yield func(TableInfo) bool) {
+		for f := range files {
+			if !yield(f.TableInfo()) {
+				return
+			}
+		}
+	}
+}
+
+// compactionPickerPolicy picks the compactions returned by a CompactionPolicy,
+// and delegates to the built-in picker for everything else.
+type compactionPickerPolicy struct {
+	compactionPicker
+	policy      CompactionPolicy
+	opts        *Options
+	vers        *version
+	l0Organizer *manifest.L0Organizer
+}
+
+var _ compactionPicker = &compactionPickerPolicy{}
+
+func newCompactionPickerPolicy(
+	picker compactionPicker, v *version, l0Organizer *manifest.L0Organizer, opts *Options,
+) *compactionPickerPolicy {
+	return &compactionPickerPolicy{
+		compactionPicker: picker,
+		policy:           opts.Experimental.CompactionPolicy,
+		opts:             opts,
+		vers:             v,
+		l0Organizer:      l0Organizer,
+	}
+}
+
+// pickAutoScore picks the first valid candidate returned by the policy, or
+// else the compaction picked by the built-in picker.
+func (p *compactionPickerPolicy) pickAutoScore(env compactionEnv) (pc *pickedCompaction) {
+	state := CompactionPolicyState{
+		Version:   CompactionPolicyVersion{v: p.vers},
+		BaseLevel: p.getBaseLevel(),
+	}
+	for _, c := range env.inProgressCompactions {
+		info := CompactionPolicyInProgress{
+			Inputs:      make([]LevelInfo, 0, len(c.inputs)),
+			OutputLevel: c.outputLevel,
+			Smallest:    c.smallest,
+			Largest:     c.largest,
+		}
+		for _, cl := range c.inputs {
+			info.Inputs = append(info.Inputs, LevelInfo{
+				Level:  cl.level,
+				Tables: slices.Collect(tableInfos(cl.files.All())),
+			})
+		}
+		state.InProgress = append(state.InProgress, info)
+	}
+	m := p.getMetrics(env.inProgressCompactions)
+	for level := range m.levels {
+		state.Levels[level] = CompactionPolicyLevelScore{
+			Score:                 m.levels[level].score,
+			FillFactor:            m.levels[level].fillFactor,
+			CompensatedFillFactor: m.levels[level].compensatedFillFactor,
+		}
+	}
+	for _, candidate := range p.policy.PickCompactions(state) {
+		if pc := p.pickCandidate(env, candidate, state.BaseLevel); pc != nil {
+			return pc
+		}
+	}
+	return p.compactionPicker.pickAutoScore(env)
+}
+
+// pickCandidate returns the compaction of the candidate, expanded to preserve
+// the ordering of the LSM, or nil if the candidate isn't valid.
+func (p *compactionPickerPolicy) pickCandidate(
+	env compactionEnv, candidate CompactionCandidate, baseLevel int,
+) *pickedCompaction {
+	startLevel, outputLevel := candidate.StartLevel, candidate.OutputLevel
+	if startLevel < 0 || startLevel >= outputLevel || outputLevel >= numLevels ||
+		len(candidate.Inputs) == 0 {
+		return nil
+	}
+	inputs := make(map[FileNum]struct{}, len(candidate.Inputs))
+	for _, fileNum := range candidate.Inputs {
+		inputs[fileNum] = struct{}{}
+	}
+	// Check that the inputs all belong to the levels of the compaction.
+	var files []*tableMetadata
+	var foundInStartLevel bool
+	for level := startLevel; level <= outputLevel; level++ {
+		for f := range p.vers.Levels[level].All() {
+			if _, ok := inputs[f.TableNum]; ok {
+				files = append(files, f)
+				foundInStartLevel = foundInStartLevel || level == startLevel
+			}
+		}
+	}
+	if len(files) != len(inputs) || !foundInStartLevel {
+		return nil
+	}
+
+	// The levels in [1, baseLevel) are empty, so the output level is used as
+	// the base level for the purpose of sizing the output tables.
+	pc := newPickedCompaction(p.opts, p.vers, p.l0Organizer, startLevel, outputLevel, min(baseLevel, max(outputLevel, 1)))
+	pc.kind = compactionKindPolicy
+	pc.smallest, pc.largest = manifest.KeyRange(pc.cmp, slices.Values(files))
+	// Expand the inputs until the key range no longer grows. Levels other
+	// than L0 are sorted by key, so this terminates once the range covers
+	// whole tables at either end in every level.
+	levels := make([]manifest.LevelSlice, outputLevel-startLevel+1)
+	for {
+		smallest, largest := pc.smallest, pc.largest
+		for i := range levels {
+			levels[i] = p.vers.Overlaps(startLevel+i, pc.userKeyBounds())
+			pc.maybeExpandBounds(manifest.KeyRange(pc.cmp, levels[i].All()))
+		}
+		if base.InternalCompare(pc.cmp, smallest, pc.smallest) == 0 &&
+			base.InternalCompare(pc.cmp, largest, pc.largest) == 0 {
+			break
+		}
+	}
+
+	pc.inputs = pc.inputs[:0]
+	for i, files := range levels {
+		level := startLevel + i
+		if files.Empty() && level != startLevel && level != outputLevel {
+			continue
+		}
+		if !canCompactTables(files, level, env.problemSpans) {
+			return nil
+		}
+		cl := compactionLevel{level: level, files: files}
+		if level == 0 {
+			cl.l0SublevelInfo = generateSublevelInfo(pc.cmp, files)
+		}
+		pc.inputs = append(pc.inputs, cl)
+	}
+	pc.startLevel = &pc.inputs[0]
+	pc.outputLevel = &pc.inputs[len(pc.inputs)-1]
+	for i := 1; i < len(pc.inputs)-1; i++ {
+		pc.extraLevels = append(pc.extraLevels, &pc.inputs[i])
+	}
+	pc.score = p.getMetrics(env.inProgressCompactions).levels[startLevel].score
+
+	// A compaction that is in progress and writing into one of the
+	// intermediate levels of this compaction, over its key range, may be
+	// producing data that this compaction would miss.
+	for _, c := range env.inProgressCompactions {
+		if c.outputLevel <= startLevel || c.outputLevel >= outputLevel {
+			continue
+		}
+		if base.InternalCompare(pc.cmp, c.largest, pc.smallest) >= 0 &&
+			base.InternalCompare(pc.cmp, c.smallest, pc.largest) <= 0 {
+			return nil
+		}
+	}
+	// Fail-safe to protect against compacting the same sstable concurrently.
+	if inputRangeAlreadyCompacting(env, pc) {
+		return nil
+	}
+	return pc
+}
@@ -0,0 +1,133 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/crlib/crstrings"
+	"github.com/cockroachdb/datadriven"
+	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/internal/manifest"
+)
+
+// candidatesPolicy returns a fixed list of candidates, and records the state
+// it was called with.
+type candidatesPolicy struct {
+	candidates []CompactionCandidate
+	state      CompactionPolicyState
+}
+
+func (p *candidatesPolicy) PickCompactions(state CompactionPolicyState) []CompactionCandidate {
+	p.state = state
+	return p.candidates
+}
+
+func TestCompactionPolicy(t *testing.T) {
+	policy := &candidatesPolicy{}
+	var picker *compactionPickerPolicy
+	var inProgress []compactionInfo
+
+	parseLevel := func(td *datadriven.TestData, s string) int {
+		level, err := strconv.Atoi(strings.TrimPrefix(s, "L"))
+		if err != nil || !strings.HasPrefix(s, "L") {
+			td.Fatalf(t, "malformed level %q", s)
+		}
+		return level
+	}
+
+	datadriven.RunTest(t, "testdata/compaction_picker_policy", func(t *testing.T, td *datadriven.TestData) string {
+		switch td.Cmd {
+		case "define":
+			opts := DefaultOptions()
+			opts.Experimental.CompactionPolicy = policy
+			td.MaybeScanArgs(t, "l0_compaction_threshold", &opts.L0CompactionThreshold)
+			opts.EnsureDefaults()
+
+			var v *version
+			var l0Organizer *manifest.L0Organizer
+			v, l0Organizer, inProgress = parsePickerVersion(t, td, opts)
+			vb := manifest.MakeVirtualBackings()
+			picker = newCompactionPickerPolicy(
+				newCompactionPickerByScore(v, l0Organizer, &vb, opts, inProgress), v, l0Organizer, opts)
+			return v.String()
+
+		case "overlaps":
+			// overlaps <level> <start> <end> lists the tables returned by
+			// CompactionPolicyVersion.Overlaps.
+			if len(td.CmdArgs) != 3 {
+				td.Fatalf(t, "overlaps <level> <start> <end>")
+			}
+			v := CompactionPolicyVersion{v: picker.vers}
+			var tables []string
+			for f := range v.Overlaps(parseLevel(td, td.CmdArgs[0].Key), []byte(td.CmdArgs[1].Key), []byte(td.CmdArgs[2].Key)) {
+				tables = append(tables, f.FileNum.String())
+			}
+			return strings.Join(tables, ",")
+
+		case "pick-auto":
+			// Each line of the input is a candidate returned by the policy, of
+			// the form:
+			//
+			//	L0 -> L6: 000001 000002
+			policy.candidates = nil
+			for _, line := range crstrings.Lines(td.Input) {
+				levels, tables, ok := strings.Cut(line, ":")
+				fields := strings.Fields(levels)
+				if !ok || len(fields) != 3 || fields[1] != "->" {
+					td.Fatalf(t, "malformed candidate %q", line)
+				}
+				c := CompactionCandidate{
+					StartLevel:  parseLevel(td, fields[0]),
+					OutputLevel: parseLevel(td, fields[2]),
+				}
+				for _, table := range strings.Fields(tables) {
+					fileNum, err := strconv.ParseUint(table, 10, 64)
+					if err != nil {
+						td.Fatalf(t, "malformed table %q", table)
+					}
+					c.Inputs = append(c.Inputs, base.FileNum(fileNum))
+				}
+				policy.candidates = append(policy.candidates, c)
+			}
+			env := compactionEnv{
+				diskAvailBytes:          math.MaxUint64,
+				earliestUnflushedSeqNum: base.SeqNumMax,
+				inProgressCompactions:   inProgress,
+			}
+			pc := picker.pickAutoScore(env)
+			if pc != nil {
+				checkClone(t, pc)
+			}
+
+			// Print the state passed to the policy, followed by the picked
+			// compaction.
+			var buf strings.Builder
+			fmt.Fprintf(&buf, "base level: %d\n", policy.state.BaseLevel)
+			for _, c := range policy.state.InProgress {
+				buf.WriteString("in progress:")
+				for _, l := range c.Inputs {
+					if len(l.Tables) == 0 {
+						continue
+					}
+					fmt.Fprintf(&buf, " L%d", l.Level)
+					for _, f := range l.Tables {
+						fmt.Fprintf(&buf, " %s", f.FileNum)
+					}
+				}
+				fmt.Fprintf(&buf, " -> L%d\n", c.OutputLevel)
+			}
+			buf.WriteString(pickedCompactionInputs(pc))
+			return buf.String()
+
+		default:
+			return fmt.Sprintf("unknown command: %s", td.Cmd)
+		}
+	})
+}
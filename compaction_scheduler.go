@@ -106,8 +106,14 @@ type WaitingCompaction struct {
 	// Priority is the priority of a compaction. It is only compared across
 	// compactions, and when the Optional value is the same.
 	Priority int
+	// SpanPriority is the highest priority of the spans of
+	// Options.Experimental.SpanPriorities that the compaction overlaps, or
+	// zero. It is only compared across compactions, and when the Optional and
+	// Priority values are the same.
+	SpanPriority int
 	// Score is only compared across compactions. It is only compared across
-	// compactions, and when the Optional and Priority are the same.
+	// compactions, and when the Optional, Priority and SpanPriority are the
+	// same.
 	Score float64
 }
 
@@ -139,25 +145,39 @@ func init() {
 	scheduledCompactionMap[compactionKindMove] = compactionOptionalAndPriority{priority: 100}
 	scheduledCompactionMap[compactionKindCopy] = compactionOptionalAndPriority{priority: 90}
 	scheduledCompactionMap[compactionKindDefault] = compactionOptionalAndPriority{priority: 80}
+	scheduledCompactionMap[compactionKindUniversal] = compactionOptionalAndPriority{priority: 80}
+	scheduledCompactionMap[compactionKindFIFO] = compactionOptionalAndPriority{priority: 100}
+	scheduledCompactionMap[compactionKindPolicy] = compactionOptionalAndPriority{priority: 80}
 	scheduledCompactionMap[compactionKindTombstoneDensity] =
 		compactionOptionalAndPriority{optional: true, priority: 60}
+	scheduledCompactionMap[compactionKindExpired] =
+		compactionOptionalAndPriority{optional: true, priority: 55}
 	scheduledCompactionMap[compactionKindElisionOnly] =
 		compactionOptionalAndPriority{optional: true, priority: 50}
 	scheduledCompactionMap[compactionKindRead] =
 		compactionOptionalAndPriority{optional: true, priority: 40}
 	scheduledCompactionMap[compactionKindRewrite] =
 		compactionOptionalAndPriority{optional: true, priority: 30}
+	scheduledCompactionMap[compactionKindTiering] =
+		compactionOptionalAndPriority{optional: true, priority: 20}
 }
 
-func makeWaitingCompaction(manual bool, kind compactionKind, score float64) WaitingCompaction {
+func makeWaitingCompaction(
+	manual bool, kind compactionKind, spanPriority int, score float64,
+) WaitingCompaction {
 	if manual {
-		return WaitingCompaction{Priority: manualCompactionPriority, Score: score}
+		return WaitingCompaction{Priority: manualCompactionPriority, SpanPriority: spanPriority, Score: score}
 	}
 	entry, ok := scheduledCompactionMap[kind]
 	if !ok {
 		panic(errors.AssertionFailedf("unexpected compactionKind %s", kind))
 	}
-	return WaitingCompaction{Optional: entry.optional, Priority: entry.priority, Score: score}
+	return WaitingCompaction{
+		Optional:     entry.optional,
+		Priority:     entry.priority,
+		SpanPriority: spanPriority,
+		Score:        score,
+	}
 }
 
 // noopGrantHandle is used in cases that don't interact with a CompactionScheduler.
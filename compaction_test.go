@@ -28,6 +28,7 @@ import (
 	"github.com/cockroachdb/datadriven"
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/errors/oserror"
+	"github.com/chris124567/pebble/bloom"
 	"github.com/chris124567/pebble/internal/base"
 	"github.com/chris124567/pebble/internal/compact"
 	"github.com/chris124567/pebble/internal/manifest"
@@ -80,6 +81,10 @@ func (p *compactionPickerForTesting) getBaseLevel() int {
 	return p.baseLevel
 }
 
+func (p *compactionPickerForTesting) getCompactionConcurrency() int {
+	return 1
+}
+
 func (p *compactionPickerForTesting) estimatedCompactionDebt() uint64 {
 	return 0
 }
@@ -1331,7 +1336,7 @@ func TestCompaction(t *testing.T) {
 		},
 		"set_with_del_sstable_Pebblev7": {
 			minVersion: formatFooterAttributes,
-			maxVersion: formatFooterAttributes,
+			maxVersion: formatTableFormatV8,
 		},
 		"value_separation": {
 			minVersion: FormatExperimentalValueSeparation,
@@ -1368,6 +1373,139 @@ func TestCompaction(t *testing.T) {
 	})
 }
 
+// TestCompactionSpanPolicy tests that the tables written for a span get the
+// physical layout and storage of the span's SpanPolicy.
+func TestCompactionSpanPolicy(t *testing.T) {
+	opts := &Options{
+		FS:                          vfs.NewMem(),
+		FormatMajorVersion:          FormatNewest,
+		DisableAutomaticCompactions: true,
+		Logger:                      testLogger{t},
+	}
+	opts.Levels = make([]LevelOptions, numLevels)
+	for i := range opts.Levels {
+		opts.Levels[i].Compression = func() Compression { return SnappyCompression }
+		opts.Levels[i].FilterPolicy = bloom.FilterPolicy(10)
+	}
+	opts.Experimental.RemoteStorage = remote.MakeSimpleFactory(map[remote.Locator]remote.Storage{
+		"": remote.NewInMem(),
+	})
+	opts.Experimental.CreateOnShared = remote.CreateOnSharedLower
+	opts.Experimental.SpanPolicyFunc = MakeStaticSpanPolicyFunc(
+		DefaultComparer.Compare, KeyRange{Start: []byte("b"), End: []byte("c")}, SpanPolicy{
+			Compression:           ZstdCompression,
+			CompressionLevel:      19,
+			BlockSize:             512,
+			BloomFilterBitsPerKey: -1,
+			TableStoragePolicy:    TableStorageShared,
+		})
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	require.NoError(t, d.SetCreatorID(1))
+
+	for _, prefix := range []string{"a", "b", "c"} {
+		for i := 0; i < 500; i++ {
+			require.NoError(t, d.Set([]byte(fmt.Sprintf("%s%04d", prefix, i)), bytes.Repeat([]byte(prefix), 20), nil))
+		}
+	}
+	require.NoError(t, d.Flush())
+
+	tables, err := d.SSTables(WithProperties())
+	require.NoError(t, err)
+	require.Len(t, tables[0], 3)
+	for _, info := range tables[0] {
+		start := string(info.Smallest.UserKey[:1])
+		if start == "b" {
+			require.Equal(t, "ZSTD", info.Properties.CompressionName)
+			require.Equal(t, "", info.Properties.FilterPolicyName)
+			require.Greater(t, info.Properties.NumDataBlocks, uint64(10))
+			require.Equal(t, BackingTypeShared, info.BackingType)
+		} else {
+			require.Equal(t, "Snappy", info.Properties.CompressionName)
+			require.Equal(t, "rocksdb.BuiltinBloomFilter", info.Properties.FilterPolicyName)
+			require.Less(t, info.Properties.NumDataBlocks, uint64(10))
+			require.Equal(t, BackingTypeLocal, info.BackingType)
+		}
+	}
+
+	// The tables are readable, whatever their layout.
+	for _, prefix := range []string{"a", "b", "c"} {
+		v, closer, err := d.Get([]byte(prefix + "0123"))
+		require.NoError(t, err)
+		require.Equal(t, bytes.Repeat([]byte(prefix), 20), v)
+		require.NoError(t, closer.Close())
+	}
+}
+
+// TestCompactionSpanPolicyMove tests that tables moved between levels are
+// copied to shared storage, or rewritten, according to their SpanPolicy.
+func TestCompactionSpanPolicyMove(t *testing.T) {
+	opts := &Options{
+		FS:                          vfs.NewMem(),
+		FormatMajorVersion:          FormatNewest,
+		DisableAutomaticCompactions: true,
+		Logger:                      testLogger{t},
+	}
+	opts.Experimental.RemoteStorage = remote.MakeSimpleFactory(map[remote.Locator]remote.Storage{
+		"": remote.NewInMem(),
+	})
+	opts.Experimental.CreateOnShared = remote.CreateOnSharedAll
+	opts.Experimental.SpanPolicyFunc = MakeStaticSpanPolicyFunc(
+		DefaultComparer.Compare, KeyRange{Start: []byte("b"), End: []byte("c")}, SpanPolicy{
+			TableStoragePolicy: TableStorageLocal,
+		})
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	require.NoError(t, d.SetCreatorID(1))
+
+	// compact writes the keys of the prefixes to a single L0 table and
+	// compacts it into L6, returning the backing types of the L6 tables by
+	// their first key.
+	compact := func(prefixes ...string) map[string]BackingType {
+		for _, prefix := range prefixes {
+			for i := 0; i < 10; i++ {
+				require.NoError(t, d.Set([]byte(fmt.Sprintf("%s%04d", prefix, i)), []byte(prefix), nil))
+			}
+		}
+		require.NoError(t, d.Flush())
+		require.NoError(t, d.Compact(context.Background(), []byte("a"), []byte("z"), false))
+		tables, err := d.SSTables()
+		require.NoError(t, err)
+		res := make(map[string]BackingType)
+		for _, info := range tables[numLevels-1] {
+			res[string(info.Smallest.UserKey)] = info.BackingType
+		}
+		return res
+	}
+	// A local table in the local span is moved, and stays local.
+	require.Equal(t, map[string]BackingType{"b0000": BackingTypeLocal}, compact("b"))
+	// A local table in the default span is copied to shared storage.
+	require.Equal(t, map[string]BackingType{
+		"a0000": BackingTypeShared,
+		"b0000": BackingTypeLocal,
+	}, compact("a"))
+
+	// The span policies of a table spanning both spans disagree.
+	for _, tc := range []struct {
+		start, end   string
+		preferShared bool
+		ok           bool
+	}{
+		{start: "a", end: "a9", preferShared: true, ok: true},
+		{start: "b", end: "b9", preferShared: false, ok: true},
+		{start: "a", end: "b", preferShared: true, ok: false},
+		{start: "b", end: "c", preferShared: true, ok: false},
+	} {
+		bounds := base.UserKeyBoundsInclusive([]byte(tc.start), []byte(tc.end))
+		preferShared, ok, err := d.opts.spanPreferSharedStorage(bounds, numLevels-1)
+		require.NoError(t, err)
+		require.Equal(t, tc.preferShared, preferShared, "%s", bounds)
+		require.Equal(t, tc.ok, ok, "%s", bounds)
+	}
+}
+
 func TestCompactionOutputLevel(t *testing.T) {
 	opts := DefaultOptions()
 	version := manifest.NewInitialVersion(opts.Comparer)
@@ -297,7 +297,22 @@ type DB struct {
 	newIters             tableNewIters
 	tableNewRangeKeyIter keyspanimpl.TableNewSpanIter
 
+	// fileKeys holds the data keys of encrypted sstables and blob files.
+	fileKeys *fileKeys
+
+	// compressionModels holds, for each level, the model choosing the
+	// compression of the data blocks of tables written to the level with
+	// AdaptiveCompression.
+	compressionModels [numLevels]*block.AdaptiveCompressionModel
+
 	commit *commitPipeline
+	// txns records the writes committed while optimistic transactions are
+	// open. Protected by commit.mu.
+	txns txnTracker
+	// locks holds the key locks acquired through LockKeys.
+	locks lockTable
+	// changes delivers committed batches to change subscribers.
+	changes changeFeed
 
 	// readState provides access to the state needed for reading without needing
 	// to acquire DB.mu.
@@ -428,6 +443,9 @@ type DB struct {
 			// downloads is the list of pending download tasks. The next download to
 			// perform is at the start of the list. New entries are added to the end.
 			downloads []*downloadSpanTask
+			// tiering is the state of the scan for cold tables to move to
+			// remote storage. See Options.Experimental.Tiering.
+			tiering tieringScan
 			// inProgress is the set of in-progress flushes and compactions.
 			// It's used in the calculation of some metrics and to initialize L0
 			// sublevels' state. Some of the compactions contained within this
@@ -545,6 +563,7 @@ type getIterAlloc struct {
 	dbi    Iterator
 	keyBuf []byte
 	get    getIter
+	ttl    ttlIter
 }
 
 var getIterAllocPool = sync.Pool{
@@ -616,6 +635,11 @@ func (d *DB) getInternal(key []byte, b *Batch, s *Snapshot) ([]byte, io.Closer,
 		readState:    readState,
 		keyBuf:       buf.keyBuf,
 	}
+	if d.opts.TTL != nil {
+		i.initTTL(&buf.ttl, d.ttlNow())
+		buf.ttl.init(pointIter)
+		i.iter, i.pointIter = &buf.ttl, &buf.ttl
+	}
 	// Set up a blob value fetcher to use for retrieving values from blob files.
 	i.blobValueFetcher.Init(d.fileCache, block.NoReadEnv)
 	get.iiopts.blobValueFetcher = &i.blobValueFetcher
@@ -631,12 +655,16 @@ func (d *DB) getInternal(key []byte, b *Batch, s *Snapshot) ([]byte, io.Closer,
 }
 
 // Set sets the value for the given key. It overwrites any previous value
-// for that key; a DB is not a multi-map.
+// for that key; a DB is not a multi-map. If opts.TTL is positive, the value
+// expires after the TTL; see Batch.SetWithTTL.
 //
 // It is safe to modify the contents of the arguments after Set returns.
 func (d *DB) Set(key, value []byte, opts *WriteOptions) error {
 	b := newBatch(d)
-	_ = b.Set(key, value, opts)
+	if err := b.Set(key, value, opts); err != nil {
+		b.release()
+		return err
+	}
 	if err := d.Apply(b, opts); err != nil {
 		return err
 	}
@@ -841,6 +869,12 @@ func (d *DB) applyInternal(batch *Batch, opts *WriteOptions, noSyncWait bool) er
 	if batch.db != nil && batch.db != d {
 		panic(fmt.Sprintf("pebble: batch db mismatch: %p != %p", batch.db, d))
 	}
+	if batch.db == nil && d.opts.TTL != nil {
+		// The values of a batch built without a DB lack TTL headers.
+		if err := checkUnownedBatchTTL(batch); err != nil {
+			return err
+		}
+	}
 
 	sync := opts.GetSync()
 	if sync && d.opts.DisableWAL {
@@ -874,10 +908,20 @@ func (d *DB) applyInternal(batch *Batch, opts *WriteOptions, noSyncWait bool) er
 		}
 	}
 	if err := d.commit.Commit(batch, sync, noSyncWait); err != nil {
+		// A transaction that fails validation is rejected before it enters the
+		// commit pipeline.
+		var conflict *ErrTxnConflict
+		if errors.As(err, &conflict) {
+			return err
+		}
 		// There isn't much we can do on an error here. The commit pipeline will be
 		// horked at this point.
 		d.opts.Logger.Fatalf("pebble: fatal commit error: %v", err)
 	}
+	// The batch's writes are visible; waiters on its key locks may proceed and
+	// subscribers may observe them.
+	batch.releaseLocks()
+	d.changes.deliver(d.mu.versions.visibleSeqNum.Load())
 	// If this is a large batch, we need to clear the batch contents as the
 	// flushable batch may still be present in the flushables queue.
 	//
@@ -1002,6 +1046,7 @@ type iterAlloc struct {
 	boundsBuf           [2][]byte
 	prefixOrFullSeekKey []byte
 	merging             mergingIter
+	ttl                 ttlIter
 	mlevels             [3 + numLevels]mergingIterLevel
 	levels              [3 + numLevels]levelIter
 	levelsPositioned    [3 + numLevels]bool
@@ -1116,6 +1161,9 @@ func (d *DB) newIter(
 		seqNum:              seqNum,
 		batchOnlyIter:       newIterOpts.batch.batchOnly,
 	}
+	if d.opts.TTL != nil {
+		dbi.initTTL(&buf.ttl, d.ttlNow())
+	}
 	if o != nil {
 		dbi.opts = *o
 		dbi.processBounds(o.LowerBound, o.UpperBound)
@@ -1552,6 +1600,10 @@ func (i *Iterator) constructPointIter(
 	buf.merging.combinedIterState = &i.lazyCombinedIter.combinedIterState
 	i.pointIter = invalidating.MaybeWrapIfInvariants(&buf.merging).(topLevelIterator)
 	i.merging = &buf.merging
+	if i.ttl != nil {
+		i.ttl.init(i.pointIter)
+		i.pointIter = i.ttl
+	}
 }
 
 // NewBatch returns a new empty write-only batch. Any reads on the batch will
@@ -2110,6 +2162,14 @@ func (d *DB) Metrics() *Metrics {
 			metrics.Levels[level].FillFactor = lm.fillFactor
 			metrics.Levels[level].CompensatedFillFactor = lm.compensatedFillFactor
 		}
+		metrics.Compact.Universal.SortedRuns = m.universal.sortedRuns
+		metrics.Compact.Universal.SizeAmplification = m.universal.sizeAmplification
+	}
+	for _, s := range d.opts.Experimental.SpanPriorities {
+		metrics.Spans = append(metrics.Spans, SpanMetrics{
+			SpanPriority: s,
+			ReadAmp:      spanReadAmp(d.cmp, vers, s.bounds()),
+		})
 	}
 	metrics.Table.ZombieCount = int64(d.mu.versions.zombieTables.Count())
 	metrics.Table.ZombieSize = d.mu.versions.zombieTables.TotalSize()
@@ -2152,6 +2212,9 @@ func (d *DB) Metrics() *Metrics {
 		metrics.Table.CompressedCountSnappy += int64(compressionTypes.snappy)
 		metrics.Table.CompressedCountZstd += int64(compressionTypes.zstd)
 		metrics.Table.CompressedCountMinLZ += int64(compressionTypes.minlz)
+		metrics.Table.CompressedCountLZ4 += int64(compressionTypes.lz4)
+		metrics.Table.CompressedCountZstdDict += int64(compressionTypes.zstdDict)
+		metrics.Table.CompressedCountAdaptive += int64(compressionTypes.adaptive)
 		metrics.Table.CompressedCountNone += int64(compressionTypes.none)
 	}
 
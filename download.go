@@ -35,11 +35,17 @@ type DownloadSpan struct {
 	// while copying only the backing file will obligate future reads to continue
 	// to compute such transforms.
 	ViaBackingFileDownload bool
+	// RecallTiered, if true, indicates that the sstables in the span that were
+	// moved to remote storage by tiering (see Options.Experimental.Tiering)
+	// should also be brought back to local storage. Such sstables are copied
+	// byte-for-byte when possible.
+	RecallTiered bool
 }
 
-// Download ensures that the LSM does not use any external sstables for the
-// given key ranges. It does so by performing appropriate compactions so that
-// all external data becomes available locally.
+// Download ensures that the LSM does not use any external sstables (or, with
+// DownloadSpan.RecallTiered, any sstables moved to remote storage by tiering)
+// for the given key ranges. It does so by performing appropriate compactions
+// so that all such data becomes available locally.
 //
 // Note that calling this method does not imply that all other compactions stop;
 // it simply informs Pebble of a list of spans for which external data should be
@@ -205,6 +211,9 @@ func (d *DB) removeDownloadTasks(tasks []*downloadSpanTask) {
 type downloadSpanTask struct {
 	downloadSpan DownloadSpan
 
+	// filter selects the files that the task downloads.
+	filter downloadFilter
+
 	// The download task pertains to sstables which *start* (as per
 	// Smallest.UserKey) within these bounds.
 	bounds base.UserKeyBounds
@@ -248,10 +257,11 @@ func (d *DB) newDownloadSpanTask(vers *version, sp DownloadSpan) (_ *downloadSpa
 	// [sp.StartKey, sp.EndKey). Expand the bounds to the left so that we
 	// include the start keys of any external sstables that overlap with
 	// sp.StartKey.
-	for _, ls := range vers.AllLevelsAndSublevels() {
+	filter := d.makeDownloadFilter(sp)
+	for layer, ls := range vers.AllLevelsAndSublevels() {
 		iter := ls.Iter()
 		if f := iter.SeekGE(d.cmp, sp.StartKey); f != nil &&
-			objstorage.IsExternalTable(d.objProvider, f.FileBacking.DiskFileNum) &&
+			filter(f, layer.Level()) &&
 			d.cmp(f.Smallest().UserKey, bounds.Start) < 0 {
 			bounds.Start = f.Smallest().UserKey
 		}
@@ -261,7 +271,7 @@ func (d *DB) newDownloadSpanTask(vers *version, sp DownloadSpan) (_ *downloadSpa
 		key:    bounds.Start,
 		seqNum: 0,
 	}
-	f, level := startCursor.NextExternalFile(d.cmp, d.objProvider, bounds, vers)
+	f, level := startCursor.NextExternalFile(d.cmp, filter, bounds, vers)
 	if f == nil {
 		// No external files in the given span.
 		return nil, false
@@ -269,12 +279,38 @@ func (d *DB) newDownloadSpanTask(vers *version, sp DownloadSpan) (_ *downloadSpa
 
 	return &downloadSpanTask{
 		downloadSpan:      sp,
+		filter:            filter,
 		bounds:            bounds,
 		taskCompletedChan: make(chan error, 1),
 		cursor:            makeCursorAtFile(f, level),
 	}, true
 }
 
+// downloadFilter returns true for the files in the given level that a download
+// task downloads.
+type downloadFilter func(f *tableMetadata, level int) bool
+
+// externalFilesFilter returns a downloadFilter that selects files with
+// external backings.
+func externalFilesFilter(objProvider objstorage.Provider) downloadFilter {
+	return func(f *tableMetadata, level int) bool {
+		return f.Virtual && objstorage.IsExternalTable(objProvider, f.FileBacking.DiskFileNum)
+	}
+}
+
+// makeDownloadFilter returns the downloadFilter for the given span: files with
+// external backings and, if RecallTiered is set, files moved to remote storage
+// by tiering.
+func (d *DB) makeDownloadFilter(sp DownloadSpan) downloadFilter {
+	external := externalFilesFilter(d.objProvider)
+	if !sp.RecallTiered {
+		return external
+	}
+	return func(f *tableMetadata, level int) bool {
+		return external(f, level) || d.isTieredTable(f, level)
+	}
+}
+
 // downloadCursor represents a position in the download process, which does not
 // depend on a specific version.
 //
@@ -342,13 +378,14 @@ func (c downloadCursor) Compare(keyCmp base.Compare, other downloadCursor) int {
 	return cmp.Compare(c.seqNum, other.seqNum)
 }
 
-// NextExternalFile returns the first file after the cursor, returning the file
-// and the level. If no such file exists, returns nil fileMetadata.
+// NextExternalFile returns the first file selected by the filter after the
+// cursor, returning the file and the level. If no such file exists, returns nil
+// fileMetadata.
 func (c downloadCursor) NextExternalFile(
-	cmp base.Compare, objProvider objstorage.Provider, bounds base.UserKeyBounds, v *version,
+	cmp base.Compare, filter downloadFilter, bounds base.UserKeyBounds, v *version,
 ) (_ *tableMetadata, level int) {
 	for !c.AtEnd() {
-		if f := c.NextExternalFileOnLevel(cmp, objProvider, bounds.End, v); f != nil {
+		if f := c.NextExternalFileOnLevel(cmp, filter, bounds.End, v); f != nil {
 			return f, c.level
 		}
 		// Go to the next level.
@@ -359,20 +396,20 @@ func (c downloadCursor) NextExternalFile(
 	return nil, manifest.NumLevels
 }
 
-// NextExternalFileOnLevel returns the first external file on c.level which is
-// after c and with Smallest.UserKey within the end bound.
+// NextExternalFileOnLevel returns the first file selected by the filter on
+// c.level which is after c and with Smallest.UserKey within the end bound.
 func (c downloadCursor) NextExternalFileOnLevel(
-	cmp base.Compare, objProvider objstorage.Provider, endBound base.UserKeyBoundary, v *version,
+	cmp base.Compare, filter downloadFilter, endBound base.UserKeyBoundary, v *version,
 ) *tableMetadata {
 	if c.level > 0 {
 		it := v.Levels[c.level].Iter()
-		return firstExternalFileInLevelIter(cmp, objProvider, c, it, endBound)
+		return firstExternalFileInLevelIter(cmp, filter, c, it, endBound)
 	}
 	// For L0, we look at all sublevel iterators and take the first file.
 	var first *tableMetadata
 	var firstCursor downloadCursor
 	for _, sublevel := range v.L0SublevelFiles {
-		f := firstExternalFileInLevelIter(cmp, objProvider, c, sublevel.Iter(), endBound)
+		f := firstExternalFileInLevelIter(cmp, filter, c, sublevel.Iter(), endBound)
 		if f != nil {
 			c := makeCursorAtFile(f, c.level)
 			if first == nil || c.Compare(cmp, firstCursor) < 0 {
@@ -386,12 +423,12 @@ func (c downloadCursor) NextExternalFileOnLevel(
 	return first
 }
 
-// firstExternalFileInLevelIter finds the first external file after the cursor
-// but which starts before the endBound. It is assumed that the iterator
-// corresponds to cursor.level.
+// firstExternalFileInLevelIter finds the first file selected by the filter
+// after the cursor but which starts before the endBound. It is assumed that the
+// iterator corresponds to cursor.level.
 func firstExternalFileInLevelIter(
 	cmp base.Compare,
-	objProvider objstorage.Provider,
+	filter downloadFilter,
 	cursor downloadCursor,
 	it manifest.LevelIterator,
 	endBound base.UserKeyBoundary,
@@ -403,7 +440,7 @@ func firstExternalFileInLevelIter(
 		f = it.Next()
 	}
 	for ; f != nil && endBound.IsUpperBoundFor(cmp, f.Smallest().UserKey); f = it.Next() {
-		if f.Virtual && objstorage.IsExternalTable(objProvider, f.FileBacking.DiskFileNum) {
+		if filter(f, cursor.level) {
 			return f
 		}
 	}
@@ -431,6 +468,14 @@ func (d *DB) tryLaunchDownloadForFile(
 	if download.downloadSpan.ViaBackingFileDownload {
 		kind = compactionKindCopy
 	}
+	if d.isTieredTable(f, level) {
+		// Tiered files are copied back to local storage, unless they were
+		// virtualized after being moved (e.g. by an excise).
+		kind = compactionKindRewrite
+		if !f.Virtual {
+			kind = compactionKindCopy
+		}
+	}
 	pc := pickDownloadCompaction(vers, l0Organizer, d.opts, env, d.mu.versions.picker.getBaseLevel(), kind, level, f)
 	if pc == nil {
 		// We are not able to run this download compaction at this time.
@@ -497,7 +542,7 @@ func (d *DB) tryLaunchDownloadCompaction(
 		// files within the bookmark. This is ok because this method is called (for
 		// this download task) at most once every time a compaction completes.
 
-		f := b.start.NextExternalFileOnLevel(d.cmp, d.objProvider, b.endBound, vers)
+		f := b.start.NextExternalFileOnLevel(d.cmp, download.filter, b.endBound, vers)
 		if f == nil {
 			// No more external files for this bookmark, remove it.
 			download.bookmarks = slices.Delete(download.bookmarks, i, i+1)
@@ -519,7 +564,7 @@ func (d *DB) tryLaunchDownloadCompaction(
 
 	// Try to advance the cursor and launch more downloads.
 	for len(download.bookmarks) < maxConcurrentDownloads {
-		f, level := download.cursor.NextExternalFile(d.cmp, d.objProvider, download.bounds, vers)
+		f, level := download.cursor.NextExternalFile(d.cmp, download.filter, download.bounds, vers)
 		if f == nil {
 			download.cursor = endCursor
 			if len(download.bookmarks) == 0 {
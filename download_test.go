@@ -60,10 +60,10 @@ func TestDownloadCursor(t *testing.T) {
 					fmt.Fprintf(&buf, "  %s\n", cursor)
 
 				case "next-file":
-					f, level := cursor.NextExternalFile(cmp, objProvider, bounds, vers)
+					f, level := cursor.NextExternalFile(cmp, externalFilesFilter(objProvider), bounds, vers)
 					if f != nil {
 						// Verify that fCursor still points to this file.
-						f2, level2 := makeCursorAtFile(f, level).NextExternalFile(cmp, objProvider, bounds, vers)
+						f2, level2 := makeCursorAtFile(f, level).NextExternalFile(cmp, externalFilesFilter(objProvider), bounds, vers)
 						if f != f2 {
 							td.Fatalf(t, "nextExternalFile returned different file")
 						}
@@ -76,7 +76,7 @@ func TestDownloadCursor(t *testing.T) {
 
 				case "iterate":
 					for {
-						f, level := cursor.NextExternalFile(cmp, objProvider, bounds, vers)
+						f, level := cursor.NextExternalFile(cmp, externalFilesFilter(objProvider), bounds, vers)
 						if f == nil {
 							fmt.Fprintf(&buf, "  no more files\n")
 							break
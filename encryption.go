@@ -0,0 +1,257 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/internal/encryption"
+	"github.com/chris124567/pebble/internal/manifest"
+	"github.com/chris124567/pebble/vfs"
+)
+
+// KeyProvider provides the keys that wrap the data keys of encrypted files.
+// See EncryptionOptions.
+type KeyProvider = encryption.KeyProvider
+
+// EncryptionOptions configures the encryption of a DB's files at rest.
+//
+// When encryption is enabled, every new sstable, blob file, WAL and MANIFEST
+// written by the DB is encrypted with AES-256 in counter mode, using a randomly
+// generated data key unique to the file. The data key is wrapped with the
+// KeyProvider's active key and recorded in the file's metadata: in the
+// MANIFEST for sstables and blob files, and in a header at the beginning of
+// the file for WALs and MANIFESTs. The OPTIONS file and ingested sstables are
+// not encrypted. WAL recycling is disabled, since a recycled WAL would be
+// encrypted with its previous data key.
+//
+// To rotate keys, change the KeyProvider's active key and call
+// DB.RotateEncryptionKey, which rewrites the sstables and blob files encrypted
+// with other keys, or not encrypted at all. WALs and MANIFESTs are rewritten with the active key as the
+// DB rolls over to new WALs and MANIFESTs. A key may be retired once no file's
+// data key is wrapped with it.
+//
+// Encryption is incompatible with the creation of sstables on shared storage,
+// whether through CreateOnShared or Tiering: the data keys of the shared
+// sstables would only be recorded in the DB's MANIFEST.
+//
+// Encryption does not authenticate the contents of sstables, blob files and
+// WALs beyond the checksums already included in their formats.
+type EncryptionOptions struct {
+	// KeyProvider provides the keys wrapping data keys. It is required, and is
+	// also required to open a DB containing encrypted files.
+	KeyProvider KeyProvider
+}
+
+// keyProvider returns the configured KeyProvider, or nil if encryption is
+// disabled.
+func (o *Options) keyProvider() KeyProvider {
+	if o.Encryption == nil {
+		return nil
+	}
+	return o.Encryption.KeyProvider
+}
+
+// createEncryptedFile wraps f, a newly created WAL or MANIFEST file, to encrypt
+// its contents if encryption is enabled.
+func createEncryptedFile(f vfs.File, p KeyProvider) (vfs.File, error) {
+	if p == nil {
+		return f, nil
+	}
+	ef, err := encryption.CreateFile(f, p)
+	if err != nil {
+		return nil, errors.CombineErrors(err, f.Close())
+	}
+	return ef, nil
+}
+
+// fileKeys holds the ciphers of a DB's encrypted sstables and blob files,
+// keyed by the files' numbers. It is populated with the files of the DB's
+// version when the DB is opened, and with files as they are created.
+type fileKeys struct {
+	// provider is nil if encryption is disabled. A DB with encrypted files
+	// cannot be opened without a provider.
+	provider KeyProvider
+	mu       struct {
+		sync.Mutex
+		keys map[base.DiskFileNum]fileKey
+	}
+}
+
+type fileKey struct {
+	cipher  *encryption.Cipher
+	encoded []byte
+}
+
+func newFileKeys(p KeyProvider) *fileKeys {
+	k := &fileKeys{provider: p}
+	k.mu.keys = make(map[base.DiskFileNum]fileKey)
+	return k
+}
+
+// create generates a data key for a new file, returning nil if encryption is
+// disabled.
+func (k *fileKeys) create(fileNum base.DiskFileNum) (*encryption.Cipher, error) {
+	if k.provider == nil {
+		return nil, nil
+	}
+	c, encoded, err := encryption.NewCipher(k.provider)
+	if err != nil {
+		return nil, err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.mu.keys[fileNum] = fileKey{cipher: c, encoded: encoded}
+	return c, nil
+}
+
+// add unwraps the encoded data key of an existing file, if any.
+func (k *fileKeys) add(fileNum base.DiskFileNum, encoded []byte) error {
+	if encoded == nil {
+		return nil
+	}
+	if k.provider == nil {
+		return errors.Errorf("pebble: file %s is encrypted, but Options.Encryption is not set", fileNum)
+	}
+	c, err := encryption.OpenCipher(k.provider, encoded)
+	if err != nil {
+		return errors.Wrapf(err, "pebble: file %s", fileNum)
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.mu.keys[fileNum] = fileKey{cipher: c, encoded: encoded}
+	return nil
+}
+
+// addVersion adds the data keys of every file in the version.
+func (k *fileKeys) addVersion(v *version, blobFiles []*manifest.BlobFileMetadata) error {
+	for level := range v.Levels {
+		for f := range v.Levels[level].All() {
+			if err := k.add(f.FileBacking.DiskFileNum, f.FileBacking.EncryptionKey); err != nil {
+				return err
+			}
+		}
+	}
+	for _, b := range blobFiles {
+		if err := k.add(b.FileNum, b.EncryptionKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// get returns the cipher of the given file, or nil if the file is not
+// encrypted.
+func (k *fileKeys) get(fileNum base.DiskFileNum) *encryption.Cipher {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.mu.keys[fileNum].cipher
+}
+
+// encodedKey returns the encoded data key of the given file, or nil if the file
+// is not encrypted.
+func (k *fileKeys) encodedKey(fileNum base.DiskFileNum) []byte {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.mu.keys[fileNum].encoded
+}
+
+// remove forgets the data key of an obsolete file.
+func (k *fileKeys) remove(fileNum base.DiskFileNum) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.mu.keys, fileNum)
+}
+
+// setEncryptionKeys records the data keys of the files created by a
+// compaction or flush in their metadata.
+func (k *fileKeys) setEncryptionKeys(ve *versionEdit) {
+	if k.provider == nil {
+		return
+	}
+	for _, nt := range ve.NewTables {
+		if !nt.Meta.Virtual {
+			nt.Meta.FileBacking.EncryptionKey = k.encodedKey(nt.Meta.FileBacking.DiskFileNum)
+		}
+	}
+	for _, b := range ve.NewBlobFiles {
+		b.EncryptionKey = k.encodedKey(b.FileNum)
+	}
+}
+
+// needsReencryption returns true if the file with the given encoded data key
+// is not encrypted with the provider's active key.
+func (k *fileKeys) needsReencryption(encoded []byte) bool {
+	if encoded == nil {
+		return true
+	}
+	keyID, err := encryption.KeyID(encoded)
+	return err != nil || keyID != k.provider.ActiveKeyID()
+}
+
+// RotateEncryptionKey re-encrypts the DB's files with the active key of
+// Options.Encryption.KeyProvider. It flushes the memtable, switching to a new
+// WAL, rewrites the sstables and blob files that are not encrypted with the
+// active key (including unencrypted files) through rewrite compactions, and
+// rotates to a new MANIFEST. It returns once every such file has been
+// rewritten. The files are marked for compaction durably, so that an
+// interrupted rotation resumes when the DB is reopened.
+func (d *DB) RotateEncryptionKey() error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	if d.fileKeys.provider == nil {
+		return errors.New("pebble: encryption is not enabled")
+	}
+	if err := d.Flush(); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	err := d.markFilesLocked(func(v *version) (found bool, files [numLevels][]*tableMetadata, _ error) {
+		for level := range v.Levels {
+			for f := range v.Levels[level].All() {
+				if d.fileKeys.needsReencryption(f.FileBacking.EncryptionKey) || d.referencesStaleBlobFile(f) {
+					files[level] = append(files[level], f)
+					found = true
+				}
+			}
+		}
+		return found, files, nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := d.compactMarkedFilesLocked(); err != nil {
+		return err
+	}
+	// Rotate to a new MANIFEST encrypted with the active key, even if no files
+	// needed to be marked.
+	jobID := d.newJobIDLocked()
+	return d.mu.versions.UpdateVersionLocked(func() (versionUpdate, error) {
+		return versionUpdate{
+			VE:                      &manifest.VersionEdit{},
+			JobID:                   jobID,
+			ForceManifestRotation:   true,
+			InProgressCompactionsFn: func() []compactionInfo { return d.getInProgressCompactionInfoLocked(nil) },
+		}, nil
+	})
+}
+
+// referencesStaleBlobFile returns true if the table references a blob file
+// that is not encrypted with the active key.
+func (d *DB) referencesStaleBlobFile(f *tableMetadata) bool {
+	for _, ref := range f.BlobReferences {
+		if meta := ref.Metadata; meta != nil && d.fileKeys.needsReencryption(meta.EncryptionKey) {
+			return true
+		}
+	}
+	return false
+}
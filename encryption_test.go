@@ -0,0 +1,200 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/chris124567/pebble/internal/encryption"
+	"github.com/chris124567/pebble/objstorage/remote"
+	"github.com/chris124567/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+type testKeyProvider struct {
+	mu     sync.Mutex
+	active string
+	keys   map[string][]byte
+}
+
+func (p *testKeyProvider) ActiveKeyID() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.active
+}
+
+func (p *testKeyProvider) Key(id string) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key, ok := p.keys[id]
+	if !ok {
+		return nil, errors.Newf("unknown key %q", id)
+	}
+	return key, nil
+}
+
+func (p *testKeyProvider) setActive(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys[id] = bytes.Repeat([]byte(id[len(id)-1:]), 32)
+	p.active = id
+}
+
+func (p *testKeyProvider) remove(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.keys, id)
+}
+
+func TestEncryption(t *testing.T) {
+	fs := vfs.NewMem()
+	provider := &testKeyProvider{keys: make(map[string][]byte)}
+	provider.setActive("k1")
+	makeOpts := func(encrypt bool) *Options {
+		opts := &Options{
+			FS:                 fs,
+			FormatMajorVersion: FormatExperimentalValueSeparation,
+			Logger:             testLogger{t},
+		}
+		if encrypt {
+			opts.Encryption = &EncryptionOptions{KeyProvider: provider}
+		}
+		opts.Experimental.ValueSeparationPolicy = func() ValueSeparationPolicy {
+			return ValueSeparationPolicy{Enabled: true, MinimumSize: 10, MaxBlobReferenceDepth: 10}
+		}
+		return opts
+	}
+	const n = 100
+	value := func(i int) []byte {
+		return []byte(fmt.Sprintf("secret-value-%03d-%s", i, strings.Repeat("x", i)))
+	}
+	check := func(d *DB) {
+		for i := 0; i < n; i++ {
+			v, closer, err := d.Get([]byte(fmt.Sprintf("key%03d", i)))
+			require.NoError(t, err)
+			require.Equal(t, value(i), v)
+			require.NoError(t, closer.Close())
+		}
+	}
+
+	d, err := Open("", makeOpts(true))
+	require.NoError(t, err)
+	for i := 0; i < n; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("key%03d", i)), value(i), nil))
+		if i == n/2 {
+			require.NoError(t, d.Flush())
+		}
+	}
+	check(d)
+	require.NoError(t, d.Close())
+
+	// No file other than the OPTIONS file may contain plaintext values.
+	ls, err := fs.List("")
+	require.NoError(t, err)
+	var types []string
+	for _, name := range ls {
+		if strings.HasPrefix(name, "OPTIONS") || strings.HasPrefix(name, "marker") || name == "LOCK" {
+			continue
+		}
+		f, err := fs.Open(name)
+		require.NoError(t, err)
+		data, err := io.ReadAll(f)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+		require.False(t, bytes.Contains(data, []byte("secret-value")), "%s contains plaintext", name)
+		types = append(types, name[strings.LastIndexByte(name, '.')+1:])
+	}
+	require.Subset(t, types, []string{"sst", "blob", "log"})
+
+	// The DB cannot be opened without the key provider.
+	_, err = Open("", makeOpts(false))
+	require.Error(t, err)
+
+	d, err = Open("", makeOpts(true))
+	require.NoError(t, err)
+	check(d)
+
+	// Rotate to a new key, after which the old key is no longer needed.
+	provider.setActive("k2")
+	require.NoError(t, d.RotateEncryptionKey())
+	d.mu.Lock()
+	v := d.mu.versions.currentVersion()
+	var tables int
+	for level := range v.Levels {
+		for f := range v.Levels[level].All() {
+			keyID, err := encryption.KeyID(f.FileBacking.EncryptionKey)
+			require.NoError(t, err)
+			require.Equal(t, "k2", keyID)
+			tables++
+		}
+	}
+	for _, b := range d.mu.versions.blobFiles.Metadatas() {
+		keyID, err := encryption.KeyID(b.EncryptionKey)
+		require.NoError(t, err)
+		require.Equal(t, "k2", keyID)
+	}
+	d.mu.Unlock()
+	require.Greater(t, tables, 0)
+	check(d)
+	require.NoError(t, d.Close())
+
+	provider.remove("k1")
+	d, err = Open("", makeOpts(true))
+	require.NoError(t, err)
+	check(d)
+	require.NoError(t, d.Close())
+}
+
+func TestEncryptionSharedStorage(t *testing.T) {
+	provider := &testKeyProvider{keys: make(map[string][]byte)}
+	provider.setActive("k1")
+	opts := &Options{Encryption: &EncryptionOptions{KeyProvider: provider}}
+	opts.Experimental.CreateOnShared = remote.CreateOnSharedAll
+	opts.Experimental.Tiering = &TieringOptions{Locator: "tier"}
+	opts.EnsureDefaults()
+	err := opts.Validate()
+	require.ErrorContains(t, err, "Encryption is incompatible with CreateOnShared")
+	require.ErrorContains(t, err, "Encryption is incompatible with Tiering")
+}
+
+func TestEncryptionOfUnencryptedDB(t *testing.T) {
+	fs := vfs.NewMem()
+	provider := &testKeyProvider{keys: make(map[string][]byte)}
+	provider.setActive("k1")
+
+	d, err := Open("", &Options{FS: fs})
+	require.NoError(t, err)
+	require.NoError(t, d.Set([]byte("a"), []byte("secret-value"), nil))
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Set([]byte("b"), []byte("secret-value"), nil))
+	require.NoError(t, d.Close())
+
+	// Enabling encryption encrypts new files, and RotateEncryptionKey rewrites
+	// the existing ones.
+	d, err = Open("", &Options{FS: fs, Encryption: &EncryptionOptions{KeyProvider: provider}})
+	require.NoError(t, err)
+	require.NoError(t, d.RotateEncryptionKey())
+	d.mu.Lock()
+	v := d.mu.versions.currentVersion()
+	for level := range v.Levels {
+		for f := range v.Levels[level].All() {
+			require.NotNil(t, f.FileBacking.EncryptionKey)
+		}
+	}
+	d.mu.Unlock()
+	for _, k := range []string{"a", "b"} {
+		v, closer, err := d.Get([]byte(k))
+		require.NoError(t, err)
+		require.Equal(t, "secret-value", string(v))
+		require.NoError(t, closer.Close())
+	}
+	require.NoError(t, d.Close())
+}
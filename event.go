@@ -166,6 +166,12 @@ type CompactionInfo struct {
 	SingleLevelOverlappingRatio float64
 	MultiLevelOverlappingRatio  float64
 
+	// FilterRemovedKeys and FilterChangedValues are the number of keys removed
+	// and the number of values changed by Options.CompactionFilter. They are
+	// set only if Done is true and the compaction succeeded.
+	FilterRemovedKeys   uint64
+	FilterChangedValues uint64
+
 	// Annotations specifies additional info to appear in a compaction's event log line
 	Annotations compactionAnnotations
 }
@@ -221,6 +227,10 @@ func (i CompactionInfo) SafeFormat(w redact.SafePrinter, _ rune) {
 		redact.Safe(i.Duration.Seconds()),
 		redact.Safe(i.TotalDuration.Seconds()),
 		redact.Safe(humanize.Bytes.Uint64(uint64(float64(outputSize)/i.Duration.Seconds()))))
+	if i.FilterRemovedKeys > 0 || i.FilterChangedValues > 0 {
+		w.Printf(", filtered %d removed, %d changed",
+			redact.Safe(i.FilterRemovedKeys), redact.Safe(i.FilterChangedValues))
+	}
 }
 
 type levelInfos []LevelInfo
@@ -16,6 +16,7 @@ import (
 
 	"github.com/cockroachdb/errors"
 	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/internal/encryption"
 	"github.com/chris124567/pebble/internal/cache"
 	"github.com/chris124567/pebble/internal/genericcache"
 	"github.com/chris124567/pebble/internal/invariants"
@@ -99,6 +100,9 @@ type fileCacheHandle struct {
 	blockCacheHandle *cache.Handle
 	objProvider      objstorage.Provider
 	readerOpts       sstable.ReaderOptions
+	// fileKeys holds the data keys of encrypted files. It is nil if the handle
+	// is not used by a DB.
+	fileKeys *fileKeys
 
 	// iterCount keeps track of how many iterators are open. It is used to keep
 	// track of leaked iterators on a per-db level.
@@ -203,6 +207,11 @@ func (h *fileCacheHandle) openFile(
 	if err != nil {
 		return nil, objstorage.ObjectMetadata{}, err
 	}
+	if h.fileKeys != nil {
+		if c := h.fileKeys.get(fileNum); c != nil {
+			f = encryption.NewReadable(f, c)
+		}
+	}
 
 	o := h.readerOpts
 	o.CacheOpts = sstableinternal.CacheOptions{
@@ -270,6 +279,9 @@ func (h *fileCacheHandle) findOrCreateBlob(
 func (h *fileCacheHandle) Evict(fileNum base.DiskFileNum, fileType base.FileType) {
 	h.fileCache.c.Evict(fileCacheKey{handle: h, fileNum: fileNum, fileType: fileType})
 	h.blockCacheHandle.EvictFile(fileNum)
+	if h.fileKeys != nil {
+		h.fileKeys.remove(fileNum)
+	}
 }
 
 func (h *fileCacheHandle) SSTStatsCollector() *block.CategoryStatsCollector {
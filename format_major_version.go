@@ -240,6 +240,11 @@ const (
 	// writing sstable.Attributes in the footer of sstables.
 	formatFooterAttributes FormatMajorVersion = iota - 1
 
+	// formatTableFormatV8 is a format major version enabling the sstable
+	// table format TableFormatPebblev8, which adds support for LZ4 compression
+	// and Zstd compression with a dictionary.
+	formatTableFormatV8 FormatMajorVersion = iota - 1
+
 	// internalFormatNewest is the most recent, possibly experimental format major
 	// version.
 	internalFormatNewest FormatMajorVersion = iota - 2
@@ -274,6 +279,8 @@ func (v FormatMajorVersion) MaxTableFormat() sstable.TableFormat {
 		return sstable.TableFormatPebblev6
 	case formatFooterAttributes:
 		return sstable.TableFormatPebblev7
+	case formatTableFormatV8:
+		return sstable.TableFormatPebblev8
 	default:
 		panic(fmt.Sprintf("pebble: unsupported format major version: %s", v))
 	}
@@ -286,7 +293,8 @@ func (v FormatMajorVersion) MinTableFormat() sstable.TableFormat {
 	case FormatDefault, FormatFlushableIngest, FormatPrePebblev1MarkedCompacted,
 		FormatDeleteSizedAndObsolete, FormatVirtualSSTables, FormatSyntheticPrefixSuffix,
 		FormatFlushableIngestExcises, FormatColumnarBlocks, FormatWALSyncChunks,
-		FormatTableFormatV6, FormatExperimentalValueSeparation, formatFooterAttributes:
+		FormatTableFormatV6, FormatExperimentalValueSeparation, formatFooterAttributes,
+		formatTableFormatV8:
 		return sstable.TableFormatPebblev1
 	default:
 		panic(fmt.Sprintf("pebble: unsupported format major version: %s", v))
@@ -341,6 +349,9 @@ var formatMajorVersionMigrations = map[FormatMajorVersion]func(*DB) error{
 	formatFooterAttributes: func(d *DB) error {
 		return d.finalizeFormatVersUpgrade(formatFooterAttributes)
 	},
+	formatTableFormatV8: func(d *DB) error {
+		return d.finalizeFormatVersUpgrade(formatTableFormatV8)
+	},
 }
 
 const formatVersionMarkerName = `format-version`
@@ -545,10 +556,6 @@ func (d *DB) compactMarkedFilesLocked() error {
 // level.
 type findFilesFunc func(v *version) (found bool, files [numLevels][]*tableMetadata, _ error)
 
-// This method is not used currently, but it will be useful the next time we need
-// to mark files for compaction.
-var _ = (*DB)(nil).markFilesLocked
-
 // markFilesLocked durably marks the files that match the given findFilesFunc for
 // compaction.
 func (d *DB) markFilesLocked(findFn findFilesFunc) error {
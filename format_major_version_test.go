@@ -31,11 +31,12 @@ func TestFormatMajorVersionStableValues(t *testing.T) {
 	require.Equal(t, FormatTableFormatV6, FormatMajorVersion(21))
 	require.Equal(t, FormatExperimentalValueSeparation, FormatMajorVersion(22))
 	require.Equal(t, formatFooterAttributes, FormatMajorVersion(23))
+	require.Equal(t, formatTableFormatV8, FormatMajorVersion(24))
 
 	// When we add a new version, we should add a check for the new version in
 	// addition to updating these expected values.
 	require.Equal(t, FormatNewest, FormatMajorVersion(21))
-	require.Equal(t, internalFormatNewest, FormatMajorVersion(23))
+	require.Equal(t, internalFormatNewest, FormatMajorVersion(24))
 }
 
 func TestFormatMajorVersion_MigrationDefined(t *testing.T) {
@@ -74,6 +75,8 @@ func TestRatchetFormat(t *testing.T) {
 	require.Equal(t, FormatExperimentalValueSeparation, d.FormatMajorVersion())
 	require.NoError(t, d.RatchetFormatMajorVersion(formatFooterAttributes))
 	require.Equal(t, formatFooterAttributes, d.FormatMajorVersion())
+	require.NoError(t, d.RatchetFormatMajorVersion(formatTableFormatV8))
+	require.Equal(t, formatTableFormatV8, d.FormatMajorVersion())
 
 	require.NoError(t, d.Close())
 
@@ -235,6 +238,7 @@ func TestFormatMajorVersions_TableFormat(t *testing.T) {
 		FormatTableFormatV6:               {sstable.TableFormatPebblev1, sstable.TableFormatPebblev6},
 		FormatExperimentalValueSeparation: {sstable.TableFormatPebblev1, sstable.TableFormatPebblev6},
 		formatFooterAttributes:            {sstable.TableFormatPebblev1, sstable.TableFormatPebblev7},
+		formatTableFormatV8:               {sstable.TableFormatPebblev1, sstable.TableFormatPebblev8},
 	}
 
 	// Valid versions.
@@ -0,0 +1,145 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package fusefilter
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/chris124567/pebble"
+	"github.com/chris124567/pebble/bloom"
+	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func newTableFilter(fingerprintBits int, keys ...[]byte) tableFilter {
+	w := FilterPolicy(fingerprintBits).NewWriter(base.TableFilter)
+	for _, key := range keys {
+		w.AddKey(key)
+	}
+	return tableFilter(w.Finish(nil))
+}
+
+func TestSmallFilter(t *testing.T) {
+	f := newTableFilter(8, []byte("hello"), []byte("world"), []byte("hello"))
+	require.True(t, f.MayContain([]byte("hello")))
+	require.True(t, f.MayContain([]byte("world")))
+
+	f = newTableFilter(8)
+	require.False(t, f.MayContain([]byte("hello")))
+	require.False(t, f.MayContain(nil))
+}
+
+func TestFilter(t *testing.T) {
+	key := func(i int) []byte {
+		return binary.LittleEndian.AppendUint32(nil, uint32(i))
+	}
+	for _, fingerprintBits := range []int{8, 16} {
+		t.Run(fmt.Sprint(fingerprintBits), func(t *testing.T) {
+			var mediocre, good int
+			for _, n := range []int{1, 2, 3, 10, 50, 100, 500, 1000, 5000, 10000, 100000} {
+				keys := make([][]byte, n)
+				for i := range keys {
+					keys[i] = key(i)
+				}
+				f := newTableFilter(fingerprintBits, keys...)
+				if n >= 100000 {
+					// Large filters use ~1.13 fingerprints per key.
+					require.Less(t, len(f), headerLen+n*fingerprintBits/8*5/4)
+				}
+
+				// All keys must match.
+				for _, k := range keys {
+					require.True(t, f.MayContain(k), "n=%d", n)
+				}
+
+				// Check the false positive rate.
+				const probes = 10000
+				var fp int
+				for i := 0; i < probes; i++ {
+					if f.MayContain(key(i + 1e9)) {
+						fp++
+					}
+				}
+				rate := float64(fp) / probes
+				maxRate := 0.01
+				if fingerprintBits == 16 {
+					maxRate = 0.001
+				}
+				require.Less(t, rate, 2*maxRate, "n=%d", n)
+				if rate > maxRate {
+					mediocre++
+				} else {
+					good++
+				}
+			}
+			require.LessOrEqual(t, mediocre, good/5)
+		})
+	}
+}
+
+func TestMalformedFilter(t *testing.T) {
+	// Filters that cannot be decoded match all keys.
+	require.True(t, tableFilter(nil).MayContain([]byte("hello")))
+	require.True(t, tableFilter([]byte{0}).MayContain([]byte("hello")))
+	f := newTableFilter(8, []byte("hello"), []byte("world"))
+	require.True(t, f[:len(f)-1].MayContain([]byte("foo")))
+}
+
+func TestDB(t *testing.T) {
+	// L6 uses a binary fuse filter, and other levels bloom filters.
+	makeOpts := func() *pebble.Options {
+		opts := &pebble.Options{FS: vfs.NewMem()}
+		opts.Levels = make([]pebble.LevelOptions, 7)
+		for i := range opts.Levels {
+			opts.Levels[i].FilterPolicy = bloom.FilterPolicy(10)
+		}
+		opts.Levels[6].FilterPolicy = FilterPolicy(8)
+		return opts
+	}
+	opts := makeOpts()
+	d, err := pebble.Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	// Write two overlapping tables, so that they are compacted rather than
+	// moved into L6.
+	for j := 0; j < 2; j++ {
+		for i := 0; i < 1000; i++ {
+			require.NoError(t, d.Set([]byte(fmt.Sprintf("a%04d", i)), []byte("v"), nil))
+		}
+		require.NoError(t, d.Flush())
+	}
+	require.NoError(t, d.Compact(context.Background(), []byte("a"), []byte("b"), false /* parallelize */))
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("b%04d", i)), []byte("v"), nil))
+	}
+	require.NoError(t, d.Flush())
+
+	tables, err := d.SSTables(pebble.WithProperties())
+	require.NoError(t, err)
+	filters := map[int]string{}
+	for level := range tables {
+		for _, info := range tables[level] {
+			filters[level] = info.Properties.FilterPolicyName
+		}
+	}
+	require.Equal(t, map[int]string{0: "rocksdb.BuiltinBloomFilter", 6: "pebble.BinaryFuseFilter"}, filters)
+
+	for _, k := range []string{"a0123", "b0456"} {
+		v, closer, err := d.Get([]byte(k))
+		require.NoError(t, err)
+		require.Equal(t, "v", string(v))
+		require.NoError(t, closer.Close())
+	}
+	for _, k := range []string{"a0123x", "b0456x"} {
+		_, _, err := d.Get([]byte(k))
+		require.ErrorIs(t, err, pebble.ErrNotFound)
+	}
+	m := d.Metrics()
+	require.Greater(t, m.Filter.Hits, int64(0))
+}
@@ -0,0 +1,151 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package index
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/chris124567/pebble"
+	"github.com/chris124567/pebble/rangekey"
+	"github.com/chris124567/pebble/sstable/block"
+)
+
+var categoryCheck = block.RegisterCategory("pebble-index-check", block.NonLatencySensitiveQoSLevel)
+
+// Scanner is implemented by pebble.DB, pebble.Snapshot and
+// pebble.EventuallyFileOnlySnapshot.
+type Scanner interface {
+	ScanInternal(
+		ctx context.Context,
+		category block.Category,
+		lower, upper []byte,
+		visitPointKey func(key *pebble.InternalKey, value pebble.LazyValue, iterInfo pebble.IteratorLevel) error,
+		visitRangeDel func(start, end []byte, seqNum pebble.SeqNum) error,
+		visitRangeKey func(start, end []byte, keys []rangekey.Key) error,
+		visitSharedFile func(sst *pebble.SharedSSTMeta) error,
+		visitExternalFile func(sst *pebble.ExternalFile) error,
+	) error
+}
+
+// Inconsistency describes an index entry that is either missing from its
+// index, or present in its index without being produced by the current value of
+// its primary key.
+type Inconsistency struct {
+	Index      string
+	IndexKey   []byte
+	PrimaryKey []byte
+	// Missing is true if the entry is missing from the index, and false if the
+	// entry is stale.
+	Missing bool
+}
+
+// String implements fmt.Stringer.
+func (c Inconsistency) String() string {
+	if c.Missing {
+		return fmt.Sprintf("missing %s(%q -> %q)", c.Index, c.IndexKey, c.PrimaryKey)
+	}
+	return fmt.Sprintf("stale %s(%q -> %q)", c.Index, c.IndexKey, c.PrimaryKey)
+}
+
+// Check verifies that the indexes are consistent with the primary keys read
+// from s, returning the inconsistencies found in key order. It reads the
+// entire DB in a single ScanInternal pass, holding the index entries found in
+// memory, and is intended for offline use: to avoid reporting spurious
+// inconsistencies, s should be a snapshot or the DB must not be written
+// concurrently.
+//
+// Check does not support primary keys written with Merge.
+func (x *Indexer) Check(ctx context.Context, s Scanner) ([]Inconsistency, error) {
+	// entries maps the keys of the index entries found to whether they are
+	// expected from a primary key.
+	entries := make(map[string]bool)
+	expected := make(map[string]struct{})
+	var keysBuf [][]byte
+	// valueBuf and entryBuf are kept apart, since the index keys returned by
+	// Definition.Keys may alias the value.
+	var valueBuf, entryBuf []byte
+	err := s.ScanInternal(ctx, categoryCheck, nil, nil,
+		func(key *pebble.InternalKey, lv pebble.LazyValue, _ pebble.IteratorLevel) error {
+			switch key.Kind() {
+			case pebble.InternalKeyKindSet, pebble.InternalKeyKindSetWithDelete:
+			case pebble.InternalKeyKindMerge:
+				return errors.Errorf("index: cannot check merged key %q", key.UserKey)
+			default:
+				// The key is deleted.
+				return nil
+			}
+			if def := x.isIndexKey(key.UserKey); def != nil {
+				entries[string(key.UserKey)] = false
+				return nil
+			}
+			value, callerOwned, err := lv.Value(valueBuf[:0])
+			if err != nil {
+				return err
+			}
+			if callerOwned {
+				valueBuf = value[:0]
+			}
+			for i := range x.defs {
+				def := &x.defs[i]
+				keysBuf = def.Keys(keysBuf[:0], key.UserKey, value)
+				for _, indexKey := range keysBuf {
+					entryBuf = encodeEntryKey(entryBuf[:0], def.Prefix, indexKey, key.UserKey)
+					expected[string(entryBuf)] = struct{}{}
+				}
+			}
+			return nil
+		},
+		func(start, end []byte, seqNum pebble.SeqNum) error { return nil },
+		func(start, end []byte, keys []rangekey.Key) error { return nil },
+		nil /* visitSharedFile */, nil, /* visitExternalFile */
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Inconsistency
+	report := func(entry string, missing bool) error {
+		def := x.isIndexKey([]byte(entry))
+		indexKey, primaryKey, ok := decodeEntryKey(nil, []byte(entry[len(def.Prefix):]))
+		if !ok {
+			return errors.Errorf("index: malformed index entry %q", entry)
+		}
+		res = append(res, Inconsistency{
+			Index:      def.Name,
+			IndexKey:   indexKey,
+			PrimaryKey: primaryKey,
+			Missing:    missing,
+		})
+		return nil
+	}
+	for entry := range expected {
+		if _, ok := entries[entry]; ok {
+			entries[entry] = true
+		} else if err := report(entry, true /* missing */); err != nil {
+			return nil, err
+		}
+	}
+	for entry, ok := range entries {
+		if !ok {
+			if err := report(entry, false /* missing */); err != nil {
+				return nil, err
+			}
+		}
+	}
+	slices.SortFunc(res, func(a, b Inconsistency) int {
+		if c := strings.Compare(a.Index, b.Index); c != 0 {
+			return c
+		}
+		if c := strings.Compare(string(a.IndexKey), string(b.IndexKey)); c != 0 {
+			return c
+		}
+		return strings.Compare(string(a.PrimaryKey), string(b.PrimaryKey))
+	})
+	return res, nil
+}
@@ -0,0 +1,367 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+// Package index maintains secondary indexes over the keys of a Pebble DB.
+//
+// An index is defined by a function from a primary key-value pair to the index
+// keys of the pair. For every index key, the index holds an entry mapping the
+// index key to the primary key. Entries are stored in the same DB as the
+// primary keys, under a prefix reserved for the index, and are written in the
+// same batch as the primary key that they index, so that they are always
+// consistent with it.
+//
+// Entries are ordered by index key and then by primary key, using an
+// order-preserving encoding of the index key. The DB must therefore order keys
+// bytewise (as pebble.DefaultComparer does), at least within the prefixes
+// reserved for indexes.
+package index // import "github.com/chris124567/pebble/index"
+
+import (
+	"bytes"
+
+	"github.com/cockroachdb/errors"
+	"github.com/chris124567/pebble"
+)
+
+// Definition defines a secondary index.
+type Definition struct {
+	// Name identifies the index. Names must be unique within an Indexer.
+	Name string
+
+	// Prefix is prepended to the keys of the index's entries, separating them
+	// from primary keys and from the entries of other indexes. Prefix must be
+	// non-empty, no primary key may begin with the Prefix of an index, and no
+	// index's Prefix may be a prefix of another index's Prefix.
+	Prefix []byte
+
+	// Keys appends the index keys of the primary key-value pair to dst and
+	// returns the result. A key-value pair may have any number of index keys.
+	// Keys must be deterministic, and must not retain key or value.
+	Keys func(dst [][]byte, key, value []byte) [][]byte
+}
+
+// Indexer maintains a set of secondary indexes over the keys of a DB.
+type Indexer struct {
+	db   *pebble.DB
+	defs []Definition
+}
+
+// New returns an Indexer maintaining the given indexes over the keys of db.
+func New(db *pebble.DB, defs ...Definition) (*Indexer, error) {
+	for i := range defs {
+		def := &defs[i]
+		if def.Name == "" || len(def.Prefix) == 0 || def.Keys == nil {
+			return nil, errors.Errorf("index: index %q must have a name, prefix and keys function", def.Name)
+		}
+		for j := range defs[:i] {
+			if defs[j].Name == def.Name {
+				return nil, errors.Errorf("index: duplicate index name %q", def.Name)
+			}
+			if bytes.HasPrefix(defs[j].Prefix, def.Prefix) || bytes.HasPrefix(def.Prefix, defs[j].Prefix) {
+				return nil, errors.Errorf("index: prefixes of indexes %q and %q overlap", defs[j].Name, def.Name)
+			}
+		}
+	}
+	return &Indexer{db: db, defs: defs}, nil
+}
+
+// definition returns the index with the given name.
+func (x *Indexer) definition(name string) (*Definition, error) {
+	for i := range x.defs {
+		if x.defs[i].Name == name {
+			return &x.defs[i], nil
+		}
+	}
+	return nil, errors.Errorf("index: unknown index %q", name)
+}
+
+// isIndexKey returns the index whose entries include key, or nil if key is a
+// primary key.
+func (x *Indexer) isIndexKey(key []byte) *Definition {
+	for i := range x.defs {
+		if bytes.HasPrefix(key, x.defs[i].Prefix) {
+			return &x.defs[i]
+		}
+	}
+	return nil
+}
+
+// Batch is an indexed batch that maintains the indexes of an Indexer. Set and
+// Delete write the index entries of the primary key along with the primary
+// key, reading the key's previous value through the batch to remove its stale
+// entries.
+//
+// Writes of a primary key through a Batch must be serialized with other writes
+// of the same key, for example by locking the key exclusively with
+// pebble.DB.LockKeys on the embedded pebble.Batch before writing it; otherwise
+// concurrent writers may each remove the entries of the same previous value and
+// leave stale entries behind. Primary keys must not be written through the embedded
+// pebble.Batch's Set, Delete, Merge or range deletions, which do not maintain
+// the indexes.
+type Batch struct {
+	*pebble.Batch
+	x       *Indexer
+	keysBuf [][]byte
+	keyBuf  []byte
+}
+
+// NewBatch returns a new indexed Batch that maintains x's indexes.
+func (x *Indexer) NewBatch() *Batch {
+	return &Batch{Batch: x.db.NewIndexedBatch(), x: x}
+}
+
+// Set sets the value of the primary key, replacing the index entries of its
+// previous value, if any, with those of the new value.
+func (b *Batch) Set(key, value []byte, opts *pebble.WriteOptions) error {
+	if err := b.deleteEntries(key, opts); err != nil {
+		return err
+	}
+	if err := b.Batch.Set(key, value, opts); err != nil {
+		return err
+	}
+	return b.setEntries(key, value, opts)
+}
+
+// Delete deletes the primary key along with the index entries of its value.
+func (b *Batch) Delete(key []byte, opts *pebble.WriteOptions) error {
+	if err := b.deleteEntries(key, opts); err != nil {
+		return err
+	}
+	return b.Batch.Delete(key, opts)
+}
+
+// deleteEntries deletes the index entries of the current value of key.
+func (b *Batch) deleteEntries(key []byte, opts *pebble.WriteOptions) error {
+	if def := b.x.isIndexKey(key); def != nil {
+		return errors.Errorf("index: primary key %q is within the prefix of index %q", key, def.Name)
+	}
+	value, closer, err := b.Batch.Get(key)
+	if errors.Is(err, pebble.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer closer.Close()
+	for i := range b.x.defs {
+		def := &b.x.defs[i]
+		b.keysBuf = def.Keys(b.keysBuf[:0], key, value)
+		for _, indexKey := range b.keysBuf {
+			b.keyBuf = encodeEntryKey(b.keyBuf[:0], def.Prefix, indexKey, key)
+			if err := b.Batch.Delete(b.keyBuf, opts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setEntries writes the index entries of the given primary key and value.
+func (b *Batch) setEntries(key, value []byte, opts *pebble.WriteOptions) error {
+	for i := range b.x.defs {
+		def := &b.x.defs[i]
+		b.keysBuf = def.Keys(b.keysBuf[:0], key, value)
+		for _, indexKey := range b.keysBuf {
+			b.keyBuf = encodeEntryKey(b.keyBuf[:0], def.Prefix, indexKey, key)
+			if err := b.Batch.Set(b.keyBuf, nil, opts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Iterator iterates over the entries of an index, in order of index key and
+// then primary key.
+type Iterator struct {
+	r        pebble.Reader
+	iter     *pebble.Iterator
+	prefix   []byte
+	valid    bool
+	indexKey []byte
+	key      []byte
+	value    []byte
+	err      error
+}
+
+// NewIter returns an iterator over the entries of the named index whose index
+// keys are within [lower, upper), reading from r. A nil lower or upper leaves
+// the range unbounded at that end. The iterator is positioned at the first
+// entry.
+func (x *Indexer) NewIter(r pebble.Reader, name string, lower, upper []byte) (*Iterator, error) {
+	def, err := x.definition(name)
+	if err != nil {
+		return nil, err
+	}
+	opts := &pebble.IterOptions{
+		LowerBound: encodeIndexKey(append([]byte(nil), def.Prefix...), lower),
+	}
+	if upper != nil {
+		opts.UpperBound = encodeIndexKey(append([]byte(nil), def.Prefix...), upper)
+	} else {
+		opts.UpperBound = prefixSuccessor(def.Prefix)
+	}
+	return newIterator(r, def.Prefix, opts)
+}
+
+// Lookup returns an iterator over the entries of the named index with the
+// given index key, reading from r. The iterator is positioned at the first
+// entry.
+func (x *Indexer) Lookup(r pebble.Reader, name string, indexKey []byte) (*Iterator, error) {
+	def, err := x.definition(name)
+	if err != nil {
+		return nil, err
+	}
+	lower := encodeIndexKey(append([]byte(nil), def.Prefix...), indexKey)
+	lower = append(lower, escapeByte, terminatorByte)
+	upper := append(lower[:len(lower)-1:len(lower)-1], terminatorByte+1)
+	return newIterator(r, def.Prefix, &pebble.IterOptions{LowerBound: lower, UpperBound: upper})
+}
+
+func newIterator(r pebble.Reader, prefix []byte, opts *pebble.IterOptions) (*Iterator, error) {
+	iter, err := r.NewIter(opts)
+	if err != nil {
+		return nil, err
+	}
+	i := &Iterator{r: r, iter: iter, prefix: prefix}
+	i.decode(iter.First())
+	return i, nil
+}
+
+func (i *Iterator) decode(valid bool) {
+	i.valid, i.value = false, nil
+	if !valid {
+		i.err = i.iter.Error()
+		return
+	}
+	var ok bool
+	i.indexKey, i.key, ok = decodeEntryKey(i.indexKey[:0], i.iter.Key()[len(i.prefix):])
+	if !ok {
+		i.err = errors.Errorf("index: malformed index entry %q", i.iter.Key())
+		return
+	}
+	i.valid = true
+}
+
+// Valid returns true if the iterator is positioned at an entry.
+func (i *Iterator) Valid() bool {
+	return i.valid
+}
+
+// Next moves the iterator to the next entry, returning true if there is one.
+func (i *Iterator) Next() bool {
+	if i.err != nil {
+		return false
+	}
+	i.decode(i.iter.Next())
+	return i.valid
+}
+
+// IndexKey returns the index key of the current entry. The returned slice is
+// only valid until the next call to Next.
+func (i *Iterator) IndexKey() []byte {
+	return i.indexKey
+}
+
+// PrimaryKey returns the primary key of the current entry. The returned slice
+// is only valid until the next call to Next.
+func (i *Iterator) PrimaryKey() []byte {
+	return i.key
+}
+
+// Value reads the value of the primary key of the current entry from the
+// iterator's reader. The returned slice is only valid until the next call to
+// Next.
+func (i *Iterator) Value() ([]byte, error) {
+	if i.value != nil {
+		return i.value, nil
+	}
+	value, closer, err := i.r.Get(i.key)
+	if err != nil {
+		return nil, err
+	}
+	i.value = append(make([]byte, 0, len(value)), value...)
+	return i.value, closer.Close()
+}
+
+// Error returns any accumulated error.
+func (i *Iterator) Error() error {
+	return i.err
+}
+
+// Close closes the iterator.
+func (i *Iterator) Close() error {
+	err := i.iter.Close()
+	if i.err != nil {
+		return i.err
+	}
+	return err
+}
+
+// The key of an index entry is the index's prefix, followed by the escaped
+// index key, a terminator and the primary key. Escaping replaces every
+// escapeByte in the index key with escapeByte, escapedByte. The terminator,
+// escapeByte, terminatorByte, sorts before any escaped byte, so that entries
+// are ordered by index key and then by primary key.
+const (
+	escapeByte     = 0x00
+	terminatorByte = 0x01
+	escapedByte    = 0xff
+)
+
+// encodeIndexKey appends the escaped index key to dst.
+func encodeIndexKey(dst, indexKey []byte) []byte {
+	for _, c := range indexKey {
+		if c == escapeByte {
+			dst = append(dst, escapeByte, escapedByte)
+		} else {
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+// encodeEntryKey appends the key of the index entry to dst.
+func encodeEntryKey(dst, prefix, indexKey, primaryKey []byte) []byte {
+	dst = append(dst, prefix...)
+	dst = encodeIndexKey(dst, indexKey)
+	dst = append(dst, escapeByte, terminatorByte)
+	return append(dst, primaryKey...)
+}
+
+// decodeEntryKey decodes the key of an index entry, without the index's
+// prefix, appending the index key to dst.
+func decodeEntryKey(dst, entry []byte) (indexKey, primaryKey []byte, ok bool) {
+	for j := 0; j < len(entry); j++ {
+		if entry[j] != escapeByte {
+			dst = append(dst, entry[j])
+			continue
+		}
+		if j+1 == len(entry) {
+			return nil, nil, false
+		}
+		switch entry[j+1] {
+		case escapedByte:
+			dst = append(dst, escapeByte)
+			j++
+		case terminatorByte:
+			return dst, entry[j+2:], true
+		default:
+			return nil, nil, false
+		}
+	}
+	return nil, nil, false
+}
+
+// prefixSuccessor returns the smallest key larger than every key beginning
+// with prefix, or nil if there is no such key.
+func prefixSuccessor(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] != 0xff {
+			succ := append([]byte(nil), prefix[:i+1]...)
+			succ[i]++
+			return succ
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package index
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/chris124567/pebble"
+	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/rangekey"
+	"github.com/chris124567/pebble/sstable/block"
+	"github.com/chris124567/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+// tagsIndex indexes the primary keys by the comma-separated tags of their
+// values.
+var tagsIndex = Definition{
+	Name:   "tags",
+	Prefix: []byte("\xfftags/"),
+	Keys: func(dst [][]byte, key, value []byte) [][]byte {
+		for _, tag := range bytes.Split(value, []byte(",")) {
+			if len(tag) > 0 {
+				dst = append(dst, tag)
+			}
+		}
+		return dst
+	},
+}
+
+// lenIndex indexes the primary keys by the length of their values.
+var lenIndex = Definition{
+	Name:   "len",
+	Prefix: []byte("\xfflen/"),
+	Keys: func(dst [][]byte, key, value []byte) [][]byte {
+		return append(dst, []byte{byte(len(value))})
+	},
+}
+
+func TestIndex(t *testing.T) {
+	db, err := pebble.Open("", &pebble.Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, db.Close()) }()
+
+	scanIndex := func(iter *Iterator, err error) string {
+		t.Helper()
+		require.NoError(t, err)
+		var res []string
+		for ; iter.Valid(); iter.Next() {
+			value, err := iter.Value()
+			require.NoError(t, err)
+			res = append(res, fmt.Sprintf("%q:%s=%s", iter.IndexKey(), iter.PrimaryKey(), value))
+		}
+		require.NoError(t, iter.Close())
+		return strings.Join(res, " ")
+	}
+
+	_, err = New(db, tagsIndex, Definition{Name: "tags2", Prefix: []byte("\xfftags/x"), Keys: tagsIndex.Keys})
+	require.Error(t, err)
+	x, err := New(db, tagsIndex, lenIndex)
+	require.NoError(t, err)
+
+	b := x.NewBatch()
+	require.NoError(t, b.Set([]byte("a"), []byte("red,big"), nil))
+	require.NoError(t, b.Set([]byte("b"), []byte("red"), nil))
+	require.NoError(t, b.Set([]byte("c"), []byte("blue,x\x00y"), nil))
+	// The batch observes its own index entries.
+	require.Equal(t, `"red":a=red,big "red":b=red`, scanIndex(x.Lookup(b, "tags", []byte("red"))))
+	require.Error(t, b.Set(tagsIndex.Prefix, nil, nil))
+	require.NoError(t, b.Commit(nil))
+	require.NoError(t, b.Close())
+
+	b = x.NewBatch()
+	// Overwriting a key replaces its index entries.
+	require.NoError(t, b.Set([]byte("a"), []byte("blue,small"), nil))
+	require.NoError(t, b.Delete([]byte("b"), nil))
+	require.NoError(t, b.Set([]byte("d"), []byte("red"), nil))
+	require.NoError(t, b.Commit(nil))
+	require.NoError(t, b.Close())
+
+	require.Equal(t, `"red":d=red`, scanIndex(x.Lookup(db, "tags", []byte("red"))))
+	require.Equal(t, `"blue":a=blue,small "blue":c=blue,x`+"\x00"+`y`,
+		scanIndex(x.Lookup(db, "tags", []byte("blue"))))
+	require.Equal(t, `"red":d=red "small":a=blue,small "x\x00y":c=blue,x`+"\x00"+`y`,
+		scanIndex(x.NewIter(db, "tags", []byte("r"), nil)))
+	require.Equal(t, `"blue":a=blue,small "blue":c=blue,x`+"\x00"+`y`,
+		scanIndex(x.NewIter(db, "tags", []byte("blue"), []byte("red"))))
+	require.Equal(t, `"\x03":d=red`, scanIndex(x.NewIter(db, "len", nil, []byte{4})))
+	_, err = x.Lookup(db, "unknown", nil)
+	require.Error(t, err)
+
+	check := func() string {
+		res, err := x.Check(context.Background(), db)
+		require.NoError(t, err)
+		var s []string
+		for _, c := range res {
+			s = append(s, c.String())
+		}
+		return strings.Join(s, "\n")
+	}
+	require.Equal(t, "", check())
+
+	// Writes that bypass the indexer leave them inconsistent.
+	require.NoError(t, db.Set([]byte("e"), []byte("green"), nil))
+	require.NoError(t, db.Delete(encodeEntryKey(nil, tagsIndex.Prefix, []byte("red"), []byte("d")), nil))
+	require.NoError(t, db.Set(encodeEntryKey(nil, tagsIndex.Prefix, []byte("red"), []byte("z")), nil, nil))
+	require.NoError(t, db.Flush())
+	require.Equal(t, strings.Join([]string{
+		`missing len("\x05" -> "e")`,
+		`missing tags("green" -> "e")`,
+		`missing tags("red" -> "d")`,
+		`stale tags("red" -> "z")`,
+	}, "\n"), check())
+}
+
+func TestEntryKeyEncoding(t *testing.T) {
+	keys := [][]byte{nil, {0}, {0, 0}, {0, 1}, {0, 0xff}, {1}, []byte("a"), []byte("a\x00"), []byte("ab")}
+	var prev []byte
+	for _, k := range keys {
+		for _, pk := range [][]byte{nil, []byte("\x00"), []byte("pk")} {
+			entry := encodeEntryKey(nil, []byte("p"), k, pk)
+			if prev != nil {
+				require.Less(t, string(prev), string(entry), "%q %q", k, pk)
+			}
+			prev = entry
+			indexKey, primaryKey, ok := decodeEntryKey(nil, entry[1:])
+			require.True(t, ok)
+			require.Equal(t, string(k), string(indexKey))
+			require.Equal(t, string(pk), string(primaryKey))
+		}
+	}
+}
+
+// copyingScanner is a Scanner that surfaces every value as a lazy value that
+// is copied into the caller's buffer when fetched, as values read from blob
+// files are.
+type copyingScanner struct {
+	db *pebble.DB
+}
+
+func (s copyingScanner) ScanInternal(
+	ctx context.Context,
+	category block.Category,
+	lower, upper []byte,
+	visitPointKey func(key *pebble.InternalKey, value pebble.LazyValue, iterInfo pebble.IteratorLevel) error,
+	visitRangeDel func(start, end []byte, seqNum pebble.SeqNum) error,
+	visitRangeKey func(start, end []byte, keys []rangekey.Key) error,
+	visitSharedFile func(sst *pebble.SharedSSTMeta) error,
+	visitExternalFile func(sst *pebble.ExternalFile) error,
+) error {
+	return s.db.ScanInternal(ctx, category, lower, upper,
+		func(key *pebble.InternalKey, lv pebble.LazyValue, iterInfo pebble.IteratorLevel) error {
+			v, _, err := lv.Value(nil)
+			if err != nil {
+				return err
+			}
+			lv = pebble.LazyValue{
+				ValueOrHandle: slices.Clone(v),
+				Fetcher: &pebble.LazyFetcher{
+					Fetcher:   copyingFetcher{},
+					Attribute: base.AttributeAndLen{ValueLen: uint32(len(v))},
+				},
+			}
+			return visitPointKey(key, lv, iterInfo)
+		}, visitRangeDel, visitRangeKey, visitSharedFile, visitExternalFile)
+}
+
+type copyingFetcher struct{}
+
+func (copyingFetcher) Fetch(
+	_ context.Context, handle []byte, _ base.DiskFileNum, _ uint32, buf []byte,
+) ([]byte, bool, error) {
+	return append(buf[:0], handle...), true, nil
+}
+
+// TestCheckFetchedValues verifies that Check does not clobber values fetched
+// into its buffer while encoding their index entries.
+func TestCheckFetchedValues(t *testing.T) {
+	db, err := pebble.Open("", &pebble.Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, db.Close()) }()
+
+	x, err := New(db, tagsIndex)
+	require.NoError(t, err)
+	b := x.NewBatch()
+	require.NoError(t, b.Set([]byte("a"), []byte("red,big,round"), nil))
+	// The value is short enough to be fetched into the buffer holding the
+	// previous index entry.
+	require.NoError(t, b.Set([]byte("b"), []byte("x,y,z"), nil))
+	require.NoError(t, b.Commit(nil))
+	require.NoError(t, b.Close())
+
+	res, err := x.Check(context.Background(), copyingScanner{db: db})
+	require.NoError(t, err)
+	require.Empty(t, res)
+}
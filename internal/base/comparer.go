@@ -241,6 +241,16 @@ type Comparer struct {
 	// valid according to this Comparer's key encoding.
 	ValidateKey ValidateKey
 
+	// BytewisePrefixes declares that the prefixes of keys (as determined by
+	// Split) are ordered bytewise:
+	//
+	//	If Compare(a, b) <= 0, then bytes.Compare(prefix(a), prefix(b)) <= 0.
+	//
+	// Features that search the prefixes of a table without the Comparer, such
+	// as sstable range filters, require it. It defaults to true if Compare is
+	// not specified.
+	BytewisePrefixes bool
+
 	// Name is the name of the comparer.
 	//
 	// The on-disk format stores the comparer name, and opening a database with a
@@ -270,6 +280,10 @@ func (c *Comparer) EnsureDefaults() *Comparer {
 	if n.Split == nil {
 		n.Split = DefaultSplit
 	}
+	if n.Compare == nil {
+		// The default implementations of Compare order prefixes bytewise.
+		n.BytewisePrefixes = true
+	}
 	if n.CompareRangeSuffixes == nil && n.Compare == nil && n.Equal == nil {
 		n.CompareRangeSuffixes = bytes.Compare
 		n.Compare = bytes.Compare
@@ -302,6 +316,7 @@ var DefaultComparer = &Comparer{
 	CompareRangeSuffixes: bytes.Compare,
 	Compare:              bytes.Compare,
 	Equal:                bytes.Equal,
+	BytewisePrefixes:     true,
 
 	AbbreviatedKey: func(key []byte) uint64 {
 		if len(key) >= 8 {
@@ -454,11 +469,12 @@ func MakeAssertComparer(c Comparer) Comparer {
 			c.ValidateKey.MustValidate(ret)
 			return ret
 		},
-		FormatKey:   c.FormatKey,
-		Split:       c.Split,
-		FormatValue: c.FormatValue,
-		ValidateKey: c.ValidateKey,
-		Name:        c.Name,
+		FormatKey:        c.FormatKey,
+		Split:            c.Split,
+		FormatValue:      c.FormatValue,
+		ValidateKey:      c.ValidateKey,
+		BytewisePrefixes: c.BytewisePrefixes,
+		Name:             c.Name,
 	}
 }
 
@@ -330,6 +330,16 @@ func (c *Handle) EvictFile(fileNum base.DiskFileNum) {
 	}
 }
 
+// FileSize returns the total size of the values cached for the specified
+// file. It can be used as a measure of how hot the file is.
+func (c *Handle) FileSize(fileNum base.DiskFileNum) int64 {
+	var size int64
+	for i := range c.cache.shards {
+		size += c.cache.shards[i].fileSize(c.id, fileNum)
+	}
+	return size
+}
+
 func (c *Handle) Close() {
 	c.cache.Unref()
 	*c = Handle{}
@@ -128,6 +128,30 @@ func TestEvictFile(t *testing.T) {
 	}
 }
 
+func TestFileSize(t *testing.T) {
+	cache := NewWithShards(100, 2)
+	defer cache.Unref()
+	h := cache.NewHandle()
+	defer h.Close()
+	other := cache.NewHandle()
+	defer other.Close()
+
+	setTestValue(h, 1, 0, "a", 5)
+	setTestValue(h, 2, 0, "a", 5)
+	setTestValue(h, 2, 1, "a", 7)
+	setTestValue(h, 2, 2, "a", 9)
+	setTestValue(other, 2, 0, "a", 11)
+	require.Equal(t, int64(5), h.FileSize(base.DiskFileNum(1)))
+	require.Equal(t, int64(21), h.FileSize(base.DiskFileNum(2)))
+	require.Equal(t, int64(0), h.FileSize(base.DiskFileNum(3)))
+	require.Equal(t, int64(11), other.FileSize(base.DiskFileNum(2)))
+
+	h.Delete(base.DiskFileNum(2), 1)
+	require.Equal(t, int64(14), h.FileSize(base.DiskFileNum(2)))
+	h.EvictFile(base.DiskFileNum(2))
+	require.Equal(t, int64(0), h.FileSize(base.DiskFileNum(2)))
+}
+
 func TestEvictAll(t *testing.T) {
 	// Verify that it is okay to evict all of the data from a cache. Previously
 	// this would trigger a nil-pointer dereference.
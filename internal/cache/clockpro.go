@@ -333,6 +333,26 @@ func (c *shard) evictFileRun(fkey key) (moreRemaining bool) {
 	return true
 }
 
+// fileSize returns the total size of the values cached for the specified
+// file. Test entries, which have no value, are not included.
+func (c *shard) fileSize(id handleID, fileNum base.DiskFileNum) int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	blocks, _ := c.files.Get(makeKey(id, fileNum, 0))
+	if blocks == nil {
+		return 0
+	}
+	var size int64
+	for b := blocks; ; {
+		if b.val != nil {
+			size += b.size
+		}
+		if b = b.fileLink.next; b == blocks {
+			return size
+		}
+	}
+}
+
 func (c *shard) Free() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
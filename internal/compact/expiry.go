@@ -0,0 +1,119 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package compact
+
+import "github.com/chris124567/pebble/internal/base"
+
+// expiringIter wraps a point iterator, surfacing SET and SETWITHDEL keys whose
+// values have expired as DELs at the same sequence number.
+//
+// An expired key that immediately follows a MERGE of the same user key is
+// surfaced unchanged: it is the base of the merge, and it is up to the merge
+// operator to expire the merged value along with it.
+type expiringIter struct {
+	base.InternalIterator
+	valueExpiry func(kv *base.InternalKV) (uint64, error)
+	now         uint64
+	equal       base.Equal
+	stats       *IterStats
+	kv          base.InternalKV
+	err         error
+	// mergeKey holds the user key of the last key surfaced, if it was a MERGE.
+	mergeKey   []byte
+	afterMerge bool
+}
+
+var _ base.InternalIterator = (*expiringIter)(nil)
+
+func (i *expiringIter) transform(kv *base.InternalKV) *base.InternalKV {
+	if kv == nil {
+		i.afterMerge = false
+		return nil
+	}
+	afterMerge := i.afterMerge && i.equal(i.mergeKey, kv.K.UserKey)
+	i.afterMerge = false
+	switch kv.Kind() {
+	case base.InternalKeyKindSet, base.InternalKeyKindSetWithDelete:
+		if afterMerge {
+			return kv
+		}
+	case base.InternalKeyKindMerge:
+		i.mergeKey = append(i.mergeKey[:0], kv.K.UserKey...)
+		i.afterMerge = true
+		return kv
+	default:
+		return kv
+	}
+	expiry, err := i.valueExpiry(kv)
+	if err != nil {
+		i.err = err
+		return nil
+	}
+	if expiry == 0 || expiry > i.now {
+		return kv
+	}
+	i.stats.CountExpired++
+	i.kv = base.InternalKV{K: base.MakeInternalKey(kv.K.UserKey, kv.SeqNum(), base.InternalKeyKindDelete)}
+	return &i.kv
+}
+
+// SeekGE implements base.InternalIterator.
+func (i *expiringIter) SeekGE(key []byte, flags base.SeekGEFlags) *base.InternalKV {
+	i.err = nil
+	i.afterMerge = false
+	return i.transform(i.InternalIterator.SeekGE(key, flags))
+}
+
+// SeekPrefixGE implements base.InternalIterator.
+func (i *expiringIter) SeekPrefixGE(prefix, key []byte, flags base.SeekGEFlags) *base.InternalKV {
+	i.err = nil
+	i.afterMerge = false
+	return i.transform(i.InternalIterator.SeekPrefixGE(prefix, key, flags))
+}
+
+// SeekLT implements base.InternalIterator.
+func (i *expiringIter) SeekLT(key []byte, flags base.SeekLTFlags) *base.InternalKV {
+	i.err = nil
+	i.afterMerge = false
+	return i.transform(i.InternalIterator.SeekLT(key, flags))
+}
+
+// First implements base.InternalIterator.
+func (i *expiringIter) First() *base.InternalKV {
+	i.err = nil
+	i.afterMerge = false
+	return i.transform(i.InternalIterator.First())
+}
+
+// Last implements base.InternalIterator.
+func (i *expiringIter) Last() *base.InternalKV {
+	i.err = nil
+	i.afterMerge = false
+	return i.transform(i.InternalIterator.Last())
+}
+
+// Next implements base.InternalIterator.
+func (i *expiringIter) Next() *base.InternalKV {
+	return i.transform(i.InternalIterator.Next())
+}
+
+// NextPrefix implements base.InternalIterator.
+func (i *expiringIter) NextPrefix(succKey []byte) *base.InternalKV {
+	return i.transform(i.InternalIterator.NextPrefix(succKey))
+}
+
+// Prev implements base.InternalIterator.
+func (i *expiringIter) Prev() *base.InternalKV {
+	i.afterMerge = false
+	return i.transform(i.InternalIterator.Prev())
+}
+
+// Error implements base.InternalIterator.
+func (i *expiringIter) Error() error {
+	if i.err != nil {
+		return i.err
+	}
+	return i.InternalIterator.Error()
+}
@@ -0,0 +1,92 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package compact
+
+import "github.com/chris124567/pebble/internal/base"
+
+// FilterDecision is the outcome of IterConfig.Filter for a key.
+type FilterDecision int8
+
+const (
+	// FilterKeep retains the key and its value.
+	FilterKeep FilterDecision = iota
+	// FilterRemove deletes the key.
+	FilterRemove
+	// FilterChangeValue replaces the key's value.
+	FilterChangeValue
+)
+
+// maybeFilter applies cfg.Filter to i.kv, a SET or SETWITHDEL key from the
+// snapshot stripe with the given index, which is about to be returned. It
+// returns true if the key was removed and should not be returned at all.
+//
+// Only keys in the newest snapshot stripe, which are not visible to any
+// snapshot, are filtered. A removed key is converted to a DEL, which shadows
+// any older versions of the key in lower stripes or levels, unless there are no
+// such versions and the DEL can be elided.
+func (i *Iter) maybeFilter(snapshotIdx int) (elided bool) {
+	if i.cfg.Filter == nil || snapshotIdx != len(i.cfg.Snapshots) {
+		return false
+	}
+	v, callerOwned, err := i.kv.V.Value(i.filterBuf[:0])
+	if err != nil {
+		i.err = err
+		return false
+	}
+	if callerOwned && cap(v) > cap(i.filterBuf) {
+		i.filterBuf = v
+	}
+	decision, newValue, err := i.cfg.Filter(i.kv.K.UserKey, v)
+	if err != nil {
+		i.err = err
+		return false
+	}
+	switch decision {
+	case FilterKeep:
+		return false
+
+	case FilterRemove:
+		i.stats.CountFilterRemoved++
+		if i.closeValueCloser() != nil {
+			return false
+		}
+		if snapshotIdx == 0 && i.delElider.ShouldElide(i.kv.K.UserKey) {
+			// There are no snapshots, and no older versions of the key in
+			// lower levels. Any older versions in the stripe have already
+			// been consumed, or are skipped by the caller.
+			return true
+		}
+		i.kv.K.SetKind(base.InternalKeyKindDelete)
+		i.kv.V = base.InternalValue{}
+		return false
+
+	case FilterChangeValue:
+		i.stats.CountFilterChanged++
+		// The new value may alias the old one, which may in turn be backed by
+		// the value closer.
+		i.filterValueBuf = append(i.filterValueBuf[:0], newValue...)
+		if i.closeValueCloser() != nil {
+			return false
+		}
+		i.kv.V = base.MakeInPlaceValue(i.filterValueBuf)
+		return false
+
+	default:
+		i.err = base.AssertionFailedf("pebble: invalid compaction filter decision %d", decision)
+		return false
+	}
+}
+
+// skipFilteredKey advances past the remainder of the stripe of a key elided by
+// maybeFilter, leaving the iterator positioned as Next expects at the top of
+// its loop.
+func (i *Iter) skipFilteredKey() {
+	if i.pos == iterPosCurForward {
+		// The key was followed by a tombstone in the same stripe; skip it and
+		// anything it shadows.
+		i.skipInStripe()
+	}
+	i.pos = iterPosCurForward
+}
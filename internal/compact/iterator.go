@@ -158,6 +158,9 @@ type Iter struct {
 	rangeDelInterleaving keyspan.InterleavingIter
 	// rangeKeyInterleaving is the interleaving iter for range keys.
 	rangeKeyInterleaving keyspan.InterleavingIter
+	// expiring transforms expired point keys into DELs. Only used if
+	// cfg.ValueExpiry is set.
+	expiring expiringIter
 
 	// iter is the iterator which interleaves points with RANGEDELs and range
 	// keys.
@@ -193,6 +196,10 @@ type Iter struct {
 	// unsafe, i.iter-owned slice that could be altered when the iterator is
 	// advanced.
 	valueBuf []byte
+	// Temporary buffers used by maybeFilter for the value passed to
+	// cfg.Filter and the changed value it returns.
+	filterBuf      []byte
+	filterValueBuf []byte
 	// valueFetcher is used by saveValue when Cloning InternalValues.
 	valueFetcher     base.LazyFetcher
 	iterKV           *base.InternalKV
@@ -294,6 +301,24 @@ type IterConfig struct {
 	// size of the elided key and the expected size that was recorded in the
 	// tombstone. For the first case (when a key doesn't exist), these will be 0.
 	MissizedDeleteCallback func(userKey []byte, elidedSize, expectedSize uint64)
+
+	// ValueExpiry, if set, returns the expiry time of the value of a SET or
+	// SETWITHDEL key, or zero if the value never expires. It should avoid
+	// fetching values separated into blob files. Keys whose values
+	// expired at or before Now are compacted as DELs, so that the key and the
+	// data it shadows are dropped once the tombstone can be elided. The base of
+	// a merge is passed to Merge even if it has expired.
+	ValueExpiry func(kv *base.InternalKV) (expiry uint64, _ error)
+	// Now is the time against which the expiry times returned by ValueExpiry
+	// are compared.
+	Now uint64
+
+	// Filter, if set, is called with the user key and value of every SET and
+	// SETWITHDEL key about to be returned that is not visible to any snapshot,
+	// including the results of merges that include their base value. It decides
+	// whether the key is kept, removed or has its value changed. A changed
+	// value is copied before Filter is called again.
+	Filter func(key, value []byte) (_ FilterDecision, newValue []byte, _ error)
 }
 
 func (c *IterConfig) ensureDefaults() {
@@ -312,6 +337,13 @@ func (c *IterConfig) ensureDefaults() {
 type IterStats struct {
 	// Count of DELSIZED keys that were missized.
 	CountMissizedDels uint64
+	// Count of SET and SETWITHDEL keys that were compacted as DELs because
+	// their values expired.
+	CountExpired uint64
+	// Count of keys removed by IterConfig.Filter.
+	CountFilterRemoved uint64
+	// Count of keys whose values were changed by IterConfig.Filter.
+	CountFilterChanged uint64
 }
 
 type iterPos int8
@@ -340,6 +372,16 @@ func NewIter(
 	}
 
 	iter := pointIter
+	if cfg.ValueExpiry != nil {
+		i.expiring = expiringIter{
+			InternalIterator: iter,
+			valueExpiry:      cfg.ValueExpiry,
+			now:              cfg.Now,
+			equal:            cfg.Comparer.Equal,
+			stats:            &i.stats,
+		}
+		iter = &i.expiring
+	}
 	if rangeDelIter != nil {
 		i.rangeDelInterleaving.Init(cfg.Comparer, iter, rangeDelIter, keyspan.InterleavingIterOpts{})
 		iter = &i.rangeDelInterleaving
@@ -595,10 +637,18 @@ func (i *Iter) Next() *base.InternalKV {
 			// entry. setNext() does the work to move the iterator forward,
 			// preserving the original value, and potentially mutating the key
 			// kind.
+			origSnapshotIdx := i.curSnapshotIdx
 			i.setNext()
 			if i.err != nil {
 				return nil
 			}
+			if i.maybeFilter(origSnapshotIdx) {
+				i.skipFilteredKey()
+				continue
+			}
+			if i.err != nil {
+				return nil
+			}
 			return &i.kv
 
 		case base.InternalKeyKindMerge:
@@ -611,11 +661,10 @@ func (i *Iter) Next() *base.InternalKV {
 			if i.err == nil {
 				i.mergeNext(valueMerger)
 			}
-			var needDelete bool
+			// includesBase is true whenever we've transformed the MERGE record
+			// into a SET.
+			var needDelete, includesBase bool
 			if i.err == nil {
-				// includesBase is true whenever we've transformed the MERGE record
-				// into a SET.
-				var includesBase bool
 				switch i.kv.K.Kind() {
 				case base.InternalKeyKindSet, base.InternalKeyKindSetWithDelete:
 					includesBase = true
@@ -635,6 +684,15 @@ func (i *Iter) Next() *base.InternalKV {
 				}
 
 				i.maybeZeroSeqnum(origSnapshotIdx)
+				if includesBase {
+					if i.maybeFilter(origSnapshotIdx) {
+						i.skipFilteredKey()
+						continue
+					}
+					if i.err != nil {
+						return nil
+					}
+				}
 				return &i.kv
 			}
 			if i.err != nil {
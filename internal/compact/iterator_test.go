@@ -56,6 +56,7 @@ func TestCompactionIter(t *testing.T) {
 	var snapshots Snapshots
 	var elideTombstones bool
 	var allowZeroSeqnum bool
+	var filter func(key, value []byte) (FilterDecision, []byte, error)
 	var ineffectualSingleDeleteKeys []string
 	var invariantViolationSingleDeleteKeys []string
 	var missizedDeleteInfo []string
@@ -85,6 +86,7 @@ func TestCompactionIter(t *testing.T) {
 			TombstoneElision: elision,
 			RangeKeyElision:  elision,
 			AllowZeroSeqNum:  allowZeroSeqnum,
+			Filter:           filter,
 			IneffectualSingleDeleteCallback: func(userKey []byte) {
 				ineffectualSingleDeleteKeys = append(ineffectualSingleDeleteKeys, string(userKey))
 			},
@@ -161,6 +163,7 @@ func TestCompactionIter(t *testing.T) {
 				snapshots = snapshots[:0]
 				elideTombstones = false
 				allowZeroSeqnum = false
+				filter = nil
 				printSnapshotPinned := false
 				printMissizedDels := false
 				printForceObsolete := false
@@ -183,6 +186,19 @@ func TestCompactionIter(t *testing.T) {
 						if err != nil {
 							return err.Error()
 						}
+					case "filter":
+						// Remove keys whose values begin with "rm", and
+						// change the values of keys whose values begin with
+						// "ch".
+						filter = func(key, value []byte) (FilterDecision, []byte, error) {
+							switch {
+							case bytes.HasPrefix(value, []byte("rm")):
+								return FilterRemove, nil, nil
+							case bytes.HasPrefix(value, []byte("ch")):
+								return FilterChangeValue, fmt.Appendf(nil, "changed(%s)", value), nil
+							}
+							return FilterKeep, nil, nil
+						}
 					case "print-snapshot-pinned":
 						printSnapshotPinned = true
 					case "print-missized-dels":
@@ -264,6 +280,10 @@ func TestCompactionIter(t *testing.T) {
 				if printMissizedDels {
 					fmt.Fprintf(&b, "missized-dels=%d\n", iter.stats.CountMissizedDels)
 				}
+				if filter != nil {
+					fmt.Fprintf(&b, "filter-removed=%d filter-changed=%d\n",
+						iter.stats.CountFilterRemoved, iter.stats.CountFilterChanged)
+				}
 				if len(ineffectualSingleDeleteKeys) > 0 {
 					fmt.Fprintf(&b, "ineffectual-single-deletes: %s\n",
 						strings.Join(ineffectualSingleDeleteKeys, ","))
@@ -288,6 +308,7 @@ func TestCompactionIter(t *testing.T) {
 	runTest(t, "testdata/iter")
 	runTest(t, "testdata/iter_set_with_del")
 	runTest(t, "testdata/iter_delete_sized")
+	runTest(t, "testdata/iter_filter")
 }
 
 // mockBlobValueFetcher is a dummy ValueFetcher implementation which produces
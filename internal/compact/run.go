@@ -77,6 +77,13 @@ type Stats struct {
 	// output objects specifically.
 	CumulativeBlobFileSize uint64
 	CountMissizedDels      uint64
+	// CountExpired is the number of keys compacted as DELs because their
+	// values expired. See IterConfig.ValueExpiry.
+	CountExpired uint64
+	// CountFilterRemoved and CountFilterChanged are the number of keys removed
+	// and the number of values changed by IterConfig.Filter.
+	CountFilterRemoved uint64
+	CountFilterChanged uint64
 }
 
 // RunnerConfig contains the parameters needed for the Runner.
@@ -282,6 +289,7 @@ func (r *Runner) writeKeysToTable(
 		return tw.ComparePrev(k) == 0
 	}
 	var pinnedKeySize, pinnedValueSize, pinnedCount uint64
+	var expiringValueSize, minExpiry, maxExpiry uint64
 	var iteratedKeys uint64
 	kv := r.kv
 	for ; kv != nil; kv = r.iter.Next() {
@@ -320,6 +328,20 @@ func (r *Runner) writeKeysToTable(
 		}
 
 		valueLen := kv.V.Len()
+		if r.iter.cfg.ValueExpiry != nil &&
+			(kv.K.Kind() == base.InternalKeyKindSet || kv.K.Kind() == base.InternalKeyKindSetWithDelete) {
+			expiry, err := r.iter.cfg.ValueExpiry(kv)
+			if err != nil {
+				return nil, err
+			}
+			if expiry != 0 {
+				if minExpiry == 0 || expiry < minExpiry {
+					minExpiry = expiry
+				}
+				maxExpiry = max(maxExpiry, expiry)
+				expiringValueSize += uint64(valueLen)
+			}
+		}
 		// Add the value to the sstable, possibly separating its value into a
 		// blob file. The ValueSeparation implementation is responsible for
 		// writing the KV to the sstable.
@@ -345,6 +367,7 @@ func (r *Runner) writeKeysToTable(
 	}
 	// Set internal sstable properties.
 	tw.SetSnapshotPinnedProperties(pinnedCount, pinnedKeySize, pinnedValueSize)
+	tw.SetValueExpiryProperties(expiringValueSize, minExpiry, maxExpiry)
 	r.stats.CumulativePinnedKeys += pinnedCount
 	r.stats.CumulativePinnedSize += pinnedKeySize + pinnedValueSize
 
@@ -368,7 +391,11 @@ func (r *Runner) Finish() Result {
 	r.err = errors.CombineErrors(r.err, r.iter.Close())
 	// The compaction iterator keeps track of a count of the number of DELSIZED
 	// keys that encoded an incorrect size.
-	r.stats.CountMissizedDels = r.iter.Stats().CountMissizedDels
+	iterStats := r.iter.Stats()
+	r.stats.CountMissizedDels = iterStats.CountMissizedDels
+	r.stats.CountExpired = iterStats.CountExpired
+	r.stats.CountFilterRemoved = iterStats.CountFilterRemoved
+	r.stats.CountFilterChanged = iterStats.CountFilterChanged
 	return Result{
 		Err:    r.err,
 		Tables: r.tables,
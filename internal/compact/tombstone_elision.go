@@ -63,6 +63,12 @@ func (e TombstoneElision) ElidesEverything() bool {
 	return e.mode == elideNotInUse && len(e.inUseRanges) == 0
 }
 
+// InUseRanges returns the "in use" key ranges outside of which tombstones are
+// elided. It returns nil if ElidesNothing or ElidesEverything is true.
+func (e TombstoneElision) InUseRanges() []base.UserKeyBounds {
+	return e.inUseRanges
+}
+
 func (e TombstoneElision) String() string {
 	switch {
 	case e.ElidesNothing():
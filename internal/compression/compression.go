@@ -14,6 +14,7 @@ const (
 	Snappy
 	Zstd
 	MinLZ
+	LZ4
 	nAlgorithms
 )
 
@@ -29,6 +30,8 @@ func (a Algorithm) String() string {
 		return "ZSTD"
 	case MinLZ:
 		return "MinLZ"
+	case LZ4:
+		return "LZ4"
 	default:
 		return fmt.Sprintf("unknown(%d)", a)
 	}
@@ -53,11 +56,24 @@ func GetCompressor(a Algorithm) Compressor {
 		return getZstdCompressor()
 	case MinLZ:
 		return minlzCompressor{}
+	case LZ4:
+		return lz4Compressor{}
 	default:
 		panic("Invalid compression type.")
 	}
 }
 
+// zstdDefaultLevel is the compression level of the Zstd compressor returned by
+// GetCompressor.
+const zstdDefaultLevel = 3
+
+// GetZstdCompressor returns a Compressor applying Zstandard compression at the
+// given level. Higher levels compress better, at a higher CPU cost; they do not
+// affect the cost of decompression significantly.
+func GetZstdCompressor(level int) Compressor {
+	return getZstdCompressorWithLevel(level)
+}
+
 type Decompressor interface {
 	// DecompressInto decompresses compressed into buf. The buf slice must have the
 	// exact size as the decompressed value. Callers may use DecompressedLen to
@@ -84,6 +100,8 @@ func GetDecompressor(a Algorithm) Decompressor {
 		return getZstdDecompressor()
 	case MinLZ:
 		return minlzDecompressor{}
+	case LZ4:
+		return lz4Decompressor{}
 	default:
 		panic("Invalid compression type.")
 	}
@@ -6,6 +6,7 @@ package compression
 
 import (
 	"encoding/binary"
+	"fmt"
 	"math/rand/v2"
 	"testing"
 	"time"
@@ -62,6 +63,50 @@ func TestDecompressionError(t *testing.T) {
 	require.Nil(t, v)
 }
 
+func TestDictionary(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	rng := rand.New(rand.NewPCG(0, 1 /* fixed seed */))
+
+	// Generate small blocks sharing common content, that compress poorly on
+	// their own.
+	makeBlock := func() []byte {
+		var b []byte
+		for len(b) < 512 {
+			b = fmt.Appendf(b, "user%06d:{\"name\":\"n%d\",\"region\":\"us-east\",\"status\":\"active\"}",
+				rng.IntN(1000000), rng.IntN(100))
+		}
+		return b
+	}
+	var samples [][]byte
+	for i := 0; i < 200; i++ {
+		samples = append(samples, makeBlock())
+	}
+	dict, err := TrainDictionary(samples, 4<<10)
+	require.NoError(t, err)
+
+	// The dictionary can be loaded from its encoding.
+	loaded, err := LoadDictionary(dict.Bytes())
+	require.NoError(t, err)
+	_, err = LoadDictionary([]byte("not a dictionary"))
+	require.Error(t, err)
+
+	var withDict, withoutDict int
+	for i := 0; i < 100; i++ {
+		block := makeBlock()
+		compressed := dict.Compressor().Compress(nil, block)
+		withDict += len(compressed)
+		withoutDict += len(GetCompressor(Zstd).Compress(nil, block))
+
+		decompressor := loaded.Decompressor()
+		n, err := decompressor.DecompressedLen(compressed)
+		require.NoError(t, err)
+		got := make([]byte, n)
+		require.NoError(t, decompressor.DecompressInto(got, compressed))
+		require.Equal(t, block, got)
+	}
+	require.Less(t, withDict, withoutDict)
+}
+
 // decompress decompresses an sstable block into memory manually allocated with
 // `cache.Alloc`.  NB: If Decompress returns (nil, nil), no decompression was
 // necessary and the caller may use `b` directly.
@@ -0,0 +1,214 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package compression
+
+import (
+	"encoding/binary"
+
+	"github.com/cockroachdb/errors"
+	"github.com/chris124567/pebble/internal/base"
+)
+
+// An LZ4 compressed block is a uvarint encoding the length of the decompressed
+// block, followed by the block in the LZ4 block format. This is the format
+// used by RocksDB (compress_format_version=2) for LZ4 compressed blocks.
+//
+// The LZ4 block format is a sequence of sequences, each made up of a token, a
+// run of literals and a match. The high 4 bits of the token hold the number of
+// literals and the low 4 bits the length of the match minus lz4MinMatch; a
+// value of 15 indicates that the length continues in the following bytes, each
+// adding its value to the length until a byte other than 255. The literals are
+// followed by the 2-byte little-endian offset of the match and the
+// continuation of the match length. The last sequence has literals only.
+const (
+	lz4MinMatch  = 4
+	lz4MaxOffset = 1<<16 - 1
+	// The last lz4LastLiterals bytes of a block are always literals, and the
+	// last match must start at least lz4MFLimit bytes before the end of the
+	// block.
+	lz4LastLiterals = 5
+	lz4MFLimit      = 12
+
+	lz4HashLog = 12
+	// lz4SkipTrigger controls how quickly the compressor skips ahead over
+	// incompressible data.
+	lz4SkipTrigger = 6
+)
+
+type lz4Compressor struct{}
+
+var _ Compressor = lz4Compressor{}
+
+func (lz4Compressor) Compress(dst, src []byte) []byte {
+	dst = binary.AppendUvarint(dst[:0], uint64(len(src)))
+	return lz4CompressBlock(dst, src)
+}
+
+func (lz4Compressor) Close() {}
+
+func lz4Hash(seq uint32) uint32 {
+	return (seq * 2654435761) >> (32 - lz4HashLog)
+}
+
+// lz4CompressBlock appends the LZ4 block encoding src to dst.
+func lz4CompressBlock(dst, src []byte) []byte {
+	// table maps the hashes of 4-byte sequences to one plus the offset at which
+	// they were last seen.
+	var table [1 << lz4HashLog]uint32
+	var anchor int
+	for i := 0; i < len(src)-lz4MFLimit; {
+		seq := binary.LittleEndian.Uint32(src[i:])
+		h := lz4Hash(seq)
+		ref := int(table[h]) - 1
+		table[h] = uint32(i + 1)
+		if ref < 0 || i-ref > lz4MaxOffset || binary.LittleEndian.Uint32(src[ref:]) != seq {
+			i += 1 + (i-anchor)>>lz4SkipTrigger
+			continue
+		}
+		// Extend the match backwards over the pending literals, and forwards
+		// up to the trailing literals.
+		for i > anchor && ref > 0 && src[i-1] == src[ref-1] {
+			i--
+			ref--
+		}
+		matchLen := lz4MinMatch
+		for i+matchLen < len(src)-lz4LastLiterals && src[i+matchLen] == src[ref+matchLen] {
+			matchLen++
+		}
+		dst = lz4AppendSequence(dst, src[anchor:i], i-ref, matchLen)
+		i += matchLen
+		anchor = i
+	}
+	return lz4AppendLiterals(dst, src[anchor:])
+}
+
+func lz4AppendLength(dst []byte, n int) []byte {
+	for ; n >= 255; n -= 255 {
+		dst = append(dst, 255)
+	}
+	return append(dst, byte(n))
+}
+
+func lz4AppendSequence(dst, literals []byte, offset, matchLen int) []byte {
+	matchLen -= lz4MinMatch
+	dst = append(dst, byte(min(len(literals), 15)<<4|min(matchLen, 15)))
+	if len(literals) >= 15 {
+		dst = lz4AppendLength(dst, len(literals)-15)
+	}
+	dst = append(dst, literals...)
+	dst = append(dst, byte(offset), byte(offset>>8))
+	if matchLen >= 15 {
+		dst = lz4AppendLength(dst, matchLen-15)
+	}
+	return dst
+}
+
+// lz4AppendLiterals appends the last sequence of a block, made up of literals
+// only.
+func lz4AppendLiterals(dst, literals []byte) []byte {
+	dst = append(dst, byte(min(len(literals), 15)<<4))
+	if len(literals) >= 15 {
+		dst = lz4AppendLength(dst, len(literals)-15)
+	}
+	return append(dst, literals...)
+}
+
+type lz4Decompressor struct{}
+
+var _ Decompressor = lz4Decompressor{}
+
+func (lz4Decompressor) DecompressInto(buf, compressed []byte) error {
+	_, prefixLen := binary.Uvarint(compressed)
+	if prefixLen <= 0 {
+		return base.CorruptionErrorf("pebble: lz4 block has invalid length")
+	}
+	return lz4DecompressBlock(buf, compressed[prefixLen:])
+}
+
+func (lz4Decompressor) DecompressedLen(b []byte) (decompressedLen int, err error) {
+	decodedLen, prefixLen := binary.Uvarint(b)
+	if prefixLen <= 0 {
+		return 0, base.CorruptionErrorf("pebble: lz4 block has invalid length")
+	}
+	return int(decodedLen), nil
+}
+
+func (lz4Decompressor) Close() {}
+
+var errLZ4Corrupt = base.CorruptionErrorf("pebble: corrupt lz4 block")
+
+// lz4DecompressBlock decodes the LZ4 block src into dst, which must be exactly
+// the size of the decoded block.
+func lz4DecompressBlock(dst, src []byte) error {
+	readLength := func(si, n int) (int, int, bool) {
+		for {
+			if si >= len(src) {
+				return 0, 0, false
+			}
+			b := src[si]
+			si++
+			n += int(b)
+			if b != 255 {
+				return si, n, true
+			}
+		}
+	}
+	var di, si int
+	for {
+		if si >= len(src) {
+			return errLZ4Corrupt
+		}
+		token := src[si]
+		si++
+		litLen := int(token >> 4)
+		if litLen == 15 {
+			var ok bool
+			if si, litLen, ok = readLength(si, litLen); !ok {
+				return errLZ4Corrupt
+			}
+		}
+		if litLen > len(src)-si || litLen > len(dst)-di {
+			return errLZ4Corrupt
+		}
+		di += copy(dst[di:], src[si:si+litLen])
+		si += litLen
+		if si == len(src) {
+			// The last sequence has literals only.
+			break
+		}
+
+		if len(src)-si < 2 {
+			return errLZ4Corrupt
+		}
+		offset := int(src[si]) | int(src[si+1])<<8
+		si += 2
+		if offset == 0 || offset > di {
+			return errLZ4Corrupt
+		}
+		matchLen := int(token & 15)
+		if matchLen == 15 {
+			var ok bool
+			if si, matchLen, ok = readLength(si, matchLen); !ok {
+				return errLZ4Corrupt
+			}
+		}
+		matchLen += lz4MinMatch
+		if matchLen > len(dst)-di {
+			return errLZ4Corrupt
+		}
+		if offset >= matchLen {
+			di += copy(dst[di:di+matchLen], dst[di-offset:])
+		} else {
+			// The match overlaps the bytes it produces.
+			for end := di + matchLen; di < end; di++ {
+				dst[di] = dst[di-offset]
+			}
+		}
+	}
+	if di != len(dst) {
+		return errors.Wrapf(errLZ4Corrupt, "decompressed %d bytes, expected %d", di, len(dst))
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLZ4(t *testing.T) {
+	rng := rand.New(rand.NewPCG(0, 1))
+	words := []string{"pebble", "lz4", "compression", "block", "sstable", "key", "value"}
+	var payloads [][]byte
+	for _, n := range []int{0, 1, 4, 12, 13, 15, 16, 100, 1000, 70000, 300000} {
+		// Compressible text, with matches both short and long, close and far.
+		var buf bytes.Buffer
+		for buf.Len() < n {
+			if rng.IntN(50) == 0 {
+				buf.Write(bytes.Repeat([]byte{byte(rng.IntN(256))}, rng.IntN(1000)))
+			} else {
+				fmt.Fprintf(&buf, "%s%d ", words[rng.IntN(len(words))], rng.IntN(100))
+			}
+		}
+		payloads = append(payloads, buf.Bytes()[:n])
+		// Incompressible data.
+		random := make([]byte, n)
+		for i := range random {
+			random[i] = byte(rng.Uint32())
+		}
+		payloads = append(payloads, random)
+	}
+	for _, payload := range payloads {
+		compressed := GetCompressor(LZ4).Compress(nil, payload)
+		got, err := decompress(LZ4, compressed)
+		require.NoError(t, err)
+		require.Equal(t, string(payload), string(got))
+
+		// Corrupting or truncating the block must not cause a panic.
+		for i := 0; i < 20 && len(compressed) > 1; i++ {
+			corrupted := bytes.Clone(compressed)
+			corrupted[1+rng.IntN(len(corrupted)-1)] ^= byte(1 + rng.IntN(255))
+			_, _ = decompress(LZ4, corrupted)
+			_, _ = decompress(LZ4, compressed[:1+rng.IntN(len(compressed)-1)])
+		}
+	}
+
+	// Highly compressible data compresses well.
+	payload := bytes.Repeat([]byte("0123456789"), 10000)
+	compressed := GetCompressor(LZ4).Compress(nil, payload)
+	require.Less(t, len(compressed), len(payload)/100)
+}
@@ -16,7 +16,8 @@ import (
 )
 
 type zstdCompressor struct {
-	ctx zstd.Ctx
+	ctx   zstd.Ctx
+	level int
 }
 
 var _ Compressor = (*zstdCompressor)(nil)
@@ -38,8 +39,8 @@ var zstdCompressorPool = sync.Pool{
 // relies on CGo.
 const UseStandardZstdLib = true
 
-// Compress compresses b with the Zstandard algorithm at default compression
-// level (level 3). It reuses the preallocated capacity of compressedBuf if it
+// Compress compresses b with the Zstandard algorithm at the compressor's
+// compression level (level 3 by default). It reuses the preallocated capacity of compressedBuf if it
 // is sufficient. The subslice `compressedBuf[:varIntLen]` should already encode
 // the length of `b` before calling Compress. It returns the encoded byte
 // slice, including the `compressedBuf[:varIntLen]` prefix.
@@ -57,7 +58,7 @@ func (z *zstdCompressor) Compress(compressedBuf []byte, b []byte) []byte {
 	}
 
 	varIntLen := binary.PutUvarint(compressedBuf, uint64(len(b)))
-	result, err := z.ctx.CompressLevel(compressedBuf[varIntLen:varIntLen+bound], b, 3)
+	result, err := z.ctx.CompressLevel(compressedBuf[varIntLen:varIntLen+bound], b, z.level)
 	if err != nil {
 		panic("Error while compressing using Zstd.")
 	}
@@ -73,7 +74,13 @@ func (z *zstdCompressor) Close() {
 }
 
 func getZstdCompressor() *zstdCompressor {
-	return zstdCompressorPool.Get().(*zstdCompressor)
+	return getZstdCompressorWithLevel(zstdDefaultLevel)
+}
+
+func getZstdCompressorWithLevel(level int) *zstdCompressor {
+	z := zstdCompressorPool.Get().(*zstdCompressor)
+	z.level = level
+	return z
 }
 
 type zstdDecompressor struct {
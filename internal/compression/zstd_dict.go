@@ -0,0 +1,170 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package compression
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/chris124567/pebble/internal/base"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Dictionary is a Zstandard dictionary, trained on samples of the data to be
+// compressed. Compressing many small blocks with a dictionary captures the
+// redundancy across blocks that is lost when each is compressed on its own.
+//
+// Dictionaries are always used through the pure Go Zstandard implementation,
+// with or without cgo. The encoder and decoder of a Dictionary are only used
+// through EncodeAll and DecodeAll, which start no goroutines, so a Dictionary
+// needs no closing.
+type Dictionary struct {
+	raw []byte
+
+	encoder struct {
+		once sync.Once
+		e    *zstd.Encoder
+		err  error
+	}
+	decoder struct {
+		once sync.Once
+		d    *zstd.Decoder
+		err  error
+	}
+}
+
+// dictionaryID is the ID of every trained dictionary. A block is only ever
+// decompressed with the dictionary of the file containing it, so IDs need not
+// be unique.
+const dictionaryID = 1
+
+// TrainDictionary trains a dictionary of at most size bytes on the given
+// samples. The content of the dictionary is made up of an equal share of
+// every sample, taken from the middle of the sample.
+func TrainDictionary(samples [][]byte, size int) (*Dictionary, error) {
+	if len(samples) == 0 {
+		return nil, errors.New("pebble: no samples to train a compression dictionary")
+	}
+	share := max(size/len(samples), 1)
+	history := make([]byte, 0, size)
+	for _, s := range samples {
+		n := min(share, len(s), size-len(history))
+		start := (len(s) - n) / 2
+		history = append(history, s[start:start+n]...)
+	}
+	raw, err := zstd.BuildDict(zstd.BuildDictOptions{
+		ID:       dictionaryID,
+		Contents: samples,
+		History:  history,
+		Offsets:  [3]int{1, 4, 8},
+		Level:    zstd.SpeedDefault,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "pebble: training compression dictionary")
+	}
+	return &Dictionary{raw: raw}, nil
+}
+
+// LoadDictionary returns the Dictionary with the given encoding, as returned by
+// Bytes. The encoding is retained by the Dictionary.
+func LoadDictionary(raw []byte) (*Dictionary, error) {
+	d := &Dictionary{raw: raw}
+	// Validate the dictionary eagerly, rather than on the first decompression.
+	if _, err := d.getDecoder(); err != nil {
+		return nil, base.CorruptionErrorf("pebble: invalid compression dictionary: %v", err)
+	}
+	return d, nil
+}
+
+// Bytes returns the encoding of the dictionary.
+func (d *Dictionary) Bytes() []byte {
+	return d.raw
+}
+
+func (d *Dictionary) getEncoder() (*zstd.Encoder, error) {
+	d.encoder.once.Do(func() {
+		d.encoder.e, d.encoder.err = zstd.NewWriter(nil,
+			zstd.WithEncoderDict(d.raw),
+			zstd.WithEncoderConcurrency(1),
+			zstd.WithEncoderLevel(zstd.SpeedDefault))
+	})
+	return d.encoder.e, d.encoder.err
+}
+
+func (d *Dictionary) getDecoder() (*zstd.Decoder, error) {
+	d.decoder.once.Do(func() {
+		d.decoder.d, d.decoder.err = zstd.NewReader(nil,
+			zstd.WithDecoderDicts(d.raw),
+			zstd.WithDecoderConcurrency(0))
+	})
+	return d.decoder.d, d.decoder.err
+}
+
+// Compressor returns a Compressor compressing with the dictionary. Like the
+// Zstd compressor, the compressed data is prefixed with a uvarint encoding the
+// length of the decompressed data.
+func (d *Dictionary) Compressor() Compressor {
+	return zstdDictCompressor{d: d}
+}
+
+// Decompressor returns a Decompressor decompressing data compressed with the
+// dictionary.
+func (d *Dictionary) Decompressor() Decompressor {
+	return zstdDictDecompressor{d: d}
+}
+
+type zstdDictCompressor struct {
+	d *Dictionary
+}
+
+var _ Compressor = zstdDictCompressor{}
+
+func (c zstdDictCompressor) Compress(dst, src []byte) []byte {
+	e, err := c.d.getEncoder()
+	if err != nil {
+		panic(errors.Wrap(err, "pebble: zstd dictionary compression"))
+	}
+	dst = binary.AppendUvarint(dst[:0], uint64(len(src)))
+	return e.EncodeAll(src, dst)
+}
+
+func (zstdDictCompressor) Close() {}
+
+type zstdDictDecompressor struct {
+	d *Dictionary
+}
+
+var _ Decompressor = zstdDictDecompressor{}
+
+func (c zstdDictDecompressor) DecompressInto(buf, compressed []byte) error {
+	_, prefixLen := binary.Uvarint(compressed)
+	if prefixLen <= 0 {
+		return base.CorruptionErrorf("pebble: compression block has invalid length")
+	}
+	dec, err := c.d.getDecoder()
+	if err != nil {
+		return err
+	}
+	result, err := dec.DecodeAll(compressed[prefixLen:], buf[:0])
+	if err != nil {
+		return err
+	}
+	if len(result) != len(buf) || (len(result) > 0 && &result[0] != &buf[0]) {
+		return base.CorruptionErrorf("pebble: decompressed into unexpected buffer: %p != %p",
+			errors.Safe(result), errors.Safe(buf))
+	}
+	return nil
+}
+
+func (zstdDictDecompressor) DecompressedLen(b []byte) (decompressedLen int, err error) {
+	decodedLen, prefixLen := binary.Uvarint(b)
+	if prefixLen <= 0 {
+		return 0, base.CorruptionErrorf("pebble: compression block has invalid length")
+	}
+	return int(decodedLen), nil
+}
+
+func (zstdDictDecompressor) Close() {}
@@ -14,7 +14,9 @@ import (
 	"github.com/klauspost/compress/zstd"
 )
 
-type zstdCompressor struct{}
+type zstdCompressor struct {
+	level int
+}
 
 var _ Compressor = zstdCompressor{}
 
@@ -29,17 +31,17 @@ var _ Compressor = zstdCompressor{}
 // relies on CGo.
 const UseStandardZstdLib = false
 
-// Compress compresses b with the Zstandard algorithm at default compression
-// level (level 3). It reuses the preallocated capacity of compressedBuf if it
+// Compress compresses b with the Zstandard algorithm at the compressor's
+// compression level (level 3 by default). It reuses the preallocated capacity of compressedBuf if it
 // is sufficient. The subslice `compressedBuf[:varIntLen]` should already encode
 // the length of `b` before calling Compress. It returns the encoded byte
 // slice, including the `compressedBuf[:varIntLen]` prefix.
-func (zstdCompressor) Compress(compressedBuf, b []byte) []byte {
+func (z zstdCompressor) Compress(compressedBuf, b []byte) []byte {
 	if len(compressedBuf) < binary.MaxVarintLen64 {
 		compressedBuf = append(compressedBuf, make([]byte, binary.MaxVarintLen64-len(compressedBuf))...)
 	}
 	varIntLen := binary.PutUvarint(compressedBuf, uint64(len(b)))
-	encoder, _ := zstd.NewWriter(nil)
+	encoder, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(z.level)))
 	result := encoder.EncodeAll(b, compressedBuf[:varIntLen])
 	if err := encoder.Close(); err != nil {
 		panic(err)
@@ -50,7 +52,11 @@ func (zstdCompressor) Compress(compressedBuf, b []byte) []byte {
 func (zstdCompressor) Close() {}
 
 func getZstdCompressor() zstdCompressor {
-	return zstdCompressor{}
+	return getZstdCompressorWithLevel(zstdDefaultLevel)
+}
+
+func getZstdCompressorWithLevel(level int) zstdCompressor {
+	return zstdCompressor{level: level}
 }
 
 type zstdDecompressor struct{}
@@ -0,0 +1,181 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+// Package encryption implements the encryption of files at rest.
+//
+// Every encrypted file is encrypted with its own randomly generated data key,
+// using AES in counter mode. The counter of every 16-byte block of the file is
+// derived from the block's offset within the file, so that any range of the
+// file may be encrypted or decrypted independently, and files may be read with
+// random access. Each data key is used for a single file, which is only ever
+// appended to, so no part of the key stream is ever reused.
+//
+// A data key is itself encrypted ("wrapped") with AES-GCM using a key obtained
+// from a KeyProvider, and the wrapped data key is recorded in the metadata of
+// the file along with the ID of the wrapping key. Rotating the wrapping key
+// only requires rewriting the files whose data keys are wrapped with old keys.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"math/bits"
+
+	"github.com/cockroachdb/errors"
+)
+
+// KeyProvider provides the keys that wrap the data keys of encrypted files.
+// Implementations must be safe for concurrent use.
+type KeyProvider interface {
+	// ActiveKeyID returns the ID of the key that wraps the data keys of new
+	// files.
+	ActiveKeyID() string
+	// Key returns the key with the given ID, which must be 16, 24 or 32 bytes
+	// long to select AES-128, AES-192 or AES-256. Key must continue to return
+	// a key for as long as any file's data key is wrapped with it.
+	Key(id string) ([]byte, error)
+}
+
+const (
+	// keyFormatVersion is the version of the encoding of wrapped data keys.
+	keyFormatVersion = 1
+	// dataKeyLen is the length of data keys, which select AES-256.
+	dataKeyLen = 32
+)
+
+// Cipher encrypts and decrypts the contents of a file with the file's data
+// key. A Cipher is safe for concurrent use.
+type Cipher struct {
+	keyID string
+	block cipher.Block
+	iv    [aes.BlockSize]byte
+}
+
+// KeyID returns the ID of the key wrapping the Cipher's data key.
+func (c *Cipher) KeyID() string {
+	return c.keyID
+}
+
+// XORKeyStreamAt XORs each byte in src with the byte of the key stream at the
+// same offset within the file, storing the result in dst. The first byte of
+// src is at offset off within the file. Encryption and decryption are the same
+// operation. Dst and src must overlap entirely or not at all.
+func (c *Cipher) XORKeyStreamAt(dst, src []byte, off int64) {
+	if len(src) == 0 {
+		return
+	}
+	// The counter of the block containing off is the IV plus the block's
+	// index, as a 128-bit big-endian integer.
+	var ctr [aes.BlockSize]byte
+	hi := binary.BigEndian.Uint64(c.iv[:8])
+	lo, carry := bits.Add64(binary.BigEndian.Uint64(c.iv[8:]), uint64(off)/aes.BlockSize, 0)
+	binary.BigEndian.PutUint64(ctr[:8], hi+carry)
+	binary.BigEndian.PutUint64(ctr[8:], lo)
+	stream := cipher.NewCTR(c.block, ctr[:])
+	if skip := off % aes.BlockSize; skip != 0 {
+		var pad [aes.BlockSize]byte
+		stream.XORKeyStream(pad[:skip], pad[:skip])
+	}
+	stream.XORKeyStream(dst, src)
+}
+
+// NewCipher generates a new data key for a file, wrapped with the provider's
+// active key. It returns a Cipher using the data key, along with the encoded
+// wrapped data key to record in the file's metadata.
+func NewCipher(p KeyProvider) (*Cipher, []byte, error) {
+	keyID := p.ActiveKeyID()
+	aead, err := newWrappingAEAD(p, keyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The data key is followed by the IV of the file's key stream.
+	var plaintext [dataKeyLen + aes.BlockSize]byte
+	if _, err := rand.Read(plaintext[:]); err != nil {
+		return nil, nil, err
+	}
+	c, err := newCipher(keyID, plaintext[:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encoded := make([]byte, 0, 1+binary.MaxVarintLen32+len(keyID)+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	encoded = append(encoded, keyFormatVersion)
+	encoded = binary.AppendUvarint(encoded, uint64(len(keyID)))
+	encoded = append(encoded, keyID...)
+	header := len(encoded)
+	encoded = encoded[:header+aead.NonceSize()]
+	if _, err := rand.Read(encoded[header:]); err != nil {
+		return nil, nil, err
+	}
+	// The version and key ID are authenticated along with the data key.
+	encoded = aead.Seal(encoded, encoded[header:], plaintext[:], encoded[:header])
+	return c, encoded, nil
+}
+
+// OpenCipher unwraps an encoded data key returned by NewCipher, returning a
+// Cipher using the data key.
+func OpenCipher(p KeyProvider, encoded []byte) (*Cipher, error) {
+	keyID, header, err := decodeKeyID(encoded)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newWrappingAEAD(p, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(encoded)-header < aead.NonceSize() {
+		return nil, errors.New("pebble: invalid encryption key")
+	}
+	nonce := encoded[header : header+aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, encoded[header+aead.NonceSize():], encoded[:header])
+	if err != nil || len(plaintext) != dataKeyLen+aes.BlockSize {
+		return nil, errors.Newf("pebble: unable to unwrap data key with key %q", errors.Safe(keyID))
+	}
+	return newCipher(keyID, plaintext)
+}
+
+// KeyID returns the ID of the key wrapping an encoded data key returned by
+// NewCipher.
+func KeyID(encoded []byte) (string, error) {
+	keyID, _, err := decodeKeyID(encoded)
+	return keyID, err
+}
+
+// decodeKeyID decodes the key ID of an encoded data key, returning the length
+// of the encoding's header.
+func decodeKeyID(encoded []byte) (keyID string, header int, _ error) {
+	if len(encoded) == 0 || encoded[0] != keyFormatVersion {
+		return "", 0, errors.New("pebble: invalid encryption key")
+	}
+	n, m := binary.Uvarint(encoded[1:])
+	if m <= 0 || uint64(len(encoded)-1-m) < n {
+		return "", 0, errors.New("pebble: invalid encryption key")
+	}
+	header = 1 + m + int(n)
+	return string(encoded[1+m : header]), header, nil
+}
+
+func newWrappingAEAD(p KeyProvider, keyID string) (cipher.AEAD, error) {
+	key, err := p.Key(keyID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "pebble: unable to get encryption key %q", errors.Safe(keyID))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "pebble: invalid encryption key %q", errors.Safe(keyID))
+	}
+	return cipher.NewGCM(block)
+}
+
+func newCipher(keyID string, plaintext []byte) (*Cipher, error) {
+	block, err := aes.NewCipher(plaintext[:dataKeyLen])
+	if err != nil {
+		return nil, err
+	}
+	c := &Cipher{keyID: keyID, block: block}
+	copy(c.iv[:], plaintext[dataKeyLen:])
+	return c, nil
+}
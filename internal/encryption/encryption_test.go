@@ -0,0 +1,118 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package encryption
+
+import (
+	"bytes"
+	"io"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/chris124567/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+type testKeyProvider map[string][]byte
+
+func (p testKeyProvider) ActiveKeyID() string { return "active" }
+
+func (p testKeyProvider) Key(id string) ([]byte, error) {
+	if key, ok := p[id]; ok {
+		return key, nil
+	}
+	return nil, errors.Newf("unknown key %q", id)
+}
+
+func TestCipher(t *testing.T) {
+	p := testKeyProvider{"active": bytes.Repeat([]byte{1}, 16)}
+	c, encoded, err := NewCipher(p)
+	require.NoError(t, err)
+	keyID, err := KeyID(encoded)
+	require.NoError(t, err)
+	require.Equal(t, "active", keyID)
+
+	plaintext := make([]byte, 1000)
+	for i := range plaintext {
+		plaintext[i] = byte(i)
+	}
+	ciphertext := make([]byte, len(plaintext))
+	c.XORKeyStreamAt(ciphertext, plaintext, 0)
+	require.NotEqual(t, plaintext, ciphertext)
+
+	// Any range of the ciphertext can be decrypted independently, by the
+	// cipher unwrapped from the encoded key.
+	c2, err := OpenCipher(p, encoded)
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		start := rand.IntN(len(plaintext))
+		end := start + rand.IntN(len(plaintext)-start+1)
+		buf := bytes.Clone(ciphertext[start:end])
+		c2.XORKeyStreamAt(buf, buf, int64(start))
+		require.Equal(t, string(plaintext[start:end]), string(buf))
+	}
+
+	// The data key cannot be unwrapped with another key.
+	_, err = OpenCipher(testKeyProvider{"active": bytes.Repeat([]byte{2}, 16)}, encoded)
+	require.Error(t, err)
+	_, err = OpenCipher(p, encoded[:len(encoded)-1])
+	require.Error(t, err)
+}
+
+func TestFile(t *testing.T) {
+	p := testKeyProvider{"active": bytes.Repeat([]byte{1}, 32)}
+	fs := vfs.NewMem()
+	readAll := func(name string, p KeyProvider) ([]byte, error) {
+		f, err := fs.Open(name)
+		require.NoError(t, err)
+		defer f.Close()
+		ef, err := OpenFile(f, p)
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(ef)
+	}
+
+	f, err := fs.Create("encrypted", vfs.WriteCategoryUnspecified)
+	require.NoError(t, err)
+	ef, err := CreateFile(f, p)
+	require.NoError(t, err)
+	for _, s := range []string{"hello ", "encrypted ", "world"} {
+		_, err := ef.Write([]byte(s))
+		require.NoError(t, err)
+	}
+	stat, err := ef.Stat()
+	require.NoError(t, err)
+	require.Equal(t, int64(len("hello encrypted world")), stat.Size())
+	require.NoError(t, ef.Close())
+
+	data, err := readAll("encrypted", p)
+	require.NoError(t, err)
+	require.Equal(t, "hello encrypted world", string(data))
+	_, err = readAll("encrypted", testKeyProvider{"active": bytes.Repeat([]byte{2}, 32)})
+	require.Error(t, err)
+	_, err = readAll("encrypted", nil)
+	require.ErrorIs(t, err, ErrNoKeyProvider)
+
+	// Files without a header are read as is.
+	f, err = fs.Create("plain", vfs.WriteCategoryUnspecified)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("hello plaintext world"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	data, err = readAll("plain", p)
+	require.NoError(t, err)
+	require.Equal(t, "hello plaintext world", string(data))
+
+	// A file with a truncated header is empty.
+	f, err = fs.Create("truncated", vfs.WriteCategoryUnspecified)
+	require.NoError(t, err)
+	_, err = f.Write(append(headerMagic[:], 100, 0))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	data, err = readAll("truncated", p)
+	require.NoError(t, err)
+	require.Empty(t, data)
+}
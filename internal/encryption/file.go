@@ -0,0 +1,177 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package encryption
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/cockroachdb/errors"
+	"github.com/chris124567/pebble/vfs"
+)
+
+// Files that have no metadata of their own to record their data keys in, such
+// as WAL and MANIFEST files, begin with a plaintext header recording the data
+// key. The header is the headerMagic, the 4-byte little-endian length of the
+// encoded data key and the encoded data key. The encrypted contents of the file
+// follow the header; offsets of the contents are relative to the end of the
+// header.
+var headerMagic = [8]byte{0xf0, 'p', 'e', 'b', 'b', 'l', 'e', 0x0e}
+
+const headerPrefixLen = 8 + 4
+
+// CreateFile writes a header recording a new data key, wrapped with the
+// provider's active key, to f, which must be a new, empty file. It returns a
+// File that encrypts the data written to f.
+func CreateFile(f vfs.File, p KeyProvider) (vfs.File, error) {
+	c, encoded, err := NewCipher(p)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, headerPrefixLen, headerPrefixLen+len(encoded))
+	copy(header, headerMagic[:])
+	binary.LittleEndian.PutUint32(header[len(headerMagic):], uint32(len(encoded)))
+	header = append(header, encoded...)
+	if _, err := f.Write(header); err != nil {
+		return nil, err
+	}
+	return &file{File: f, c: c, headerLen: int64(len(header))}, nil
+}
+
+// ErrNoKeyProvider is returned by OpenFile when opening an encrypted file
+// without a key provider.
+var ErrNoKeyProvider = errors.New("pebble: file is encrypted, but no key provider was given")
+
+// OpenFile reads the header of f, an existing file, returning a File that
+// decrypts the data read from f. If f does not begin with a header, OpenFile
+// returns f itself. If f begins with a header but the provider is nil because
+// encryption is disabled, OpenFile returns ErrNoKeyProvider.
+//
+// A file whose header is truncated is treated as empty: nothing can have been
+// durably written to a file before its header.
+func OpenFile(f vfs.File, p KeyProvider) (vfs.File, error) {
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := stat.Size()
+	var prefix [headerPrefixLen]byte
+	if size < int64(len(headerMagic)) {
+		return f, nil
+	}
+	n, err := f.ReadAt(prefix[:min(int64(len(prefix)), size)], 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !bytes.Equal(prefix[:len(headerMagic)], headerMagic[:]) {
+		return f, nil
+	}
+	if n < headerPrefixLen {
+		return &file{File: f, headerLen: size}, nil
+	}
+	headerLen := int64(headerPrefixLen) + int64(binary.LittleEndian.Uint32(prefix[len(headerMagic):]))
+	if size < headerLen {
+		return &file{File: f, headerLen: size}, nil
+	}
+	if p == nil {
+		return nil, ErrNoKeyProvider
+	}
+	encoded := make([]byte, headerLen-headerPrefixLen)
+	if _, err := f.ReadAt(encoded, headerPrefixLen); err != nil {
+		return nil, err
+	}
+	c, err := OpenCipher(p, encoded)
+	if err != nil {
+		return nil, err
+	}
+	return &file{File: f, c: c, headerLen: headerLen}, nil
+}
+
+// file is a vfs.File that encrypts the contents of the file following its
+// header. The cipher is nil if the header is truncated, in which case the file
+// has no contents.
+type file struct {
+	vfs.File
+	c         *Cipher
+	headerLen int64
+	readOff   int64
+	writeOff  int64
+}
+
+var _ vfs.File = (*file)(nil)
+
+func (f *file) xorKeyStreamAt(p []byte, off int64) {
+	if f.c != nil {
+		f.c.XORKeyStreamAt(p, p, off)
+	}
+}
+
+// Read implements io.Reader.
+func (f *file) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.readOff)
+	f.readOff += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadAt implements io.ReaderAt.
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	n, err := f.File.ReadAt(p, off+f.headerLen)
+	f.xorKeyStreamAt(p[:n], off)
+	return n, err
+}
+
+// Write implements io.Writer. Write is allowed to modify p, so it is encrypted
+// in place.
+func (f *file) Write(p []byte) (int, error) {
+	f.xorKeyStreamAt(p, f.writeOff)
+	n, err := f.File.Write(p)
+	f.writeOff += int64(n)
+	return n, err
+}
+
+// WriteAt implements io.WriterAt.
+func (f *file) WriteAt(p []byte, off int64) (int, error) {
+	buf := append([]byte(nil), p...)
+	f.xorKeyStreamAt(buf, off)
+	return f.File.WriteAt(buf, off+f.headerLen)
+}
+
+// Preallocate implements vfs.File.
+func (f *file) Preallocate(offset, length int64) error {
+	return f.File.Preallocate(offset+f.headerLen, length)
+}
+
+// Stat implements vfs.File. The size of the file excludes its header.
+func (f *file) Stat() (vfs.FileInfo, error) {
+	stat, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return fileInfo{FileInfo: stat, headerLen: f.headerLen}, nil
+}
+
+// SyncTo implements vfs.File.
+func (f *file) SyncTo(length int64) (fullSync bool, err error) {
+	return f.File.SyncTo(length + f.headerLen)
+}
+
+// Prefetch implements vfs.File.
+func (f *file) Prefetch(offset, length int64) error {
+	return f.File.Prefetch(offset+f.headerLen, length)
+}
+
+type fileInfo struct {
+	vfs.FileInfo
+	headerLen int64
+}
+
+// Size implements os.FileInfo.
+func (fi fileInfo) Size() int64 {
+	return max(fi.FileInfo.Size()-fi.headerLen, 0)
+}
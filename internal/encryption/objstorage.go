@@ -0,0 +1,68 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package encryption
+
+import (
+	"context"
+
+	"github.com/chris124567/pebble/objstorage"
+)
+
+// NewWritable returns a Writable that encrypts the data written to w with c.
+func NewWritable(w objstorage.Writable, c *Cipher) objstorage.Writable {
+	return &writable{Writable: w, c: c}
+}
+
+type writable struct {
+	objstorage.Writable
+	c   *Cipher
+	off int64
+}
+
+// Write implements objstorage.Writable. Write is allowed to modify p, so it is
+// encrypted in place.
+func (w *writable) Write(p []byte) error {
+	w.c.XORKeyStreamAt(p, p, w.off)
+	w.off += int64(len(p))
+	return w.Writable.Write(p)
+}
+
+// NewReadable returns a Readable that decrypts the data read from r with c.
+func NewReadable(r objstorage.Readable, c *Cipher) objstorage.Readable {
+	return &readable{Readable: r, c: c}
+}
+
+type readable struct {
+	objstorage.Readable
+	c *Cipher
+}
+
+// ReadAt implements objstorage.Readable.
+func (r *readable) ReadAt(ctx context.Context, p []byte, off int64) error {
+	if err := r.Readable.ReadAt(ctx, p, off); err != nil {
+		return err
+	}
+	r.c.XORKeyStreamAt(p, p, off)
+	return nil
+}
+
+// NewReadHandle implements objstorage.Readable.
+func (r *readable) NewReadHandle(readBeforeSize objstorage.ReadBeforeSize) objstorage.ReadHandle {
+	return &readHandle{ReadHandle: r.Readable.NewReadHandle(readBeforeSize), c: r.c}
+}
+
+type readHandle struct {
+	objstorage.ReadHandle
+	c *Cipher
+}
+
+// ReadAt implements objstorage.ReadHandle.
+func (h *readHandle) ReadAt(ctx context.Context, p []byte, off int64) error {
+	if err := h.ReadHandle.ReadAt(ctx, p, off); err != nil {
+		return err
+	}
+	h.c.XORKeyStreamAt(p, p, off)
+	return nil
+}
@@ -77,6 +77,9 @@ type BlobFileMetadata struct {
 	// File creation time in seconds since the epoch (1970-01-01 00:00:00
 	// UTC).
 	CreationTime uint64
+	// EncryptionKey is the encoded, wrapped data key that the file is
+	// encrypted with, or nil if the file is not encrypted.
+	EncryptionKey []byte
 
 	// Mutable state
 
@@ -1403,6 +1403,31 @@ func (s *l0Sublevels) PickBaseCompaction(
 	baseFiles LevelSlice,
 	baseLevel int,
 	problemSpans *problemspans.ByLevel,
+) *L0CompactionFiles {
+	return s.pickBaseCompaction(logger, minCompactionDepth, baseFiles, baseLevel, problemSpans, nil)
+}
+
+// PickBaseCompactionWithin is like PickBaseCompaction, but only considers seed
+// intervals that overlap the bounds. The picked compaction may extend beyond
+// the bounds.
+func (s *l0Sublevels) PickBaseCompactionWithin(
+	logger base.Logger,
+	minCompactionDepth int,
+	baseFiles LevelSlice,
+	baseLevel int,
+	problemSpans *problemspans.ByLevel,
+	bounds base.UserKeyBounds,
+) *L0CompactionFiles {
+	return s.pickBaseCompaction(logger, minCompactionDepth, baseFiles, baseLevel, problemSpans, &bounds)
+}
+
+func (s *l0Sublevels) pickBaseCompaction(
+	logger base.Logger,
+	minCompactionDepth int,
+	baseFiles LevelSlice,
+	baseLevel int,
+	problemSpans *problemspans.ByLevel,
+	within *base.UserKeyBounds,
 ) *L0CompactionFiles {
 	// For LBase compactions, we consider intervals in a greedy manner in the
 	// following order:
@@ -1426,10 +1451,13 @@ func (s *l0Sublevels) PickBaseCompaction(
 		if interval.isBaseCompacting || depth < minCompactionDepth {
 			continue
 		}
-		if problemSpans != nil {
+		if problemSpans != nil || within != nil {
 			endKey := s.orderedIntervals[i+1].startKey
 			bounds := base.UserKeyBoundsEndExclusiveIf(interval.startKey.key, endKey.key, !endKey.isInclusiveEndBound)
-			if problemSpans.Overlaps(baseLevel, bounds) {
+			if problemSpans != nil && problemSpans.Overlaps(baseLevel, bounds) {
+				continue
+			}
+			if within != nil && !within.Overlaps(s.cmp, &bounds) {
 				continue
 			}
 		}
@@ -157,6 +157,27 @@ type TableStats struct {
 	// This statistic is used to determine eligibility for a tombstone density
 	// compaction.
 	TombstoneDenseBlocksRatio float64
+	// ExpiringValueSize is the estimated disk space used by values with an
+	// expiry time, and MinValueExpiry and MaxValueExpiry are the earliest and
+	// latest of their expiry times in Unix nanoseconds.
+	ExpiringValueSize uint64
+	MinValueExpiry    uint64
+	MaxValueExpiry    uint64
+}
+
+// ExpiredBytesEstimate estimates the disk space used by values that have
+// expired as of now, in Unix nanoseconds, assuming expiry times are uniformly
+// distributed between MinValueExpiry and MaxValueExpiry.
+func (s *TableStats) ExpiredBytesEstimate(now uint64) uint64 {
+	switch {
+	case s.ExpiringValueSize == 0 || now < s.MinValueExpiry:
+		return 0
+	case now >= s.MaxValueExpiry:
+		return s.ExpiringValueSize
+	default:
+		frac := float64(now-s.MinValueExpiry) / float64(s.MaxValueExpiry-s.MinValueExpiry)
+		return uint64(frac * float64(s.ExpiringValueSize))
+	}
 }
 
 // boundType represents the type of key (point or range) present as the smallest
@@ -547,6 +568,9 @@ func (m *TableMetadata) EstimatedReferenceSize() uint64 {
 type FileBacking struct {
 	DiskFileNum base.DiskFileNum
 	Size        uint64
+	// EncryptionKey is the encoded, wrapped data key that the backing file is
+	// encrypted with, or nil if the file is not encrypted.
+	EncryptionKey []byte
 
 	// Reference count for the backing file, used to determine when a backing file
 	// is obsolete and can be removed.
@@ -609,6 +633,16 @@ func (m *TableMetadata) InitPhysicalBacking() {
 	}
 }
 
+// physicalEncryptionKey returns the encryption key of a physical table's
+// backing file. The encryption keys of the backing files of virtual tables are
+// recorded with the backings themselves.
+func (m *TableMetadata) physicalEncryptionKey() []byte {
+	if m.Virtual || m.FileBacking == nil {
+		return nil
+	}
+	return m.FileBacking.EncryptionKey
+}
+
 // InitVirtualBacking creates a new FileBacking for a virtual table.
 //
 // The Smallest/Largest bounds must already be set to their final values.
@@ -58,6 +58,11 @@ const (
 	tagRemovedBackingTable = 106
 	tagNewBlobFile         = 107
 	tagDeletedBlobFile     = 108
+	// tagCreatedEncryptedBackingTable and tagNewEncryptedBlobFile are
+	// tagCreatedBackingTable and tagNewBlobFile followed by the file's
+	// encryption key.
+	tagCreatedEncryptedBackingTable = 109
+	tagNewEncryptedBlobFile         = 110
 
 	// The custom tags sub-format used by tagNewFile4 and above. All tags less
 	// than customTagNonSafeIgnoreMask are safe to ignore and their format must be
@@ -71,6 +76,7 @@ const (
 	customTagSyntheticPrefix   = 67
 	customTagSyntheticSuffix   = 68
 	customTagBlobReferences    = 69
+	customTagEncryptionKey     = 70
 )
 
 // DeletedTableEntry holds the state for a sstable deletion from a level. The
@@ -231,7 +237,7 @@ func (v *VersionEdit) Decode(r io.Reader) error {
 			v.RemovedBackingTables = append(
 				v.RemovedBackingTables, base.DiskFileNum(n),
 			)
-		case tagCreatedBackingTable:
+		case tagCreatedBackingTable, tagCreatedEncryptedBackingTable:
 			dfn, err := d.readUvarint()
 			if err != nil {
 				return err
@@ -244,6 +250,11 @@ func (v *VersionEdit) Decode(r io.Reader) error {
 				DiskFileNum: base.DiskFileNum(dfn),
 				Size:        size,
 			}
+			if tag == tagCreatedEncryptedBackingTable {
+				if fileBacking.EncryptionKey, err = d.readBytes(); err != nil {
+					return err
+				}
+			}
 			v.CreatedBackingTables = append(v.CreatedBackingTables, fileBacking)
 		case tagDeletedFile:
 			level, err := d.readLevel()
@@ -360,6 +371,7 @@ func (v *VersionEdit) Decode(r io.Reader) error {
 			var syntheticSuffix sstable.SyntheticSuffix
 			var blobReferences BlobReferences
 			var blobReferenceDepth BlobReferenceDepth
+			var encryptionKey []byte
 			if tag == tagNewFile4 || tag == tagNewFile5 {
 				for {
 					customTag, err := d.readUvarint()
@@ -441,6 +453,11 @@ func (v *VersionEdit) Decode(r io.Reader) error {
 						}
 						continue
 
+					case customTagEncryptionKey:
+						if encryptionKey, err = d.readBytes(); err != nil {
+							return err
+						}
+
 					default:
 						if (customTag & customTagNonSafeIgnoreMask) != 0 {
 							return base.CorruptionErrorf("new-file4: custom field not supported: %d", customTag)
@@ -494,6 +511,7 @@ func (v *VersionEdit) Decode(r io.Reader) error {
 			m.boundsSet = true
 			if !virtualState.virtual {
 				m.InitPhysicalBacking()
+				m.FileBacking.EncryptionKey = encryptionKey
 			}
 
 			nfe := NewTableEntry{
@@ -505,7 +523,7 @@ func (v *VersionEdit) Decode(r io.Reader) error {
 			}
 			v.NewTables = append(v.NewTables, nfe)
 
-		case tagNewBlobFile:
+		case tagNewBlobFile, tagNewEncryptedBlobFile:
 			fileNum, err := d.readFileNum()
 			if err != nil {
 				return err
@@ -522,11 +540,18 @@ func (v *VersionEdit) Decode(r io.Reader) error {
 			if err != nil {
 				return err
 			}
+			var encryptionKey []byte
+			if tag == tagNewEncryptedBlobFile {
+				if encryptionKey, err = d.readBytes(); err != nil {
+					return err
+				}
+			}
 			v.NewBlobFiles = append(v.NewBlobFiles, &BlobFileMetadata{
-				FileNum:      base.DiskFileNum(fileNum),
-				Size:         size,
-				ValueSize:    valueSize,
-				CreationTime: creationTime,
+				FileNum:       base.DiskFileNum(fileNum),
+				Size:          size,
+				ValueSize:     valueSize,
+				CreationTime:  creationTime,
+				EncryptionKey: encryptionKey,
 			})
 
 		case tagDeletedBlobFile:
@@ -723,9 +748,16 @@ func (v *VersionEdit) Encode(w io.Writer) error {
 		e.writeUvarint(uint64(dfn))
 	}
 	for _, fileBacking := range v.CreatedBackingTables {
-		e.writeUvarint(tagCreatedBackingTable)
+		if fileBacking.EncryptionKey != nil {
+			e.writeUvarint(tagCreatedEncryptedBackingTable)
+		} else {
+			e.writeUvarint(tagCreatedBackingTable)
+		}
 		e.writeUvarint(uint64(fileBacking.DiskFileNum))
 		e.writeUvarint(fileBacking.Size)
+		if fileBacking.EncryptionKey != nil {
+			e.writeBytes(fileBacking.EncryptionKey)
+		}
 	}
 	// RocksDB requires LastSeqNum to be encoded for the first MANIFEST entry,
 	// even though its value is zero. We detect this by encoding LastSeqNum when
@@ -740,7 +772,9 @@ func (v *VersionEdit) Encode(w io.Writer) error {
 		e.writeUvarint(uint64(x.FileNum))
 	}
 	for _, x := range v.NewTables {
-		customFields := x.Meta.MarkedForCompaction || x.Meta.CreationTime != 0 || x.Meta.Virtual || len(x.Meta.BlobReferences) > 0
+		encryptionKey := x.Meta.physicalEncryptionKey()
+		customFields := x.Meta.MarkedForCompaction || x.Meta.CreationTime != 0 || x.Meta.Virtual ||
+			len(x.Meta.BlobReferences) > 0 || encryptionKey != nil
 		var tag uint64
 		switch {
 		case x.Meta.HasRangeKeys:
@@ -816,15 +850,26 @@ func (v *VersionEdit) Encode(w io.Writer) error {
 					e.writeUvarint(ref.ValueSize)
 				}
 			}
+			if encryptionKey != nil {
+				e.writeUvarint(customTagEncryptionKey)
+				e.writeBytes(encryptionKey)
+			}
 			e.writeUvarint(customTagTerminate)
 		}
 	}
 	for _, x := range v.NewBlobFiles {
-		e.writeUvarint(tagNewBlobFile)
+		if x.EncryptionKey != nil {
+			e.writeUvarint(tagNewEncryptedBlobFile)
+		} else {
+			e.writeUvarint(tagNewBlobFile)
+		}
 		e.writeUvarint(uint64(x.FileNum))
 		e.writeUvarint(x.Size)
 		e.writeUvarint(x.ValueSize)
 		e.writeUvarint(x.CreationTime)
+		if x.EncryptionKey != nil {
+			e.writeBytes(x.EncryptionKey)
+		}
 	}
 	for x := range v.DeletedBlobFiles {
 		e.writeUvarint(tagDeletedBlobFile)
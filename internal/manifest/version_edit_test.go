@@ -211,6 +211,19 @@ func TestVersionEditRoundTrip(t *testing.T) {
 		base.MakeExclusiveSentinelKey(base.InternalKeyKindRangeKeySet, []byte("z")),
 	)
 	m6.InitPhysicalBacking()
+	m6.FileBacking.EncryptionKey = []byte("backing-key")
+
+	m7 := (&TableMetadata{
+		TableNum:     812,
+		Size:         8120,
+		CreationTime: 812070,
+	}).ExtendPointKeyBounds(
+		cmp,
+		base.MakeInternalKey([]byte("b"), 0, base.InternalKeyKindSet),
+		base.MakeInternalKey([]byte("c"), 0, base.InternalKeyKindSet),
+	)
+	m7.InitPhysicalBacking()
+	m7.FileBacking.EncryptionKey = []byte("table-key")
 
 	testCases := []VersionEdit{
 		// An empty version edit.
@@ -253,6 +266,25 @@ func TestVersionEditRoundTrip(t *testing.T) {
 				},
 			},
 		},
+		// A version edit with encrypted files.
+		{
+			CreatedBackingTables: []*FileBacking{m6.FileBacking},
+			NewTables: []NewTableEntry{
+				{
+					Level: 6,
+					Meta:  m7,
+				},
+			},
+			NewBlobFiles: []*BlobFileMetadata{
+				{
+					FileNum:       813,
+					Size:          8130,
+					ValueSize:     8000,
+					CreationTime:  813080,
+					EncryptionKey: []byte("blob-key"),
+				},
+			},
+		},
 	}
 	for _, tc := range testCases {
 		if err := checkRoundTrip(tc); err != nil {
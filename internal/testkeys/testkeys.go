@@ -54,6 +54,7 @@ var Comparer = &base.Comparer{
 	CompareRangeSuffixes: compareSuffixes,
 	Compare:              compare,
 	Equal:                func(a, b []byte) bool { return compare(a, b) == 0 },
+	BytewisePrefixes:     true,
 	AbbreviatedKey: func(k []byte) uint64 {
 		return base.DefaultComparer.AbbreviatedKey(k[:split(k)])
 	},
@@ -201,6 +201,12 @@ type Iterator struct {
 	comparer  base.Comparer
 	iter      internalIterator
 	pointIter topLevelIterator
+	// ttl, if non-nil, wraps the point iterator to expire values with a
+	// time-to-live. See Options.TTL.
+	ttl *ttlIter
+	// txn, if non-nil, is the transaction that created the iterator. Bounds
+	// set through SetBounds, SetOptions or Clone are added to its read set.
+	txn *Txn
 	// Either readState or version is set, but not both.
 	readState *readState
 	version   *version
@@ -2481,6 +2487,10 @@ func (i *Iterator) SetBounds(lower, upper []byte) {
 	// positioning method to reposition the iterator.
 	i.requiresReposition = true
 
+	if i.txn != nil {
+		i.txn.recordSpan(lower, upper)
+	}
+
 	if ((i.opts.LowerBound == nil) == (lower == nil)) &&
 		((i.opts.UpperBound == nil) == (upper == nil)) &&
 		i.equal(i.opts.LowerBound, lower) &&
@@ -2593,6 +2603,10 @@ func (i *Iterator) SetOptions(o *IterOptions) {
 	// positioning method to reposition the iterator.
 	i.requiresReposition = true
 
+	if i.txn != nil {
+		i.txn.recordSpan(o.LowerBound, o.UpperBound)
+	}
+
 	// Check if global state requires we close all internal iterators.
 	//
 	// If the Iterator is in an error state, invalidate the existing iterators
@@ -2885,6 +2899,15 @@ func (i *Iterator) CloneWithContext(ctx context.Context, opts CloneOptions) (*It
 		newIters:            i.newIters,
 		newIterRangeKey:     i.newIterRangeKey,
 		seqNum:              i.seqNum,
+		txn:                 i.txn,
+	}
+	if dbi.txn != nil {
+		dbi.txn.recordSpan(dbi.opts.LowerBound, dbi.opts.UpperBound)
+	}
+	if i.ttl != nil {
+		// Expire values as of the same time as the cloned iterator.
+		dbi.merge = i.ttl.merge
+		dbi.initTTL(&buf.ttl, i.ttl.now)
 	}
 	dbi.processBounds(dbi.opts.LowerBound, dbi.opts.UpperBound)
 
@@ -0,0 +1,147 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"encoding/binary"
+
+	"github.com/cockroachdb/errors"
+)
+
+// iterCursorVersion is the version of the encoding of iterator cursors.
+const iterCursorVersion = 1
+
+// Flags of an encoded iterator cursor.
+const (
+	iterCursorReverse  = 1 << 0
+	iterCursorHasLower = 1 << 1
+	iterCursorHasUpper = 1 << 2
+)
+
+var errInvalidIterCursor = errors.New("pebble: invalid iterator cursor")
+
+// Cursor returns an opaque, serializable cursor recording the iterator's
+// current position, direction of iteration, bounds and key types. The cursor
+// may be passed to ResumeIter, possibly in another process, to resume
+// iteration from the current position.
+//
+// The iterator must be positioned at a valid key. Cursors cannot be created
+// for iterators using prefix iteration.
+func (i *Iterator) Cursor() ([]byte, error) {
+	if i.iterValidityState != IterValid {
+		return nil, errors.New("pebble: cannot create a cursor for an unpositioned iterator")
+	}
+	if i.hasPrefix {
+		return nil, errors.New("pebble: cannot create a cursor for a prefix iterator")
+	}
+	var flags byte
+	switch i.pos {
+	case iterPosCurReverse, iterPosPrev, iterPosCurReversePaused:
+		flags |= iterCursorReverse
+	}
+	if i.opts.LowerBound != nil {
+		flags |= iterCursorHasLower
+	}
+	if i.opts.UpperBound != nil {
+		flags |= iterCursorHasUpper
+	}
+	buf := make([]byte, 0, 3+3*binary.MaxVarintLen32+len(i.key)+len(i.opts.LowerBound)+len(i.opts.UpperBound))
+	buf = append(buf, iterCursorVersion, flags, byte(i.opts.KeyTypes))
+	for _, b := range [3][]byte{i.key, i.opts.LowerBound, i.opts.UpperBound} {
+		buf = binary.AppendUvarint(buf, uint64(len(b)))
+		buf = append(buf, b...)
+	}
+	return buf, nil
+}
+
+// iterCursor is a decoded iterator cursor.
+type iterCursor struct {
+	key          []byte
+	reverse      bool
+	lower, upper []byte
+	keyTypes     IterKeyType
+}
+
+func decodeIterCursor(buf []byte) (iterCursor, error) {
+	if len(buf) < 3 || buf[0] != iterCursorVersion {
+		return iterCursor{}, errInvalidIterCursor
+	}
+	flags := buf[1]
+	c := iterCursor{
+		reverse:  flags&iterCursorReverse != 0,
+		keyTypes: IterKeyType(buf[2]),
+	}
+	if c.keyTypes < 0 || c.keyTypes > IterKeyTypePointsAndRanges {
+		return iterCursor{}, errInvalidIterCursor
+	}
+	buf = buf[3:]
+	for _, dst := range [3]*[]byte{&c.key, &c.lower, &c.upper} {
+		n, m := binary.Uvarint(buf)
+		if m <= 0 || uint64(len(buf)-m) < n {
+			return iterCursor{}, errInvalidIterCursor
+		}
+		*dst = buf[m : m+int(n) : m+int(n)]
+		buf = buf[m+int(n):]
+	}
+	if len(buf) != 0 {
+		return iterCursor{}, errInvalidIterCursor
+	}
+	if flags&iterCursorHasLower == 0 {
+		c.lower = nil
+	}
+	if flags&iterCursorHasUpper == 0 {
+		c.upper = nil
+	}
+	return c, nil
+}
+
+// ResumeIter returns a new iterator reading from r that resumes iteration from
+// the position recorded by cursor, which must have been returned by
+// Iterator.Cursor. The iterator adopts the cursor's bounds and key types, and
+// any other options from o, which may be nil.
+//
+// The returned iterator is positioned at the first position following the
+// cursor's position in the cursor's direction of iteration: the position that
+// Next (or Prev, for a cursor created while iterating in reverse) would have
+// moved to. Valid reports whether there is such a position. If the key at the
+// cursor's position still exists, RangeKeyChanged reports whether the range
+// keys differ from those at the cursor's position, as if iteration had
+// continued uninterrupted; callers resuming partway through a range key should
+// therefore consult RangeKeys regardless of RangeKeyChanged.
+//
+// To resume at exactly the same state of the DB, r should be the same
+// EventuallyFileOnlySnapshot or Snapshot that the original iterator read from.
+// Otherwise, the resumed iterator observes the current state of r, and keys
+// written after the cursor's position are observed.
+func ResumeIter(r Reader, cursor []byte, o *IterOptions) (*Iterator, error) {
+	c, err := decodeIterCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	var opts IterOptions
+	if o != nil {
+		opts = *o
+	}
+	opts.LowerBound, opts.UpperBound, opts.KeyTypes = c.lower, c.upper, c.keyTypes
+	iter, err := r.NewIter(&opts)
+	if err != nil {
+		return nil, err
+	}
+	// Step from the cursor's position if it still exists, so that
+	// RangeKeyChanged is relative to it.
+	if iter.SeekGE(c.key) && iter.equal(iter.Key(), c.key) {
+		if c.reverse {
+			iter.Prev()
+		} else {
+			iter.Next()
+		}
+	} else if c.reverse {
+		iter.SeekLT(c.key)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, errors.CombineErrors(err, iter.Close())
+	}
+	return iter, nil
+}
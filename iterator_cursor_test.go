@@ -0,0 +1,149 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/chris124567/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+// formatIterPos formats the iterator's current position, including any range
+// key covering it and whether the range key changed.
+func formatIterPos(iter *Iterator) string {
+	var buf strings.Builder
+	hasPoint, hasRange := iter.HasPointAndRange()
+	fmt.Fprintf(&buf, "%s:", iter.Key())
+	if hasPoint {
+		fmt.Fprintf(&buf, "%s", iter.Value())
+	}
+	if hasRange {
+		start, end := iter.RangeBounds()
+		fmt.Fprintf(&buf, " [%s-%s)", start, end)
+		for _, k := range iter.RangeKeys() {
+			fmt.Fprintf(&buf, " %s=%s", k.Suffix, k.Value)
+		}
+	}
+	if iter.RangeKeyChanged() {
+		buf.WriteString(" (changed)")
+	}
+	return buf.String()
+}
+
+func TestIteratorCursor(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	for _, k := range []string{"a", "b", "d", "f", "g", "h", "j", "m", "n"} {
+		require.NoError(t, d.Set([]byte(k), []byte(strings.ToUpper(k)), nil))
+	}
+	require.NoError(t, d.RangeKeySet([]byte("c"), []byte("i"), []byte("@1"), []byte("x"), nil))
+	require.NoError(t, d.RangeKeySet([]byte("e"), []byte("k"), []byte("@2"), []byte("y"), nil))
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Set([]byte("e"), []byte("E"), nil))
+
+	efos := d.NewEventuallyFileOnlySnapshot([]KeyRange{{Start: []byte("a"), End: []byte("z")}})
+	defer func() { require.NoError(t, efos.Close()) }()
+
+	for _, tc := range []struct {
+		name    string
+		opts    IterOptions
+		reverse bool
+	}{
+		{name: "points", opts: IterOptions{}},
+		{name: "ranges", opts: IterOptions{KeyTypes: IterKeyTypePointsAndRanges}},
+		{name: "ranges-reverse", opts: IterOptions{KeyTypes: IterKeyTypePointsAndRanges}, reverse: true},
+		{name: "bounded", opts: IterOptions{
+			KeyTypes:   IterKeyTypePointsAndRanges,
+			LowerBound: []byte("bb"),
+			UpperBound: []byte("hh"),
+		}},
+		{name: "bounded-reverse", opts: IterOptions{
+			KeyTypes:   IterKeyTypeRangesOnly,
+			LowerBound: []byte("d"),
+			UpperBound: []byte("j"),
+		}, reverse: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := tc.opts
+			first, next := (*Iterator).First, (*Iterator).Next
+			if tc.reverse {
+				first, next = (*Iterator).Last, (*Iterator).Prev
+			}
+			iter, err := efos.NewIter(&opts)
+			require.NoError(t, err)
+			var expected []string
+			for valid := first(iter); valid; valid = next(iter) {
+				expected = append(expected, formatIterPos(iter))
+			}
+			require.NoError(t, iter.Close())
+			require.NotEmpty(t, expected)
+
+			// Page through the keys, two at a time, resuming each page from
+			// the cursor of the previous one. Writes after the snapshot are
+			// not observed, and range keys are reported as changed exactly as
+			// during uninterrupted iteration.
+			require.NoError(t, d.Set([]byte("c"), []byte("C"), nil))
+			var pages []string
+			iter, err = efos.NewIter(&opts)
+			require.NoError(t, err)
+			valid := first(iter)
+			for valid {
+				for n := 0; valid && n < 2; n++ {
+					pages = append(pages, formatIterPos(iter))
+					if n < 1 {
+						valid = next(iter)
+					}
+				}
+				if !valid {
+					break
+				}
+				cursor, err := iter.Cursor()
+				require.NoError(t, err)
+				require.NoError(t, iter.Close())
+				// The resumed iterator adopts the cursor's options.
+				iter, err = ResumeIter(efos, cursor, nil)
+				require.NoError(t, err)
+				valid = iter.Valid()
+			}
+			require.NoError(t, iter.Close())
+			require.Equal(t, expected, pages)
+			require.NoError(t, d.Delete([]byte("c"), nil))
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		iter, err := d.NewIter(nil)
+		require.NoError(t, err)
+		_, err = iter.Cursor()
+		require.Error(t, err)
+		require.True(t, iter.SeekPrefixGE([]byte("a")))
+		_, err = iter.Cursor()
+		require.Error(t, err)
+		require.True(t, iter.First())
+		cursor, err := iter.Cursor()
+		require.NoError(t, err)
+		require.NoError(t, iter.Close())
+		for i := 0; i < len(cursor); i++ {
+			_, err := ResumeIter(d, cursor[:i], nil)
+			require.ErrorIs(t, err, errInvalidIterCursor)
+		}
+		_, err = ResumeIter(d, append(cursor, 0), nil)
+		require.ErrorIs(t, err, errInvalidIterCursor)
+
+		// Without a snapshot, the resumed iterator observes keys written
+		// after the cursor's position.
+		require.NoError(t, d.Set([]byte("aa"), []byte("AA"), nil))
+		iter, err = ResumeIter(d, cursor, nil)
+		require.NoError(t, err)
+		require.True(t, iter.Valid())
+		require.Equal(t, "aa:AA", formatIterPos(iter))
+		require.NoError(t, iter.Close())
+	})
+}
@@ -0,0 +1,664 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/sstable"
+)
+
+// maxKeyspaceID is the largest permitted KeyspaceOptions.ID. The keyspace with
+// ID i occupies the encoded key range [i, i+1), so the largest ID must leave
+// room for an exclusive upper bound.
+const maxKeyspaceID = 0xfe
+
+// KeyspaceOptions configures a keyspace: a logical, independently ordered
+// namespace of keys stored within a single DB. All keyspaces of a DB share the
+// WAL, memtables and commit pipeline, so a single Batch may atomically write
+// to several keyspaces.
+//
+// Internally, every key is prefixed with the keyspace's one-byte ID. Keys of
+// different keyspaces never interleave: the keyspace with the smaller ID sorts
+// first, and keys within a keyspace are ordered by the keyspace's Comparer.
+type KeyspaceOptions struct {
+	// Name identifies the keyspace in DB.Keyspace. Names must be unique within
+	// a DB.
+	Name string
+
+	// ID is the persistent identifier of the keyspace, stored as a prefix of
+	// every key in the keyspace. IDs must be unique within a DB and must be
+	// <= 0xfe. The ID of a keyspace must never change over the lifetime of the
+	// DB.
+	ID uint8
+
+	// Comparer defines the ordering of keys within the keyspace. The default
+	// value uses the same ordering as bytes.Compare.
+	//
+	// Point and range suffix comparisons (ComparePointSuffixes and
+	// CompareRangeSuffixes) are shared by all keyspaces of a DB and are taken
+	// from the first keyspace; every keyspace's suffix encoding must be
+	// compatible with them. Keyspaces whose Split returns the full key have
+	// empty suffixes and are compatible with any suffix encoding.
+	Comparer *Comparer
+
+	// Merger defines the merge operation for values written with Merge into the
+	// keyspace. The default merger concatenates values.
+	Merger *Merger
+
+	// Levels optionally overrides the block-level options (block size, restart
+	// interval, compression and filter policy) used when writing sstables
+	// containing the keyspace's keys. Flushes and compactions never write an
+	// sstable containing keys from more than one keyspace. If nil, the DB-wide
+	// Options.Levels are used.
+	Levels []LevelOptions
+}
+
+// level returns the LevelOptions for the specified level, falling back to the
+// DB-wide options when the keyspace does not override them.
+func (o *KeyspaceOptions) level(dbOpts *Options, level int) LevelOptions {
+	if len(o.Levels) == 0 {
+		return dbOpts.Level(level)
+	}
+	if level < len(o.Levels) {
+		return o.Levels[level]
+	}
+	return o.Levels[len(o.Levels)-1]
+}
+
+// keyspaceSet holds the resolved keyspaces of a DB, indexed by ID.
+type keyspaceSet struct {
+	byID   [maxKeyspaceID + 1]*KeyspaceOptions
+	byName map[string]*KeyspaceOptions
+	// comparer and merger are the composite Comparer and Merger installed as
+	// Options.Comparer and Options.Merger.
+	comparer *Comparer
+	merger   *Merger
+}
+
+// makeKeyspaceSet validates the provided keyspaces and constructs the
+// composite Comparer and Merger dispatching to the per-keyspace ones.
+func makeKeyspaceSet(keyspaces []KeyspaceOptions) (*keyspaceSet, error) {
+	keyspaces = slices.Clone(keyspaces)
+	s := &keyspaceSet{byName: make(map[string]*KeyspaceOptions, len(keyspaces))}
+	for i := range keyspaces {
+		ks := &keyspaces[i]
+		switch {
+		case ks.Name == "":
+			return nil, errors.Errorf("pebble: keyspace with ID %d has no name", ks.ID)
+		case ks.ID > maxKeyspaceID:
+			return nil, errors.Errorf("pebble: keyspace %q has ID %d > %d", ks.Name, ks.ID, maxKeyspaceID)
+		case s.byID[ks.ID] != nil:
+			return nil, errors.Errorf("pebble: keyspaces %q and %q share ID %d",
+				s.byID[ks.ID].Name, ks.Name, ks.ID)
+		case s.byName[ks.Name] != nil:
+			return nil, errors.Errorf("pebble: duplicate keyspace name %q", ks.Name)
+		}
+		ks.Comparer = ks.Comparer.EnsureDefaults()
+		if ks.Merger == nil {
+			ks.Merger = DefaultMerger
+		}
+		ks.Levels = slices.Clone(ks.Levels)
+		for j := range ks.Levels {
+			ks.Levels[j].EnsureDefaults()
+			if ks.Levels[j].RangeFilterPrefixLength > 0 && !ks.Comparer.BytewisePrefixes {
+				return nil, errors.Errorf("pebble: keyspace %q sets RangeFilterPrefixLength, but its Comparer %q does not declare BytewisePrefixes",
+					ks.Name, ks.Comparer.Name)
+			}
+		}
+		s.byID[ks.ID] = ks
+		s.byName[ks.Name] = ks
+	}
+	s.comparer = s.makeComparer(keyspaces)
+	s.merger = s.makeMerger(keyspaces)
+	return s, nil
+}
+
+// lookup returns the keyspace that the encoded key belongs to and the key with
+// the keyspace prefix stripped. If the key's prefix does not correspond to a
+// configured keyspace, the returned KeyspaceOptions is nil.
+func (s *keyspaceSet) lookup(key []byte) (*KeyspaceOptions, []byte) {
+	if len(key) == 0 {
+		return nil, key
+	}
+	return s.byID[key[0]], key[1:]
+}
+
+func (s *keyspaceSet) comparerFor(key []byte) (*Comparer, []byte) {
+	ks, rest := s.lookup(key)
+	if ks == nil {
+		return DefaultComparer, rest
+	}
+	return ks.Comparer, rest
+}
+
+func (s *keyspaceSet) makeComparer(keyspaces []KeyspaceOptions) *Comparer {
+	var names []string
+	for i := range keyspaces {
+		names = append(names, fmt.Sprintf("%d:%s=%s", keyspaces[i].ID, keyspaces[i].Name, keyspaces[i].Comparer.Name))
+	}
+	comparePointSuffixes, compareRangeSuffixes := bytes.Compare, bytes.Compare
+	// The keyspace ID is the first byte of every key, so the prefixes are
+	// ordered bytewise if they are within every keyspace.
+	bytewisePrefixes := true
+	for i := range keyspaces {
+		bytewisePrefixes = bytewisePrefixes && keyspaces[i].Comparer.BytewisePrefixes
+	}
+	if len(keyspaces) > 0 {
+		if c := keyspaces[0].Comparer; c.ComparePointSuffixes != nil {
+			comparePointSuffixes = c.ComparePointSuffixes
+		}
+		compareRangeSuffixes = keyspaces[0].Comparer.CompareRangeSuffixes
+	}
+	return &Comparer{
+		Compare: func(a, b []byte) int {
+			if len(a) == 0 || len(b) == 0 || a[0] != b[0] {
+				return bytes.Compare(a[:min(len(a), 1)], b[:min(len(b), 1)])
+			}
+			c, _ := s.comparerFor(a)
+			return c.Compare(a[1:], b[1:])
+		},
+		Equal: func(a, b []byte) bool {
+			if len(a) == 0 || len(b) == 0 || a[0] != b[0] {
+				return len(a) == 0 && len(b) == 0
+			}
+			c, _ := s.comparerFor(a)
+			return c.Equal(a[1:], b[1:])
+		},
+		AbbreviatedKey: func(key []byte) uint64 {
+			if len(key) == 0 {
+				return 0
+			}
+			c, rest := s.comparerFor(key)
+			return uint64(key[0])<<56 | c.AbbreviatedKey(rest)>>8
+		},
+		Separator: func(dst, a, b []byte) []byte {
+			if a[0] != b[0] || len(a) == 1 || len(b) == 1 {
+				return append(dst, a...)
+			}
+			c, rest := s.comparerFor(a)
+			return c.Separator(append(dst, a[0]), rest, b[1:])
+		},
+		Successor: func(dst, a []byte) []byte {
+			if len(a) == 0 {
+				return dst
+			}
+			c, rest := s.comparerFor(a)
+			return c.Successor(append(dst, a[0]), rest)
+		},
+		ImmediateSuccessor: func(dst, a []byte) []byte {
+			if len(a) == 0 {
+				return append(dst, 0x00)
+			}
+			c, rest := s.comparerFor(a)
+			return c.ImmediateSuccessor(append(dst, a[0]), rest)
+		},
+		Split: func(a []byte) int {
+			if len(a) == 0 {
+				return 0
+			}
+			c, rest := s.comparerFor(a)
+			return 1 + c.Split(rest)
+		},
+		ComparePointSuffixes: comparePointSuffixes,
+		CompareRangeSuffixes: compareRangeSuffixes,
+		BytewisePrefixes:     bytewisePrefixes,
+		FormatKey: func(key []byte) fmt.Formatter {
+			ks, rest := s.lookup(key)
+			if ks == nil {
+				return base.FormatBytes(key)
+			}
+			return keyspaceFormatter{name: ks.Name, inner: ks.Comparer.FormatKey(rest)}
+		},
+		ValidateKey: func(key []byte) error {
+			ks, rest := s.lookup(key)
+			if ks == nil {
+				return errors.Errorf("pebble: key %q does not belong to any keyspace", key)
+			}
+			if ks.Comparer.ValidateKey != nil {
+				return ks.Comparer.ValidateKey(rest)
+			}
+			return nil
+		},
+		Name: "pebble.keyspaces(" + strings.Join(names, ",") + ")",
+	}
+}
+
+func (s *keyspaceSet) makeMerger(keyspaces []KeyspaceOptions) *Merger {
+	var names []string
+	for i := range keyspaces {
+		names = append(names, fmt.Sprintf("%d:%s=%s", keyspaces[i].ID, keyspaces[i].Name, keyspaces[i].Merger.Name))
+	}
+	return &Merger{
+		Merge: func(key, value []byte) (ValueMerger, error) {
+			ks, rest := s.lookup(key)
+			if ks == nil {
+				return DefaultMerger.Merge(key, value)
+			}
+			return ks.Merger.Merge(rest, value)
+		},
+		Name: "pebble.keyspaces(" + strings.Join(names, ",") + ")",
+	}
+}
+
+// keyspaceEndKey returns the exclusive end of the keyspace containing the encoded key,
+// or nil if the key is empty.
+func keyspaceEndKey(key []byte) []byte {
+	if len(key) == 0 {
+		return nil
+	}
+	return []byte{key[0] + 1}
+}
+
+// clampSpanPolicyEnd returns the end key of a span policy starting at
+// startKey, clamped to the end of the keyspace containing startKey. This
+// ensures flushes and compactions split their outputs at keyspace boundaries.
+func (s *keyspaceSet) clampSpanPolicyEnd(cmp Compare, startKey, endKey []byte) []byte {
+	ksEnd := keyspaceEndKey(startKey)
+	if ksEnd == nil || (len(endKey) > 0 && cmp(endKey, ksEnd) <= 0) {
+		return endKey
+	}
+	return ksEnd
+}
+
+// applyLevelOptions overrides the block-level writer options for an sstable
+// starting at firstKey with the options of the keyspace containing firstKey.
+func (s *keyspaceSet) applyLevelOptions(
+	o *Options, writerOpts *sstable.WriterOptions, level int, firstKey []byte,
+) {
+	ks, _ := s.lookup(firstKey)
+	if ks == nil || len(ks.Levels) == 0 {
+		return
+	}
+	lo := ks.level(o, level)
+	writerOpts.BlockRestartInterval = lo.BlockRestartInterval
+	writerOpts.BlockSize = lo.BlockSize
+	writerOpts.BlockSizeThreshold = lo.BlockSizeThreshold
+	writerOpts.Compression = resolveDefaultCompression(lo.Compression())
+	writerOpts.FilterPolicy = lo.FilterPolicy
+	writerOpts.FilterType = lo.FilterType
+	writerOpts.PartitionFilters = lo.PartitionFilters
+	writerOpts.RangeFilterPrefixLength = lo.RangeFilterPrefixLength
+	writerOpts.IndexBlockSize = lo.IndexBlockSize
+}
+
+// keyspaceFormatter formats a key as "<keyspace>/<key>".
+type keyspaceFormatter struct {
+	name  string
+	inner fmt.Formatter
+}
+
+// Format implements the fmt.Formatter interface.
+func (f keyspaceFormatter) Format(s fmt.State, c rune) {
+	fmt.Fprintf(s, "%s/", f.name)
+	f.inner.Format(s, c)
+}
+
+// Keyspace is a handle to a keyspace of a DB. See KeyspaceOptions.
+//
+// All keys passed to and returned by a Keyspace's methods are unprefixed keys
+// within the keyspace.
+type Keyspace struct {
+	db   *DB
+	opts *KeyspaceOptions
+}
+
+// Keyspace returns a handle to the keyspace with the given name, or nil if the
+// DB has no such keyspace.
+func (d *DB) Keyspace(name string) *Keyspace {
+	if d.opts.private.keyspaces == nil {
+		return nil
+	}
+	ks, ok := d.opts.private.keyspaces.byName[name]
+	if !ok {
+		return nil
+	}
+	return &Keyspace{db: d, opts: ks}
+}
+
+// Name returns the name of the keyspace.
+func (k *Keyspace) Name() string {
+	return k.opts.Name
+}
+
+// Comparer returns the Comparer that orders keys within the keyspace.
+func (k *Keyspace) Comparer() *Comparer {
+	return k.opts.Comparer
+}
+
+// EncodeKey appends the encoded form of the keyspace key to dst. Encoded keys
+// are the keys stored in the DB, and may be used with APIs that have no
+// keyspace-scoped equivalent (e.g. ingestion).
+func (k *Keyspace) EncodeKey(dst, key []byte) []byte {
+	return append(append(dst, k.opts.ID), key...)
+}
+
+// DecodeKey strips the keyspace prefix from an encoded key. It returns false
+// if the key does not belong to the keyspace.
+func (k *Keyspace) DecodeKey(encoded []byte) ([]byte, bool) {
+	if len(encoded) == 0 || encoded[0] != k.opts.ID {
+		return nil, false
+	}
+	return encoded[1:], true
+}
+
+// bounds returns the encoded [lower, upper) bounds corresponding to the
+// provided keyspace bounds. A nil bound is replaced by the corresponding bound
+// of the entire keyspace.
+func (k *Keyspace) bounds(lower, upper []byte) (encLower, encUpper []byte) {
+	if lower != nil {
+		encLower = k.EncodeKey(nil, lower)
+	} else {
+		encLower = []byte{k.opts.ID}
+	}
+	if upper != nil {
+		encUpper = k.EncodeKey(nil, upper)
+	} else {
+		encUpper = []byte{k.opts.ID + 1}
+	}
+	return encLower, encUpper
+}
+
+// Reader returns a view of the keyspace reading from r, which may be the DB, a
+// Snapshot, an EventuallyFileOnlySnapshot or an indexed Batch.
+func (k *Keyspace) Reader(r Reader) KeyspaceReader {
+	return KeyspaceReader{ks: k, r: r}
+}
+
+// Writer returns a view of the keyspace writing to w, which is typically a
+// Batch. Writing to the same Batch through several keyspaces' Writers commits
+// the writes to all keyspaces atomically.
+func (k *Keyspace) Writer(w Writer) KeyspaceWriter {
+	return KeyspaceWriter{ks: k, w: w}
+}
+
+// Get gets the value for the given key in the keyspace. See DB.Get.
+func (k *Keyspace) Get(key []byte) ([]byte, io.Closer, error) {
+	return k.Reader(k.db).Get(key)
+}
+
+// MultiGet gets the values for the given keys in the keyspace. See
+// DB.MultiGet.
+func (k *Keyspace) MultiGet(keys [][]byte) ([][]byte, error) {
+	encoded := make([][]byte, len(keys))
+	for i := range keys {
+		encoded[i] = k.EncodeKey(nil, keys[i])
+	}
+	return k.db.MultiGet(encoded)
+}
+
+// NewIter returns an iterator over the keyspace. See DB.NewIter.
+func (k *Keyspace) NewIter(o *IterOptions) (*KeyspaceIterator, error) {
+	return k.Reader(k.db).NewIterWithContext(context.Background(), o)
+}
+
+// Set sets the value for the given key in the keyspace. See DB.Set.
+func (k *Keyspace) Set(key, value []byte, opts *WriteOptions) error {
+	return k.Writer(k.db).Set(key, value, opts)
+}
+
+// Delete deletes the value for the given key in the keyspace. See DB.Delete.
+func (k *Keyspace) Delete(key []byte, opts *WriteOptions) error {
+	return k.Writer(k.db).Delete(key, opts)
+}
+
+// DeleteRange deletes all of the keys in the keyspace in the range [start,
+// end). See DB.DeleteRange.
+func (k *Keyspace) DeleteRange(start, end []byte, opts *WriteOptions) error {
+	return k.Writer(k.db).DeleteRange(start, end, opts)
+}
+
+// Merge adds an action to the DB that merges the value at key with the new
+// value, using the keyspace's Merger. See DB.Merge.
+func (k *Keyspace) Merge(key, value []byte, opts *WriteOptions) error {
+	return k.Writer(k.db).Merge(key, value, opts)
+}
+
+// Compact compacts the specified range of keys in the keyspace. A nil start
+// or end denotes the corresponding bound of the entire keyspace. See
+// DB.Compact.
+func (k *Keyspace) Compact(ctx context.Context, start, end []byte, parallelize bool) error {
+	encStart, encEnd := k.bounds(start, end)
+	return k.db.Compact(ctx, encStart, encEnd, parallelize)
+}
+
+// KeyspaceReader reads the keys of a single keyspace from a Reader.
+type KeyspaceReader struct {
+	ks *Keyspace
+	r  Reader
+}
+
+// Get gets the value for the given key in the keyspace. See Reader.Get.
+func (r KeyspaceReader) Get(key []byte) ([]byte, io.Closer, error) {
+	return r.r.Get(r.ks.EncodeKey(nil, key))
+}
+
+// NewIter returns an iterator over the keyspace. See Reader.NewIter.
+func (r KeyspaceReader) NewIter(o *IterOptions) (*KeyspaceIterator, error) {
+	return r.NewIterWithContext(context.Background(), o)
+}
+
+// NewIterWithContext is like NewIter, and additionally accepts a context for
+// tracing.
+func (r KeyspaceReader) NewIterWithContext(
+	ctx context.Context, o *IterOptions,
+) (*KeyspaceIterator, error) {
+	ki := &KeyspaceIterator{ks: r.ks}
+	var opts IterOptions
+	if o != nil {
+		opts = *o
+	}
+	opts.LowerBound, opts.UpperBound = r.ks.bounds(opts.LowerBound, opts.UpperBound)
+	if skip := opts.SkipPoint; skip != nil {
+		opts.SkipPoint = func(userKey []byte) bool { return skip(userKey[1:]) }
+	}
+	iter, err := r.r.NewIterWithContext(ctx, &opts)
+	if err != nil {
+		return nil, err
+	}
+	ki.iter = iter
+	return ki, nil
+}
+
+// KeyspaceWriter writes keys of a single keyspace to a Writer.
+type KeyspaceWriter struct {
+	ks *Keyspace
+	w  Writer
+}
+
+// Set sets the value for the given key in the keyspace. See Writer.Set.
+func (w KeyspaceWriter) Set(key, value []byte, opts *WriteOptions) error {
+	return w.w.Set(w.ks.EncodeKey(nil, key), value, opts)
+}
+
+// Delete deletes the value for the given key in the keyspace. See
+// Writer.Delete.
+func (w KeyspaceWriter) Delete(key []byte, opts *WriteOptions) error {
+	return w.w.Delete(w.ks.EncodeKey(nil, key), opts)
+}
+
+// SingleDelete single-deletes the given key in the keyspace. See
+// Writer.SingleDelete.
+func (w KeyspaceWriter) SingleDelete(key []byte, opts *WriteOptions) error {
+	return w.w.SingleDelete(w.ks.EncodeKey(nil, key), opts)
+}
+
+// DeleteRange deletes all of the keys in the keyspace in the range [start,
+// end). A nil end denotes the end of the keyspace. See Writer.DeleteRange.
+func (w KeyspaceWriter) DeleteRange(start, end []byte, opts *WriteOptions) error {
+	encStart, encEnd := w.ks.bounds(start, end)
+	return w.w.DeleteRange(encStart, encEnd, opts)
+}
+
+// Merge merges the value at key with the new value, using the keyspace's
+// Merger. See Writer.Merge.
+func (w KeyspaceWriter) Merge(key, value []byte, opts *WriteOptions) error {
+	return w.w.Merge(w.ks.EncodeKey(nil, key), value, opts)
+}
+
+// RangeKeySet sets a range key in the keyspace. See Writer.RangeKeySet.
+func (w KeyspaceWriter) RangeKeySet(start, end, suffix, value []byte, opts *WriteOptions) error {
+	encStart, encEnd := w.ks.bounds(start, end)
+	return w.w.RangeKeySet(encStart, encEnd, suffix, value, opts)
+}
+
+// RangeKeyUnset removes a range key in the keyspace. See
+// Writer.RangeKeyUnset.
+func (w KeyspaceWriter) RangeKeyUnset(start, end, suffix []byte, opts *WriteOptions) error {
+	encStart, encEnd := w.ks.bounds(start, end)
+	return w.w.RangeKeyUnset(encStart, encEnd, suffix, opts)
+}
+
+// RangeKeyDelete deletes all range keys in the keyspace within [start, end).
+// See Writer.RangeKeyDelete.
+func (w KeyspaceWriter) RangeKeyDelete(start, end []byte, opts *WriteOptions) error {
+	encStart, encEnd := w.ks.bounds(start, end)
+	return w.w.RangeKeyDelete(encStart, encEnd, opts)
+}
+
+// KeyspaceIterator iterates over the keys of a single keyspace. It mirrors the
+// Iterator API, translating keys to and from their encoded form.
+type KeyspaceIterator struct {
+	ks      *Keyspace
+	iter    *Iterator
+	seekBuf []byte
+}
+
+func (i *KeyspaceIterator) encode(key []byte) []byte {
+	i.seekBuf = i.ks.EncodeKey(i.seekBuf[:0], key)
+	return i.seekBuf
+}
+
+// SeekGE moves the iterator to the first key/value pair whose key is greater
+// than or equal to the given key. See Iterator.SeekGE.
+func (i *KeyspaceIterator) SeekGE(key []byte) bool {
+	return i.iter.SeekGE(i.encode(key))
+}
+
+// SeekPrefixGE moves the iterator to the first key/value pair whose key is
+// greater than or equal to the given key and shares its prefix. See
+// Iterator.SeekPrefixGE.
+func (i *KeyspaceIterator) SeekPrefixGE(key []byte) bool {
+	return i.iter.SeekPrefixGE(i.encode(key))
+}
+
+// SeekLT moves the iterator to the last key/value pair whose key is less than
+// the given key. See Iterator.SeekLT.
+func (i *KeyspaceIterator) SeekLT(key []byte) bool {
+	return i.iter.SeekLT(i.encode(key))
+}
+
+// First moves the iterator to the first key/value pair. See Iterator.First.
+func (i *KeyspaceIterator) First() bool {
+	return i.iter.First()
+}
+
+// Last moves the iterator to the last key/value pair. See Iterator.Last.
+func (i *KeyspaceIterator) Last() bool {
+	return i.iter.Last()
+}
+
+// Next moves the iterator to the next key/value pair. See Iterator.Next.
+func (i *KeyspaceIterator) Next() bool {
+	return i.iter.Next()
+}
+
+// NextPrefix moves the iterator to the next key/value pair with a different
+// prefix. See Iterator.NextPrefix.
+func (i *KeyspaceIterator) NextPrefix() bool {
+	return i.iter.NextPrefix()
+}
+
+// Prev moves the iterator to the previous key/value pair. See Iterator.Prev.
+func (i *KeyspaceIterator) Prev() bool {
+	return i.iter.Prev()
+}
+
+// Valid returns true if the iterator is positioned at a valid key/value pair.
+func (i *KeyspaceIterator) Valid() bool {
+	return i.iter.Valid()
+}
+
+// Key returns the key of the current key/value pair, without the keyspace
+// prefix. See Iterator.Key.
+func (i *KeyspaceIterator) Key() []byte {
+	return i.iter.Key()[1:]
+}
+
+// Value returns the value of the current key/value pair. See Iterator.Value.
+func (i *KeyspaceIterator) Value() []byte {
+	return i.iter.Value()
+}
+
+// ValueAndErr returns the value of the current key/value pair, and any error
+// encountered retrieving it. See Iterator.ValueAndErr.
+func (i *KeyspaceIterator) ValueAndErr() ([]byte, error) {
+	return i.iter.ValueAndErr()
+}
+
+// HasPointAndRange indicates whether there exists a point key, a range key or
+// both at the current iterator position. See Iterator.HasPointAndRange.
+func (i *KeyspaceIterator) HasPointAndRange() (hasPoint, hasRange bool) {
+	return i.iter.HasPointAndRange()
+}
+
+// RangeKeyChanged indicates whether the most recent iterator positioning
+// operation resulted in the iterator stepping into or out of a new range key.
+// See Iterator.RangeKeyChanged.
+func (i *KeyspaceIterator) RangeKeyChanged() bool {
+	return i.iter.RangeKeyChanged()
+}
+
+// RangeBounds returns the start (inclusive) and end (exclusive) bounds of the
+// range key covering the current iterator position, without the keyspace
+// prefix. A nil end denotes the end of the keyspace. See
+// Iterator.RangeBounds.
+func (i *KeyspaceIterator) RangeBounds() (start, end []byte) {
+	start, end = i.iter.RangeBounds()
+	if start != nil {
+		start = start[1:]
+	}
+	if len(end) > 0 && end[0] == i.ks.opts.ID {
+		end = end[1:]
+	} else {
+		end = nil
+	}
+	return start, end
+}
+
+// RangeKeys returns the range key values and their suffixes covering the
+// current iterator position. See Iterator.RangeKeys.
+func (i *KeyspaceIterator) RangeKeys() []RangeKeyData {
+	return i.iter.RangeKeys()
+}
+
+// SetBounds sets the lower and upper bounds for the iterator. A nil bound
+// denotes the corresponding bound of the entire keyspace. See
+// Iterator.SetBounds.
+func (i *KeyspaceIterator) SetBounds(lower, upper []byte) {
+	i.iter.SetBounds(i.ks.bounds(lower, upper))
+}
+
+// Error returns any accumulated error. See Iterator.Error.
+func (i *KeyspaceIterator) Error() error {
+	return i.iter.Error()
+}
+
+// Stats returns the current stats of the iterator. See Iterator.Stats.
+func (i *KeyspaceIterator) Stats() IteratorStats {
+	return i.iter.Stats()
+}
+
+// Close closes the iterator. See Iterator.Close.
+func (i *KeyspaceIterator) Close() error {
+	return i.iter.Close()
+}
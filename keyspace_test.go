@@ -0,0 +1,143 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/chris124567/pebble/bloom"
+	"github.com/chris124567/pebble/internal/testkeys"
+	"github.com/chris124567/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+// reverseMerger is a Merger that prepends operands instead of appending them,
+// allowing tests to observe which keyspace's Merger was used.
+var reverseMerger = &Merger{
+	Merge: func(key, value []byte) (ValueMerger, error) {
+		return &reverseValueMerger{buf: append([]byte(nil), value...)}, nil
+	},
+	Name: "test.reverse",
+}
+
+type reverseValueMerger struct {
+	buf []byte
+}
+
+func (m *reverseValueMerger) MergeNewer(value []byte) error {
+	m.buf = append(append([]byte(nil), value...), m.buf...)
+	return nil
+}
+
+func (m *reverseValueMerger) MergeOlder(value []byte) error {
+	m.buf = append(m.buf, value...)
+	return nil
+}
+
+func (m *reverseValueMerger) Finish(includesBase bool) ([]byte, io.Closer, error) {
+	return m.buf, nil, nil
+}
+
+func testKeyspaceOptions() *Options {
+	return &Options{
+		FS: vfs.NewMem(),
+		Keyspaces: []KeyspaceOptions{
+			{Name: "mvcc", ID: 1, Comparer: testkeys.Comparer},
+			{Name: "users", ID: 2, Merger: reverseMerger},
+			{Name: "orders", ID: 3, Levels: []LevelOptions{{FilterPolicy: bloom.FilterPolicy(10)}}},
+		},
+	}
+}
+
+func collectKeyspace(t *testing.T, ks *Keyspace, o *IterOptions) string {
+	iter, err := ks.NewIter(o)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, iter.Close()) }()
+	var s string
+	for valid := iter.First(); valid; valid = iter.Next() {
+		s += fmt.Sprintf("%s=%s ", iter.Key(), iter.Value())
+	}
+	require.NoError(t, iter.Error())
+	return s
+}
+
+func TestKeyspaces(t *testing.T) {
+	d, err := Open("", testKeyspaceOptions())
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	require.Nil(t, d.Keyspace("missing"))
+	mvcc, users, orders := d.Keyspace("mvcc"), d.Keyspace("users"), d.Keyspace("orders")
+
+	// A single batch writes atomically to several keyspaces.
+	b := d.NewBatch()
+	require.NoError(t, mvcc.Writer(b).Set([]byte("a@3"), []byte("a3"), nil))
+	require.NoError(t, mvcc.Writer(b).Set([]byte("a@5"), []byte("a5"), nil))
+	require.NoError(t, mvcc.Writer(b).Set([]byte("b@1"), []byte("b1"), nil))
+	require.NoError(t, users.Writer(b).Set([]byte("a"), []byte("alice"), nil))
+	require.NoError(t, users.Writer(b).Merge([]byte("m"), []byte("1"), nil))
+	require.NoError(t, orders.Writer(b).Set([]byte("a"), []byte("order-a"), nil))
+	require.NoError(t, orders.Writer(b).Set([]byte("z"), []byte("order-z"), nil))
+	require.NoError(t, b.Commit(nil))
+	require.NoError(t, users.Merge([]byte("m"), []byte("2"), nil))
+
+	check := func() {
+		// The mvcc keyspace orders versions by descending timestamp.
+		require.Equal(t, "a@5=a5 a@3=a3 b@1=b1 ", collectKeyspace(t, mvcc, nil))
+		// The users keyspace uses its own merger.
+		require.Equal(t, "a=alice m=21 ", collectKeyspace(t, users, nil))
+		require.Equal(t, "a=order-a z=order-z ", collectKeyspace(t, orders, nil))
+		require.Equal(t, "a=order-a ", collectKeyspace(t, orders, &IterOptions{UpperBound: []byte("b")}))
+
+		v, closer, err := orders.Get([]byte("z"))
+		require.NoError(t, err)
+		require.Equal(t, "order-z", string(v))
+		require.NoError(t, closer.Close())
+		_, _, err = users.Get([]byte("z"))
+		require.ErrorIs(t, err, ErrNotFound)
+	}
+	check()
+
+	require.NoError(t, d.Flush())
+	require.NoError(t, users.Compact(context.Background(), nil, nil, false))
+	check()
+
+	// Flushes and compactions split sstables at keyspace boundaries.
+	tables, err := d.SSTables()
+	require.NoError(t, err)
+	for _, level := range tables {
+		for _, tbl := range level {
+			require.Equal(t, tbl.Smallest.UserKey[0], tbl.Largest.UserKey[0],
+				"sstable %s spans keyspaces", tbl.FileNum)
+		}
+	}
+
+	// Range deletions are confined to the keyspace.
+	require.NoError(t, users.DeleteRange(nil, nil, nil))
+	require.Equal(t, "", collectKeyspace(t, users, nil))
+	require.Equal(t, "a=order-a z=order-z ", collectKeyspace(t, orders, nil))
+}
+
+func TestKeyspacesValidate(t *testing.T) {
+	for _, tc := range []struct {
+		keyspaces []KeyspaceOptions
+		expected  string
+	}{
+		{[]KeyspaceOptions{{Name: "a", ID: 1}, {Name: "b", ID: 1}}, "share ID 1"},
+		{[]KeyspaceOptions{{Name: "a", ID: 1}, {Name: "a", ID: 2}}, "duplicate keyspace name"},
+		{[]KeyspaceOptions{{ID: 1}}, "has no name"},
+		{[]KeyspaceOptions{{Name: "a", ID: 0xff}}, "has ID 255"},
+	} {
+		_, err := Open("", &Options{FS: vfs.NewMem(), Keyspaces: tc.keyspaces})
+		require.ErrorContains(t, err, tc.expected)
+	}
+
+	opts := &Options{FS: vfs.NewMem(), Keyspaces: []KeyspaceOptions{{Name: "a", ID: 1}}, Comparer: testkeys.Comparer}
+	_, err := Open("", opts)
+	require.ErrorContains(t, err, "must not be set when Keyspaces is set")
+}
@@ -543,7 +543,7 @@ func (d *DB) CheckLevels(stats *CheckLevelsStats) error {
 		newIters:  d.newIters,
 		seqNum:    seqNum,
 		stats:     stats,
-		merge:     d.merge,
+		merge:     d.storedMerge(),
 		formatKey: d.opts.Comparer.FormatKey,
 		readEnv:   block.ReadEnv{
 			// TODO(jackson): Add categorized stats.
@@ -0,0 +1,360 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+)
+
+// LockMode is the mode in which DB.LockKeys acquires key locks.
+type LockMode uint8
+
+const (
+	// LockShared permits other shared holders but excludes exclusive holders.
+	LockShared LockMode = iota
+	// LockExclusive excludes all other holders.
+	LockExclusive
+)
+
+// String implements fmt.Stringer.
+func (m LockMode) String() string {
+	switch m {
+	case LockShared:
+		return "shared"
+	case LockExclusive:
+		return "exclusive"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrLockDeadlock is returned by DB.LockKeys when waiting for a lock would
+// deadlock with other lock holders. The locks acquired by the failed call are
+// released and its upgrades reverted; locks acquired by earlier calls remain
+// held in their earlier modes.
+var ErrLockDeadlock = errors.New("pebble: lock acquisition would deadlock")
+
+// LockKeys acquires in-memory locks on the given keys on behalf of the batch,
+// waiting for conflicting holders to release them. The locks are released
+// when the batch is committed, reset or closed, so a read-modify-write
+// sequence performed through an indexed batch can be serialized against other
+// batches locking the same keys.
+//
+// Keys are locked in sorted order. Waiters are granted locks in FIFO order. A
+// shared lock held by the batch is upgraded to an exclusive lock if requested.
+// If waiting would create a cycle of batches waiting on one another,
+// LockKeys returns ErrLockDeadlock. If ctx is done before all the locks are
+// acquired, LockKeys returns the context's error. In both cases, locks
+// acquired by this call are released, and locks it upgraded from shared to
+// exclusive are downgraded back to shared.
+//
+// Locks are advisory: they only exclude other callers of LockKeys and do not
+// affect writes that do not lock keys.
+func (d *DB) LockKeys(ctx context.Context, b *Batch, keys [][]byte, mode LockMode) error {
+	if b.locks != nil && b.locks != &d.locks {
+		panic("pebble: batch holds locks of another DB")
+	}
+	keys = slices.Clone(keys)
+	slices.SortFunc(keys, d.cmp)
+	keys = slices.CompactFunc(keys, d.equal)
+	var acquired, upgraded []string
+	for _, k := range keys {
+		grant, err := d.locks.acquire(ctx, b, string(k), mode)
+		if err != nil {
+			d.locks.revert(b, acquired, upgraded)
+			return err
+		}
+		switch grant {
+		case lockGranted:
+			acquired = append(acquired, string(k))
+		case lockUpgraded:
+			upgraded = append(upgraded, string(k))
+		}
+	}
+	return nil
+}
+
+// LockKeys acquires locks on the given keys on behalf of the transaction. The
+// locks are released when the transaction is committed or closed. See
+// DB.LockKeys.
+func (t *Txn) LockKeys(ctx context.Context, keys [][]byte, mode LockMode) error {
+	if t.done {
+		return errTxnClosed
+	}
+	return t.db.LockKeys(ctx, t.batch, keys, mode)
+}
+
+// keyLock is the state of a single locked key.
+type keyLock struct {
+	// exclusive is the holder of an exclusive lock, if any.
+	exclusive *Batch
+	// shared holds the holders of shared locks.
+	shared map[*Batch]struct{}
+	// queue holds the waiters in arrival order.
+	queue []*lockWaiter
+}
+
+func (kl *keyLock) empty() bool {
+	return kl.exclusive == nil && len(kl.shared) == 0 && len(kl.queue) == 0
+}
+
+// compatible returns true if owner could hold the lock in the given mode
+// alongside the current holders.
+func (kl *keyLock) compatible(owner *Batch, mode LockMode) bool {
+	if kl.exclusive != nil && kl.exclusive != owner {
+		return false
+	}
+	if mode == LockExclusive {
+		for h := range kl.shared {
+			if h != owner {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// lockWaiter is a batch waiting for a lock.
+type lockWaiter struct {
+	owner   *Batch
+	key     string
+	mode    LockMode
+	granted bool
+	ch      chan struct{}
+}
+
+// lockTable implements DB.LockKeys.
+type lockTable struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+	// held maps each owner to the keys it holds locks on.
+	held map[*Batch]map[string]struct{}
+	// waiting maps each blocked owner to the lock it waits for.
+	waiting map[*Batch]*lockWaiter
+}
+
+func (lt *lockTable) init() {
+	lt.locks = make(map[string]*keyLock)
+	lt.held = make(map[*Batch]map[string]struct{})
+	lt.waiting = make(map[*Batch]*lockWaiter)
+}
+
+// lockGrant describes how lockTable.acquire changed the owner's lock on a key.
+type lockGrant uint8
+
+const (
+	// lockAlreadyHeld indicates the owner already held the lock in a mode at
+	// least as strong as the one requested.
+	lockAlreadyHeld lockGrant = iota
+	// lockGranted indicates the owner did not previously hold a lock on the
+	// key.
+	lockGranted
+	// lockUpgraded indicates the owner's shared lock was upgraded to an
+	// exclusive lock.
+	lockUpgraded
+)
+
+// acquire acquires a lock on key for owner, returning how the owner's lock on
+// the key changed.
+func (lt *lockTable) acquire(
+	ctx context.Context, owner *Batch, key string, mode LockMode,
+) (lockGrant, error) {
+	lt.mu.Lock()
+	kl := lt.locks[key]
+	if kl == nil {
+		kl = &keyLock{shared: make(map[*Batch]struct{})}
+		lt.locks[key] = kl
+	}
+	_, held := lt.held[owner][key]
+	if kl.exclusive == owner || (mode == LockShared && held) {
+		lt.mu.Unlock()
+		return lockAlreadyHeld, nil
+	}
+	grant := lockGranted
+	if held {
+		grant = lockUpgraded
+	}
+	// Grant immediately if compatible and no one is queued ahead. Upgrades are
+	// granted ahead of the queue since the queued waiters are blocked on the
+	// owner anyway.
+	if kl.compatible(owner, mode) && (len(kl.queue) == 0 || held) {
+		lt.grantLocked(kl, owner, key, mode)
+		lt.mu.Unlock()
+		return grant, nil
+	}
+	w := &lockWaiter{owner: owner, key: key, mode: mode, ch: make(chan struct{})}
+	kl.queue = append(kl.queue, w)
+	lt.waiting[owner] = w
+	if lt.deadlockedLocked(owner) {
+		lt.removeWaiterLocked(kl, w)
+		lt.mu.Unlock()
+		return lockAlreadyHeld, ErrLockDeadlock
+	}
+	lt.mu.Unlock()
+
+	select {
+	case <-w.ch:
+		return grant, nil
+	case <-ctx.Done():
+		lt.mu.Lock()
+		defer lt.mu.Unlock()
+		if w.granted {
+			// The lock was granted concurrently with the cancellation.
+			return grant, nil
+		}
+		lt.removeWaiterLocked(kl, w)
+		lt.grantWaitersLocked(key, kl)
+		return lockAlreadyHeld, errors.Wrapf(ctx.Err(), "pebble: waiting for %s lock", mode)
+	}
+}
+
+func (lt *lockTable) grantLocked(kl *keyLock, owner *Batch, key string, mode LockMode) {
+	if mode == LockExclusive {
+		delete(kl.shared, owner)
+		kl.exclusive = owner
+	} else {
+		kl.shared[owner] = struct{}{}
+	}
+	keys := lt.held[owner]
+	if keys == nil {
+		keys = make(map[string]struct{})
+		lt.held[owner] = keys
+	}
+	keys[key] = struct{}{}
+	owner.locks = lt
+}
+
+func (lt *lockTable) removeWaiterLocked(kl *keyLock, w *lockWaiter) {
+	if i := slices.Index(kl.queue, w); i >= 0 {
+		kl.queue = slices.Delete(kl.queue, i, i+1)
+	}
+	delete(lt.waiting, w.owner)
+}
+
+// grantWaitersLocked grants the lock on key to queued waiters, in FIFO order,
+// for as long as they are compatible with the holders.
+func (lt *lockTable) grantWaitersLocked(key string, kl *keyLock) {
+	for len(kl.queue) > 0 {
+		w := kl.queue[0]
+		if !kl.compatible(w.owner, w.mode) {
+			break
+		}
+		kl.queue = kl.queue[1:]
+		delete(lt.waiting, w.owner)
+		lt.grantLocked(kl, w.owner, key, w.mode)
+		w.granted = true
+		close(w.ch)
+	}
+	if kl.empty() {
+		delete(lt.locks, key)
+	}
+}
+
+// blockersLocked returns the owners that the waiter w is blocked on: the
+// incompatible holders of the lock, and the waiters queued ahead of it.
+func (lt *lockTable) blockersLocked(w *lockWaiter) []*Batch {
+	kl := lt.locks[w.key]
+	var blockers []*Batch
+	if kl.exclusive != nil && kl.exclusive != w.owner {
+		blockers = append(blockers, kl.exclusive)
+	}
+	if w.mode == LockExclusive {
+		for h := range kl.shared {
+			if h != w.owner {
+				blockers = append(blockers, h)
+			}
+		}
+	}
+	for _, q := range kl.queue {
+		if q == w {
+			break
+		}
+		if q.owner != w.owner {
+			blockers = append(blockers, q.owner)
+		}
+	}
+	return blockers
+}
+
+// deadlockedLocked returns true if owner transitively waits on itself.
+func (lt *lockTable) deadlockedLocked(owner *Batch) bool {
+	visited := make(map[*Batch]struct{})
+	stack := []*Batch{owner}
+	for len(stack) > 0 {
+		b := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		w := lt.waiting[b]
+		if w == nil {
+			continue
+		}
+		for _, blocker := range lt.blockersLocked(w) {
+			if blocker == owner {
+				return true
+			}
+			if _, ok := visited[blocker]; !ok {
+				visited[blocker] = struct{}{}
+				stack = append(stack, blocker)
+			}
+		}
+	}
+	return false
+}
+
+// revert undoes a failed DB.LockKeys call, releasing the owner's locks on the
+// acquired keys and downgrading its locks on the upgraded keys to shared.
+func (lt *lockTable) revert(owner *Batch, acquired, upgraded []string) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	for _, key := range acquired {
+		lt.releaseKeyLocked(owner, key)
+	}
+	for _, key := range upgraded {
+		kl := lt.locks[key]
+		if kl == nil || kl.exclusive != owner {
+			continue
+		}
+		kl.exclusive = nil
+		kl.shared[owner] = struct{}{}
+		lt.grantWaitersLocked(key, kl)
+	}
+	if len(lt.held[owner]) == 0 {
+		delete(lt.held, owner)
+	}
+}
+
+// releaseAll releases all locks held by the owner.
+func (lt *lockTable) releaseAll(owner *Batch) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	for key := range lt.held[owner] {
+		lt.releaseKeyLocked(owner, key)
+	}
+	delete(lt.held, owner)
+	owner.locks = nil
+}
+
+func (lt *lockTable) releaseKeyLocked(owner *Batch, key string) {
+	delete(lt.held[owner], key)
+	kl := lt.locks[key]
+	if kl == nil {
+		return
+	}
+	if kl.exclusive == owner {
+		kl.exclusive = nil
+	}
+	delete(kl.shared, owner)
+	lt.grantWaitersLocked(key, kl)
+}
+
+// releaseLocks releases any key locks held by the batch. See DB.LockKeys.
+func (b *Batch) releaseLocks() {
+	if b.locks != nil {
+		b.locks.releaseAll(b)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/chris124567/pebble"
 	"github.com/chris124567/pebble/bloom"
+	"github.com/chris124567/pebble/fusefilter"
 	"github.com/chris124567/pebble/internal/base"
 	"github.com/chris124567/pebble/objstorage/remote"
 	"github.com/chris124567/pebble/sstable"
@@ -788,18 +789,27 @@ func RandomOptions(
 	// little bigger than that as the minimum target.
 	lopts.TargetFileSize = max(lopts.TargetFileSize, 12)
 
-	// We either use no bloom filter, the default filter, or a filter with
-	// randomized bits-per-key setting. We zero out the Filters map. It'll get
-	// repopulated on EnsureDefaults accordingly.
+	// We either use no filter, the default bloom filter, a binary fuse filter,
+	// or a bloom filter with randomized bits-per-key setting. We zero out the
+	// Filters map. It'll get repopulated on EnsureDefaults accordingly.
 	opts.Filters = nil
-	switch rng.IntN(3) {
+	switch rng.IntN(4) {
 	case 0:
 		lopts.FilterPolicy = nil
 	case 1:
 		lopts.FilterPolicy = bloom.FilterPolicy(10)
+	case 2:
+		lopts.FilterPolicy = fusefilter.FilterPolicy(8)
 	default:
 		lopts.FilterPolicy = newTestingFilterPolicy(1 << rng.IntN(5))
 	}
+	if lopts.FilterPolicy != nil {
+		lopts.PartitionFilters = rng.IntN(2) == 0
+	}
+	// Range filters require prefixes that are ordered bytewise.
+	if opts.Comparer.BytewisePrefixes && rng.IntN(2) == 0 {
+		lopts.RangeFilterPrefixLength = 1 + rng.IntN(8)
+	}
 
 	// We use either no compression, snappy compression or zstd compression.
 	switch rng.IntN(3) {
@@ -991,6 +1001,8 @@ func filterPolicyFromName(name string) (pebble.FilterPolicy, error) {
 		return nil, nil
 	case "rocksdb.BuiltinBloomFilter":
 		return bloom.FilterPolicy(10), nil
+	case "pebble.BinaryFuseFilter":
+		return fusefilter.FilterPolicy(8), nil
 	}
 	var bitsPerKey int
 	if _, err := fmt.Sscanf(name, testingFilterPolicyFmt, &bitsPerKey); err != nil {
@@ -112,6 +112,10 @@ type LevelMetrics struct {
 	// flushing sstables. This metric is always zero for all levels other than
 	// L0.
 	BlobBytesFlushed uint64
+	// Compression tallies the data blocks written to sstables in the level with
+	// AdaptiveCompression, by the compression setting chosen for each block.
+	// The achieved compression ratio is Compression.CompressionRatio().
+	Compression block.CompressionStats
 
 	MultiLevel struct {
 		// TableBytesInTop are the total bytes in a multilevel compaction coming
@@ -168,6 +172,7 @@ func (m *LevelMetrics) Add(u *LevelMetrics) {
 	m.BlobBytesWritten += u.BlobBytesWritten
 	m.BlobBytesFlushed += u.BlobBytesFlushed
 	m.BlobBytesReadEstimate += u.BlobBytesReadEstimate
+	m.Compression.Add(&u.Compression)
 	m.MultiLevel.TableBytesInTop += u.MultiLevel.TableBytesInTop
 	m.MultiLevel.TableBytesRead += u.MultiLevel.TableBytesRead
 	m.MultiLevel.TableBytesIn += u.MultiLevel.TableBytesIn
@@ -212,7 +217,12 @@ type Metrics struct {
 		MoveCount             int64
 		ReadCount             int64
 		TombstoneDensityCount int64
+		ExpiredCount          int64
 		RewriteCount          int64
+		TieringCount          int64
+		UniversalCount        int64
+		FIFOCount             int64
+		PolicyCount           int64
 		MultiLevelCount       int64
 		CounterLevelCount     int64
 		// An estimate of the number of bytes that need to be compacted for the LSM
@@ -241,6 +251,17 @@ type Metrics struct {
 		// Duration records the cumulative duration of all compactions since the
 		// database was opened.
 		Duration time.Duration
+		// Universal holds the metrics of the universal compaction picker. They
+		// are only populated when Options.Experimental.CompactionStyle is
+		// CompactionStyleUniversal.
+		Universal struct {
+			// SortedRuns is the number of sorted runs: the L0 sublevels and the
+			// non-empty levels below L0.
+			SortedRuns int
+			// SizeAmplification is the size of the sorted runs above the oldest
+			// one, as a fraction of the size of the oldest one.
+			SizeAmplification float64
+		}
 	}
 
 	Ingest struct {
@@ -270,6 +291,10 @@ type Metrics struct {
 
 	Levels [numLevels]LevelMetrics
 
+	// Spans holds the metrics of the spans of
+	// Options.Experimental.SpanPriorities, in the same order.
+	Spans []SpanMetrics
+
 	MemTable struct {
 		// The number of bytes allocated by memtables and large (flushable)
 		// batches.
@@ -295,6 +320,10 @@ type Metrics struct {
 		// A cumulative total number of missized DELSIZED keys encountered by
 		// compactions since the database was opened.
 		MissizedTombstonesCount uint64
+		// A cumulative total number of keys whose values expired (see
+		// Options.TTL) and were compacted as tombstones by flushes and
+		// compactions since the database was opened.
+		ExpiredValuesCount uint64
 	}
 
 	Snapshots struct {
@@ -337,6 +366,13 @@ type Metrics struct {
 		CompressedCountZstd int64
 		// The number of sstables that are compressed with minlz.
 		CompressedCountMinLZ int64
+		// The number of sstables that are compressed with lz4.
+		CompressedCountLZ4 int64
+		// The number of sstables that are compressed with zstd, using a
+		// dictionary.
+		CompressedCountZstdDict int64
+		// The number of sstables whose data blocks are compressed adaptively.
+		CompressedCountAdaptive int64
 		// The number of sstables that are uncompressed.
 		CompressedCountNone int64
 
@@ -775,6 +811,15 @@ func (m *Metrics) SafeFormat(w redact.SafePrinter, _ rune) {
 	if count := m.Table.CompressedCountMinLZ; count > 0 {
 		w.Printf(" minlz: %d", redact.Safe(count))
 	}
+	if count := m.Table.CompressedCountLZ4; count > 0 {
+		w.Printf(" lz4: %d", redact.Safe(count))
+	}
+	if count := m.Table.CompressedCountZstdDict; count > 0 {
+		w.Printf(" zstd-dict: %d", redact.Safe(count))
+	}
+	if count := m.Table.CompressedCountAdaptive; count > 0 {
+		w.Printf(" adaptive: %d", redact.Safe(count))
+	}
 	if count := m.Table.CompressedCountNone; count > 0 {
 		w.Printf(" none: %d", redact.Safe(count))
 	}
@@ -782,6 +827,12 @@ func (m *Metrics) SafeFormat(w redact.SafePrinter, _ rune) {
 		w.Printf(" unknown: %d", redact.Safe(count))
 	}
 	w.Printf("\n")
+	for level := range m.Levels {
+		if c := &m.Levels[level].Compression; !c.IsZero() {
+			w.Printf("Adaptive compression L%d: ratio %.2f %s\n",
+				redact.Safe(level), redact.Safe(c.CompressionRatio()), *c)
+		}
+	}
 	if m.Table.Garbage.PointDeletionsBytesEstimate > 0 || m.Table.Garbage.RangeDeletionsBytesEstimate > 0 {
 		w.Printf("Garbage: point-deletions %s range-deletions %s\n",
 			humanize.Bytes.Uint64(m.Table.Garbage.PointDeletionsBytesEstimate),
@@ -830,6 +881,10 @@ func (m *Metrics) SafeFormat(w redact.SafePrinter, _ rune) {
 
 	w.Printf("Table iters: %d\n", redact.Safe(m.TableIters))
 	w.Printf("Filter utility: %.1f%%\n", redact.Safe(hitRate(m.Filter.Hits, m.Filter.Misses)))
+	if m.Filter.RangeFilterHits+m.Filter.RangeFilterMisses > 0 {
+		w.Printf("Range filter utility: %.1f%%\n",
+			redact.Safe(hitRate(m.Filter.RangeFilterHits, m.Filter.RangeFilterMisses)))
+	}
 	w.Printf("Ingestions: %d  as flushable: %d (%s in %d tables)\n",
 		redact.Safe(m.Ingest.Count),
 		redact.Safe(m.Flush.AsIngestCount),
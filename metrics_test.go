@@ -493,6 +493,38 @@ func TestMetricsWAmpDisableWAL(t *testing.T) {
 	require.NoError(t, d.Close())
 }
 
+func TestMetricsAdaptiveCompression(t *testing.T) {
+	opts := &Options{FS: vfs.NewMem(), FormatMajorVersion: FormatNewest}
+	opts.Levels = make([]LevelOptions, numLevels)
+	for i := range opts.Levels {
+		opts.Levels[i].Compression = func() Compression { return AdaptiveCompression }
+	}
+	opts.Experimental.AdaptiveCompression.SampleInterval = 2
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	// Write two overlapping tables, so that they are compacted rather than
+	// moved into L6.
+	for j := 0; j < 2; j++ {
+		for i := 0; i < 2000; i++ {
+			v := fmt.Sprintf(`{"id":%d,"name":"user%d","active":%t}`, i, i%100, j == 0)
+			require.NoError(t, d.Set([]byte(fmt.Sprintf("key%05d", i)), []byte(v), nil))
+		}
+		require.NoError(t, d.Flush())
+	}
+	require.NoError(t, d.Compact(context.Background(), []byte("a"), []byte("z"), false /* parallelize */))
+
+	m := d.Metrics()
+	for _, level := range []int{0, numLevels - 1} {
+		c := &m.Levels[level].Compression
+		require.False(t, c.IsZero(), "L%d", level)
+		require.Greater(t, c.CompressionRatio(), 0.0)
+		require.Less(t, c.CompressionRatio(), 1.0)
+	}
+	require.Greater(t, m.Table.CompressedCountAdaptive, int64(0))
+	require.Contains(t, m.String(), "Adaptive compression L6")
+	require.NoError(t, d.Close())
+}
+
 // TestMetricsWALBytesWrittenMonotonicity tests that the
 // Metrics.WAL.BytesWritten metric is always nondecreasing.
 // It's a regression test for issue #3505.
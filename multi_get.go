@@ -0,0 +1,174 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cockroachdb/errors"
+)
+
+// maxMultiGetConcurrency bounds the number of iterators a single MultiGet uses
+// to look up keys in parallel.
+const maxMultiGetConcurrency = 8
+
+// MultiGet gets the values for the given keys. The returned slice holds the
+// value of keys[i] at index i, or nil if the DB does not contain keys[i]; a key
+// with an empty value has a non-nil, empty value. The values are owned by the
+// caller.
+//
+// MultiGet observes a consistent view of the DB. It is more efficient than
+// calling Get for each key: the keys are looked up in sorted order, reusing a
+// single iterator stack, so that keys within the same sstable share the
+// sstable's filter and index block lookups. Keys that fall within different
+// sstables of the bottommost level are looked up in parallel, so that data
+// blocks missing from the block cache are read concurrently.
+//
+// It is safe to modify the contents of the keys after MultiGet returns.
+func (d *DB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return d.multiGetInternal(keys, nil /* batch */, nil /* snapshot */)
+}
+
+// MultiGet gets the values for the given keys, as of the snapshot. See
+// DB.MultiGet.
+func (s *Snapshot) MultiGet(keys [][]byte) ([][]byte, error) {
+	if s.db == nil {
+		panic(ErrClosed)
+	}
+	return s.db.multiGetInternal(keys, nil /* batch */, s)
+}
+
+// MultiGet gets the values for the given keys from the batch and the DB. The
+// batch must not be mutated until MultiGet returns. See DB.MultiGet.
+func (b *Batch) MultiGet(keys [][]byte) ([][]byte, error) {
+	if b.index == nil {
+		return nil, ErrNotIndexed
+	}
+	return b.db.multiGetInternal(keys, b, nil /* snapshot */)
+}
+
+func (d *DB) multiGetInternal(keys [][]byte, b *Batch, s *Snapshot) ([][]byte, error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	values := make([][]byte, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return d.cmp(keys[a], keys[b])
+	})
+
+	var newIterOpts newIterOpts
+	if s != nil {
+		newIterOpts.snapshot.seqNum = s.seqNum
+	}
+	iter := d.newIter(context.Background(), b, newIterOpts, &IterOptions{
+		KeyTypes: IterKeyTypePointsOnly,
+		Category: categoryGet,
+	})
+	groups := d.multiGetGroups(iter, keys, order)
+	if len(groups) == 1 {
+		err := iter.multiGet(keys, order, values)
+		return values, errors.CombineErrors(err, iter.Close())
+	}
+
+	// Clone the iterator for each additional worker, so that all workers read
+	// the same version of the DB. The workers claim groups in increasing key
+	// order, so that each iterator only ever seeks forward.
+	iters := make([]*Iterator, min(len(groups), maxMultiGetConcurrency))
+	iters[0] = iter
+	var err error
+	for j := 1; j < len(iters) && err == nil; j++ {
+		iters[j], err = iter.Clone(CloneOptions{})
+	}
+	if err == nil {
+		errs := make([]error, len(iters))
+		var nextGroup atomic.Int64
+		var wg sync.WaitGroup
+		for j := range iters {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					g := int(nextGroup.Add(1) - 1)
+					if g >= len(groups) {
+						return
+					}
+					if errs[j] = iters[j].multiGet(keys, groups[g], values); errs[j] != nil {
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		err = errors.Join(errs...)
+	}
+	for _, iter := range iters {
+		if iter != nil {
+			err = errors.CombineErrors(err, iter.Close())
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// multiGetGroups partitions the sorted keys into groups that may be looked up
+// in parallel. Keys are grouped by the sstable of the bottommost non-empty
+// level containing them, which is where most data, and therefore most block
+// cache misses, reside.
+func (d *DB) multiGetGroups(iter *Iterator, keys [][]byte, order []int) [][]int {
+	v := iter.version
+	if v == nil {
+		v = iter.readState.current
+	}
+	level := numLevels - 1
+	for level > 0 && v.Levels[level].Empty() {
+		level--
+	}
+	var groups [][]int
+	files := v.Levels[level].Iter()
+	f := files.First()
+	start := 0
+	for j, idx := range order {
+		var nextFile bool
+		for f != nil && d.cmp(f.Largest().UserKey, keys[idx]) < 0 {
+			f = files.Next()
+			nextFile = true
+		}
+		if nextFile && j > start {
+			groups = append(groups, order[start:j])
+			start = j
+		}
+	}
+	return append(groups, order[start:])
+}
+
+// multiGet looks up the given keys, which must be in sorted order, storing
+// their values in values.
+func (i *Iterator) multiGet(keys [][]byte, order []int, values [][]byte) error {
+	for _, idx := range order {
+		key := keys[idx]
+		if i.SeekPrefixGE(key) && i.equal(i.Key(), key) {
+			v, err := i.ValueAndErr()
+			if err != nil {
+				return err
+			}
+			values[idx] = append(make([]byte, 0, len(v)), v...)
+		} else if err := i.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
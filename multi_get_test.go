@@ -0,0 +1,97 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/chris124567/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiGet(t *testing.T) {
+	seed := uint64(rand.Int64())
+	t.Logf("seed: %d", seed)
+	rng := rand.New(rand.NewPCG(seed, seed))
+
+	opts := &Options{
+		FS:                          vfs.NewMem(),
+		DisableAutomaticCompactions: true,
+		Levels:                      make([]LevelOptions, numLevels),
+	}
+	for i := range opts.Levels {
+		opts.Levels[i].TargetFileSize = 1 << 10
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	key := func(i int) []byte { return []byte(fmt.Sprintf("key%04d", i)) }
+	const numKeys = 1000
+	// Write the keys twice, so that the compaction rewrites them into many
+	// tables rather than moving a single flushed table.
+	for _, suffix := range []string{"old", ""} {
+		for i := 0; i < numKeys; i += 2 {
+			require.NoError(t, d.Set(key(i), []byte(fmt.Sprintf("v%d%s", i, suffix)), nil))
+		}
+		require.NoError(t, d.Set(key(numKeys), nil, nil))
+		require.NoError(t, d.Flush())
+	}
+	require.NoError(t, d.Compact(context.Background(), key(0), key(numKeys+1), false))
+	require.Greater(t, d.Metrics().Levels[numLevels-1].TablesCount, int64(1))
+
+	// Overwrite, merge and delete some keys, in memtables and in L0.
+	snap := d.NewSnapshot()
+	defer func() { require.NoError(t, snap.Close()) }()
+	for i := 0; i < numKeys; i += 10 {
+		require.NoError(t, d.Merge(key(i), []byte("+m"), nil))
+		require.NoError(t, d.Delete(key(i+2), nil))
+	}
+	require.NoError(t, d.DeleteRange(key(500), key(600), nil))
+	require.NoError(t, d.Flush())
+	for i := 0; i < numKeys; i += 7 {
+		require.NoError(t, d.Set(key(i), []byte("new"), nil))
+	}
+	b := d.NewIndexedBatch()
+	defer func() { require.NoError(t, b.Close()) }()
+	for i := 0; i < numKeys; i += 13 {
+		require.NoError(t, b.Set(key(i), []byte("batch"), nil))
+		require.NoError(t, b.Delete(key(i+1), nil))
+	}
+
+	readers := map[string]interface {
+		Get([]byte) ([]byte, io.Closer, error)
+		MultiGet([][]byte) ([][]byte, error)
+	}{"db": d, "snapshot": snap, "batch": b}
+	for name, r := range readers {
+		t.Run(name, func(t *testing.T) {
+			for iter := 0; iter < 10; iter++ {
+				keys := make([][]byte, rng.IntN(200))
+				for j := range keys {
+					keys[j] = key(rng.IntN(numKeys + 2))
+				}
+				values, err := r.MultiGet(keys)
+				require.NoError(t, err)
+				require.Len(t, values, len(keys))
+				for j := range keys {
+					v, closer, err := r.Get(keys[j])
+					if errors.Is(err, ErrNotFound) {
+						require.Nil(t, values[j], "%s", keys[j])
+						continue
+					}
+					require.NoError(t, err)
+					require.NotNil(t, values[j], "%s", keys[j])
+					require.Equal(t, string(v), string(values[j]), "%s", keys[j])
+					require.NoError(t, closer.Close())
+				}
+			}
+		})
+	}
+}
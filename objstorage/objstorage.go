@@ -228,6 +228,12 @@ type CreateOptions struct {
 	// the provider has shared storage configured.
 	PreferSharedStorage bool
 
+	// ForceSharedStorage causes the object to be created on the shared storage
+	// identified by SharedLocator, regardless of the provider's CreateOnShared
+	// setting. It is used to move existing objects to shared storage.
+	ForceSharedStorage bool
+	SharedLocator      remote.Locator
+
 	// SharedCleanupMethod is used for the object when it is created on shared storage.
 	// The default (zero) value is SharedRefTracking.
 	SharedCleanupMethod SharedCleanupMethod
@@ -308,6 +314,10 @@ type Provider interface {
 	// Cannot be called if shared storage is not configured for the provider.
 	SetCreatorID(creatorID CreatorID) error
 
+	// CreatorID returns the CreatorID set with SetCreatorID, or zero if it was
+	// not set.
+	CreatorID() CreatorID
+
 	// IsSharedForeign returns whether this object is owned by a different node.
 	IsSharedForeign(meta ObjectMetadata) bool
 
@@ -319,6 +319,20 @@ func (p *provider) OpenForReading(
 	return r, nil
 }
 
+// sharedLocator returns the locator of the shared storage on which an object
+// created with the given options is placed, or false if the object is created
+// on local storage.
+func (p *provider) sharedLocator(opts objstorage.CreateOptions) (remote.Locator, bool) {
+	switch {
+	case opts.ForceSharedStorage:
+		return opts.SharedLocator, true
+	case opts.PreferSharedStorage && p.st.Remote.CreateOnShared != remote.CreateOnSharedNone:
+		return p.st.Remote.CreateOnSharedLocator, true
+	default:
+		return "", false
+	}
+}
+
 // Create creates a new object and opens it for writing.
 //
 // The object is not guaranteed to be durable (accessible in case of crashes)
@@ -329,8 +343,8 @@ func (p *provider) Create(
 	fileNum base.DiskFileNum,
 	opts objstorage.CreateOptions,
 ) (w objstorage.Writable, meta objstorage.ObjectMetadata, err error) {
-	if opts.PreferSharedStorage && p.st.Remote.CreateOnShared != remote.CreateOnSharedNone {
-		w, meta, err = p.sharedCreate(ctx, fileType, fileNum, p.st.Remote.CreateOnSharedLocator, opts)
+	if locator, ok := p.sharedLocator(opts); ok {
+		w, meta, err = p.sharedCreate(ctx, fileType, fileNum, locator, opts)
 	} else {
 		var category vfs.DiskWriteCategory
 		if opts.WriteCategory != "" {
@@ -424,8 +438,7 @@ func (p *provider) LinkOrCopyFromLocal(
 	dstFileNum base.DiskFileNum,
 	opts objstorage.CreateOptions,
 ) (objstorage.ObjectMetadata, error) {
-	shared := opts.PreferSharedStorage && p.st.Remote.CreateOnShared != remote.CreateOnSharedNone
-	if !shared && srcFS == p.st.FS {
+	if _, shared := p.sharedLocator(opts); !shared && srcFS == p.st.FS {
 		// Wrap the normal filesystem with one which wraps newly created files with
 		// vfs.NewSyncingFile.
 		fs := vfs.NewSyncingFS(p.st.FS, vfs.SyncingFileOptions{
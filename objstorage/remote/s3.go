@@ -0,0 +1,545 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// S3Options configures a Storage implementation backed by an S3-compatible
+// object store. See NewS3.
+type S3Options struct {
+	// Endpoint is the base URL of the S3 service, for example
+	// "https://s3.us-east-1.amazonaws.com" or "http://localhost:9000".
+	Endpoint string
+	// Region is the region used to sign requests. The default value is
+	// "us-east-1".
+	Region string
+	// Bucket is the name of the bucket that stores the objects.
+	Bucket string
+	// Prefix is prepended to all object names. It is typically used to store
+	// the objects of a store under a "directory" of a shared bucket, in which
+	// case it should end in "/".
+	Prefix string
+	// UsePathStyle addresses the bucket through the URL path
+	// (<endpoint>/<bucket>/<object>) rather than as a subdomain of the endpoint
+	// host (<bucket>.<endpoint>/<object>). Most S3-compatible stores other than
+	// AWS S3 require path-style addressing.
+	UsePathStyle bool
+
+	// AccessKeyID and SecretAccessKey are the credentials used to sign
+	// requests with AWS Signature Version 4. If AccessKeyID is empty, requests
+	// are sent unsigned.
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is the optional session token of temporary credentials.
+	SessionToken string
+
+	// HTTPClient is the client used to send requests. The default value is
+	// http.DefaultClient.
+	HTTPClient *http.Client
+
+	// PartSize is the size of the parts of multipart uploads. Objects no
+	// larger than PartSize are uploaded with a single request. S3 requires
+	// parts other than the last one to be at least 5 MiB. The default value is
+	// 16 MiB.
+	PartSize int
+
+	// MaxRetries is the number of times a request that failed with a
+	// transient error (a network error, a server error or throttling) is
+	// retried. Retries are delayed with jittered exponential backoff, starting
+	// at MinRetryBackoff and growing up to MaxRetryBackoff. The default value
+	// is 5; a negative value disables retries.
+	MaxRetries      int
+	MinRetryBackoff time.Duration
+	MaxRetryBackoff time.Duration
+}
+
+// EnsureDefaults ensures that the default values for all of the options have
+// been initialized. It is valid to call EnsureDefaults on a nil receiver. A
+// non-nil result will always be returned.
+func (o *S3Options) EnsureDefaults() *S3Options {
+	if o == nil {
+		o = &S3Options{}
+	}
+	if o.Region == "" {
+		o.Region = "us-east-1"
+	}
+	if o.HTTPClient == nil {
+		o.HTTPClient = http.DefaultClient
+	}
+	if o.PartSize <= 0 {
+		o.PartSize = 16 << 20
+	}
+	if o.MaxRetries == 0 {
+		o.MaxRetries = 5
+	}
+	if o.MinRetryBackoff <= 0 {
+		o.MinRetryBackoff = 50 * time.Millisecond
+	}
+	if o.MaxRetryBackoff <= 0 {
+		o.MaxRetryBackoff = 5 * time.Second
+	}
+	return o
+}
+
+// NewS3 returns an implementation of the remote.Storage interface backed by
+// a bucket of an S3-compatible object store. Reads of objects are performed
+// using ranged GET requests, and objects are written using multipart uploads
+// once they grow beyond the configured part size.
+func NewS3(opts S3Options) (Storage, error) {
+	opts.EnsureDefaults()
+	if opts.Bucket == "" {
+		return nil, errors.New("pebble: S3 bucket not specified")
+	}
+	endpoint, err := url.Parse(opts.Endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "pebble: invalid S3 endpoint %q", opts.Endpoint)
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, errors.Newf("pebble: invalid S3 endpoint %q", opts.Endpoint)
+	}
+	return &s3Store{opts: opts, endpoint: endpoint}, nil
+}
+
+// s3Store is an implementation of the remote.Storage interface backed by an
+// S3-compatible object store.
+type s3Store struct {
+	opts     S3Options
+	endpoint *url.URL
+}
+
+var _ Storage = (*s3Store)(nil)
+
+// Close is part of the remote.Storage interface.
+func (s *s3Store) Close() error {
+	return nil
+}
+
+// ReadObject is part of the remote.Storage interface.
+func (s *s3Store) ReadObject(
+	ctx context.Context, objName string,
+) (_ ObjectReader, objSize int64, _ error) {
+	size, err := s.size(ctx, objName)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &s3Reader{store: s, objName: objName, size: size}, size, nil
+}
+
+type s3Reader struct {
+	store   *s3Store
+	objName string
+	size    int64
+}
+
+var _ ObjectReader = (*s3Reader)(nil)
+
+// ReadAt is part of the remote.ObjectReader interface.
+func (r *s3Reader) ReadAt(ctx context.Context, p []byte, offset int64) error {
+	if len(p) == 0 {
+		return nil
+	}
+	if offset < 0 || offset+int64(len(p)) > r.size {
+		return io.EOF
+	}
+	header := http.Header{}
+	header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+int64(len(p))-1))
+	return r.store.retry(ctx, func() error {
+		resp, err := r.store.send(ctx, http.MethodGet, r.objName, nil, header, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusPartialContent {
+			// The store ignored the range and returned the entire object.
+			if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+				return err
+			}
+		}
+		_, err = io.ReadFull(resp.Body, p)
+		return err
+	})
+}
+
+// Close is part of the remote.ObjectReader interface.
+func (r *s3Reader) Close() error {
+	r.store = nil
+	return nil
+}
+
+// CreateObject is part of the remote.Storage interface.
+func (s *s3Store) CreateObject(objName string) (io.WriteCloser, error) {
+	return &s3Writer{store: s, objName: objName}, nil
+}
+
+// s3Writer buffers written data, uploading it in a single request when the
+// writer is closed if it doesn't exceed the part size, or with a multipart
+// upload otherwise.
+type s3Writer struct {
+	store   *s3Store
+	objName string
+	buf     []byte
+	// uploadID is set once a multipart upload has been initiated.
+	uploadID string
+	parts    []s3CompletedPart
+	err      error
+}
+
+var _ io.WriteCloser = (*s3Writer)(nil)
+
+func (w *s3Writer) Write(p []byte) (n int, err error) {
+	if w.store == nil {
+		panic("Write after Close")
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	partSize := w.store.opts.PartSize
+	for len(p) > 0 {
+		m := min(len(p), partSize-len(w.buf))
+		w.buf = append(w.buf, p[:m]...)
+		p = p[m:]
+		n += m
+		// Wait for more data before uploading a full part, so that the last
+		// part of a multipart upload is never empty.
+		if len(w.buf) == partSize && len(p) > 0 {
+			if w.err = w.uploadPart(); w.err != nil {
+				return n, w.err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (w *s3Writer) uploadPart() error {
+	ctx := context.Background()
+	if w.uploadID == "" {
+		var res struct {
+			UploadID string `xml:"UploadId"`
+		}
+		query := url.Values{"uploads": {""}}
+		if err := w.store.sendXML(ctx, http.MethodPost, w.objName, query, nil, &res); err != nil {
+			return err
+		}
+		w.uploadID = res.UploadID
+	}
+	partNumber := len(w.parts) + 1
+	query := url.Values{
+		"partNumber": {strconv.Itoa(partNumber)},
+		"uploadId":   {w.uploadID},
+	}
+	var etag string
+	err := w.store.retry(ctx, func() error {
+		resp, err := w.store.send(ctx, http.MethodPut, w.objName, query, nil, w.buf)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		etag = resp.Header.Get("ETag")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	w.parts = append(w.parts, s3CompletedPart{PartNumber: partNumber, ETag: etag})
+	w.buf = w.buf[:0]
+	return nil
+}
+
+func (w *s3Writer) Close() error {
+	if w.store == nil {
+		return w.err
+	}
+	defer func() { w.store = nil }()
+	if w.err == nil {
+		w.err = w.finish()
+	}
+	if w.err != nil && w.uploadID != "" {
+		// Abort the multipart upload so that the store discards the uploaded
+		// parts. This is best effort; the store may be configured to clean up
+		// incomplete uploads too.
+		query := url.Values{"uploadId": {w.uploadID}}
+		if resp, err := w.store.send(context.Background(), http.MethodDelete, w.objName, query, nil, nil); err == nil {
+			resp.Body.Close()
+		}
+	}
+	return w.err
+}
+
+func (w *s3Writer) finish() error {
+	ctx := context.Background()
+	if w.uploadID == "" {
+		return w.store.retry(ctx, func() error {
+			resp, err := w.store.send(ctx, http.MethodPut, w.objName, nil, nil, w.buf)
+			if err != nil {
+				return err
+			}
+			return resp.Body.Close()
+		})
+	}
+	if err := w.uploadPart(); err != nil {
+		return err
+	}
+	body, err := xml.Marshal(s3CompleteMultipartUpload{Parts: w.parts})
+	if err != nil {
+		return err
+	}
+	query := url.Values{"uploadId": {w.uploadID}}
+	var res struct{}
+	return w.store.sendXML(ctx, http.MethodPost, w.objName, query, body, &res)
+}
+
+// List is part of the remote.Storage interface.
+func (s *s3Store) List(prefix, delimiter string) ([]string, error) {
+	ctx := context.Background()
+	fullPrefix := s.opts.Prefix + prefix
+	var res []string
+	var continuationToken string
+	for {
+		query := url.Values{
+			"list-type": {"2"},
+			"prefix":    {fullPrefix},
+		}
+		if delimiter != "" {
+			query.Set("delimiter", delimiter)
+		}
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		var page s3ListBucketResult
+		if err := s.sendXML(ctx, http.MethodGet, "" /* objName */, query, nil, &page); err != nil {
+			return nil, err
+		}
+		for _, c := range page.Contents {
+			res = append(res, strings.TrimPrefix(c.Key, fullPrefix))
+		}
+		for _, p := range page.CommonPrefixes {
+			res = append(res, strings.TrimSuffix(strings.TrimPrefix(p.Prefix, fullPrefix), delimiter))
+		}
+		if !page.IsTruncated {
+			return res, nil
+		}
+		continuationToken = page.NextContinuationToken
+	}
+}
+
+// Delete is part of the remote.Storage interface.
+func (s *s3Store) Delete(objName string) error {
+	ctx := context.Background()
+	return s.retry(ctx, func() error {
+		resp, err := s.send(ctx, http.MethodDelete, objName, nil, nil, nil)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	})
+}
+
+// Size is part of the remote.Storage interface.
+func (s *s3Store) Size(objName string) (int64, error) {
+	return s.size(context.Background(), objName)
+}
+
+func (s *s3Store) size(ctx context.Context, objName string) (size int64, _ error) {
+	err := s.retry(ctx, func() error {
+		resp, err := s.send(ctx, http.MethodHead, objName, nil, nil, nil)
+		if err != nil {
+			return err
+		}
+		size = resp.ContentLength
+		return resp.Body.Close()
+	})
+	if err == nil && size < 0 {
+		return 0, errors.Newf("pebble: S3 object %q has unknown size", objName)
+	}
+	return size, err
+}
+
+// IsNotExistError is part of the remote.Storage interface.
+func (s *s3Store) IsNotExistError(err error) bool {
+	var s3Err *s3Error
+	return errors.As(err, &s3Err) && s3Err.StatusCode == http.StatusNotFound &&
+		// HEAD responses have no body, so the error code is unknown.
+		(s3Err.Code == "" || s3Err.Code == "NoSuchKey")
+}
+
+// s3Error is an error response from the store.
+type s3Error struct {
+	Method     string
+	Key        string
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *s3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("S3 %s %q: %s", e.Method, e.Key, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("S3 %s %q: %s: %s (%d)", e.Method, e.Key, e.Code, e.Message, e.StatusCode)
+}
+
+// retryable returns true if the request that produced the error may succeed
+// if sent again.
+func (e *s3Error) retryable() bool {
+	switch {
+	case e.StatusCode >= 500, e.StatusCode == http.StatusTooManyRequests:
+		return true
+	case e.Code == "RequestTimeout", e.Code == "SlowDown":
+		return true
+	default:
+		return false
+	}
+}
+
+// retry runs fn until it succeeds, fails with an error that isn't transient,
+// or the maximum number of retries is reached.
+func (s *s3Store) retry(ctx context.Context, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= s.opts.MaxRetries || ctx.Err() != nil {
+			return err
+		}
+		var s3Err *s3Error
+		if errors.As(err, &s3Err) && !s3Err.retryable() {
+			return err
+		}
+		// Back off exponentially, with jitter over the latter half of the
+		// interval.
+		backoff := min(s.opts.MinRetryBackoff<<attempt, s.opts.MaxRetryBackoff)
+		backoff = backoff/2 + rand.N(backoff/2+1)
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		}
+	}
+}
+
+// send sends a single request for the named object (or the bucket, if objName
+// is empty). Responses with a non-2xx status code are converted to an
+// *s3Error. On success, the caller must close the response body.
+func (s *s3Store) send(
+	ctx context.Context,
+	method, objName string,
+	query url.Values,
+	header http.Header,
+	body []byte,
+) (*http.Response, error) {
+	key := s.opts.Prefix + objName
+	if objName == "" {
+		key = ""
+	}
+	u := *s.endpoint
+	path := "/" + key
+	if s.opts.UsePathStyle {
+		path = "/" + s.opts.Bucket + path
+	} else {
+		u.Host = s.opts.Bucket + "." + u.Host
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawPath = s3URIEncode(u.Path, false /* encodeSlash */)
+	u.RawQuery = s3CanonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if s.opts.AccessKeyID != "" {
+		s.sign(req, body, time.Now())
+	}
+	resp, err := s.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	s3Err := &s3Error{Method: method, Key: key, StatusCode: resp.StatusCode}
+	var errResp s3ErrorResponse
+	if b, err := io.ReadAll(resp.Body); err == nil && xml.Unmarshal(b, &errResp) == nil {
+		s3Err.Code = errResp.Code
+		s3Err.Message = errResp.Message
+	}
+	return nil, s3Err
+}
+
+// sendXML sends a request, retrying on transient errors, and decodes the XML
+// response body into res.
+func (s *s3Store) sendXML(
+	ctx context.Context, method, objName string, query url.Values, body []byte, res interface{},
+) error {
+	return s.retry(ctx, func() error {
+		resp, err := s.send(ctx, method, objName, query, nil, body)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		// Some requests (notably CompleteMultipartUpload) can fail after the
+		// store has sent a 200 status code, in which case the failure is
+		// reported in the response body.
+		var errResp s3ErrorResponse
+		if xml.Unmarshal(b, &errResp) == nil && errResp.XMLName.Local == "Error" {
+			return &s3Error{
+				Method:     method,
+				Key:        s.opts.Prefix + objName,
+				StatusCode: http.StatusInternalServerError,
+				Code:       errResp.Code,
+				Message:    errResp.Message,
+			}
+		}
+		return xml.Unmarshal(b, res)
+	})
+}
+
+type s3ErrorResponse struct {
+	XMLName xml.Name
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type s3CompleteMultipartUpload struct {
+	XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+	Parts   []s3CompletedPart `xml:"Part"`
+}
+
+type s3ListBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
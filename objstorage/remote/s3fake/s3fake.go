@@ -0,0 +1,410 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+// Package s3fake implements an in-process fake of the subset of the S3 API
+// used by remote.NewS3, so that remote storage can be exercised end to end in
+// tests without network access.
+//
+// The fake serves path-style requests (<url>/<bucket>/<key>) and supports
+// HEAD, ranged GET, PUT and DELETE of objects, multipart uploads and
+// ListObjectsV2. Request signatures are not verified.
+package s3fake
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Server is a fake S3 server listening on a local port.
+type Server struct {
+	srv *httptest.Server
+	mu  struct {
+		sync.Mutex
+		buckets      map[string]map[string][]byte
+		uploads      map[string]*upload
+		nextUploadID int
+		maxKeys      int
+		// injectedFailures is the number of upcoming requests that fail with a
+		// 503 Service Unavailable (SlowDown) error.
+		injectedFailures int
+		requests         int
+	}
+}
+
+type upload struct {
+	bucket, key string
+	parts       map[int][]byte
+}
+
+// NewServer starts a new fake S3 server. The server must be closed with
+// Close.
+func NewServer() *Server {
+	s := &Server{}
+	s.mu.buckets = make(map[string]map[string][]byte)
+	s.mu.uploads = make(map[string]*upload)
+	s.mu.maxKeys = 1000
+	s.srv = httptest.NewServer(s)
+	return s
+}
+
+// URL returns the base URL of the server, for use as the S3 endpoint.
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// CreateBucket creates an empty bucket, if it doesn't exist already. Requests
+// for buckets that don't exist fail with NoSuchBucket.
+func (s *Server) CreateBucket(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.mu.buckets[name]; !ok {
+		s.mu.buckets[name] = make(map[string][]byte)
+	}
+}
+
+// InjectFailures causes the next n requests to fail with a transient error.
+func (s *Server) InjectFailures(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mu.injectedFailures = n
+}
+
+// SetMaxKeys sets the maximum number of results of each page of a listing,
+// unless the request specifies a smaller maximum.
+func (s *Server) SetMaxKeys(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mu.maxKeys = n
+}
+
+// Requests returns the number of requests received by the server, including
+// those failed by InjectFailures.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mu.requests
+}
+
+// Object returns the contents of an object.
+func (s *Server) Object(bucket, key string) (data []byte, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok = s.mu.buckets[bucket][key]
+	return data, ok
+}
+
+// PendingUploads returns the number of multipart uploads that were initiated
+// but neither completed nor aborted.
+func (s *Server) PendingUploads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.mu.uploads)
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mu.requests++
+	if s.mu.injectedFailures > 0 {
+		s.mu.injectedFailures--
+		writeError(w, http.StatusServiceUnavailable, "SlowDown", "injected failure")
+		return
+	}
+
+	bucketName, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	bucket, ok := s.mu.buckets[bucketName]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+	query := r.URL.Query()
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		s.listObjects(w, bucket, query)
+	case key == "":
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "unsupported bucket operation")
+
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		s.mu.nextUploadID++
+		id := strconv.Itoa(s.mu.nextUploadID)
+		s.mu.uploads[id] = &upload{bucket: bucketName, key: key, parts: make(map[int][]byte)}
+		writeXML(w, http.StatusOK, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Bucket   string   `xml:"Bucket"`
+			Key      string   `xml:"Key"`
+			UploadID string   `xml:"UploadId"`
+		}{Bucket: bucketName, Key: key, UploadID: id})
+	case query.Has("uploadId"):
+		s.multipartUpload(w, r.Method, bucket, bucketName, key, query, body)
+
+	case r.Method == http.MethodHead:
+		data, ok := bucket[key]
+		if !ok {
+			// HEAD responses have no body.
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("ETag", etag(data))
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet:
+		data, ok := bucket[key]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		s.getObject(w, r.Header.Get("Range"), data)
+	case r.Method == http.MethodPut:
+		bucket[key] = body
+		w.Header().Set("ETag", etag(body))
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodDelete:
+		delete(bucket, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "unsupported object operation")
+	}
+}
+
+func (s *Server) getObject(w http.ResponseWriter, rangeHeader string, data []byte) {
+	if rangeHeader == "" {
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+		return
+	}
+	start, end, ok := parseRange(rangeHeader, len(data))
+	if !ok {
+		writeError(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange", "The requested range is not satisfiable")
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(end-start))
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, len(data)))
+	w.WriteHeader(http.StatusPartialContent)
+	_, _ = w.Write(data[start:end])
+}
+
+// parseRange parses a single byte range header ("bytes=a-b", "bytes=a-" or
+// "bytes=-n") into the half-open interval [start, end).
+func parseRange(header string, size int) (start, end int, ok bool) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+	first, last, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	if first == "" {
+		n, err := strconv.Atoi(last)
+		if err != nil || n <= 0 {
+			return 0, 0, false
+		}
+		return max(size-n, 0), size, size > 0
+	}
+	start, err := strconv.Atoi(first)
+	if err != nil || start >= size {
+		return 0, 0, false
+	}
+	end = size
+	if last != "" {
+		e, err := strconv.Atoi(last)
+		if err != nil || e < start {
+			return 0, 0, false
+		}
+		end = min(e+1, size)
+	}
+	return start, end, true
+}
+
+func (s *Server) multipartUpload(
+	w http.ResponseWriter,
+	method string,
+	bucket map[string][]byte,
+	bucketName, key string,
+	query map[string][]string,
+	body []byte,
+) {
+	id := query["uploadId"][0]
+	u, ok := s.mu.uploads[id]
+	if !ok || u.bucket != bucketName || u.key != key {
+		writeError(w, http.StatusNotFound, "NoSuchUpload", "The specified upload does not exist")
+		return
+	}
+	switch method {
+	case http.MethodPut:
+		partNumber, err := strconv.Atoi(query["partNumber"][0])
+		if err != nil || partNumber < 1 || partNumber > 10000 {
+			writeError(w, http.StatusBadRequest, "InvalidArgument", "invalid part number")
+			return
+		}
+		u.parts[partNumber] = body
+		w.Header().Set("ETag", etag(body))
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodPost:
+		var req struct {
+			Parts []struct {
+				PartNumber int    `xml:"PartNumber"`
+				ETag       string `xml:"ETag"`
+			} `xml:"Part"`
+		}
+		if err := xml.Unmarshal(body, &req); err != nil || len(req.Parts) == 0 {
+			writeError(w, http.StatusBadRequest, "MalformedXML", "invalid CompleteMultipartUpload request")
+			return
+		}
+		var data []byte
+		for i, p := range req.Parts {
+			part, ok := u.parts[p.PartNumber]
+			if !ok || p.ETag != etag(part) {
+				writeError(w, http.StatusBadRequest, "InvalidPart", "One or more of the specified parts could not be found")
+				return
+			}
+			if i > 0 && p.PartNumber <= req.Parts[i-1].PartNumber {
+				writeError(w, http.StatusBadRequest, "InvalidPartOrder", "The list of parts was not in ascending order")
+				return
+			}
+			data = append(data, part...)
+		}
+		bucket[key] = data
+		delete(s.mu.uploads, id)
+		writeXML(w, http.StatusOK, struct {
+			XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+			Bucket  string   `xml:"Bucket"`
+			Key     string   `xml:"Key"`
+			ETag    string   `xml:"ETag"`
+		}{Bucket: bucketName, Key: key, ETag: etag(data)})
+
+	case http.MethodDelete:
+		delete(s.mu.uploads, id)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "unsupported upload operation")
+	}
+}
+
+func (s *Server) listObjects(w http.ResponseWriter, bucket map[string][]byte, query map[string][]string) {
+	get := func(name string) string {
+		if v := query[name]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	if get("list-type") != "2" {
+		writeError(w, http.StatusNotImplemented, "NotImplemented", "only ListObjectsV2 is supported")
+		return
+	}
+	prefix, delimiter := get("prefix"), get("delimiter")
+	maxKeys := s.mu.maxKeys
+	if v := get("max-keys"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "InvalidArgument", "invalid max-keys")
+			return
+		}
+		maxKeys = min(maxKeys, n)
+	}
+	// The continuation token is the last key or common prefix returned by the
+	// previous page.
+	after := get("continuation-token")
+
+	keys := make([]string, 0, len(bucket))
+	for k := range bucket {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	type content struct {
+		Key  string `xml:"Key"`
+		Size int    `xml:"Size"`
+		ETag string `xml:"ETag"`
+	}
+	type commonPrefix struct {
+		Prefix string `xml:"Prefix"`
+	}
+	res := struct {
+		XMLName               xml.Name       `xml:"ListBucketResult"`
+		Prefix                string         `xml:"Prefix"`
+		Delimiter             string         `xml:"Delimiter,omitempty"`
+		KeyCount              int            `xml:"KeyCount"`
+		MaxKeys               int            `xml:"MaxKeys"`
+		IsTruncated           bool           `xml:"IsTruncated"`
+		NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+		Contents              []content      `xml:"Contents"`
+		CommonPrefixes        []commonPrefix `xml:"CommonPrefixes"`
+	}{Prefix: prefix, Delimiter: delimiter, MaxKeys: maxKeys}
+	var last string
+	for _, k := range keys {
+		entry, isPrefix := k, false
+		if delimiter != "" {
+			if i := strings.Index(k[len(prefix):], delimiter); i >= 0 {
+				entry, isPrefix = k[:len(prefix)+i+len(delimiter)], true
+			}
+		}
+		if entry <= after || (isPrefix && entry == last) {
+			continue
+		}
+		if res.KeyCount == maxKeys {
+			res.IsTruncated = true
+			res.NextContinuationToken = last
+			break
+		}
+		if isPrefix {
+			res.CommonPrefixes = append(res.CommonPrefixes, commonPrefix{Prefix: entry})
+		} else {
+			res.Contents = append(res.Contents, content{Key: k, Size: len(bucket[k]), ETag: etag(bucket[k])})
+		}
+		res.KeyCount++
+		last = entry
+	}
+	writeXML(w, http.StatusOK, res)
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func writeXML(w http.ResponseWriter, status int, v interface{}) {
+	b, err := xml.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, xml.Header)
+	_, _ = w.Write(b)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeXML(w, status, struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string   `xml:"Code"`
+		Message string   `xml:"Message"`
+	}{Code: code, Message: message})
+}
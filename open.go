@@ -30,8 +30,8 @@ import (
 	"github.com/chris124567/pebble/internal/manual"
 	"github.com/chris124567/pebble/objstorage"
 	"github.com/chris124567/pebble/objstorage/objstorageprovider"
-	"github.com/chris124567/pebble/objstorage/remote"
 	"github.com/chris124567/pebble/record"
+	"github.com/chris124567/pebble/sstable/block"
 	"github.com/chris124567/pebble/vfs"
 	"github.com/chris124567/pebble/wal"
 	"github.com/prometheus/client_golang/prometheus"
@@ -160,7 +160,7 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 		// We will initialize the store at the minimum possible format, then upgrade
 		// the format to the desired one. This helps test the format upgrade code.
 		formatVersion = FormatMinSupported
-		if opts.Experimental.CreateOnShared != remote.CreateOnSharedNone {
+		if opts.usesSharedObjects() {
 			formatVersion = FormatMinForSharedObjects
 		}
 		// There is no format version marker file. There are three cases:
@@ -181,7 +181,7 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 			}
 		}()
 	} else {
-		if opts.Experimental.CreateOnShared != remote.CreateOnSharedNone && formatVersion < FormatMinForSharedObjects {
+		if opts.usesSharedObjects() && formatVersion < FormatMinForSharedObjects {
 			return nil, errors.Newf(
 				"pebble: database %q configured with shared objects but written in too old format major version %d",
 				dirname, formatVersion)
@@ -226,6 +226,7 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 		abbreviatedKey:      opts.Comparer.AbbreviatedKey,
 		largeBatchThreshold: (opts.MemTableSize - uint64(memTableEmptySize)) / 2,
 		fileLock:            fileLock,
+		fileKeys:            newFileKeys(opts.keyProvider()),
 		dataDir:             dataDir,
 		closed:              new(atomic.Value),
 		closedCh:            make(chan struct{}),
@@ -233,6 +234,9 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 	d.mu.versions = &versionSet{}
 	d.diskAvailBytes.Store(math.MaxUint64)
 	d.problemSpans.Init(manifest.NumLevels, opts.Comparer.Compare)
+	for i := range d.compressionModels {
+		d.compressionModels[i] = block.NewAdaptiveCompressionModel(opts.Experimental.AdaptiveCompression)
+	}
 
 	defer func() {
 		// If an error or panic occurs during open, attempt to release the manually
@@ -269,11 +273,16 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 	}()
 
 	d.commit = newCommitPipeline(commitEnv{
-		logSeqNum:     &d.mu.versions.logSeqNum,
-		visibleSeqNum: &d.mu.versions.visibleSeqNum,
-		apply:         d.commitApply,
-		write:         d.commitWrite,
+		logSeqNum:      &d.mu.versions.logSeqNum,
+		visibleSeqNum:  &d.mu.versions.visibleSeqNum,
+		apply:          d.commitApply,
+		write:          d.commitWrite,
+		validateTxn:    d.txns.validate,
+		trackWrites:    d.txns.trackBatch,
+		captureChanges: d.changes.capture,
 	})
+	d.txns.init(opts.Comparer.Compare)
+	d.locks.init()
 	d.mu.nextJobID = 1
 	d.mu.mem.nextSize = opts.MemTableSize
 	if d.mu.mem.nextSize > initialMemTableSize {
@@ -339,6 +348,9 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 			dirname, d.objProvider, opts, manifestFileNum, manifestMarker, d.FormatMajorVersion, &d.mu.Mutex); err != nil {
 			return nil, err
 		}
+		if err := d.fileKeys.addVersion(d.mu.versions.currentVersion(), d.mu.versions.blobFiles.Metadatas()); err != nil {
+			return nil, err
+		}
 		if opts.ErrorIfNotPristine {
 			liveFileNums := make(map[base.DiskFileNum]struct{})
 			d.mu.versions.addLiveFileNums(liveFileNums)
@@ -365,6 +377,7 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 		Secondary:            wal.Dir{},
 		MinUnflushedWALNum:   wal.NumWAL(d.mu.versions.minUnflushedLogNum),
 		MaxNumRecyclableLogs: opts.MemTableStopWritesThreshold + 1,
+		KeyProvider:          opts.keyProvider(),
 		NoSyncOnClose:        opts.NoSyncOnClose,
 		BytesPerSync:         opts.WALBytesPerSync,
 		PreallocateSize:      d.walPreallocateSize,
@@ -375,6 +388,11 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 		EventListener:        walEventListenerAdaptor{l: opts.EventListener},
 		WriteWALSyncOffsets:  func() bool { return d.FormatMajorVersion() >= FormatWALSyncChunks },
 	}
+	if walOpts.KeyProvider != nil {
+		// A recycled WAL would be overwritten with a new data key, leaving its
+		// stale tail encrypted with the previous one.
+		walOpts.MaxNumRecyclableLogs = 0
+	}
 	if opts.WALFailover != nil {
 		walOpts.Secondary = opts.WALFailover.Secondary
 		walOpts.FailoverOptions = opts.WALFailover.FailoverOptions
@@ -414,6 +432,7 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 		defer opts.FileCache.Unref()
 	}
 	d.fileCache = opts.FileCache.newHandle(d.cacheHandle, d.objProvider, d.opts.LoggerAndTracer, d.opts.MakeReaderOptions(), d.reportCorruption)
+	d.fileCache.fileKeys = d.fileKeys
 	d.newIters = d.fileCache.newIters
 	d.tableNewRangeKeyIter = tableNewRangeKeyIter(d.newIters)
 
@@ -627,6 +646,21 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 
 	d.maybeScheduleFlush()
 	d.maybeScheduleCompaction()
+	if !d.opts.ReadOnly {
+		var interval time.Duration
+		if t := d.opts.Experimental.Tiering; t != nil {
+			interval = t.CheckInterval
+		}
+		if f := d.opts.Experimental.FIFOCompaction; f != nil && f.TTL > 0 &&
+			d.opts.Experimental.CompactionStyle == CompactionStyleFIFO {
+			if interval == 0 || f.CheckInterval < interval {
+				interval = f.CheckInterval
+			}
+		}
+		if interval > 0 {
+			go d.runCompactionChecks(interval)
+		}
+	}
 
 	// Note: this is a no-op if invariants are disabled or race is enabled.
 	//
@@ -869,7 +903,7 @@ func (d *DB) replayIngestedFlushable(
 func (d *DB) replayWAL(
 	jobID JobID, ll wal.LogicalLog, strictWALTail bool,
 ) (flushableIngests []*ingestedFlushable, maxSeqNum base.SeqNum, err error) {
-	rr := ll.OpenForRead()
+	rr := ll.OpenEncryptedForRead(d.opts.keyProvider())
 	defer func() { _ = rr.Close() }()
 	var (
 		b               Batch
@@ -39,6 +39,7 @@ import (
 	"github.com/chris124567/pebble/objstorage"
 	"github.com/chris124567/pebble/objstorage/objstorageprovider"
 	"github.com/chris124567/pebble/objstorage/remote"
+	"github.com/chris124567/pebble/objstorage/remote/s3fake"
 	"github.com/chris124567/pebble/sstable"
 	"github.com/chris124567/pebble/vfs"
 	"github.com/chris124567/pebble/vfs/atomicfs"
@@ -335,7 +336,7 @@ func TestNewDBFilenames(t *testing.T) {
 			"LOCK",
 			"MANIFEST-000001",
 			"OPTIONS-000003",
-			"marker.format-version.000010.023",
+			"marker.format-version.000011.024",
 			"marker.manifest.000001.MANIFEST-000001",
 		},
 	}
@@ -1548,6 +1549,57 @@ func TestOpenRatchetsNextFileNum(t *testing.T) {
 
 }
 
+func TestOpenSharedS3(t *testing.T) {
+	srv := s3fake.NewServer()
+	defer srv.Close()
+	srv.CreateBucket("pebble")
+	shared, err := remote.NewS3(remote.S3Options{
+		Endpoint:     srv.URL(),
+		Bucket:       "pebble",
+		Prefix:       "db/",
+		UsePathStyle: true,
+		PartSize:     4 << 10,
+	})
+	require.NoError(t, err)
+
+	mem := vfs.NewMem()
+	opts := &Options{FS: mem, Logger: testLogger{t}}
+	opts.Experimental.CreateOnShared = remote.CreateOnSharedAll
+	opts.Experimental.RemoteStorage = remote.MakeSimpleFactory(map[remote.Locator]remote.Storage{
+		"": shared,
+	})
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	require.NoError(t, d.SetCreatorID(1))
+
+	// Write enough data for sstables to be uploaded in several parts.
+	value := bytes.Repeat([]byte("v"), 100)
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("key%04d", i)), value, nil))
+	}
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Compact(context.Background(), []byte("a"), []byte("z"), false))
+	objs, err := shared.List("", "")
+	require.NoError(t, err)
+	require.NotEmpty(t, objs)
+	require.NoError(t, d.Close())
+
+	// Reopen the DB and read the data back from the shared sstables.
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	iter, err := d.NewIter(nil)
+	require.NoError(t, err)
+	n := 0
+	for valid := iter.First(); valid; valid = iter.Next() {
+		require.Equal(t, fmt.Sprintf("key%04d", n), string(iter.Key()))
+		require.Equal(t, value, iter.Value())
+		n++
+	}
+	require.NoError(t, iter.Close())
+	require.Equal(t, 1000, n)
+}
+
 func TestMkdirAllAndSyncParents(t *testing.T) {
 	if filepath.Separator != '/' {
 		t.Skip("skipping due to path separator")
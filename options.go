@@ -18,6 +18,7 @@ import (
 
 	"github.com/cockroachdb/crlib/fifo"
 	"github.com/cockroachdb/errors"
+	"github.com/chris124567/pebble/bloom"
 	"github.com/chris124567/pebble/internal/base"
 	"github.com/chris124567/pebble/internal/cache"
 	"github.com/chris124567/pebble/internal/humanize"
@@ -53,8 +54,21 @@ const (
 	// MinLZCompression is only supported with table formats v6+. Older formats
 	// fall back to snappy.
 	MinLZCompression = block.MinLZCompression
+	// LZ4Compression and ZstdDictionaryCompression are only supported with
+	// table formats v8+. Older formats fall back to snappy.
+	LZ4Compression            = block.LZ4Compression
+	ZstdDictionaryCompression = block.ZstdDictionaryCompression
+	// AdaptiveCompression chooses the compression of each data block, learning
+	// the compression ratio and CPU cost of each setting per level. It is only
+	// supported with table formats v6+. Older formats fall back to snappy. See
+	// Options.Experimental.AdaptiveCompression.
+	AdaptiveCompression = block.AdaptiveCompression
 )
 
+// AdaptiveCompressionOptions exports the block.AdaptiveCompressionOptions
+// type.
+type AdaptiveCompressionOptions = block.AdaptiveCompressionOptions
+
 // FilterType exports the base.FilterType type.
 type FilterType = base.FilterType
 
@@ -375,6 +389,11 @@ type WriteOptions struct {
 	//
 	// The default value is true.
 	Sync bool
+
+	// TTL, if positive, is the time-to-live of the values written by Set with
+	// these options. See Batch.SetWithTTL. It requires TTL to be enabled
+	// through Options.TTL.
+	TTL time.Duration
 }
 
 // Sync specifies the default write options for writes which synchronize to
@@ -433,6 +452,33 @@ type LevelOptions struct {
 	// filters should be preferred except under constrained memory situations.
 	FilterType FilterType
 
+	// PartitionFilters partitions the table filter of each sstable into
+	// several filter blocks, each covering the data blocks of one index block.
+	// A lookup then reads and caches only the filter partition covering the
+	// lookup key, which reduces the block cache footprint of filters for very
+	// large sstables. Partitioned filters are only written with columnar table
+	// formats.
+	//
+	// The default value is false.
+	PartitionFilters bool
+
+	// RangeFilterPrefixLength, if positive, enables a range filter in each
+	// sstable holding the distinct key prefixes of the table truncated to this
+	// many bytes. Iterators with both a lower and upper bound consult the range
+	// filter to skip sstables containing no keys within the bounds, which
+	// speeds up short range scans over empty key ranges. Longer truncation
+	// lengths make the filter more precise, but larger. Range filters are only
+	// written with columnar table formats. Range filter hits and misses are
+	// counted separately from those of FilterPolicy, in
+	// FilterMetrics.RangeFilterHits and RangeFilterMisses.
+	//
+	// The filter orders and searches the prefixes bytewise, so it requires a
+	// Comparer that declares Comparer.BytewisePrefixes; Options.Validate
+	// rejects the option otherwise.
+	//
+	// The default value is 0, which disables range filters.
+	RangeFilterPrefixLength int
+
 	// IndexBlockSize is the target uncompressed size in bytes of each index
 	// block. When the index block size is larger than this target, two-level
 	// indexes are automatically enabled. Setting this option to a large value
@@ -514,7 +560,8 @@ type Options struct {
 	// than' relationship. The same comparison algorithm must be used for reads
 	// and writes over the lifetime of the DB.
 	//
-	// The default value uses the same ordering as bytes.Compare.
+	// The default value uses the same ordering as bytes.Compare. Comparer must
+	// not be set if Keyspaces is set.
 	Comparer *Comparer
 
 	// DebugCheck is invoked, if non-nil, whenever a new version is being
@@ -673,6 +720,37 @@ type Options struct {
 		// compaction will never get triggered.
 		MultiLevelCompactionHeuristic MultiLevelHeuristic
 
+		// CompactionStyle selects the compaction picker. The default,
+		// CompactionStyleLeveled, picks leveled compactions.
+		// CompactionStyleUniversal picks tiered compactions that merge sorted
+		// runs of similar size, configured by UniversalCompaction.
+		// CompactionStyleFIFO drops the oldest tables, as configured by
+		// FIFOCompaction.
+		CompactionStyle CompactionStyle
+
+		// UniversalCompaction configures the compactions picked when
+		// CompactionStyle is CompactionStyleUniversal. If nil, the defaults
+		// are used. See UniversalCompactionOptions.
+		UniversalCompaction *UniversalCompactionOptions
+
+		// FIFOCompaction configures the compactions picked when
+		// CompactionStyle is CompactionStyleFIFO, and must be set in that case.
+		// See FIFOCompactionOptions.
+		FIFOCompaction *FIFOCompactionOptions
+
+		// CompactionPolicy, if set, picks automatic compactions ahead of the
+		// picker selected by CompactionStyle, which picks the compactions
+		// when the policy returns none. See CompactionPolicy.
+		CompactionPolicy CompactionPolicy
+
+		// SpanPriorities assigns compaction priorities and read amplification
+		// targets to spans of user keys. The compaction picker prefers
+		// compactions of the spans exceeding their target, and the priority of
+		// the spans overlapping a waiting compaction is reported to the
+		// CompactionScheduler. The read amplification of each span is exposed
+		// in Metrics.Spans. See SpanPriority.
+		SpanPriorities []SpanPriority
+
 		// EnableColumnarBlocks is used to decide whether to enable writing
 		// TableFormatPebblev5 sstables. This setting is only respected by
 		// FormatColumnarBlocks. In lower format major versions, the
@@ -715,6 +793,29 @@ type Options struct {
 		CreateOnShared        remote.CreateOnSharedStrategy
 		CreateOnSharedLocator remote.Locator
 
+		// Tiering, if set, enables the background migration of existing local
+		// sstables that are cold to remote storage. Unlike CreateOnShared, which
+		// places sstables when they are created, tiering moves sstables after the
+		// fact, based on their age, level and block cache heat. See
+		// TieringOptions.
+		//
+		// Can only be used when RemoteStorage is set (and recognizes
+		// Tiering.Locator).
+		Tiering *TieringOptions
+
+		// RemoteCompactor, if set, is used to offload compactions to a separate
+		// worker, which reads the input sstables from and writes the output
+		// sstables to shared storage. The worker runs the compaction with
+		// RunRemoteCompaction.
+		//
+		// Only compactions whose inputs are all physical sstables on shared
+		// storage, without blob references, and whose outputs would be created
+		// on shared storage are offloaded. Other compactions, flushes, and
+		// compactions whose offload fails are run locally.
+		//
+		// Can only be used when CreateOnShared is set.
+		RemoteCompactor RemoteCompactor
+
 		// CacheSizeBytesBytes is the size of the on-disk block cache for objects
 		// on shared storage in bytes. If it is 0, no cache is used.
 		SecondaryCacheSizeBytes int64
@@ -739,6 +840,13 @@ type Options struct {
 
 		// SpanPolicyFunc is used to determine the SpanPolicy for a key region.
 		SpanPolicyFunc SpanPolicyFunc
+
+		// AdaptiveCompression configures the levels whose Compression is
+		// AdaptiveCompression: the target trade-off between compression ratio
+		// and CPU time, and how often data blocks are sampled to learn the ratio
+		// and CPU cost of each compression setting. The zero value uses the
+		// defaults.
+		AdaptiveCompression AdaptiveCompressionOptions
 	}
 
 	// Filters is a map from filter policy name to filter policy. It is used for
@@ -888,9 +996,39 @@ type Options struct {
 	// Merger defines the associative merge operation to use for merging values
 	// written with {Batch,DB}.Merge.
 	//
-	// The default merger concatenates values.
+	// The default merger concatenates values. Merger must not be set if
+	// Keyspaces is set.
 	Merger *Merger
 
+	// Keyspaces configures logical keyspaces within the DB, each with its own
+	// Comparer, Merger and LevelOptions. See KeyspaceOptions. When set, the
+	// Comparer and Merger of the DB are composites that dispatch to the
+	// keyspace of each key, and keys should be read and written through the
+	// handles returned by DB.Keyspace.
+	//
+	// The set of keyspaces is part of the DB's comparer name, and so it cannot
+	// change over the lifetime of the DB.
+	Keyspaces []KeyspaceOptions
+
+	// TTL, if set, enables values with a time-to-live, written with
+	// Batch.SetWithTTL or with WriteOptions.TTL. Reads through iterators and Get
+	// do not observe expired values, and compactions drop them. See
+	// TTLOptions.
+	//
+	// Enabling TTL changes the format in which values are stored, and so it
+	// must be set when the DB is created and cannot be changed afterwards.
+	// Values in ingested sstables, and values read through external iterators
+	// or DB.ScanInternal, are in the stored format.
+	TTL *TTLOptions
+
+	// CompactionFilter, if set, is consulted by compactions to remove keys or
+	// change their values. See CompactionFilter.
+	CompactionFilter CompactionFilter
+
+	// Encryption, if set, enables the encryption of the DB's files at rest. See
+	// EncryptionOptions.
+	Encryption *EncryptionOptions
+
 	// CompactionConcurrencyRange returns a [lower, upper] range for the number of
 	// compactions Pebble runs in parallel (with the caveats below), not including
 	// download compactions (which have a separate limit specified by
@@ -1109,6 +1247,10 @@ type Options struct {
 		// obsolete file deletion (to make events deterministic).
 		testingAlwaysWaitForCleanup bool
 
+		// keyspaces holds the resolved Keyspaces. It is populated by
+		// EnsureDefaults.
+		keyspaces *keyspaceSet
+
 		// fsCloser holds a closer that should be invoked after a DB using these
 		// Options is closed. This is used to automatically stop the
 		// long-running goroutine associated with the disk-health-checking FS.
@@ -1154,8 +1296,137 @@ type SpanPolicy struct {
 	// ValueStoragePolicy is a hint used to determine where to store the values
 	// for KVs.
 	ValueStoragePolicy ValueStoragePolicy
+
+	// Compression, if not DefaultCompression, overrides the compression of the
+	// sstables written for the span.
+	Compression Compression
+
+	// CompressionLevel, if non-zero, is the compression level of the data
+	// blocks of the sstables written for the span, for compression algorithms
+	// that support levels (only Zstandard). For example, an archival span can
+	// use ZstdCompression with a CompressionLevel of 19.
+	CompressionLevel int
+
+	// BlockSize, if non-zero, overrides the target uncompressed size in bytes
+	// of the data blocks of the sstables written for the span.
+	BlockSize int
+
+	// BloomFilterBitsPerKey, if non-zero, overrides the filter policy of the
+	// sstables written for the span. A positive value writes bloom filters with
+	// the given number of bits per key, and a negative value disables filters.
+	BloomFilterBitsPerKey int
+
+	// TableStoragePolicy determines whether the sstables written for the span
+	// are created on shared storage.
+	TableStoragePolicy TableStoragePolicy
+}
+
+// applyWriterOptions overrides the writer options of an sstable written for
+// the span with the policy.
+func (p *SpanPolicy) applyWriterOptions(writerOpts *sstable.WriterOptions) {
+	if p.Compression != DefaultCompression {
+		writerOpts.Compression = p.Compression
+	}
+	if p.CompressionLevel != 0 {
+		writerOpts.CompressionLevel = p.CompressionLevel
+	}
+	if p.BlockSize > 0 {
+		writerOpts.BlockSize = p.BlockSize
+	}
+	switch {
+	case p.BloomFilterBitsPerKey > 0:
+		writerOpts.FilterPolicy = bloom.FilterPolicy(p.BloomFilterBitsPerKey)
+		writerOpts.FilterType = TableFilter
+	case p.BloomFilterBitsPerKey < 0:
+		writerOpts.FilterPolicy = nil
+	}
+	if p.DisableValueSeparationBySuffix {
+		writerOpts.DisableValueBlocks = true
+	}
 }
 
+// preferSharedStorage returns whether the sstables written for the span to the
+// given level should be created on shared storage.
+func (p *SpanPolicy) preferSharedStorage(strategy remote.CreateOnSharedStrategy, level int) bool {
+	switch p.TableStoragePolicy {
+	case TableStorageLocal:
+		return false
+	case TableStorageShared:
+		return strategy != remote.CreateOnSharedNone
+	default:
+		return remote.ShouldCreateShared(strategy, level)
+	}
+}
+
+// spanPreferSharedStorage returns whether the sstables holding the keys of the
+// bounds in the given level should be created on shared storage, according to
+// the span policies overlapping the bounds. If the policies disagree, ok is
+// false and the placement dictated by CreateOnShared for the level is
+// returned; a table covering the bounds must then be rewritten for each span
+// to honor the policies.
+func (o *Options) spanPreferSharedStorage(
+	bounds base.UserKeyBounds, level int,
+) (preferShared, ok bool, _ error) {
+	strategy := o.Experimental.CreateOnShared
+	if strategy == remote.CreateOnSharedNone {
+		return false, true, nil
+	}
+	first, ok := true, true
+	err := o.forEachSpanPolicy(bounds, func(policy *SpanPolicy) bool {
+		p := policy.preferSharedStorage(strategy, level)
+		if !first && p != preferShared {
+			ok = false
+			return false
+		}
+		first, preferShared = false, p
+		return true
+	})
+	if err != nil {
+		return false, false, err
+	}
+	if !ok {
+		return remote.ShouldCreateShared(strategy, level), false, nil
+	}
+	return preferShared, true, nil
+}
+
+// forEachSpanPolicy calls fn with each of the span policies overlapping the
+// bounds, in key order, until fn returns false.
+func (o *Options) forEachSpanPolicy(bounds base.UserKeyBounds, fn func(*SpanPolicy) bool) error {
+	cmp := o.Comparer.Compare
+	for key := bounds.Start; ; {
+		policy, endKey, err := o.Experimental.SpanPolicyFunc(key)
+		if err != nil {
+			return err
+		}
+		if !fn(&policy) || len(endKey) == 0 || cmp(endKey, key) <= 0 || !bounds.End.IsUpperBoundFor(cmp, endKey) {
+			return nil
+		}
+		key = endKey
+	}
+}
+
+// TableStoragePolicy determines whether sstables are created on shared
+// storage.
+type TableStoragePolicy uint8
+
+const (
+	// TableStorageDefault is the default value; Pebble will respect
+	// Options.Experimental.CreateOnShared.
+	TableStorageDefault TableStoragePolicy = iota
+
+	// TableStorageLocal indicates sstables should be created on local storage,
+	// regardless of their level. Local sstables in the levels that would
+	// otherwise be shared prevent skip-shared iteration (see
+	// DB.ScanInternal).
+	TableStorageLocal
+
+	// TableStorageShared indicates sstables should be created on shared
+	// storage, regardless of their level. It is only respected if
+	// Options.Experimental.CreateOnShared is not remote.CreateOnSharedNone.
+	TableStorageShared
+)
+
 // ValueStoragePolicy is a hint used to determine where to store the values for
 // KVs.
 type ValueStoragePolicy uint8
@@ -1243,6 +1514,18 @@ func (o *Options) EnsureDefaults() {
 	if o.Cache == nil && o.CacheSize == 0 {
 		o.CacheSize = cacheDefaultSize
 	}
+	if len(o.Keyspaces) > 0 && o.private.keyspaces == nil {
+		// Errors are reported by Validate.
+		if s, err := makeKeyspaceSet(o.Keyspaces); err == nil {
+			o.private.keyspaces = s
+			if o.Comparer == nil {
+				o.Comparer = s.comparer
+			}
+			if o.Merger == nil {
+				o.Merger = s.merger
+			}
+		}
+	}
 	o.Comparer = o.Comparer.EnsureDefaults()
 
 	if o.BytesPerSync <= 0 {
@@ -1360,6 +1643,21 @@ func (o *Options) EnsureDefaults() {
 	if o.Merger == nil {
 		o.Merger = DefaultMerger
 	}
+	if o.TTL != nil {
+		o.TTL.EnsureDefaults()
+	}
+	if o.Experimental.Tiering != nil {
+		o.Experimental.Tiering.EnsureDefaults()
+	}
+	if o.Experimental.CompactionStyle == CompactionStyleUniversal && o.Experimental.UniversalCompaction == nil {
+		o.Experimental.UniversalCompaction = &UniversalCompactionOptions{}
+	}
+	if o.Experimental.UniversalCompaction != nil {
+		o.Experimental.UniversalCompaction.EnsureDefaults()
+	}
+	if o.Experimental.FIFOCompaction != nil {
+		o.Experimental.FIFOCompaction.EnsureDefaults()
+	}
 	if o.CompactionConcurrencyRange == nil {
 		o.CompactionConcurrencyRange = func() (int, int) { return 1, 1 }
 	}
@@ -1372,7 +1670,7 @@ func (o *Options) EnsureDefaults() {
 
 	if o.FormatMajorVersion == FormatDefault {
 		o.FormatMajorVersion = FormatMinSupported
-		if o.Experimental.CreateOnShared != remote.CreateOnSharedNone {
+		if o.usesSharedObjects() {
 			o.FormatMajorVersion = FormatMinForSharedObjects
 		}
 	}
@@ -1464,6 +1762,33 @@ func (o *Options) initMaps() {
 			}
 		}
 	}
+	// The filter policies below are added to a copy of the Filters map, which
+	// may be shared with other Options.
+	copied := false
+	addFilter := func(fp FilterPolicy) {
+		if _, ok := o.Filters[fp.Name()]; ok {
+			return
+		}
+		if !copied {
+			filters := make(map[string]FilterPolicy, len(o.Filters)+1)
+			for name, fp := range o.Filters {
+				filters[name] = fp
+			}
+			o.Filters, copied = filters, true
+		}
+		o.Filters[fp.Name()] = fp
+	}
+	// A SpanPolicy may write bloom filters at any level. The bits per key of a
+	// bloom filter are not needed to read it, so any bloom.FilterPolicy reads
+	// them all.
+	addFilter(bloom.FilterPolicy(10))
+	for i := range o.Keyspaces {
+		for j := range o.Keyspaces[i].Levels {
+			if fp := o.Keyspaces[i].Levels[j].FilterPolicy; fp != nil {
+				addFilter(fp)
+			}
+		}
+	}
 }
 
 // Level returns the LevelOptions for the specified level.
@@ -1514,6 +1839,9 @@ func (o *Options) String() string {
 	fmt.Fprintf(&buf, "  compaction_garbage_fraction_for_max_concurrency=%.2f\n",
 		o.Experimental.CompactionGarbageFractionForMaxConcurrency())
 	fmt.Fprintf(&buf, "  comparer=%s\n", o.Comparer.Name)
+	if o.Experimental.CompactionStyle != CompactionStyleLeveled {
+		fmt.Fprintf(&buf, "  compaction_style=%s\n", o.Experimental.CompactionStyle)
+	}
 	fmt.Fprintf(&buf, "  disable_wal=%t\n", o.DisableWAL)
 	if o.Experimental.DisableIngestAsFlushable != nil && o.Experimental.DisableIngestAsFlushable() {
 		fmt.Fprintf(&buf, "  disable_ingest_as_flushable=%t\n", true)
@@ -1560,6 +1888,20 @@ func (o *Options) String() string {
 	// older version reads the options.
 	fmt.Fprintf(&buf, "  strict_wal_tail=%t\n", true)
 	fmt.Fprintf(&buf, "  table_cache_shards=%d\n", o.Experimental.FileCacheShards)
+	if o.TTL != nil {
+		fmt.Fprintf(&buf, "  ttl_compaction_threshold=%f\n", o.TTL.CompactionThreshold)
+	}
+	if f := o.Experimental.FIFOCompaction; f != nil {
+		fmt.Fprintf(&buf, "  fifo_intra_l0=%t\n", f.IntraL0)
+		fmt.Fprintf(&buf, "  fifo_max_size=%d\n", f.MaxSize)
+		fmt.Fprintf(&buf, "  fifo_ttl=%s\n", f.TTL)
+	}
+	if u := o.Experimental.UniversalCompaction; u != nil {
+		fmt.Fprintf(&buf, "  universal_max_merge_width=%d\n", u.MaxMergeWidth)
+		fmt.Fprintf(&buf, "  universal_max_size_amplification_percent=%d\n", u.MaxSizeAmplificationPercent)
+		fmt.Fprintf(&buf, "  universal_min_merge_width=%d\n", u.MinMergeWidth)
+		fmt.Fprintf(&buf, "  universal_size_ratio=%d\n", u.SizeRatio)
+	}
 	fmt.Fprintf(&buf, "  validate_on_ingest=%t\n", o.Experimental.ValidateOnIngest)
 	fmt.Fprintf(&buf, "  wal_dir=%s\n", o.WALDir)
 	fmt.Fprintf(&buf, "  wal_bytes_per_sync=%d\n", o.WALBytesPerSync)
@@ -1614,6 +1956,12 @@ func (o *Options) String() string {
 		fmt.Fprintf(&buf, "  compression=%s\n", resolveDefaultCompression(l.Compression()))
 		fmt.Fprintf(&buf, "  filter_policy=%s\n", filterPolicyName(l.FilterPolicy))
 		fmt.Fprintf(&buf, "  filter_type=%s\n", l.FilterType)
+		if l.PartitionFilters {
+			fmt.Fprintf(&buf, "  partition_filters=%t\n", true)
+		}
+		if l.RangeFilterPrefixLength > 0 {
+			fmt.Fprintf(&buf, "  range_filter_prefix_length=%d\n", l.RangeFilterPrefixLength)
+		}
 		fmt.Fprintf(&buf, "  index_block_size=%d\n", l.IndexBlockSize)
 		fmt.Fprintf(&buf, "  target_file_size=%d\n", l.TargetFileSize)
 	}
@@ -1747,6 +2095,19 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 			}
 		}
 
+		fifoCompaction := func() *FIFOCompactionOptions {
+			if o.Experimental.FIFOCompaction == nil {
+				o.Experimental.FIFOCompaction = &FIFOCompactionOptions{}
+			}
+			return o.Experimental.FIFOCompaction
+		}
+		universalCompaction := func() *UniversalCompactionOptions {
+			if o.Experimental.UniversalCompaction == nil {
+				o.Experimental.UniversalCompaction = &UniversalCompactionOptions{}
+			}
+			return o.Experimental.UniversalCompaction
+		}
+
 		switch {
 		case section == "Version":
 			switch key {
@@ -1784,6 +2145,17 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				if comparer != nil {
 					o.Comparer = comparer
 				}
+			case "compaction_style":
+				switch value {
+				case "leveled":
+					o.Experimental.CompactionStyle = CompactionStyleLeveled
+				case "universal":
+					o.Experimental.CompactionStyle = CompactionStyleUniversal
+				case "fifo":
+					o.Experimental.CompactionStyle = CompactionStyleFIFO
+				default:
+					err = errors.Newf("unrecognized compaction style: %s", value)
+				}
 			case "compaction_debt_concurrency":
 				o.Experimental.CompactionDebtConcurrency, err = strconv.ParseUint(value, 10, 64)
 			case "compaction_garbage_fraction_for_max_concurrency":
@@ -1816,6 +2188,12 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				if v, err = strconv.ParseBool(value); err == nil {
 					o.Experimental.EnableColumnarBlocks = func() bool { return v }
 				}
+			case "fifo_intra_l0":
+				fifoCompaction().IntraL0, err = strconv.ParseBool(value)
+			case "fifo_max_size":
+				fifoCompaction().MaxSize, err = strconv.ParseUint(value, 10, 64)
+			case "fifo_ttl":
+				fifoCompaction().TTL, err = time.ParseDuration(value)
 			case "flush_delay_delete_range":
 				o.FlushDelayDeleteRange, err = time.ParseDuration(value)
 			case "flush_delay_range_key":
@@ -1976,6 +2354,9 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				o.Experimental.TombstoneDenseCompactionThreshold, err = strconv.ParseFloat(value, 64)
 			case "table_cache_shards":
 				o.Experimental.FileCacheShards, err = strconv.Atoi(value)
+			case "ttl_compaction_threshold":
+				o.TTL = &TTLOptions{}
+				o.TTL.CompactionThreshold, err = strconv.ParseFloat(value, 64)
 			case "table_format":
 				switch value {
 				case "leveldb":
@@ -1985,6 +2366,14 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				}
 			case "table_property_collectors":
 				// No longer implemented; ignore.
+			case "universal_max_merge_width":
+				universalCompaction().MaxMergeWidth, err = strconv.Atoi(value)
+			case "universal_max_size_amplification_percent":
+				universalCompaction().MaxSizeAmplificationPercent, err = strconv.Atoi(value)
+			case "universal_min_merge_width":
+				universalCompaction().MinMergeWidth, err = strconv.Atoi(value)
+			case "universal_size_ratio":
+				universalCompaction().SizeRatio, err = strconv.Atoi(value)
 			case "validate_on_ingest":
 				o.Experimental.ValidateOnIngest, err = strconv.ParseBool(value)
 			case "wal_dir":
@@ -2097,6 +2486,12 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 					l.Compression = func() Compression { return ZstdCompression }
 				case "MinLZ":
 					l.Compression = func() Compression { return MinLZCompression }
+				case "LZ4":
+					l.Compression = func() Compression { return LZ4Compression }
+				case "ZSTDDict":
+					l.Compression = func() Compression { return ZstdDictionaryCompression }
+				case "Adaptive":
+					l.Compression = func() Compression { return AdaptiveCompression }
 				default:
 					return errors.Errorf("pebble: unknown compression: %q", errors.Safe(value))
 				}
@@ -2113,6 +2508,10 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				}
 			case "index_block_size":
 				l.IndexBlockSize, err = strconv.Atoi(value)
+			case "partition_filters":
+				l.PartitionFilters, err = strconv.ParseBool(value)
+			case "range_filter_prefix_length":
+				l.RangeFilterPrefixLength, err = strconv.Atoi(value)
 			case "target_file_size":
 				l.TargetFileSize, err = strconv.ParseInt(value, 10, 64)
 			default:
@@ -2174,8 +2573,14 @@ func (e ErrMissingWALRecoveryDir) Error() string {
 // This function only looks at specific keys and does not error out if the
 // options are newer and contain unknown keys.
 func (o *Options) CheckCompatibility(previousOptions string) error {
+	var ttl bool
 	visitKeyValue := func(i, j int, section, key, value string) error {
 		switch section + "." + key {
+		case "Options.ttl_compaction_threshold":
+			ttl = true
+			if o.TTL == nil {
+				return errors.Errorf("pebble: TTL is enabled in the options file but not in the options")
+			}
 		case "Options.comparer":
 			if value != o.Comparer.Name {
 				return errors.Errorf("pebble: comparer name from file %q != comparer name from options %q",
@@ -2205,7 +2610,13 @@ func (o *Options) CheckCompatibility(previousOptions string) error {
 		}
 		return nil
 	}
-	return parseOptions(previousOptions, parseOptionsFuncs{visitKeyValue: visitKeyValue})
+	if err := parseOptions(previousOptions, parseOptionsFuncs{visitKeyValue: visitKeyValue}); err != nil {
+		return err
+	}
+	if o.TTL != nil && !ttl {
+		return errors.Errorf("pebble: TTL is enabled in the options but not in the options file")
+	}
+	return nil
 }
 
 // Validate verifies that the options are mutually consistent. For example,
@@ -2224,6 +2635,34 @@ func (o *Options) Validate() error {
 		fmt.Fprintf(&buf, "L0StopWritesThreshold (%d) must be >= L0CompactionThreshold (%d)\n",
 			o.L0StopWritesThreshold, o.L0CompactionThreshold)
 	}
+	switch o.Experimental.CompactionStyle {
+	case CompactionStyleLeveled, CompactionStyleUniversal:
+	case CompactionStyleFIFO:
+		if f := o.Experimental.FIFOCompaction; f == nil || (f.MaxSize == 0 && f.TTL == 0) {
+			fmt.Fprintf(&buf, "FIFOCompaction.MaxSize or FIFOCompaction.TTL must be set when CompactionStyle is fifo\n")
+		}
+	default:
+		fmt.Fprintf(&buf, "unknown CompactionStyle (%s)\n", o.Experimental.CompactionStyle)
+	}
+	if u := o.Experimental.UniversalCompaction; u != nil && u.MaxMergeWidth > 0 && u.MaxMergeWidth < u.MinMergeWidth {
+		fmt.Fprintf(&buf, "UniversalCompaction.MaxMergeWidth (%d) must be >= MinMergeWidth (%d)\n",
+			u.MaxMergeWidth, u.MinMergeWidth)
+	}
+	for i := range o.Levels {
+		if o.Levels[i].RangeFilterPrefixLength > 0 && !o.Comparer.BytewisePrefixes {
+			fmt.Fprintf(&buf, "Levels[%d].RangeFilterPrefixLength requires a Comparer that declares BytewisePrefixes (%q does not)\n",
+				i, o.Comparer.Name)
+		}
+	}
+	for _, s := range o.Experimental.SpanPriorities {
+		if o.Comparer.Compare(s.Start, s.End) >= 0 {
+			fmt.Fprintf(&buf, "SpanPriorities span [%s, %s) must not be empty\n",
+				o.Comparer.FormatKey(s.Start), o.Comparer.FormatKey(s.End))
+		}
+		if s.MaxReadAmp < 0 {
+			fmt.Fprintf(&buf, "SpanPriorities MaxReadAmp (%d) must be >= 0\n", s.MaxReadAmp)
+		}
+	}
 	if uint64(o.MemTableSize) >= maxMemTableSize {
 		fmt.Fprintf(&buf, "MemTableSize (%s) must be < %s\n",
 			humanize.Bytes.Uint64(uint64(o.MemTableSize)), humanize.Bytes.Uint64(maxMemTableSize))
@@ -2240,6 +2679,43 @@ func (o *Options) Validate() error {
 		fmt.Fprintf(&buf, "FormatMajorVersion (%d) when CreateOnShared is set must be at least %d\n",
 			o.FormatMajorVersion, FormatMinForSharedObjects)
 	}
+	if t := o.Experimental.Tiering; t != nil {
+		if o.Experimental.RemoteStorage == nil {
+			fmt.Fprintf(&buf, "Tiering requires RemoteStorage to be set\n")
+		}
+		if o.FormatMajorVersion < FormatMinForSharedObjects {
+			fmt.Fprintf(&buf, "FormatMajorVersion (%d) when Tiering is set must be at least %d\n",
+				o.FormatMajorVersion, FormatMinForSharedObjects)
+		}
+		if t.MinLevel < 1 || t.MinLevel >= numLevels {
+			fmt.Fprintf(&buf, "Tiering.MinLevel (%d) must be between 1 and %d\n", t.MinLevel, numLevels-1)
+		}
+	}
+	if o.Experimental.RemoteCompactor != nil && o.Experimental.CreateOnShared == remote.CreateOnSharedNone {
+		fmt.Fprintf(&buf, "RemoteCompactor requires CreateOnShared to be set\n")
+	}
+	if o.Encryption != nil {
+		if o.Encryption.KeyProvider == nil {
+			fmt.Fprintf(&buf, "Encryption.KeyProvider must be set when Encryption is set\n")
+		}
+		// The data keys of shared objects are recorded in the DB's MANIFEST,
+		// where other DBs sharing the objects cannot read them.
+		if o.Experimental.CreateOnShared != remote.CreateOnSharedNone {
+			fmt.Fprintf(&buf, "Encryption is incompatible with CreateOnShared\n")
+		}
+		if o.Experimental.Tiering != nil {
+			fmt.Fprintf(&buf, "Encryption is incompatible with Tiering\n")
+		}
+	}
+	if len(o.Keyspaces) > 0 {
+		if o.private.keyspaces == nil {
+			if _, err := makeKeyspaceSet(o.Keyspaces); err != nil {
+				fmt.Fprintf(&buf, "%s\n", err)
+			}
+		} else if o.Comparer != o.private.keyspaces.comparer || o.Merger != o.private.keyspaces.merger {
+			fmt.Fprintf(&buf, "Comparer and Merger must not be set when Keyspaces is set\n")
+		}
+	}
 	if len(o.KeySchemas) > 0 {
 		if o.KeySchema == "" {
 			fmt.Fprintf(&buf, "KeySchemas is set but KeySchema is not\n")
@@ -2294,6 +2770,8 @@ func (o *Options) MakeWriterOptions(level int, format sstable.TableFormat) sstab
 	writerOpts.Compression = resolveDefaultCompression(levelOpts.Compression())
 	writerOpts.FilterPolicy = levelOpts.FilterPolicy
 	writerOpts.FilterType = levelOpts.FilterType
+	writerOpts.PartitionFilters = levelOpts.PartitionFilters
+	writerOpts.RangeFilterPrefixLength = levelOpts.RangeFilterPrefixLength
 	writerOpts.IndexBlockSize = levelOpts.IndexBlockSize
 	if o.KeySchema != "" {
 		var ok bool
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/cockroachdb/errors"
+	"github.com/chris124567/pebble/bloom"
 	"github.com/chris124567/pebble/internal/base"
 	"github.com/chris124567/pebble/internal/testkeys"
 	"github.com/chris124567/pebble/vfs"
@@ -412,6 +413,37 @@ func TestOptionsValidate(t *testing.T) {
 	}
 }
 
+func TestOptionsValidateRangeFilter(t *testing.T) {
+	notBytewise := *DefaultComparer
+	notBytewise.Name = "not-bytewise"
+	notBytewise.BytewisePrefixes = false
+
+	opts := &Options{Comparer: &notBytewise}
+	opts.Levels = []LevelOptions{{RangeFilterPrefixLength: 4}}
+	opts.EnsureDefaults()
+	require.Regexp(t, `Levels\[0\]\.RangeFilterPrefixLength requires a Comparer that declares BytewisePrefixes`,
+		opts.Validate())
+	opts.Comparer = DefaultComparer
+	require.NoError(t, opts.Validate())
+
+	opts = &Options{
+		Keyspaces: []KeyspaceOptions{
+			{ID: 1, Name: "a", Comparer: DefaultComparer},
+			{ID: 2, Name: "b", Comparer: &notBytewise, Levels: []LevelOptions{{RangeFilterPrefixLength: 4}}},
+		},
+	}
+	opts.EnsureDefaults()
+	require.Regexp(t, `keyspace "b" sets RangeFilterPrefixLength`, opts.Validate())
+}
+
+func TestOptionsFiltersNotModified(t *testing.T) {
+	filters := map[string]FilterPolicy{}
+	opts := &Options{Filters: filters}
+	opts.EnsureDefaults()
+	require.Contains(t, opts.Filters, bloom.FilterPolicy(10).Name())
+	require.Empty(t, filters)
+}
+
 func TestKeyCategories(t *testing.T) {
 	kc := MakeUserKeyCategories(base.DefaultComparer.Compare, []UserKeyCategory{
 		{Name: "b", UpperBound: []byte("b")},
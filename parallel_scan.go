@@ -0,0 +1,176 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+)
+
+// parallelScanContextCheckInterval is the number of keys a ParallelScan
+// partition visits between checks for cancellation.
+const parallelScanContextCheckInterval = 128
+
+// ParallelScan scans the point keys within span concurrently, splitting the
+// span into at most n partitions. A nil span.Start or span.End leaves the span
+// unbounded at that end.
+//
+// The partitions are split on sstable boundaries such that each holds roughly
+// the same number of bytes of sstables, and are scanned by iterators that all
+// observe the same consistent view of the DB. For each partition, fn is called
+// for every key in the partition in key order, along with the index of the
+// partition; partitions are numbered in key order. fn is called concurrently
+// for different partitions. The key and value are only valid for the duration
+// of the call.
+//
+// If fn returns an error, or the context is canceled, ParallelScan stops all
+// partitions and returns the error.
+func (d *DB) ParallelScan(
+	ctx context.Context, span KeyRange, n int, fn func(partition int, key, value []byte) error,
+) error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if n <= 0 {
+		return errors.Errorf("pebble: invalid ParallelScan partition count %d", n)
+	}
+	if span.Start != nil && span.End != nil && d.cmp(span.Start, span.End) >= 0 {
+		return errors.New("pebble: invalid ParallelScan span (start >= end)")
+	}
+	iter := d.newIter(ctx, nil /* batch */, newIterOpts{}, &IterOptions{
+		LowerBound: span.Start,
+		UpperBound: span.End,
+		KeyTypes:   IterKeyTypePointsOnly,
+	})
+	v := iter.version
+	if v == nil {
+		v = iter.readState.current
+	}
+	splits := d.parallelScanSplits(v, span, n)
+
+	// Clone the iterator for every additional partition, so that all
+	// partitions observe the same version of the DB.
+	iters := make([]*Iterator, len(splits)+1)
+	iters[0] = iter
+	var err error
+	for i := 1; i < len(iters) && err == nil; i++ {
+		iters[i], err = iter.Clone(CloneOptions{
+			IterOptions: &IterOptions{KeyTypes: IterKeyTypePointsOnly},
+		})
+	}
+	if err == nil {
+		for i, iter := range iters {
+			lower, upper := span.Start, span.End
+			if i > 0 {
+				lower = splits[i-1]
+			}
+			if i < len(splits) {
+				upper = splits[i]
+			}
+			iter.SetBounds(lower, upper)
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		errs := make([]error, len(iters))
+		var wg sync.WaitGroup
+		for i, iter := range iters {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if errs[i] = scanPartition(ctx, iter, i, fn); errs[i] != nil {
+					cancel()
+				}
+			}()
+		}
+		wg.Wait()
+		// Report the error that caused the scan to stop, rather than the
+		// context cancellation it caused in other partitions.
+		for i := range errs {
+			if errs[i] != nil && (err == nil || errors.Is(err, context.Canceled)) {
+				err = errs[i]
+			}
+		}
+	}
+	for _, iter := range iters {
+		if iter != nil {
+			err = errors.CombineErrors(err, iter.Close())
+		}
+	}
+	return err
+}
+
+// scanPartition calls fn for every key of the iterator.
+func scanPartition(
+	ctx context.Context, iter *Iterator, partition int, fn func(partition int, key, value []byte) error,
+) error {
+	var count int
+	for valid := iter.First(); valid; valid = iter.Next() {
+		if count++; count%parallelScanContextCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		value, err := iter.ValueAndErr()
+		if err != nil {
+			return err
+		}
+		if err := fn(partition, iter.Key(), value); err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
+
+// parallelScanSplits returns up to n-1 keys, in increasing order and strictly
+// within span, that split span into partitions holding roughly the same number
+// of bytes of sstables in v. The split keys are the smallest keys of sstables,
+// and each sstable's size is attributed to the partition containing its
+// smallest key.
+func (d *DB) parallelScanSplits(v *version, span KeyRange, n int) [][]byte {
+	type table struct {
+		smallest []byte
+		size     uint64
+	}
+	var tables []table
+	var total uint64
+	for level := range v.Levels {
+		for f := range v.Levels[level].All() {
+			if span.Start != nil && d.cmp(f.Largest().UserKey, span.Start) < 0 {
+				continue
+			}
+			if span.End != nil && d.cmp(f.Smallest().UserKey, span.End) >= 0 {
+				continue
+			}
+			tables = append(tables, table{smallest: f.Smallest().UserKey, size: f.Size})
+			total += f.Size
+		}
+	}
+	if n == 1 || total == 0 {
+		return nil
+	}
+	slices.SortFunc(tables, func(a, b table) int {
+		return d.cmp(a.smallest, b.smallest)
+	})
+
+	var splits [][]byte
+	var cumulative uint64
+	for _, t := range tables {
+		if len(splits) == n-1 {
+			break
+		}
+		// Split before t if the bytes preceding it fill the next partition.
+		target := total * uint64(len(splits)+1) / uint64(n)
+		if cumulative >= target && (span.Start == nil || d.cmp(t.smallest, span.Start) > 0) &&
+			(len(splits) == 0 || d.cmp(t.smallest, splits[len(splits)-1]) > 0) {
+			splits = append(splits, slices.Clone(t.smallest))
+		}
+		cumulative += t.size
+	}
+	return splits
+}
@@ -0,0 +1,113 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/chris124567/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallelScan(t *testing.T) {
+	opts := &Options{
+		FS:                          vfs.NewMem(),
+		DisableAutomaticCompactions: true,
+		Levels:                      make([]LevelOptions, numLevels),
+	}
+	for i := range opts.Levels {
+		opts.Levels[i].TargetFileSize = 4 << 10
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	key := func(i int) []byte { return []byte(fmt.Sprintf("key%05d", i)) }
+	const numKeys = 10000
+	for _, suffix := range []string{"old", "new"} {
+		b := d.NewBatch()
+		for i := 0; i < numKeys; i++ {
+			require.NoError(t, b.Set(key(i), []byte(fmt.Sprintf("%d-%s", i, suffix)), nil))
+		}
+		require.NoError(t, b.Commit(nil))
+		require.NoError(t, d.Flush())
+	}
+	require.NoError(t, d.Compact(context.Background(), key(0), key(numKeys), false))
+	require.Greater(t, d.Metrics().Levels[numLevels-1].TablesCount, int64(8))
+	// Keys in the memtable are scanned too.
+	require.NoError(t, d.Set(key(numKeys), []byte("mem"), nil))
+
+	scan := func(span KeyRange, n int) [][]string {
+		var mu sync.Mutex
+		partitions := make([][]string, n)
+		var maxPartition int
+		require.NoError(t, d.ParallelScan(context.Background(), span, n,
+			func(partition int, key, value []byte) error {
+				mu.Lock()
+				defer mu.Unlock()
+				partitions[partition] = append(partitions[partition], string(key)+"="+string(value))
+				maxPartition = max(maxPartition, partition)
+				return nil
+			}))
+		return partitions[:maxPartition+1]
+	}
+	expected := func(start, end int) []string {
+		var res []string
+		for i := start; i < end; i++ {
+			res = append(res, fmt.Sprintf("%s=%d-new", key(i), i))
+		}
+		if end > numKeys {
+			res[len(res)-1] = string(key(numKeys)) + "=mem"
+		}
+		return res
+	}
+
+	for _, tc := range []struct {
+		span       KeyRange
+		start, end int
+	}{
+		{span: KeyRange{}, start: 0, end: numKeys + 1},
+		{span: KeyRange{Start: key(1234), End: key(8765)}, start: 1234, end: 8765},
+		{span: KeyRange{Start: key(5000)}, start: 5000, end: numKeys + 1},
+	} {
+		for _, n := range []int{1, 4, 16} {
+			t.Run(fmt.Sprintf("%s-%s/n=%d", tc.span.Start, tc.span.End, n), func(t *testing.T) {
+				partitions := scan(tc.span, n)
+				require.LessOrEqual(t, len(partitions), n)
+				if n > 1 {
+					require.Greater(t, len(partitions), 1)
+				}
+				// The partitions are ordered and together hold every key.
+				var all []string
+				for _, p := range partitions {
+					require.NotEmpty(t, p)
+					all = append(all, p...)
+				}
+				require.Equal(t, expected(tc.start, tc.end), all)
+				// The partitions are balanced.
+				for _, p := range partitions {
+					require.Less(t, len(p), 3*len(all)/len(partitions))
+				}
+			})
+		}
+	}
+
+	t.Run("error", func(t *testing.T) {
+		errFoo := errors.New("foo")
+		err := d.ParallelScan(context.Background(), KeyRange{}, 4,
+			func(partition int, k, value []byte) error {
+				if string(k) == string(key(7000)) {
+					return errFoo
+				}
+				return nil
+			})
+		require.ErrorIs(t, err, errFoo)
+		require.Error(t, d.ParallelScan(context.Background(), KeyRange{}, 0, nil))
+	})
+}
@@ -0,0 +1,605 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/internal/cache"
+	"github.com/chris124567/pebble/internal/compact"
+	"github.com/chris124567/pebble/internal/manifest"
+	"github.com/chris124567/pebble/objstorage"
+	"github.com/chris124567/pebble/objstorage/objstorageprovider"
+	"github.com/chris124567/pebble/objstorage/remote"
+	"github.com/chris124567/pebble/sstable"
+	"github.com/chris124567/pebble/sstable/block"
+	"github.com/chris124567/pebble/vfs"
+)
+
+// RemoteCompactor runs compactions on behalf of a DB, typically by sending
+// them to a separate worker process. See
+// Options.Experimental.RemoteCompactor.
+type RemoteCompactor interface {
+	// Compact runs the compaction described by job and returns a description
+	// of its outputs. Both are opaque encodings: the job must be passed to
+	// RunRemoteCompaction, and its result returned as is.
+	//
+	// Compact must not return before the worker has stopped running the job.
+	// If Compact returns an error, the DB runs the compaction locally instead.
+	// ctx is cancelled if the compaction is cancelled or the DB is closed, in
+	// which case Compact should stop the worker and return promptly; the DB
+	// closes only once Compact has returned.
+	//
+	// The worker creates the outputs on shared storage, named after file
+	// numbers that the DB reserved for the job and never uses otherwise. If
+	// Compact returns an error after the worker created outputs, for instance
+	// because the result was lost in transit, the DB does not know of the
+	// outputs and never removes them: the RemoteCompactor is responsible for
+	// removing the outputs of jobs whose result it failed to deliver.
+	Compact(ctx context.Context, job []byte) (result []byte, _ error)
+}
+
+// NewLocalRemoteCompactor returns a RemoteCompactor that runs compactions in
+// the current process, with RunRemoteCompaction and the given Options. It is
+// mostly useful for testing.
+func NewLocalRemoteCompactor(opts *Options) RemoteCompactor {
+	return localRemoteCompactor{opts: opts}
+}
+
+type localRemoteCompactor struct {
+	opts *Options
+}
+
+// Compact is part of the RemoteCompactor interface.
+func (c localRemoteCompactor) Compact(ctx context.Context, job []byte) ([]byte, error) {
+	return RunRemoteCompaction(ctx, c.opts, job)
+}
+
+// remoteCompactionJob describes a compaction run by a RemoteCompactor. It is
+// encoded as JSON.
+type remoteCompactionJob struct {
+	Comparer    string               `json:"comparer"`
+	Merger      string               `json:"merger"`
+	TableFormat sstable.TableFormat  `json:"table_format"`
+	CreatorID   objstorage.CreatorID `json:"creator_id"`
+	// Locator is the shared storage on which the outputs are created.
+	Locator remote.Locator `json:"locator"`
+	// Inputs describes the input levels, in the order of compaction.inputs.
+	// The last one is the output level.
+	Inputs []remoteCompactionLevel `json:"inputs"`
+	// Tables is an encoded version edit whose NewTables are the tables of each
+	// of the Inputs, in order, followed by the grandparent tables.
+	Tables []byte `json:"tables"`
+	// Backings holds the remote object backing of each input table.
+	Backings          [][]byte               `json:"backings"`
+	Snapshots         compact.Snapshots      `json:"snapshots,omitempty"`
+	Smallest          base.InternalKey       `json:"smallest"`
+	Largest           base.InternalKey       `json:"largest"`
+	L0SplitKeys       [][]byte               `json:"l0_split_keys,omitempty"`
+	MaxOutputFileSize uint64                 `json:"max_output_file_size"`
+	MaxOverlapBytes   uint64                 `json:"max_overlap_bytes"`
+	DelElision        remoteTombstoneElision `json:"del_elision"`
+	RangeKeyElision   remoteTombstoneElision `json:"range_key_elision"`
+	// TTLNow is the time against which the expiry of values is evaluated, if
+	// TTL is enabled.
+	TTLNow uint64 `json:"ttl_now,omitempty"`
+	// FileNums is the range of file numbers [start, end) reserved by the DB for
+	// the outputs.
+	FileNums [2]base.DiskFileNum `json:"file_nums"`
+}
+
+type remoteCompactionLevel struct {
+	Level     int `json:"level"`
+	NumTables int `json:"num_tables"`
+	// SubLevels holds the L0 sublevel of each table of the start level of a
+	// compaction out of L0, in which case the tables are ordered by sublevel.
+	SubLevels []int `json:"sublevels,omitempty"`
+}
+
+type remoteTombstoneElision struct {
+	ElideNothing bool                 `json:"elide_nothing,omitempty"`
+	InUseRanges  []base.UserKeyBounds `json:"in_use_ranges,omitempty"`
+}
+
+func makeRemoteTombstoneElision(e compact.TombstoneElision) remoteTombstoneElision {
+	return remoteTombstoneElision{
+		ElideNothing: e.ElidesNothing(),
+		InUseRanges:  e.InUseRanges(),
+	}
+}
+
+func (e remoteTombstoneElision) tombstoneElision() compact.TombstoneElision {
+	if e.ElideNothing {
+		return compact.NoTombstoneElision()
+	}
+	return compact.ElideTombstonesOutsideOf(e.InUseRanges)
+}
+
+// remoteCompactionResult describes the outputs of a compaction run by a
+// RemoteCompactor. It is encoded as JSON.
+type remoteCompactionResult struct {
+	Tables []remoteCompactionOutput `json:"tables"`
+	Stats  compact.Stats            `json:"stats"`
+}
+
+type remoteCompactionOutput struct {
+	FileNum      base.DiskFileNum       `json:"file_num"`
+	Backing      []byte                 `json:"backing"`
+	CreationTime time.Time              `json:"creation_time"`
+	Meta         sstable.WriterMetadata `json:"meta"`
+}
+
+// remoteCompactionOutputs configures the outputs of a compaction run by a
+// remote compaction worker.
+type remoteCompactionOutputs struct {
+	locator      remote.Locator
+	fileNumLimit base.DiskFileNum
+}
+
+// maybeRunRemoteCompaction runs the compaction with the configured
+// RemoteCompactor, if it can be offloaded. It returns false if the compaction
+// must be run locally instead, either because it cannot be offloaded or
+// because the remote compaction failed.
+//
+// d.mu must not be held.
+func (d *DB) maybeRunRemoteCompaction(
+	jobID JobID,
+	c *compaction,
+	snapshots compact.Snapshots,
+	tableFormat sstable.TableFormat,
+	valueSeparation compact.ValueSeparation,
+) (compact.Result, bool) {
+	if !d.canRunRemoteCompaction(c, valueSeparation) {
+		return compact.Result{}, false
+	}
+	result, err := d.runRemoteCompaction(jobID, c, snapshots, tableFormat)
+	if err != nil {
+		d.opts.Logger.Infof("[JOB %d] remote compaction failed, running it locally: %v", jobID, err)
+		return compact.Result{}, false
+	}
+	return result, true
+}
+
+// canRunRemoteCompaction returns true if the compaction can be offloaded to
+// the RemoteCompactor: it is not a flush, its inputs are all physical tables
+// on shared storage without blob references, it does not separate values, and
+// its outputs would all be placed on shared storage, according to
+// CreateOnShared and the span policies of the compaction.
+func (d *DB) canRunRemoteCompaction(c *compaction, valueSeparation compact.ValueSeparation) bool {
+	if d.opts.Experimental.RemoteCompactor == nil || len(c.flushing) != 0 ||
+		!d.objProvider.CreatorID().IsSet() {
+		return false
+	}
+	if preferShared, ok, err := d.opts.spanPreferSharedStorage(c.userKeyBounds(), c.outputLevel.level); err != nil || !ok || !preferShared {
+		return false
+	}
+	if _, ok := valueSeparation.(compact.NeverSeparateValues); !ok {
+		return false
+	}
+	for _, cl := range c.inputs {
+		for f := range cl.files.All() {
+			if f.Virtual || len(f.BlobReferences) > 0 {
+				return false
+			}
+			meta, err := d.objProvider.Lookup(base.FileTypeTable, f.FileBacking.DiskFileNum)
+			if err != nil || !meta.IsShared() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// runRemoteCompaction runs the compaction with the RemoteCompactor and
+// attaches its outputs. If an error is returned, no outputs were attached and
+// the compaction can be run locally. Errors that occur once the outputs are
+// attached are instead returned in the Result, along with the outputs.
+//
+// d.mu must not be held.
+func (d *DB) runRemoteCompaction(
+	jobID JobID, c *compaction, snapshots compact.Snapshots, tableFormat sstable.TableFormat,
+) (compact.Result, error) {
+	job := remoteCompactionJob{
+		Comparer:          d.opts.Comparer.Name,
+		Merger:            d.opts.Merger.Name,
+		TableFormat:       tableFormat,
+		CreatorID:         d.objProvider.CreatorID(),
+		Locator:           d.opts.Experimental.CreateOnSharedLocator,
+		Snapshots:         snapshots,
+		Smallest:          c.smallest,
+		Largest:           c.largest,
+		L0SplitKeys:       c.l0Limits,
+		MaxOutputFileSize: c.maxOutputFileSize,
+		MaxOverlapBytes:   c.maxOverlapBytes,
+		DelElision:        makeRemoteTombstoneElision(c.delElision),
+		RangeKeyElision:   makeRemoteTombstoneElision(c.rangeKeyElision),
+	}
+	if d.opts.TTL != nil {
+		job.TTLNow = d.ttlNow()
+	}
+
+	ve := &versionEdit{}
+	addInput := func(level int, f *tableMetadata) error {
+		meta, err := d.objProvider.Lookup(base.FileTypeTable, f.FileBacking.DiskFileNum)
+		if err != nil {
+			return err
+		}
+		h, err := d.objProvider.RemoteObjectBacking(&meta)
+		if err != nil {
+			return err
+		}
+		// The handle protects the object from removal, which is not needed as
+		// the compaction holds a reference to the version.
+		defer h.Close()
+		backing, err := h.Get()
+		if err != nil {
+			return err
+		}
+		ve.NewTables = append(ve.NewTables, newTableEntry{Level: level, Meta: f})
+		job.Backings = append(job.Backings, backing)
+		return nil
+	}
+	for i := range c.inputs {
+		cl := &c.inputs[i]
+		in := remoteCompactionLevel{Level: cl.level}
+		numTables := len(ve.NewTables)
+		if cl == c.startLevel && cl.l0SublevelInfo != nil {
+			for _, info := range cl.l0SublevelInfo {
+				for f := range info.All() {
+					if err := addInput(cl.level, f); err != nil {
+						return compact.Result{}, err
+					}
+					in.SubLevels = append(in.SubLevels, info.sublevel.Sublevel())
+				}
+			}
+		} else {
+			for f := range cl.files.All() {
+				if err := addInput(cl.level, f); err != nil {
+					return compact.Result{}, err
+				}
+			}
+		}
+		in.NumTables = len(ve.NewTables) - numTables
+		job.Inputs = append(job.Inputs, in)
+	}
+	for f := range c.grandparents.All() {
+		ve.NewTables = append(ve.NewTables, newTableEntry{Level: c.outputLevel.level + 1, Meta: f})
+	}
+	var buf bytes.Buffer
+	if err := ve.Encode(&buf); err != nil {
+		return compact.Result{}, err
+	}
+	job.Tables = buf.Bytes()
+
+	n := remoteCompactionFileNums(c)
+	start := d.mu.versions.reserveDiskFileNums(n)
+	job.FileNums = [2]base.DiskFileNum{start, start + base.DiskFileNum(n)}
+
+	encodedJob, err := json.Marshal(&job)
+	if err != nil {
+		return compact.Result{}, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.cancelRemoteCompaction(ctx, cancel, c)
+	encodedResult, err := d.opts.Experimental.RemoteCompactor.Compact(ctx, encodedJob)
+	if err != nil {
+		if ctx.Err() != nil {
+			// The compaction was cancelled, or the DB is closing: running the
+			// compaction locally would be pointless.
+			if err := d.closed.Load(); err != nil {
+				return compact.Result{Err: err.(error)}, nil
+			}
+			return compact.Result{Err: ErrCancelledCompaction}, nil
+		}
+		return compact.Result{}, err
+	}
+	var res remoteCompactionResult
+	if err := json.Unmarshal(encodedResult, &res); err != nil {
+		return compact.Result{}, errors.Wrap(err, "pebble: decoding remote compaction result")
+	}
+
+	// The outputs are named after file numbers that this DB reserved, so
+	// attaching them hands their ownership to the DB.
+	objs := make([]objstorage.RemoteObjectToAttach, len(res.Tables))
+	for i, t := range res.Tables {
+		if t.FileNum < job.FileNums[0] || t.FileNum >= job.FileNums[1] {
+			return compact.Result{}, errors.Errorf("pebble: remote compaction output %s outside of the reserved file numbers", t.FileNum)
+		}
+		objs[i] = objstorage.RemoteObjectToAttach{
+			FileNum:  t.FileNum,
+			FileType: base.FileTypeTable,
+			Backing:  t.Backing,
+		}
+	}
+	metas, err := d.objProvider.AttachRemoteObjects(objs)
+	if err != nil {
+		return compact.Result{}, err
+	}
+	result := compact.Result{
+		Tables: make([]compact.OutputTable, len(res.Tables)),
+		Stats:  res.Stats,
+	}
+	for i, t := range res.Tables {
+		d.opts.EventListener.TableCreated(TableCreateInfo{
+			JobID:   int(jobID),
+			Reason:  c.kind.compactingOrFlushing(),
+			Path:    d.objProvider.Path(metas[i]),
+			FileNum: metas[i].DiskFileNum,
+		})
+		result.Tables[i] = compact.OutputTable{
+			CreationTime: t.CreationTime,
+			ObjMeta:      metas[i],
+			WriterMeta:   t.Meta,
+		}
+	}
+	if c.cancel.Load() {
+		return result.WithError(ErrCancelledCompaction), nil
+	}
+	result.Err = d.objProvider.Sync()
+	return result, nil
+}
+
+// remoteCompactionCancelCheckInterval is the interval at which a remote
+// compaction checks whether it was cancelled.
+const remoteCompactionCancelCheckInterval = 100 * time.Millisecond
+
+// cancelRemoteCompaction calls cancel once the compaction is cancelled or the
+// DB is closed, unless ctx is done first. Compactions are cancelled by setting
+// compaction.cancel, so it is polled.
+func (d *DB) cancelRemoteCompaction(ctx context.Context, cancel context.CancelFunc, c *compaction) {
+	ticker := time.NewTicker(remoteCompactionCancelCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.closedCh:
+			cancel()
+			return
+		case <-ticker.C:
+			if c.cancel.Load() {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// remoteCompactionFileNums returns the number of file numbers reserved for the
+// outputs of a remote compaction. It generously overestimates the number of
+// outputs; a remote compaction that needs more fails.
+func remoteCompactionFileNums(c *compaction) uint64 {
+	var inputSize uint64
+	for _, cl := range c.inputs {
+		inputSize += cl.files.TableSizeSum()
+	}
+	n := inputSize/max(c.maxOutputFileSize, 1) +
+		c.grandparents.AggregateSizeSum()/max(c.maxOverlapBytes, 1) +
+		uint64(c.grandparents.Len()) + uint64(len(c.l0Limits))
+	return 2*n + 16
+}
+
+// RunRemoteCompaction runs a compaction sent by a DB to its RemoteCompactor,
+// and returns the description of its outputs that the RemoteCompactor must
+// return to the DB. It is typically called by a worker process (see the
+// compact-worker command of cmd/pebble).
+//
+// The input tables are read from, and the outputs written to, the shared
+// storage of the DB, which must be accessible through
+// opts.Experimental.RemoteStorage. The comparer and merger of opts must match
+// the DB's. The other options that affect the outputs of compactions, such as
+// the writer options of the levels, CompactionFilter, TTL and SpanPolicyFunc,
+// are those of opts, and should also match the DB's.
+//
+// On error, the outputs created so far are removed.
+func RunRemoteCompaction(ctx context.Context, opts *Options, job []byte) ([]byte, error) {
+	var j remoteCompactionJob
+	if err := json.Unmarshal(job, &j); err != nil {
+		return nil, errors.Wrap(err, "pebble: decoding remote compaction job")
+	}
+	opts = opts.Clone()
+	opts.EnsureDefaults()
+	switch {
+	case opts.Experimental.RemoteStorage == nil:
+		return nil, errors.New("pebble: remote compaction requires RemoteStorage to be set")
+	case opts.Comparer.Name != j.Comparer:
+		return nil, errors.Errorf("pebble: remote compaction comparer %q does not match %q", j.Comparer, opts.Comparer.Name)
+	case opts.Merger.Name != j.Merger:
+		return nil, errors.Errorf("pebble: remote compaction merger %q does not match %q", j.Merger, opts.Merger.Name)
+	case (opts.TTL != nil) != (j.TTLNow != 0):
+		return nil, errors.New("pebble: remote compaction TTL configuration does not match the DB's")
+	case len(j.Inputs) < 2 || len(j.Inputs) > 3:
+		return nil, errors.Errorf("pebble: remote compaction has %d input levels", len(j.Inputs))
+	}
+	if opts.TTL != nil {
+		ttl := *opts.TTL
+		ttl.Now = func() time.Time { return time.Unix(0, int64(j.TTLNow)) }
+		opts.TTL = &ttl
+	}
+
+	var ve versionEdit
+	if err := ve.Decode(bytes.NewReader(j.Tables)); err != nil {
+		return nil, errors.Wrap(err, "pebble: decoding remote compaction tables")
+	}
+	if len(j.Backings) > len(ve.NewTables) {
+		return nil, errors.New("pebble: remote compaction has more backings than tables")
+	}
+
+	settings := objstorageprovider.DefaultSettings(vfs.NewMem(), "")
+	settings.Logger = opts.Logger
+	settings.Remote.StorageFactory = opts.Experimental.RemoteStorage
+	provider, err := objstorageprovider.Open(settings)
+	if err != nil {
+		return nil, err
+	}
+	defer provider.Close()
+	if err := provider.SetCreatorID(j.CreatorID); err != nil {
+		return nil, err
+	}
+	objs := make([]objstorage.RemoteObjectToAttach, len(j.Backings))
+	for i := range j.Backings {
+		objs[i] = objstorage.RemoteObjectToAttach{
+			FileNum:  ve.NewTables[i].Meta.FileBacking.DiskFileNum,
+			FileType: base.FileTypeTable,
+			Backing:  j.Backings[i],
+		}
+	}
+	if _, err := provider.AttachRemoteObjects(objs); err != nil {
+		return nil, err
+	}
+
+	// The compaction runs on a skeletal DB which only holds what compactions
+	// need.
+	blockCache := opts.Cache
+	if blockCache == nil {
+		blockCache = cache.New(opts.CacheSize)
+		defer blockCache.Unref()
+	}
+	d := &DB{
+		cacheHandle: blockCache.NewHandle(),
+		opts:        opts,
+		cmp:         opts.Comparer.Compare,
+		equal:       opts.Comparer.Equal,
+		merge:       opts.Merger.Merge,
+		split:       opts.Comparer.Split,
+		objProvider: provider,
+		fileKeys:    newFileKeys(nil),
+	}
+	defer d.cacheHandle.Close()
+	d.mu.versions = &versionSet{}
+	d.mu.versions.nextFileNum.Store(uint64(j.FileNums[0]))
+	for i := range d.compressionModels {
+		d.compressionModels[i] = block.NewAdaptiveCompressionModel(opts.Experimental.AdaptiveCompression)
+	}
+	fileCache := opts.FileCache
+	if fileCache == nil {
+		fileCache = NewFileCache(opts.Experimental.FileCacheShards, FileCacheSize(opts.MaxOpenFiles))
+		defer fileCache.Unref()
+	}
+	reportCorruption := func(_ any, err error) error { return err }
+	d.fileCache = fileCache.newHandle(d.cacheHandle, provider, opts.LoggerAndTracer, opts.MakeReaderOptions(), reportCorruption)
+	defer func() { _ = d.fileCache.Close() }()
+	d.fileCache.fileKeys = d.fileKeys
+	d.newIters = d.fileCache.newIters
+	d.tableNewRangeKeyIter = tableNewRangeKeyIter(d.newIters)
+
+	c, err := j.newCompaction(opts, &ve)
+	if err != nil {
+		return nil, err
+	}
+	c.remoteOutputs = &remoteCompactionOutputs{
+		locator:      j.Locator,
+		fileNumLimit: j.FileNums[1],
+	}
+	result := d.compactAndWrite(0, c, j.Snapshots, j.TableFormat, compact.NeverSeparateValues{})
+	res := remoteCompactionResult{Stats: result.Stats}
+	if result.Err == nil {
+		res.Tables = make([]remoteCompactionOutput, len(result.Tables))
+		for i := range result.Tables {
+			t := &result.Tables[i]
+			res.Tables[i] = remoteCompactionOutput{
+				FileNum:      t.ObjMeta.DiskFileNum,
+				CreationTime: t.CreationTime,
+				Meta:         t.WriterMeta,
+			}
+			h, err := provider.RemoteObjectBacking(&t.ObjMeta)
+			if err == nil {
+				res.Tables[i].Backing, err = h.Get()
+				h.Close()
+			}
+			if err != nil {
+				result.Err = err
+				break
+			}
+		}
+	}
+	var encoded []byte
+	if result.Err == nil {
+		encoded, result.Err = json.Marshal(&res)
+	}
+	if result.Err != nil {
+		for i := range result.Tables {
+			_ = provider.Remove(base.FileTypeTable, result.Tables[i].ObjMeta.DiskFileNum)
+		}
+		return nil, result.Err
+	}
+	return encoded, nil
+}
+
+// newCompaction constructs the compaction described by the job, whose tables
+// were decoded in ve.
+func (j *remoteCompactionJob) newCompaction(opts *Options, ve *versionEdit) (*compaction, error) {
+	cmp := opts.Comparer.Compare
+	c := &compaction{
+		kind:              compactionKindDefault,
+		cmp:               cmp,
+		equal:             opts.Comparer.Equal,
+		comparer:          opts.Comparer,
+		formatKey:         opts.Comparer.FormatKey,
+		logger:            opts.Logger,
+		beganAt:           time.Now(),
+		inputs:            make([]compactionLevel, len(j.Inputs)),
+		maxOutputFileSize: j.MaxOutputFileSize,
+		maxOverlapBytes:   j.MaxOverlapBytes,
+		smallest:          j.Smallest,
+		largest:           j.Largest,
+		l0Limits:          j.L0SplitKeys,
+		delElision:        j.DelElision.tombstoneElision(),
+		rangeKeyElision:   j.RangeKeyElision.tombstoneElision(),
+		grantHandle:       noopGrantHandle{},
+	}
+	tables := make([]*tableMetadata, len(ve.NewTables))
+	for i := range ve.NewTables {
+		tables[i] = ve.NewTables[i].Meta
+	}
+	for i, in := range j.Inputs {
+		if in.NumTables > len(tables) {
+			return nil, errors.New("pebble: remote compaction has fewer tables than inputs")
+		}
+		files := tables[:in.NumTables]
+		tables = tables[in.NumTables:]
+		cl := &c.inputs[i]
+		cl.level = in.Level
+		if in.Level == 0 {
+			cl.files = manifest.NewLevelSliceSeqSorted(files)
+		} else {
+			cl.files = manifest.NewLevelSliceKeySorted(cmp, files)
+		}
+		if len(in.SubLevels) == 0 {
+			continue
+		}
+		if len(in.SubLevels) != len(files) {
+			return nil, errors.New("pebble: remote compaction has inconsistent L0 sublevels")
+		}
+		for start := 0; start < len(files); {
+			end := start + 1
+			for end < len(files) && in.SubLevels[end] == in.SubLevels[start] {
+				end++
+			}
+			cl.l0SublevelInfo = append(cl.l0SublevelInfo, sublevelInfo{
+				LevelSlice: manifest.NewLevelSliceKeySorted(cmp, files[start:end]),
+				sublevel:   manifest.L0Sublevel(in.SubLevels[start]),
+			})
+			start = end
+		}
+	}
+	c.startLevel = &c.inputs[0]
+	c.outputLevel = &c.inputs[len(c.inputs)-1]
+	for i := 1; i < len(c.inputs)-1; i++ {
+		c.extraLevels = append(c.extraLevels, &c.inputs[i])
+	}
+	if c.startLevel.level == 0 && c.startLevel.l0SublevelInfo == nil {
+		return nil, errors.New("pebble: remote compaction out of L0 is missing sublevels")
+	}
+	// The remaining tables are the grandparents.
+	c.grandparents = manifest.NewLevelSliceKeySorted(cmp, tables)
+	return c, nil
+}
@@ -0,0 +1,246 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/objstorage/remote"
+	"github.com/chris124567/pebble/vfs"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type testRemoteCompactor struct {
+	RemoteCompactor
+	jobs atomic.Int32
+	fail atomic.Bool
+}
+
+func (c *testRemoteCompactor) Compact(ctx context.Context, job []byte) ([]byte, error) {
+	c.jobs.Add(1)
+	if c.fail.Load() {
+		return nil, errors.New("worker unavailable")
+	}
+	return c.RemoteCompactor.Compact(ctx, job)
+}
+
+func TestRemoteCompaction(t *testing.T) {
+	storage := remote.NewInMem()
+	factory := remote.MakeSimpleFactory(map[remote.Locator]remote.Storage{"": storage})
+	workerOpts := &Options{Logger: testLogger{t}}
+	workerOpts.Experimental.RemoteStorage = factory
+	compactor := &testRemoteCompactor{RemoteCompactor: NewLocalRemoteCompactor(workerOpts)}
+
+	opts := &Options{
+		FS:                          vfs.NewMem(),
+		Logger:                      testLogger{t},
+		DisableAutomaticCompactions: true,
+	}
+	opts.Levels = make([]LevelOptions, numLevels)
+	for i := range opts.Levels {
+		opts.Levels[i].TargetFileSize = 1 << 10
+	}
+	opts.Experimental.RemoteStorage = factory
+	opts.Experimental.CreateOnShared = remote.CreateOnSharedAll
+	opts.Experimental.RemoteCompactor = compactor
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	require.NoError(t, d.SetCreatorID(1))
+
+	// Write overlapping tables in L0, with deletions of every other key.
+	expected := map[string]string{}
+	for round := 0; round < 4; round++ {
+		for i := 0; i < 500; i++ {
+			key := fmt.Sprintf("key%04d", i)
+			if (i+round)%2 == 0 {
+				require.NoError(t, d.Delete([]byte(key), nil))
+				delete(expected, key)
+				continue
+			}
+			value := fmt.Sprintf("value%d-%d", round, i)
+			require.NoError(t, d.Set([]byte(key), []byte(value), nil))
+			expected[key] = value
+		}
+		require.NoError(t, d.Flush())
+	}
+	require.NoError(t, d.DeleteRange([]byte("key0100"), []byte("key0200"), nil))
+	for i := 100; i < 200; i++ {
+		delete(expected, fmt.Sprintf("key%04d", i))
+	}
+	require.NoError(t, d.Flush())
+
+	check := func(d *DB) {
+		iter, err := d.NewIter(nil)
+		require.NoError(t, err)
+		n := 0
+		for valid := iter.First(); valid; valid = iter.Next() {
+			require.Equal(t, expected[string(iter.Key())], string(iter.Value()), "key %s", iter.Key())
+			n++
+		}
+		require.NoError(t, iter.Close())
+		require.Equal(t, len(expected), n)
+	}
+	// checkShared verifies that all tables are on shared storage and returns
+	// the number of tables.
+	checkShared := func(d *DB) int {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		n := 0
+		for _, l := range d.mu.versions.currentVersion().Levels {
+			for f := range l.All() {
+				meta, err := d.objProvider.Lookup(base.FileTypeTable, f.FileBacking.DiskFileNum)
+				require.NoError(t, err)
+				require.True(t, meta.IsShared())
+				n++
+			}
+		}
+		return n
+	}
+
+	require.NoError(t, d.Compact(context.Background(), []byte("a"), []byte("z"), false))
+	require.Positive(t, compactor.jobs.Load())
+	// The small target file size splits the outputs.
+	require.Greater(t, checkShared(d), 1)
+	check(d)
+
+	// Compactions whose offload fails are run locally.
+	compactor.fail.Store(true)
+	jobs := compactor.jobs.Load()
+	for i := 0; i < 500; i += 3 {
+		key := fmt.Sprintf("key%04d", i)
+		require.NoError(t, d.Set([]byte(key), []byte("updated"), nil))
+		expected[key] = "updated"
+	}
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Compact(context.Background(), []byte("a"), []byte("z"), false))
+	require.Greater(t, compactor.jobs.Load(), jobs)
+	checkShared(d)
+	check(d)
+	require.NoError(t, d.Close())
+
+	// The outputs of remote compactions are owned by the DB.
+	compactor.fail.Store(false)
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	checkShared(d)
+	check(d)
+	require.NoError(t, d.Close())
+}
+
+func TestRemoteCompactionSpanPolicy(t *testing.T) {
+	storage := remote.NewInMem()
+	factory := remote.MakeSimpleFactory(map[remote.Locator]remote.Storage{"": storage})
+	// The local policy is enabled once the tables are written, so that the
+	// inputs of the compaction are all on shared storage.
+	var enabled atomic.Bool
+	localPolicy := func([]byte) (SpanPolicy, []byte, error) {
+		if !enabled.Load() {
+			return SpanPolicy{}, nil, nil
+		}
+		return SpanPolicy{TableStoragePolicy: TableStorageLocal}, nil, nil
+	}
+
+	// run writes overlapping tables in L0, compacts them, and returns the
+	// number of jobs sent to the worker and the number of local tables.
+	run := func(dbPolicy, workerPolicy SpanPolicyFunc) (jobs int32, local int) {
+		enabled.Store(false)
+		workerOpts := &Options{Logger: testLogger{t}}
+		workerOpts.Experimental.RemoteStorage = factory
+		workerOpts.Experimental.SpanPolicyFunc = workerPolicy
+		compactor := &testRemoteCompactor{RemoteCompactor: NewLocalRemoteCompactor(workerOpts)}
+
+		opts := &Options{
+			FS:                          vfs.NewMem(),
+			Logger:                      testLogger{t},
+			DisableAutomaticCompactions: true,
+		}
+		opts.Experimental.RemoteStorage = factory
+		opts.Experimental.CreateOnShared = remote.CreateOnSharedAll
+		opts.Experimental.RemoteCompactor = compactor
+		opts.Experimental.SpanPolicyFunc = dbPolicy
+		d, err := Open("", opts)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, d.Close()) }()
+		require.NoError(t, d.SetCreatorID(1))
+		for round := 0; round < 2; round++ {
+			for i := 0; i < 500; i++ {
+				require.NoError(t, d.Set([]byte(fmt.Sprintf("key%04d", i)), []byte(fmt.Sprint(round)), nil))
+			}
+			require.NoError(t, d.Flush())
+		}
+		enabled.Store(true)
+		require.NoError(t, d.Compact(context.Background(), []byte("a"), []byte("z"), false))
+
+		tables, err := d.SSTables()
+		require.NoError(t, err)
+		for _, level := range tables {
+			for _, info := range level {
+				if info.BackingType == BackingTypeLocal {
+					local++
+				}
+			}
+		}
+		return compactor.jobs.Load(), local
+	}
+
+	// The DB doesn't offload compactions whose outputs its span policies
+	// place on local storage.
+	jobs, local := run(localPolicy, nil)
+	require.Zero(t, jobs)
+	require.Positive(t, local)
+
+	// If the span policies of the worker place an output on local storage, the
+	// compaction fails remotely and is run locally.
+	jobs, local = run(nil, localPolicy)
+	require.Positive(t, jobs)
+	require.Zero(t, local)
+}
+
+// hangingRemoteCompactor runs jobs until their context is cancelled.
+type hangingRemoteCompactor struct {
+	started chan struct{}
+}
+
+func (c *hangingRemoteCompactor) Compact(ctx context.Context, job []byte) ([]byte, error) {
+	c.started <- struct{}{}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRemoteCompactionCancel(t *testing.T) {
+	compactor := &hangingRemoteCompactor{started: make(chan struct{}, 1)}
+	opts := &Options{
+		FS:                          vfs.NewMem(),
+		Logger:                      testLogger{t},
+		DisableAutomaticCompactions: true,
+	}
+	opts.Experimental.RemoteStorage = remote.MakeSimpleFactory(map[remote.Locator]remote.Storage{
+		"": remote.NewInMem(),
+	})
+	opts.Experimental.CreateOnShared = remote.CreateOnSharedAll
+	opts.Experimental.RemoteCompactor = compactor
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	require.NoError(t, d.SetCreatorID(1))
+	for _, key := range []string{"a", "b", "a"} {
+		require.NoError(t, d.Set([]byte(key), nil, nil))
+		require.NoError(t, d.Flush())
+	}
+
+	// Closing the DB cancels the context of a hung worker, and the compaction
+	// fails instead of running locally.
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.Compact(context.Background(), []byte("a"), []byte("z"), false)
+	}()
+	<-compactor.started
+	require.NoError(t, d.Close())
+	require.ErrorIs(t, <-errCh, ErrClosed)
+}
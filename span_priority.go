@@ -0,0 +1,162 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/internal/manifest"
+)
+
+// SpanPriority assigns a compaction priority to a span of user keys. See
+// Options.Experimental.SpanPriorities.
+//
+// Spans allow a DB shared by several tenants, each owning a span of the key
+// space, to keep the read amplification of some tenants low regardless of the
+// write load of the others.
+type SpanPriority struct {
+	// Start and End bound the span of user keys [Start, End).
+	Start, End []byte
+	// Priority is the priority of compactions overlapping the span. Higher
+	// values are more important. When spans overlap, a compaction has the
+	// highest priority of the spans it overlaps.
+	Priority int
+	// MaxReadAmp, if positive, is the read amplification the span should stay
+	// at or below, counting the L0 sublevels and the levels below L0 that hold
+	// tables overlapping the span. While the span exceeds it, the leveled
+	// compaction picker prefers compactions seeded with the span's tables in
+	// the shallowest level ahead of score-based compactions, and the DB allows
+	// one more concurrent compaction until a compaction overlaps the span.
+	// Zero means no limit.
+	MaxReadAmp int
+}
+
+// SpanMetrics holds the metrics of a span of
+// Options.Experimental.SpanPriorities.
+type SpanMetrics struct {
+	SpanPriority
+	// ReadAmp is the current read amplification of the span, counting the L0
+	// sublevels and the levels below L0 that hold tables overlapping the span.
+	ReadAmp int
+}
+
+func (s *SpanPriority) bounds() base.UserKeyBounds {
+	return base.UserKeyBoundsEndExclusive(s.Start, s.End)
+}
+
+func (s *SpanPriority) overReadAmp(cmp base.Compare, v *version) bool {
+	return s.MaxReadAmp > 0 && spanReadAmp(cmp, v, s.bounds()) > s.MaxReadAmp
+}
+
+// spanReadAmp returns the number of L0 sublevels and levels below L0 that hold
+// tables overlapping the bounds.
+func spanReadAmp(cmp base.Compare, v *version, bounds base.UserKeyBounds) int {
+	var readAmp int
+	for _, files := range v.AllLevelsAndSublevels() {
+		if overlaps := files.Overlaps(cmp, bounds); !overlaps.Empty() {
+			readAmp++
+		}
+	}
+	return readAmp
+}
+
+// spanPriority returns the highest priority of the spans that overlap the
+// bounds, or zero if none do.
+func spanPriority(cmp base.Compare, spans []SpanPriority, bounds base.UserKeyBounds) int {
+	var priority int
+	for i := range spans {
+		if b := spans[i].bounds(); b.Overlaps(cmp, &bounds) {
+			priority = max(priority, spans[i].Priority)
+		}
+	}
+	return priority
+}
+
+// pickSpanPriority picks a compaction of the highest-priority span whose read
+// amplification exceeds its MaxReadAmp, if any. The compaction starts from the
+// shallowest level holding tables overlapping the span, with a seed table in
+// the span that is expanded like the seed of a score-based compaction, so it
+// is subject to the same size limits. Repeated compactions eventually clear
+// the level of the span's tables, reducing its read amplification.
+func (p *compactionPickerByScore) pickSpanPriority(env compactionEnv) *pickedCompaction {
+	var spans []*SpanPriority
+	for i := range p.opts.Experimental.SpanPriorities {
+		if s := &p.opts.Experimental.SpanPriorities[i]; s.overReadAmp(p.opts.Comparer.Compare, p.vers) {
+			spans = append(spans, s)
+		}
+	}
+	slices.SortStableFunc(spans, func(a, b *SpanPriority) int {
+		return cmp.Compare(b.Priority, a.Priority)
+	})
+	for _, s := range spans {
+		for level := 0; level < numLevels-1; level++ {
+			files := p.vers.Overlaps(level, s.bounds())
+			if files.Empty() {
+				continue
+			}
+			// The shallowest tables can't be compacted yet if no compaction is
+			// picked, and compacting deeper tables wouldn't reduce the read
+			// amplification.
+			if pc := p.pickSpanSeed(env, s, level, files); pc != nil {
+				pc.score = float64(spanReadAmp(p.opts.Comparer.Compare, p.vers, s.bounds())) / float64(s.MaxReadAmp)
+				return pc
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// pickSpanSeed picks a compaction out of the level seeded with one of the
+// files overlapping the span.
+func (p *compactionPickerByScore) pickSpanSeed(
+	env compactionEnv, s *SpanPriority, level int, files manifest.LevelSlice,
+) *pickedCompaction {
+	if level == 0 {
+		lcf := p.l0Organizer.PickBaseCompactionWithin(p.opts.Logger, 1,
+			p.vers.Levels[p.baseLevel].Slice(), p.baseLevel, env.problemSpans, s.bounds())
+		if lcf == nil {
+			return nil
+		}
+		pc := newPickedCompactionFromL0(lcf, p.opts, p.vers, p.l0Organizer, p.baseLevel, true)
+		if !pc.setupInputs(p.opts, env.diskAvailBytes, pc.startLevel, env.problemSpans) {
+			return nil
+		}
+		if pc = pc.maybeAddLevel(p.opts, env.diskAvailBytes); inputRangeAlreadyCompacting(env, pc) {
+			return nil
+		}
+		return pc
+	}
+	iter := files.Iter()
+	for f := iter.First(); f != nil; f = iter.Next() {
+		if f.IsCompacting() {
+			continue
+		}
+		pc := pickAutoLPositive(env, p.opts, p.vers, p.l0Organizer, candidateLevelInfo{
+			level:       level,
+			outputLevel: defaultOutputLevel(level, p.baseLevel),
+			file:        iter.Take(),
+		}, p.baseLevel)
+		if pc != nil && !inputRangeAlreadyCompacting(env, pc) {
+			return pc
+		}
+	}
+	return nil
+}
+
+// overlapsInProgress returns true if one of the in-progress compactions
+// overlaps the span.
+func (s *SpanPriority) overlapsInProgress(cmp base.Compare, inProgress []compactionInfo) bool {
+	bounds := s.bounds()
+	for i := range inProgress {
+		b := base.UserKeyBoundsFromInternal(inProgress[i].smallest, inProgress[i].largest)
+		if bounds.Overlaps(cmp, &b) {
+			return true
+		}
+	}
+	return false
+}
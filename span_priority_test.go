@@ -0,0 +1,103 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/datadriven"
+	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/internal/manifest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpanPriorities(t *testing.T) {
+	spans := []SpanPriority{
+		{Start: []byte("a/"), End: []byte("a0"), Priority: 10, MaxReadAmp: 2},
+		{Start: []byte("b/"), End: []byte("b0")},
+	}
+	require.Equal(t, 10, spanPriority(base.DefaultComparer.Compare, spans,
+		base.UserKeyBoundsInclusive([]byte("a/1"), []byte("b/1"))))
+	require.Equal(t, 0, spanPriority(base.DefaultComparer.Compare, spans,
+		base.UserKeyBoundsInclusive([]byte("b/1"), []byte("c"))))
+
+	opts := &Options{}
+	opts.Experimental.SpanPriorities = []SpanPriority{
+		{Start: []byte("b"), End: []byte("a")},
+	}
+	opts.EnsureDefaults()
+	require.Error(t, opts.Validate())
+	opts.Experimental.SpanPriorities = spans
+	require.NoError(t, opts.Validate())
+}
+
+func TestCompactionPickerSpanPriority(t *testing.T) {
+	var opts *Options
+	var picker *compactionPickerByScore
+	var inProgress []compactionInfo
+
+	datadriven.RunTest(t, "testdata/compaction_picker_span_priority", func(t *testing.T, td *datadriven.TestData) string {
+		switch td.Cmd {
+		case "define":
+			// Each span is given by an argument of the form
+			// span=(<start>,<end>,<priority>,<max read amp>).
+			opts = DefaultOptions()
+			for _, arg := range td.CmdArgs {
+				if arg.Key != "span" {
+					continue
+				}
+				if len(arg.Vals) != 4 {
+					td.Fatalf(t, "malformed span %s", arg)
+				}
+				s := SpanPriority{Start: []byte(arg.Vals[0]), End: []byte(arg.Vals[1])}
+				var err error
+				if s.Priority, err = strconv.Atoi(arg.Vals[2]); err != nil {
+					td.Fatalf(t, "%v", err)
+				}
+				if s.MaxReadAmp, err = strconv.Atoi(arg.Vals[3]); err != nil {
+					td.Fatalf(t, "%v", err)
+				}
+				opts.Experimental.SpanPriorities = append(opts.Experimental.SpanPriorities, s)
+			}
+			td.MaybeScanArgs(t, "l0_compaction_threshold", &opts.L0CompactionThreshold)
+			opts.EnsureDefaults()
+
+			var v *version
+			var l0Organizer *manifest.L0Organizer
+			v, l0Organizer, inProgress = parsePickerVersion(t, td, opts)
+			vb := manifest.MakeVirtualBackings()
+			picker = newCompactionPickerByScore(v, l0Organizer, &vb, opts, inProgress)
+
+			var buf strings.Builder
+			buf.WriteString(v.String())
+			for _, s := range opts.Experimental.SpanPriorities {
+				fmt.Fprintf(&buf, "span [%s, %s): priority=%d read-amp=%d max-read-amp=%d\n",
+					s.Start, s.End, s.Priority, spanReadAmp(opts.Comparer.Compare, v, s.bounds()), s.MaxReadAmp)
+			}
+			fmt.Fprintf(&buf, "span compaction slot: %t\n", picker.spanCompactionSlot)
+			return buf.String()
+
+		case "pick-auto":
+			env := compactionEnv{
+				diskAvailBytes:          math.MaxUint64,
+				earliestUnflushedSeqNum: base.SeqNumMax,
+				inProgressCompactions:   inProgress,
+			}
+			pc := picker.pickAutoScore(env)
+			if pc == nil {
+				return "nil"
+			}
+			checkClone(t, pc)
+			return fmt.Sprintf("%sscore: %.2f\n", pickedCompactionInputs(pc), pc.score)
+
+		default:
+			return fmt.Sprintf("unknown command: %s", td.Cmd)
+		}
+	})
+}
@@ -0,0 +1,357 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package block
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/crlib/crtime"
+	"github.com/cockroachdb/redact"
+	"github.com/chris124567/pebble/internal/compression"
+	"github.com/chris124567/pebble/internal/humanize"
+)
+
+// CompressionSetting is a compression algorithm, along with a compression
+// level for algorithms that support levels. AdaptiveCompression chooses a
+// setting for each data block.
+type CompressionSetting uint8
+
+// The compression settings AdaptiveCompression chooses from.
+const (
+	CompressionSettingNone CompressionSetting = iota
+	CompressionSettingSnappy
+	CompressionSettingMinLZ
+	CompressionSettingZstd1
+	CompressionSettingZstd3
+	CompressionSettingZstd7
+	NumCompressionSettings
+)
+
+var compressionSettings = [NumCompressionSettings]struct {
+	name      string
+	algorithm compression.Algorithm
+	level     int
+}{
+	CompressionSettingNone:   {name: "none", algorithm: compression.None},
+	CompressionSettingSnappy: {name: "snappy", algorithm: compression.Snappy},
+	CompressionSettingMinLZ:  {name: "minlz", algorithm: compression.MinLZ},
+	CompressionSettingZstd1:  {name: "zstd1", algorithm: compression.Zstd, level: 1},
+	CompressionSettingZstd3:  {name: "zstd3", algorithm: compression.Zstd, level: 3},
+	CompressionSettingZstd7:  {name: "zstd7", algorithm: compression.Zstd, level: 7},
+}
+
+// String implements fmt.Stringer.
+func (s CompressionSetting) String() string {
+	if s < NumCompressionSettings {
+		return compressionSettings[s].name
+	}
+	return fmt.Sprintf("unknown(%d)", s)
+}
+
+func (s CompressionSetting) newCompressor() compression.Compressor {
+	if cs := compressionSettings[s]; cs.algorithm == compression.Zstd {
+		return compression.GetZstdCompressor(cs.level)
+	}
+	return compression.GetCompressor(compressionSettings[s].algorithm)
+}
+
+// CompressionSettingStats tallies the data blocks written with a compression
+// setting.
+type CompressionSettingStats struct {
+	Blocks            uint64
+	UncompressedBytes uint64
+	CompressedBytes   uint64
+}
+
+// CompressionStats tallies data blocks written with AdaptiveCompression, by
+// the compression setting chosen for them. Blocks stored uncompressed because
+// compression did not shrink them sufficiently are counted as
+// CompressionSettingNone.
+type CompressionStats [NumCompressionSettings]CompressionSettingStats
+
+// Add adds the given stats to s.
+func (s *CompressionStats) Add(o *CompressionStats) {
+	for i := range s {
+		s[i].Blocks += o[i].Blocks
+		s[i].UncompressedBytes += o[i].UncompressedBytes
+		s[i].CompressedBytes += o[i].CompressedBytes
+	}
+}
+
+// IsZero returns true if no block was tallied.
+func (s *CompressionStats) IsZero() bool {
+	return *s == CompressionStats{}
+}
+
+// CompressionRatio returns the ratio of the compressed to the uncompressed size
+// of all the tallied blocks, or 0 if no block was tallied.
+func (s *CompressionStats) CompressionRatio() float64 {
+	var uncompressed, compressed uint64
+	for i := range s {
+		uncompressed += s[i].UncompressedBytes
+		compressed += s[i].CompressedBytes
+	}
+	if uncompressed == 0 {
+		return 0
+	}
+	return float64(compressed) / float64(uncompressed)
+}
+
+// String implements fmt.Stringer.
+func (s CompressionStats) String() string {
+	return redact.StringWithoutMarkers(s)
+}
+
+// SafeFormat implements redact.SafeFormatter.
+func (s CompressionStats) SafeFormat(w redact.SafePrinter, _ rune) {
+	var sep string
+	for i := range s {
+		if s[i].Blocks == 0 {
+			continue
+		}
+		w.Printf("%s%s: %d blocks %s->%s", redact.SafeString(sep), CompressionSetting(i),
+			redact.Safe(s[i].Blocks), humanize.Bytes.Uint64(s[i].UncompressedBytes),
+			humanize.Bytes.Uint64(s[i].CompressedBytes))
+		sep = ", "
+	}
+}
+
+// AdaptiveCompressionOptions configures AdaptiveCompression.
+type AdaptiveCompressionOptions struct {
+	// CPUWeight is the target trade-off between compression ratio and CPU
+	// time. A compression setting is chosen over a cheaper one only if, for
+	// each additional nanosecond of CPU time spent compressing and
+	// decompressing a block, it shrinks the block by at least CPUWeight more
+	// bytes. Higher values favor cheaper settings, and lower values favor
+	// better compression. Defaults to DefaultAdaptiveCompressionCPUWeight.
+	CPUWeight float64
+	// SampleInterval is the interval, in blocks, at which blocks are
+	// compressed with every setting to refresh the statistics of each setting.
+	// Defaults to DefaultAdaptiveCompressionSampleInterval.
+	SampleInterval int
+}
+
+// Defaults for AdaptiveCompressionOptions.
+const (
+	DefaultAdaptiveCompressionCPUWeight      = 0.05
+	DefaultAdaptiveCompressionSampleInterval = 32
+)
+
+// EnsureDefaults ensures that the default values for all options are set if a
+// valid value was not already specified.
+func (o AdaptiveCompressionOptions) EnsureDefaults() AdaptiveCompressionOptions {
+	if o.CPUWeight <= 0 {
+		o.CPUWeight = DefaultAdaptiveCompressionCPUWeight
+	}
+	if o.SampleInterval <= 0 {
+		o.SampleInterval = DefaultAdaptiveCompressionSampleInterval
+	}
+	return o
+}
+
+// adaptiveWarmupBlocks is the number of blocks sampled by a new
+// AdaptiveCompressionModel, before sampling every SampleInterval blocks.
+const adaptiveWarmupBlocks = 8
+
+// adaptiveDecay is the weight of the existing statistics of a setting when
+// they are updated with a new sample.
+const adaptiveDecay = 0.8
+
+// AdaptiveCompressionModel learns the compression ratio and CPU cost of each
+// compression setting, from samples of the data blocks compressed with
+// AdaptiveCompression, and chooses the setting with which to compress blocks.
+// The model is safe for concurrent use: a model is typically shared by all the
+// tables written to an LSM level, whose data tends to compress alike.
+//
+// The CPU cost of a setting is measured as the wall time of compressing and
+// decompressing samples, so it is approximate.
+type AdaptiveCompressionModel struct {
+	opts AdaptiveCompressionOptions
+	// blocks counts the blocks compressed with the model, to decide which to
+	// sample.
+	blocks atomic.Uint64
+	// choice is the CompressionSetting with the lowest cost.
+	choice atomic.Uint32
+	mu     struct {
+		sync.Mutex
+		settings [NumCompressionSettings]adaptiveSettingStats
+	}
+}
+
+type adaptiveSettingStats struct {
+	samples uint64
+	// ratio is the average ratio of the stored size to the uncompressed size.
+	ratio float64
+	// nanosPerByte is the average CPU time spent compressing and
+	// decompressing, per uncompressed byte.
+	nanosPerByte float64
+}
+
+// NewAdaptiveCompressionModel returns a new AdaptiveCompressionModel, which
+// compresses with snappy until it learns better.
+func NewAdaptiveCompressionModel(opts AdaptiveCompressionOptions) *AdaptiveCompressionModel {
+	m := &AdaptiveCompressionModel{opts: opts.EnsureDefaults()}
+	m.choice.Store(uint32(CompressionSettingSnappy))
+	return m
+}
+
+// Choice returns the compression setting currently chosen by the model.
+func (m *AdaptiveCompressionModel) Choice() CompressionSetting {
+	return CompressionSetting(m.choice.Load())
+}
+
+// Estimate returns the current estimates of the model for the given setting:
+// the ratio of the stored size of a block to its uncompressed size, and the CPU
+// time spent compressing and decompressing a block, per uncompressed byte. ok
+// is false if the setting was never sampled.
+func (m *AdaptiveCompressionModel) Estimate(
+	s CompressionSetting,
+) (ratio float64, nanosPerByte float64, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := m.mu.settings[s]
+	return st.ratio, st.nanosPerByte, st.samples > 0
+}
+
+func (m *AdaptiveCompressionModel) cost(ratio, nanosPerByte float64) float64 {
+	return ratio + m.opts.CPUWeight*nanosPerByte
+}
+
+// shouldSample is called for every block compressed with the model, and
+// returns true if the block should be sampled.
+func (m *AdaptiveCompressionModel) shouldSample() bool {
+	n := m.blocks.Add(1)
+	return n <= adaptiveWarmupBlocks || n%uint64(m.opts.SampleInterval) == 0
+}
+
+// addSample updates the statistics of every setting with the measurements of
+// a sampled block, and updates the choice of the model.
+func (m *AdaptiveCompressionModel) addSample(sample *[NumCompressionSettings]adaptiveSettingStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	best, bestCost := CompressionSettingNone, 0.0
+	for s := range m.mu.settings {
+		st := &m.mu.settings[s]
+		if st.samples == 0 {
+			st.ratio, st.nanosPerByte = sample[s].ratio, sample[s].nanosPerByte
+		} else {
+			st.ratio = adaptiveDecay*st.ratio + (1-adaptiveDecay)*sample[s].ratio
+			st.nanosPerByte = adaptiveDecay*st.nanosPerByte + (1-adaptiveDecay)*sample[s].nanosPerByte
+		}
+		st.samples++
+		if c := m.cost(st.ratio, st.nanosPerByte); s == 0 || c < bestCost {
+			best, bestCost = CompressionSetting(s), c
+		}
+	}
+	m.choice.Store(uint32(best))
+}
+
+// adaptiveCompressor compresses each block with the setting chosen by its
+// model, sampling some blocks to refine the model.
+type adaptiveCompressor struct {
+	model       *AdaptiveCompressionModel
+	compressors [NumCompressionSettings]compression.Compressor
+	// scratch, sampled and decompressed are buffers used when sampling.
+	// sampled holds the output of the setting with the lowest cost.
+	scratch      []byte
+	sampled      []byte
+	decompressed []byte
+	// last is the setting with which the last block was compressed.
+	last  CompressionSetting
+	stats CompressionStats
+}
+
+func (a *adaptiveCompressor) compressor(s CompressionSetting) compression.Compressor {
+	if a.compressors[s] == nil {
+		a.compressors[s] = s.newCompressor()
+	}
+	return a.compressors[s]
+}
+
+func (a *adaptiveCompressor) Compress(dst, src []byte) (CompressionIndicator, []byte) {
+	s := a.model.Choice()
+	var sampled []byte
+	if a.model.shouldSample() && len(src) > 0 {
+		s, sampled = a.sample(src)
+	}
+	a.last = s
+	ci := compressionIndicatorFromAlgorithm(compressionSettings[s].algorithm)
+	if sampled != nil {
+		return ci, append(dst[:0], sampled...)
+	}
+	return ci, a.compressor(s).Compress(dst, src)
+}
+
+// sample compresses and decompresses src with every setting, adds the
+// measurements to the model, and returns the setting with the lowest cost for
+// src, along with its output unless it is CompressionSettingNone.
+func (a *adaptiveCompressor) sample(src []byte) (CompressionSetting, []byte) {
+	var sample [NumCompressionSettings]adaptiveSettingStats
+	sample[CompressionSettingNone].ratio = 1
+	best, bestCost := CompressionSettingNone, a.model.cost(1, 0)
+	for s := CompressionSettingNone + 1; s < NumCompressionSettings; s++ {
+		start := crtime.NowMono()
+		a.scratch = a.compressor(s).Compress(a.scratch[:0], src)
+		elapsed := start.Elapsed()
+		ratio := float64(len(a.scratch)) / float64(len(src))
+		// CompressAndChecksum stores blocks that compression does not shrink
+		// by at least 12.5% uncompressed; these need no decompression.
+		if len(a.scratch) >= len(src)-len(src)/8 {
+			ratio = 1
+		} else {
+			elapsed += a.timeDecompression(s, len(src))
+		}
+		sample[s].ratio = ratio
+		sample[s].nanosPerByte = float64(elapsed.Nanoseconds()) / float64(len(src))
+		if c := a.model.cost(sample[s].ratio, sample[s].nanosPerByte); c < bestCost {
+			best, bestCost = s, c
+			a.scratch, a.sampled = a.sampled, a.scratch
+		}
+	}
+	a.model.addSample(&sample)
+	if best == CompressionSettingNone {
+		return best, nil
+	}
+	return best, a.sampled
+}
+
+// timeDecompression returns the time spent decompressing a.scratch, which
+// holds a block of the given length compressed with the given setting.
+func (a *adaptiveCompressor) timeDecompression(s CompressionSetting, n int) time.Duration {
+	if cap(a.decompressed) < n {
+		a.decompressed = make([]byte, n)
+	}
+	a.decompressed = a.decompressed[:n]
+	d := compression.GetDecompressor(compressionSettings[s].algorithm)
+	defer d.Close()
+	start := crtime.NowMono()
+	if err := d.DecompressInto(a.decompressed, a.scratch); err != nil {
+		panic(err)
+	}
+	return start.Elapsed()
+}
+
+// recordBlock tallies a block compressed by the last call to Compress.
+func (a *adaptiveCompressor) recordBlock(ci CompressionIndicator, uncompressedLen, storedLen int) {
+	s := a.last
+	if ci == NoCompressionIndicator {
+		s = CompressionSettingNone
+	}
+	a.stats[s].Blocks++
+	a.stats[s].UncompressedBytes += uint64(uncompressedLen)
+	a.stats[s].CompressedBytes += uint64(storedLen)
+}
+
+func (a *adaptiveCompressor) Close() {
+	for _, c := range a.compressors {
+		if c != nil {
+			c.Close()
+		}
+	}
+	*a = adaptiveCompressor{}
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/chris124567/pebble/internal/base"
 	"github.com/chris124567/pebble/internal/bitflip"
 	"github.com/chris124567/pebble/internal/cache"
+	"github.com/chris124567/pebble/internal/compression"
 	"github.com/chris124567/pebble/internal/crc"
 	"github.com/chris124567/pebble/internal/invariants"
 	"github.com/chris124567/pebble/internal/sstableinternal"
@@ -404,6 +405,8 @@ type Reader struct {
 	readable     objstorage.Readable
 	opts         ReaderOptions
 	checksumType ChecksumType
+	// dict is the compression dictionary of the file, if any.
+	dict *compression.Dictionary
 }
 
 // ReaderOptions configures a block reader.
@@ -436,6 +439,19 @@ func (r *Reader) ChecksumType() ChecksumType {
 	return r.checksumType
 }
 
+// SetCompressionDictionary sets the compression dictionary used to decompress
+// blocks compressed with a dictionary. It must be called before any such block
+// is read.
+func (r *Reader) SetCompressionDictionary(dict *compression.Dictionary) {
+	r.dict = dict
+}
+
+// CompressionDictionary returns the compression dictionary of the file, or nil
+// if it has none.
+func (r *Reader) CompressionDictionary() *compression.Dictionary {
+	return r.dict
+}
+
 // Read reads the block referenced by the provided handle. The readHandle is
 // optional.
 func (r *Reader) Read(
@@ -563,13 +579,13 @@ func (r *Reader) doRead(
 		decompressed = compressed
 	} else {
 		// Decode the length of the decompressed value.
-		decodedLen, err := DecompressedLen(typ, compressed.BlockData())
+		decodedLen, err := DecompressedLen(typ, r.dict, compressed.BlockData())
 		if err != nil {
 			compressed.Release()
 			return Value{}, err
 		}
 		decompressed = Alloc(decodedLen, env.BufferPool)
-		err = DecompressInto(typ, compressed.BlockData(), decompressed.BlockData())
+		err = DecompressInto(typ, r.dict, compressed.BlockData(), decompressed.BlockData())
 		compressed.Release()
 		if err != nil {
 			decompressed.Release()
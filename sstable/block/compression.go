@@ -29,6 +29,20 @@ const (
 	// MinLZCompression is only supported with table formats v6+. Older formats
 	// fall back to snappy.
 	MinLZCompression
+	// LZ4Compression and ZstdDictionaryCompression are only supported with
+	// table formats v7+. Older formats fall back to snappy.
+	LZ4Compression
+	// ZstdDictionaryCompression compresses data blocks with Zstandard, using a
+	// dictionary trained on the first data blocks of each table and stored in
+	// the table. Other blocks are compressed with Zstandard, without a
+	// dictionary.
+	ZstdDictionaryCompression
+	// AdaptiveCompression compresses each data block with the compression
+	// setting chosen by an AdaptiveCompressionModel, which learns the ratio and
+	// CPU cost of each setting from samples of the blocks. Other blocks are
+	// compressed with MinLZ. It is only supported with table formats v6+.
+	// Older formats fall back to snappy.
+	AdaptiveCompression
 	NCompression
 )
 
@@ -38,6 +52,13 @@ var algorithm = [...]compression.Algorithm{
 	SnappyCompression:  compression.Snappy,
 	ZstdCompression:    compression.Zstd,
 	MinLZCompression:   compression.MinLZ,
+	LZ4Compression:     compression.LZ4,
+	// Blocks are only compressed with a dictionary through a Compressor
+	// returned by GetDictionaryCompressor.
+	ZstdDictionaryCompression: compression.Zstd,
+	// Data blocks are only compressed adaptively through a Compressor
+	// returned by GetAdaptiveCompressor.
+	AdaptiveCompression: compression.MinLZ,
 }
 
 func (c Compression) algorithm() compression.Algorithm {
@@ -58,6 +79,12 @@ func (c Compression) String() string {
 		return "ZSTD"
 	case MinLZCompression:
 		return "MinLZ"
+	case LZ4Compression:
+		return "LZ4"
+	case ZstdDictionaryCompression:
+		return "ZSTDDict"
+	case AdaptiveCompression:
+		return "Adaptive"
 	default:
 		return "Unknown"
 	}
@@ -77,6 +104,12 @@ func CompressionFromString(s string) Compression {
 		return ZstdCompression
 	case "MinLZ":
 		return MinLZCompression
+	case "LZ4":
+		return LZ4Compression
+	case "ZSTDDict":
+		return ZstdDictionaryCompression
+	case "Adaptive":
+		return AdaptiveCompression
 	default:
 		return DefaultCompression
 	}
@@ -105,6 +138,9 @@ const (
 	XpressCompressionIndicator CompressionIndicator = 6
 	ZstdCompressionIndicator   CompressionIndicator = 7
 	MinLZCompressionIndicator  CompressionIndicator = 8
+	// ZstdDictCompressionIndicator indicates a block compressed with
+	// Zstandard, using the compression dictionary of the table.
+	ZstdDictCompressionIndicator CompressionIndicator = 9
 )
 
 // String implements fmt.Stringer.
@@ -128,6 +164,8 @@ func (i CompressionIndicator) String() string {
 		return "zstd"
 	case 8:
 		return "minlz"
+	case 9:
+		return "zstd-dict"
 	default:
 		panic(errors.Newf("sstable: unknown block type: %d", i))
 	}
@@ -143,6 +181,8 @@ func (i CompressionIndicator) algorithm() compression.Algorithm {
 		return compression.Zstd
 	case MinLZCompressionIndicator:
 		return compression.MinLZ
+	case Lz4CompressionIndicator:
+		return compression.LZ4
 	default:
 		panic("Invalid compression type.")
 	}
@@ -158,6 +198,8 @@ func compressionIndicatorFromAlgorithm(algo compression.Algorithm) CompressionIn
 		return ZstdCompressionIndicator
 	case compression.MinLZ:
 		return MinLZCompressionIndicator
+	case compression.LZ4:
+		return Lz4CompressionIndicator
 	default:
 		panic("invalid algorithm")
 	}
@@ -166,8 +208,10 @@ func compressionIndicatorFromAlgorithm(algo compression.Algorithm) CompressionIn
 // DecompressedLen returns the length of the provided block once decompressed,
 // allowing the caller to allocate a buffer exactly sized to the decompressed
 // payload.
-func DecompressedLen(ci CompressionIndicator, b []byte) (decompressedLen int, err error) {
-	decompressor := GetDecompressor(ci)
+func DecompressedLen(
+	ci CompressionIndicator, dict *compression.Dictionary, b []byte,
+) (decompressedLen int, err error) {
+	decompressor := GetDecompressor(ci, dict)
 	defer decompressor.Close()
 	return decompressor.DecompressedLen(b)
 }
@@ -175,8 +219,13 @@ func DecompressedLen(ci CompressionIndicator, b []byte) (decompressedLen int, er
 // DecompressInto decompresses compressed into buf. The buf slice must have the
 // exact size as the decompressed value. Callers may use DecompressedLen to
 // determine the correct size.
-func DecompressInto(ci CompressionIndicator, compressed []byte, buf []byte) error {
-	decompressor := GetDecompressor(ci)
+//
+// The dictionary is the compression dictionary of the table containing the
+// block, or nil if the table has none.
+func DecompressInto(
+	ci CompressionIndicator, dict *compression.Dictionary, compressed []byte, buf []byte,
+) error {
+	decompressor := GetDecompressor(ci, dict)
 	defer decompressor.Close()
 	err := decompressor.DecompressInto(buf, compressed)
 	if err != nil {
@@ -279,6 +328,9 @@ func CompressAndChecksumWithCompressor(
 		ci = NoCompressionIndicator
 		buf = append(buf[:0], blockData...)
 	}
+	if compressor.adaptive != nil {
+		compressor.adaptive.recordBlock(ci, len(blockData), len(buf))
+	}
 
 	*dst = buf
 
@@ -5,11 +5,13 @@
 package block
 
 import (
+	"bytes"
 	"fmt"
 	"math/rand/v2"
 	"testing"
 	"time"
 
+	"github.com/chris124567/pebble/internal/compression"
 	"github.com/stretchr/testify/require"
 )
 
@@ -54,3 +56,129 @@ func TestBufferRandomized(t *testing.T) {
 		})
 	}
 }
+
+// countingCompressor counts the blocks compressed by a compressor.
+type countingCompressor struct {
+	compression.Compressor
+	n int
+}
+
+func (c *countingCompressor) Compress(dst, src []byte) []byte {
+	c.n++
+	return c.Compressor.Compress(dst, src)
+}
+
+func TestAdaptiveCompressionReusesSample(t *testing.T) {
+	model := NewAdaptiveCompressionModel(AdaptiveCompressionOptions{CPUWeight: 1e-9})
+	a := &adaptiveCompressor{model: model}
+	defer a.Close()
+	var counters [NumCompressionSettings]*countingCompressor
+	for s := range counters {
+		counters[s] = &countingCompressor{Compressor: CompressionSetting(s).newCompressor()}
+		a.compressors[s] = counters[s]
+	}
+	src := bytes.Repeat([]byte("compressible "), 1000)
+	// The first block is sampled: it is compressed once with every setting,
+	// and its output is that of the setting chosen by sampling.
+	ci, compressed := a.Compress(nil, src)
+	require.NotEqual(t, CompressionSettingNone, a.last)
+	for s := CompressionSettingNone + 1; s < NumCompressionSettings; s++ {
+		require.Equal(t, 1, counters[s].n, "%s", s)
+	}
+	require.Equal(t, compressionIndicatorFromAlgorithm(compressionSettings[a.last].algorithm), ci)
+	decompressed := make([]byte, len(src))
+	require.NoError(t, DecompressInto(ci, nil, compressed, decompressed))
+	require.Equal(t, src, decompressed)
+}
+
+func TestAdaptiveCompression(t *testing.T) {
+	rng := rand.New(rand.NewPCG(0, uint64(time.Now().UnixNano())))
+	randomBlock := func() []byte {
+		b := make([]byte, 32<<10)
+		for i := range b {
+			b[i] = byte(rng.Uint32())
+		}
+		return b
+	}
+	compressibleBlock := func() []byte {
+		var buf bytes.Buffer
+		for buf.Len() < 32<<10 {
+			fmt.Fprintf(&buf, `{"id":%d,"name":"user%d","email":"user%d@example.com","active":%t}`,
+				rng.IntN(1e6), rng.IntN(1000), rng.IntN(1000), rng.IntN(2) == 0)
+		}
+		return buf.Bytes()
+	}
+
+	testCases := []struct {
+		name      string
+		cpuWeight float64
+		block     func() []byte
+		check     func(t *testing.T, s CompressionSetting)
+	}{
+		{
+			name:      "incompressible",
+			cpuWeight: DefaultAdaptiveCompressionCPUWeight,
+			block:     randomBlock,
+			check: func(t *testing.T, s CompressionSetting) {
+				require.Equal(t, CompressionSettingNone, s)
+			},
+		},
+		{
+			name:      "favor-ratio",
+			cpuWeight: 1e-9,
+			block:     compressibleBlock,
+			check: func(t *testing.T, s CompressionSetting) {
+				require.Contains(t, []CompressionSetting{
+					CompressionSettingZstd1, CompressionSettingZstd3, CompressionSettingZstd7,
+				}, s)
+			},
+		},
+		{
+			name:      "favor-cpu",
+			cpuWeight: 1e9,
+			block:     compressibleBlock,
+			check: func(t *testing.T, s CompressionSetting) {
+				require.Equal(t, CompressionSettingNone, s)
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			model := NewAdaptiveCompressionModel(AdaptiveCompressionOptions{
+				CPUWeight:      tc.cpuWeight,
+				SampleInterval: 4,
+			})
+			c := GetAdaptiveCompressor(model)
+			defer c.Close()
+			var checksummer Checksummer
+			checksummer.Type = ChecksumTypeCRC32c
+			var buf []byte
+			var uncompressedBytes uint64
+			for i := 0; i < 20; i++ {
+				b := tc.block()
+				uncompressedBytes += uint64(len(b))
+				pb := CompressAndChecksumWithCompressor(&buf, b, c, &checksummer)
+				ci := CompressionIndicator(pb.trailer[0])
+				decompressed := make([]byte, len(b))
+				if ci == NoCompressionIndicator {
+					copy(decompressed, pb.data)
+				} else {
+					require.NoError(t, DecompressInto(ci, nil, pb.data, decompressed))
+				}
+				require.Equal(t, b, decompressed)
+			}
+			tc.check(t, model.Choice())
+
+			stats := c.Stats()
+			var blocks, total uint64
+			for i := range stats {
+				blocks += stats[i].Blocks
+				total += stats[i].UncompressedBytes
+			}
+			require.Equal(t, uint64(20), blocks)
+			require.Equal(t, uncompressedBytes, total)
+			require.Greater(t, stats[model.Choice()].Blocks, uint64(0))
+			t.Logf("%s (ratio %.2f)", stats, stats.CompressionRatio())
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package block
 
-import "github.com/chris124567/pebble/internal/compression"
+import (
+	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/internal/compression"
+)
 
 // Compressor is used to compress blocks. Typical usage:
 //
@@ -11,6 +14,10 @@ import "github.com/chris124567/pebble/internal/compression"
 type Compressor struct {
 	algorithm  compression.Algorithm
 	compressor compression.Compressor
+	// dict is set if the Compressor compresses with a dictionary.
+	dict *compression.Dictionary
+	// adaptive is set if the Compressor chooses the compression of each block.
+	adaptive *adaptiveCompressor
 }
 
 // GetCompressor returns a Compressor that applies the given compression. Close
@@ -23,10 +30,59 @@ func GetCompressor(c Compression) Compressor {
 	}
 }
 
+// GetCompressorWithLevel returns a Compressor that applies the given
+// compression at the given compression level. Only Zstandard supports levels;
+// other algorithms ignore the level, and a zero level selects the default
+// level. Close must be called when it is no longer needed.
+func GetCompressorWithLevel(c Compression, level int) Compressor {
+	algorithm := c.algorithm()
+	if level == 0 || algorithm != compression.Zstd {
+		return GetCompressor(c)
+	}
+	return Compressor{
+		algorithm:  algorithm,
+		compressor: compression.GetZstdCompressor(level),
+	}
+}
+
+// GetDictionaryCompressor returns a Compressor that applies Zstandard
+// compression with the given dictionary. Blocks it compresses can only be
+// decompressed with the same dictionary. Close must be called when it is no
+// longer needed.
+func GetDictionaryCompressor(dict *compression.Dictionary) Compressor {
+	return Compressor{
+		algorithm:  compression.Zstd,
+		compressor: dict.Compressor(),
+		dict:       dict,
+	}
+}
+
+// GetAdaptiveCompressor returns a Compressor that compresses each block with
+// the compression setting chosen by the given model, sampling some blocks to
+// refine the model. Close must be called when it is no longer needed.
+func GetAdaptiveCompressor(model *AdaptiveCompressionModel) Compressor {
+	return Compressor{adaptive: &adaptiveCompressor{model: model}}
+}
+
+// Stats returns the tally of the blocks compressed by an adaptive Compressor,
+// through CompressAndChecksumWithCompressor. It is zero for other Compressors.
+func (c *Compressor) Stats() CompressionStats {
+	if c.adaptive == nil {
+		return CompressionStats{}
+	}
+	return c.adaptive.stats
+}
+
 // Compress a block, appending the compressed data to dst[:0].
 //
 // In addition to the buffer, returns the algorithm that was used.
 func (c *Compressor) Compress(dst, src []byte) (CompressionIndicator, []byte) {
+	if c.adaptive != nil {
+		return c.adaptive.Compress(dst, src)
+	}
+	if c.dict != nil {
+		return ZstdDictCompressionIndicator, c.compressor.Compress(dst, src)
+	}
 	ci := compressionIndicatorFromAlgorithm(c.algorithm)
 	return ci, c.compressor.Compress(dst, src)
 }
@@ -34,12 +90,43 @@ func (c *Compressor) Compress(dst, src []byte) (CompressionIndicator, []byte) {
 // Close must be called when the Compressor is no longer needed.
 // After Close is called, the Compressor must not be used again.
 func (c *Compressor) Close() {
-	c.compressor.Close()
+	if c.adaptive != nil {
+		c.adaptive.Close()
+	} else {
+		c.compressor.Close()
+	}
 	*c = Compressor{}
 }
 
 type Decompressor = compression.Decompressor
 
-func GetDecompressor(c CompressionIndicator) Decompressor {
+// GetDecompressor returns a Decompressor for blocks with the given compression
+// indicator. The dictionary is used for blocks compressed with a dictionary; it
+// is the compression dictionary of the table containing the blocks, or nil if
+// the table has none.
+func GetDecompressor(c CompressionIndicator, dict *compression.Dictionary) Decompressor {
+	if c == ZstdDictCompressionIndicator {
+		if dict == nil {
+			return missingDictionaryDecompressor{}
+		}
+		return dict.Decompressor()
+	}
 	return compression.GetDecompressor(c.algorithm())
 }
+
+// missingDictionaryDecompressor is the Decompressor of blocks compressed with a
+// dictionary, in a table without a dictionary.
+type missingDictionaryDecompressor struct{}
+
+var errMissingDictionary = base.CorruptionErrorf(
+	"pebble: block compressed with a dictionary, in a table without a compression dictionary")
+
+func (missingDictionaryDecompressor) DecompressInto(buf, compressed []byte) error {
+	return errMissingDictionary
+}
+
+func (missingDictionaryDecompressor) DecompressedLen(b []byte) (decompressedLen int, err error) {
+	return 0, errMissingDictionary
+}
+
+func (missingDictionaryDecompressor) Close() {}
@@ -16,6 +16,7 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/chris124567/pebble/internal/base"
 	"github.com/chris124567/pebble/internal/bytealloc"
+	"github.com/chris124567/pebble/internal/compression"
 	"github.com/chris124567/pebble/internal/invariants"
 	"github.com/chris124567/pebble/internal/keyspan"
 	"github.com/chris124567/pebble/objstorage"
@@ -61,7 +62,11 @@ type RawColumnWriter struct {
 	// filter accumulates the filter block. If populated, the filter ingests
 	// either the output of w.split (i.e. a prefix extractor) if w.split is not
 	// nil, or the full keys otherwise.
-	filterBlock  filterWriter
+	filterBlock filterWriter
+	// rangeFilter accumulates the range filter block. It's nil if
+	// WriterOptions.RangeFilterPrefixLength is zero or the Comparer doesn't
+	// declare BytewisePrefixes.
+	rangeFilter  *rangeFilterWriter
 	prevPointKey struct {
 		trailer    base.InternalKeyTrailer
 		isObsolete bool
@@ -112,8 +117,32 @@ type RawColumnWriter struct {
 
 	// RawColumnWriter writes data sequentially so each writer can have a compressor
 	compressor block.Compressor
+
+	// compressionDict holds the state of the compression dictionary of the
+	// table, when data blocks are compressed with ZstdDictionaryCompression.
+	compressionDict struct {
+		// samples holds copies of the first data blocks of the table, on which
+		// the dictionary is trained.
+		samples     [][]byte
+		sampledSize int
+		// dict is the trained dictionary, or the dictionary of the table data
+		// blocks are copied from. Once set, data blocks are compressed with it.
+		dict *compression.Dictionary
+		// done is set once no more samples are needed.
+		done bool
+	}
 }
 
+const (
+	// compressionDictSampleSize is the size of the data blocks sampled to train
+	// a compression dictionary. Data blocks written before the dictionary is
+	// trained are compressed without it.
+	compressionDictSampleSize = 256 << 10
+	// compressionDictSize is the maximum size of a trained compression
+	// dictionary.
+	compressionDictSize = 16 << 10
+)
+
 // Assert that *RawColumnWriter implements RawWriter.
 var _ RawWriter = (*RawColumnWriter)(nil)
 
@@ -153,11 +182,18 @@ func newColumnarWriter(
 	if o.FilterPolicy != nil {
 		switch o.FilterType {
 		case TableFilter:
-			w.filterBlock = newTableFilterWriter(o.FilterPolicy)
+			if o.PartitionFilters {
+				w.filterBlock = newPartitionedFilterWriter(o.FilterPolicy)
+			} else {
+				w.filterBlock = newTableFilterWriter(o.FilterPolicy)
+			}
 		default:
 			panic(fmt.Sprintf("unknown filter type: %v", o.FilterType))
 		}
 	}
+	if o.RangeFilterPrefixLength > 0 && o.Comparer.BytewisePrefixes {
+		w.rangeFilter = newRangeFilterWriter(o.RangeFilterPrefixLength)
+	}
 
 	numBlockPropertyCollectors := len(o.BlockPropertyCollectors)
 	if !o.disableObsoleteCollector {
@@ -194,7 +230,15 @@ func newColumnarWriter(
 	w.cpuMeasurer = cpuMeasurer
 	go w.drainWriteQueue()
 
-	w.compressor = block.GetCompressor(w.opts.Compression)
+	if w.opts.Compression == block.AdaptiveCompression {
+		model := w.opts.AdaptiveCompressionModel
+		if model == nil {
+			model = block.NewAdaptiveCompressionModel(block.AdaptiveCompressionOptions{})
+		}
+		w.compressor = block.GetAdaptiveCompressor(model)
+	} else {
+		w.compressor = block.GetCompressorWithLevel(w.opts.Compression, w.opts.CompressionLevel)
+	}
 	return w
 }
 
@@ -267,6 +311,14 @@ func (w *RawColumnWriter) SetSnapshotPinnedProperties(
 	w.props.SnapshotPinnedValueSize = pinnedValueSize
 }
 
+// SetValueExpiryProperties sets the properties for values with an expiry time.
+// Should only be used internally by Pebble.
+func (w *RawColumnWriter) SetValueExpiryProperties(valueSize, minExpiry, maxExpiry uint64) {
+	w.props.ExpiringValueSize = valueSize
+	w.props.MinValueExpiry = minExpiry
+	w.props.MaxValueExpiry = maxExpiry
+}
+
 // Metadata returns the metadata for the finished sstable. Only valid to call
 // after the sstable has been finished.
 func (w *RawColumnWriter) Metadata() (*WriterMetadata, error) {
@@ -497,6 +549,9 @@ func (w *RawColumnWriter) add(
 	if w.filterBlock != nil {
 		w.filterBlock.addKey(key.UserKey[:eval.kcmp.PrefixLen])
 	}
+	if w.rangeFilter != nil {
+		w.rangeFilter.addKey(key.UserKey[:eval.kcmp.PrefixLen])
+	}
 	w.meta.updateSeqNum(key.SeqNum())
 	if !w.meta.HasPointKeys {
 		w.meta.SetSmallestPointKey(key.Clone())
@@ -717,6 +772,10 @@ func (w *RawColumnWriter) enqueueDataBlock(
 		}
 	}
 
+	if w.opts.Compression == block.ZstdDictionaryCompression && !w.compressionDict.done {
+		w.sampleForCompressionDict(serializedBlock)
+	}
+
 	// Serialize the data block, compress it and send it to the write queue.
 	cb := compressedBlockPool.Get().(*compressedBlock)
 	cb.blockBuf.checksummer.Type = w.opts.Checksum
@@ -729,6 +788,35 @@ func (w *RawColumnWriter) enqueueDataBlock(
 	return w.enqueuePhysicalBlock(cb, separator)
 }
 
+// sampleForCompressionDict records a sample of the provided data block. Once
+// enough data blocks are sampled, it trains the compression dictionary of the
+// table, and switches to compressing data blocks with it. If training fails,
+// data blocks continue to be compressed without a dictionary.
+func (w *RawColumnWriter) sampleForCompressionDict(serializedBlock []byte) {
+	d := &w.compressionDict
+	d.samples = append(d.samples, slices.Clone(serializedBlock))
+	d.sampledSize += len(serializedBlock)
+	if d.sampledSize < compressionDictSampleSize {
+		return
+	}
+	dict, err := compression.TrainDictionary(d.samples, compressionDictSize)
+	d.samples = nil
+	d.done = true
+	if err != nil {
+		// The dictionary is an optimization; proceed without it.
+		return
+	}
+	w.useCompressionDict(dict)
+}
+
+// useCompressionDict switches to compressing data blocks with the provided
+// dictionary, which is written to the table when it is finished.
+func (w *RawColumnWriter) useCompressionDict(dict *compression.Dictionary) {
+	w.compressionDict.dict = dict
+	w.compressor.Close()
+	w.compressor = block.GetDictionaryCompressor(dict)
+}
+
 func (w *RawColumnWriter) enqueuePhysicalBlock(cb *compressedBlock, separator []byte) error {
 	dataBlockHandle := block.Handle{
 		Offset: w.queuedDataSize,
@@ -760,6 +848,11 @@ func (w *RawColumnWriter) enqueuePhysicalBlock(cb *compressedBlock, separator []
 		if err = w.finishIndexBlock(w.indexBlock.Rows() - 1); err != nil {
 			return err
 		}
+		// Partitioned filters are cut along with the index blocks, so that
+		// each filter partition covers the data blocks of one index block.
+		if f, ok := w.filterBlock.(*partitionedFilterWriter); ok {
+			f.finishPartition(w.indexBuffering.partitions[len(w.indexBuffering.partitions)-1].sep.UserKey)
+		}
 		// finishIndexBlock reset the index block builder, and we can
 		// add the block handle to this new index block.
 		_ = w.indexBlock.AddBlockHandle(separator, dataBlockHandle, dataBlockProps)
@@ -773,6 +866,9 @@ func (w *RawColumnWriter) enqueuePhysicalBlock(cb *compressedBlock, separator []
 	for i := range w.blockPropCollectors {
 		w.blockPropCollectors[i].AddPrevDataBlockToIndexBlock()
 	}
+	if f, ok := w.filterBlock.(*partitionedFilterWriter); ok {
+		f.finishDataBlock(separator)
+	}
 	return nil
 }
 
@@ -959,7 +1055,16 @@ func (w *RawColumnWriter) Close() (err error) {
 	}
 
 	// Write the filter block.
-	if w.filterBlock != nil {
+	if f, ok := w.filterBlock.(*partitionedFilterWriter); ok {
+		size, err := w.layout.WritePartitionedFilter(f)
+		if err != nil {
+			return err
+		}
+		if size > 0 {
+			w.props.FilterPolicyName = f.policyName()
+			w.props.FilterSize = size
+		}
+	} else if w.filterBlock != nil {
 		bh, err := w.layout.WriteFilterBlock(w.filterBlock)
 		if err != nil {
 			return err
@@ -968,6 +1073,13 @@ func (w *RawColumnWriter) Close() (err error) {
 		w.props.FilterSize = bh.Length
 	}
 
+	// Write the range filter block if non-empty.
+	if w.rangeFilter != nil && !w.rangeFilter.empty() {
+		if _, err := w.layout.WriteRangeFilterBlock(w.rangeFilter.finish()); err != nil {
+			return err
+		}
+	}
+
 	// Write the range deletion block if non-empty.
 	if w.rangeDelBlock.KeyCount() > 0 {
 		w.props.NumRangeDeletions = uint64(w.rangeDelBlock.KeyCount())
@@ -1000,6 +1112,15 @@ func (w *RawColumnWriter) Close() (err error) {
 		w.props.ValueBlocksSize = vbStats.ValueBlocksAndIndexSize
 	}
 
+	// Write the compression dictionary.
+	if dict := w.compressionDict.dict; dict != nil {
+		bh, err := w.layout.WriteCompressionDictBlock(dict.Bytes())
+		if err != nil {
+			return err
+		}
+		w.props.CompressionDictionarySize = bh.Length
+	}
+
 	// Write the properties block.
 	{
 		// Finish and record the prop collectors if props are not yet recorded.
@@ -1062,6 +1183,7 @@ func (w *RawColumnWriter) Close() (err error) {
 		return err
 	}
 	w.meta.Properties = w.props
+	w.meta.CompressionStats = w.compressor.Stats()
 	w.compressor.Close()
 	// Release any held memory and make any future calls error.
 	*w = RawColumnWriter{meta: w.meta, err: errWriterClosed}
@@ -1145,20 +1267,31 @@ func (w *RawColumnWriter) rewriteSuffixes(
 	if err := rewriteRangeKeyBlockToWriter(r, w, from, to); err != nil {
 		return errors.Wrap(err, "rewriting range key blocks")
 	}
-	// Copy over the filter block if it exists.
+	// Copy over the filter block if it exists. Rewriting suffixes leaves
+	// prefixes unchanged, so the filter of the original table remains valid
+	// whether or not it's partitioned: a partitioned filter is located by
+	// prefix, and the partition separators order the same way against any
+	// prefix after suffix replacement.
 	if w.filterBlock != nil {
-		if filterBlockBH, ok := l.FilterByName(w.filterBlock.metaName()); ok {
-			filterBlock, _, err := readBlockBuf(sstBytes, filterBlockBH, r.blockReader.ChecksumType(), nil)
+		policyName := w.filterBlock.policyName()
+		if filterBlockBH, ok := l.FilterByName(fullFilterMetaPrefix + policyName); ok {
+			filterBlock, _, err := readBlockBuf(sstBytes, filterBlockBH, r.blockReader.ChecksumType(), nil /* dict */, nil)
 			if err != nil {
 				return errors.Wrap(err, "reading filter")
 			}
 			w.filterBlock = copyFilterWriter{
-				origPolicyName: w.filterBlock.policyName(),
-				origMetaName:   w.filterBlock.metaName(),
+				origPolicyName: policyName,
+				origMetaName:   fullFilterMetaPrefix + policyName,
 				// Clone the filter block, because readBlockBuf allows the
 				// returned byte slice to point directly into sst.
 				data: slices.Clone(filterBlock),
 			}
+		} else if _, ok := l.FilterByName(partitionedFilterMetaPrefix + policyName); ok {
+			partitions, err := r.readFilterPartitions(context.TODO(), noReadHandle)
+			if err != nil {
+				return errors.Wrap(err, "reading filter")
+			}
+			w.filterBlock = copyPartitionedFilterWriter(policyName, partitions)
 		}
 	}
 	return nil
@@ -1263,8 +1396,20 @@ func (w *RawColumnWriter) copyFilter(filter []byte, filterName string) error {
 		return errors.New("mismatched filters")
 	}
 	w.filterBlock = copyFilterWriter{
-		origPolicyName: w.filterBlock.policyName(), origMetaName: w.filterBlock.metaName(), data: filter,
+		origPolicyName: filterName, origMetaName: fullFilterMetaPrefix + filterName, data: filter,
+	}
+	return nil
+}
+
+// copyPartitionedFilter copies the specified partitioned filter to the table.
+// Like copyFilter, it's used by the sstable copier.
+func (w *RawColumnWriter) copyPartitionedFilter(
+	partitions []filterPartition, filterName string,
+) error {
+	if w.filterBlock != nil && filterName != w.filterBlock.policyName() {
+		return errors.New("mismatched filters")
 	}
+	w.filterBlock = copyPartitionedFilterWriter(filterName, partitions)
 	return nil
 }
 
@@ -1282,4 +1427,11 @@ func (w *RawColumnWriter) copyProperties(props Properties) {
 	w.props.TopLevelIndexSize = 0
 	w.props.IndexSize = 0
 	w.props.IndexType = 0
+	w.props.CompressionDictionarySize = 0
+}
+
+// copyCompressionDict implements RawWriter.
+func (w *RawColumnWriter) copyCompressionDict(dict *compression.Dictionary) {
+	w.compressionDict.done = true
+	w.useCompressionDict(dict)
 }
@@ -93,7 +93,17 @@ func CopySpan(
 	// Set the filter block to be copied over if it exists. It will return false
 	// positives for keys in blocks of the original file that we don't copy, but
 	// filters can always have false positives, so this is fine.
-	if r.tableFilter != nil {
+	if r.tableFilter != nil && r.tableFilter.partitioned {
+		// A partitioned filter is located by prefix, so it remains valid for a
+		// subset of the original data blocks too.
+		partitions, err := r.readFilterPartitions(ctx, rh)
+		if err != nil {
+			return 0, errors.Wrap(err, "reading filter")
+		}
+		if err := w.copyPartitionedFilter(partitions, r.Properties.FilterPolicyName); err != nil {
+			return 0, errors.Wrap(err, "copying filter")
+		}
+	} else if r.tableFilter != nil {
 		filterBlock, err := r.readFilterBlock(ctx, block.NoReadEnv, rh, r.filterBH)
 		if err != nil {
 			return 0, errors.Wrap(err, "reading filter")
@@ -111,6 +121,12 @@ func CopySpan(
 	// than under-counts.
 	w.copyProperties(r.Properties)
 
+	// Copied data blocks may be compressed with the compression dictionary of
+	// the source file, which must be copied too.
+	if dict := r.blockReader.CompressionDictionary(); dict != nil {
+		w.copyCompressionDict(dict)
+	}
+
 	// Find the blocks that intersect our span.
 	blocks, err := intersectingIndexEntries(ctx, r, rh, indexH, start, end)
 	if err != nil {
@@ -4,7 +4,12 @@
 
 package sstable
 
-import "sync/atomic"
+import (
+	"slices"
+	"sync/atomic"
+
+	"github.com/cockroachdb/errors"
+)
 
 // FilterMetrics holds metrics for the filter policy.
 type FilterMetrics struct {
@@ -16,6 +21,13 @@ type FilterMetrics struct {
 	// the filter policy was checked but was unable to filter an access of a data
 	// block.
 	Misses int64
+	// The number of hits for range filters (see
+	// WriterOptions.RangeFilterPrefixLength). This is the number of times a
+	// range filter was used to skip an sstable for an iterator's bounds.
+	RangeFilterHits int64
+	// The number of misses for range filters. This is the number of times a
+	// range filter was checked but was unable to skip an sstable.
+	RangeFilterMisses int64
 }
 
 // FilterMetricsTracker is used to keep track of filter metrics. It contains the
@@ -26,13 +38,19 @@ type FilterMetricsTracker struct {
 	hits atomic.Int64
 	// See FilterMetrics.Misses.
 	misses atomic.Int64
+	// See FilterMetrics.RangeFilterHits.
+	rangeFilterHits atomic.Int64
+	// See FilterMetrics.RangeFilterMisses.
+	rangeFilterMisses atomic.Int64
 }
 
 // Load returns the current values as FilterMetrics.
 func (m *FilterMetricsTracker) Load() FilterMetrics {
 	return FilterMetrics{
-		Hits:   m.hits.Load(),
-		Misses: m.misses.Load(),
+		Hits:              m.hits.Load(),
+		Misses:            m.misses.Load(),
+		RangeFilterHits:   m.rangeFilterHits.Load(),
+		RangeFilterMisses: m.rangeFilterMisses.Load(),
 	}
 }
 
@@ -46,6 +64,9 @@ type filterWriter interface {
 type tableFilterReader struct {
 	policy  FilterPolicy
 	metrics *FilterMetricsTracker
+	// partitioned is set if the table filter is partitioned, in which case the
+	// reader's filter block handle refers to the partition index block.
+	partitioned bool
 }
 
 func newTableFilterReader(policy FilterPolicy, metrics *FilterMetricsTracker) *tableFilterReader {
@@ -94,9 +115,146 @@ func (f *tableFilterWriter) finish() ([]byte, error) {
 }
 
 func (f *tableFilterWriter) metaName() string {
-	return "fullfilter." + f.policy.Name()
+	return fullFilterMetaPrefix + f.policy.Name()
 }
 
 func (f *tableFilterWriter) policyName() string {
 	return f.policy.Name()
 }
+
+const (
+	// fullFilterMetaPrefix prefixes the policy name to form the metaindex name
+	// of a table filter block.
+	fullFilterMetaPrefix = "fullfilter."
+	// partitionedFilterMetaPrefix prefixes the policy name to form the
+	// metaindex name of the partition index block of a partitioned table
+	// filter.
+	partitionedFilterMetaPrefix = "partitionedfilter."
+)
+
+// filterPartition is a finished partition of a partitioned table filter.
+type filterPartition struct {
+	// separator is ≥ the largest user key covered by the partition, and ≤ the
+	// smallest user key covered by the next partition.
+	separator []byte
+	// data is the partition's filter, encoded as a table filter.
+	data []byte
+}
+
+// partitionedFilterWriter builds a table filter split into partitions, each
+// covering the data blocks of one index block of the table. The partitions
+// are written as separate filter blocks, indexed by a partition index block
+// mapping each partition's separator to its block handle. A lookup only needs
+// to read the partition index and the single partition covering the lookup
+// prefix, rather than a filter covering the entire table.
+//
+// Each partition additionally contains the prefix of the first key of the
+// following partition. A prefix whose keys straddle a partition boundary is
+// therefore always present in the first partition with a separator ≥ the
+// prefix, which is the partition a reader locates.
+type partitionedFilterWriter struct {
+	policy FilterPolicy
+	name   string
+	// writer accumulates the current partition, and count is the number of
+	// keys added to it.
+	writer FilterWriter
+	count  int
+	// pending holds the distinct prefixes of the keys of the data block being
+	// built. They're added to the current partition once the data block is
+	// finished, because the block may end up in the next partition.
+	pending filterPrefixes
+	// lastSeparator is the separator of the most recently finished data block.
+	lastSeparator []byte
+	partitions    []filterPartition
+}
+
+func newPartitionedFilterWriter(policy FilterPolicy) *partitionedFilterWriter {
+	return &partitionedFilterWriter{
+		policy: policy,
+		name:   policy.Name(),
+		writer: policy.NewWriter(TableFilter),
+	}
+}
+
+// copyPartitionedFilterWriter returns a partitionedFilterWriter that writes
+// the provided partitions, copied from another sstable.
+func copyPartitionedFilterWriter(
+	policyName string, partitions []filterPartition,
+) *partitionedFilterWriter {
+	return &partitionedFilterWriter{
+		name:       policyName,
+		partitions: partitions,
+	}
+}
+
+func (f *partitionedFilterWriter) addKey(key []byte) {
+	p := &f.pending
+	if n := len(p.ends); n > 0 && string(p.buf[p.start(n-1):]) == string(key) {
+		return
+	}
+	p.buf = append(p.buf, key...)
+	p.ends = append(p.ends, len(p.buf))
+}
+
+// finishDataBlock adds the prefixes of the keys of the finished data block to
+// the current partition.
+func (f *partitionedFilterWriter) finishDataBlock(separator []byte) {
+	p := &f.pending
+	for i := range p.ends {
+		f.writer.AddKey(p.buf[p.start(i):p.ends[i]])
+	}
+	f.count += len(p.ends)
+	p.buf = p.buf[:0]
+	p.ends = p.ends[:0]
+	f.lastSeparator = append(f.lastSeparator[:0], separator...)
+}
+
+// finishPartition finishes the current partition, which covers keys up to
+// the provided separator. It must be called before finishDataBlock is called
+// for the first data block of the next partition.
+func (f *partitionedFilterWriter) finishPartition(separator []byte) {
+	if f.count == 0 {
+		return
+	}
+	if p := &f.pending; len(p.ends) > 0 {
+		f.writer.AddKey(p.buf[:p.ends[0]])
+	}
+	f.partitions = append(f.partitions, filterPartition{
+		separator: slices.Clone(separator),
+		data:      f.writer.Finish(nil),
+	})
+	f.writer = f.policy.NewWriter(TableFilter)
+	f.count = 0
+}
+
+// finishPartitions finishes the last partition and returns all partitions.
+func (f *partitionedFilterWriter) finishPartitions() []filterPartition {
+	f.finishPartition(f.lastSeparator)
+	return f.partitions
+}
+
+func (f *partitionedFilterWriter) finish() ([]byte, error) {
+	return nil, errors.AssertionFailedf("partitioned filters must be written with WritePartitionedFilter")
+}
+
+func (f *partitionedFilterWriter) metaName() string {
+	return partitionedFilterMetaPrefix + f.name
+}
+
+func (f *partitionedFilterWriter) policyName() string {
+	return f.name
+}
+
+// filterPrefixes holds a sequence of prefixes in a single buffer.
+type filterPrefixes struct {
+	buf  []byte
+	ends []int
+}
+
+// start returns the offset of the i'th prefix.
+func (p *filterPrefixes) start(i int) int {
+	if i == 0 {
+		return 0
+	}
+	return p.ends[i-1]
+}
@@ -56,6 +56,11 @@ const (
 	// Supported by CockroachDB v25.3 and later.
 	TableFormatPebblev7
 
+	// TableFormatPebblev8 adds:
+	//  - LZ4 compression support;
+	//  - Zstd compression with a dictionary, stored in a meta block.
+	TableFormatPebblev8
+
 	NumTableFormats
 
 	TableFormatMax = NumTableFormats - 1
@@ -76,6 +81,7 @@ var footerSizes [NumTableFormats]int = [NumTableFormats]int{
 	TableFormatPebblev5:  rocksDBFooterLen,
 	TableFormatPebblev6:  checkedPebbleDBFooterLen,
 	TableFormatPebblev7:  pebbleDBv7FooterLen,
+	TableFormatPebblev8:  pebbleDBv7FooterLen,
 }
 
 // TableFormatPebblev4, in addition to DELSIZED, introduces the use of
@@ -279,6 +285,8 @@ func parseTableFormat(magic []byte, version uint32) (TableFormat, error) {
 			return TableFormatPebblev6, nil
 		case 7:
 			return TableFormatPebblev7, nil
+		case 8:
+			return TableFormatPebblev8, nil
 		default:
 			return TableFormatUnspecified, base.CorruptionErrorf(
 				"(unsupported pebble format version %d)", errors.Safe(version))
@@ -328,6 +336,8 @@ func (f TableFormat) AsTuple() (string, uint32) {
 		return pebbleDBMagic, 6
 	case TableFormatPebblev7:
 		return pebbleDBMagic, 7
+	case TableFormatPebblev8:
+		return pebbleDBMagic, 8
 	default:
 		panic("sstable: unknown table format version tuple")
 	}
@@ -356,6 +366,8 @@ func (f TableFormat) String() string {
 		return "(Pebble,v6)"
 	case TableFormatPebblev7:
 		return "(Pebble,v7)"
+	case TableFormatPebblev8:
+		return "(Pebble,v8)"
 	default:
 		panic("sstable: unknown table format version tuple")
 	}
@@ -35,18 +35,20 @@ type Layout struct {
 	// ValidateBlockChecksums, which validates a static list of BlockHandles
 	// referenced in this struct.
 
-	Data       []block.HandleWithProperties
-	Index      []block.Handle
-	TopIndex   block.Handle
-	Filter     []NamedBlockHandle
-	RangeDel   block.Handle
-	RangeKey   block.Handle
-	ValueBlock []block.Handle
-	ValueIndex block.Handle
-	Properties block.Handle
-	MetaIndex  block.Handle
-	Footer     block.Handle
-	Format     TableFormat
+	Data            []block.HandleWithProperties
+	Index           []block.Handle
+	TopIndex        block.Handle
+	Filter          []NamedBlockHandle
+	RangeFilter     block.Handle
+	RangeDel        block.Handle
+	RangeKey        block.Handle
+	ValueBlock      []block.Handle
+	ValueIndex      block.Handle
+	CompressionDict block.Handle
+	Properties      block.Handle
+	MetaIndex       block.Handle
+	Footer          block.Handle
+	Format          TableFormat
 }
 
 // NamedBlockHandle holds a block.Handle and corresponding name.
@@ -79,6 +81,9 @@ func (l *Layout) orderedBlocks() []NamedBlockHandle {
 		blocks = append(blocks, NamedBlockHandle{l.TopIndex, "top-index"})
 	}
 	blocks = append(blocks, l.Filter...)
+	if l.RangeFilter.Length != 0 {
+		blocks = append(blocks, NamedBlockHandle{l.RangeFilter, "range-filter"})
+	}
 	if l.RangeDel.Length != 0 {
 		blocks = append(blocks, NamedBlockHandle{l.RangeDel, "range-del"})
 	}
@@ -91,6 +96,9 @@ func (l *Layout) orderedBlocks() []NamedBlockHandle {
 	if l.ValueIndex.Length != 0 {
 		blocks = append(blocks, NamedBlockHandle{l.ValueIndex, "value-index"})
 	}
+	if l.CompressionDict.Length != 0 {
+		blocks = append(blocks, NamedBlockHandle{l.CompressionDict, "compression-dict"})
+	}
 	if l.Properties.Length != 0 {
 		blocks = append(blocks, NamedBlockHandle{l.Properties, "properties"})
 	}
@@ -575,13 +583,15 @@ func decodeLayout(comparer *base.Comparer, data []byte, tableFormat TableFormat)
 		return Layout{}, err
 	}
 	layout := Layout{
-		MetaIndex:  foot.metaindexBH,
-		Properties: meta[metaPropertiesName],
-		RangeDel:   meta[metaRangeDelV2Name],
-		RangeKey:   meta[metaRangeKeyName],
-		ValueIndex: vbih.Handle,
-		Footer:     foot.footerBH,
-		Format:     foot.format,
+		MetaIndex:       foot.metaindexBH,
+		Properties:      meta[metaPropertiesName],
+		RangeDel:        meta[metaRangeDelV2Name],
+		RangeKey:        meta[metaRangeKeyName],
+		RangeFilter:     meta[metaRangeFilterName],
+		ValueIndex:      vbih.Handle,
+		CompressionDict: meta[metaCompressionDictName],
+		Footer:          foot.footerBH,
+		Format:          foot.format,
 	}
 	decompressedProps, err := decompressInMemory(data, layout.Properties)
 	if err != nil {
@@ -642,6 +652,9 @@ func decodeLayout(comparer *base.Comparer, data []byte, tableFormat TableFormat)
 	return layout, nil
 }
 
+// decompressInMemory decompresses the given block of the table. It does not
+// support data blocks, which may be compressed with the table's compression
+// dictionary.
 func decompressInMemory(data []byte, bh block.Handle) ([]byte, error) {
 	typ := block.CompressionIndicator(data[bh.Offset+bh.Length])
 	var decompressed []byte
@@ -649,12 +662,12 @@ func decompressInMemory(data []byte, bh block.Handle) ([]byte, error) {
 		return data[bh.Offset : bh.Offset+bh.Length], nil
 	}
 	// Decode the length of the decompressed value.
-	decodedLen, err := block.DecompressedLen(typ, data[bh.Offset:bh.Offset+bh.Length])
+	decodedLen, err := block.DecompressedLen(typ, nil /* dict */, data[bh.Offset:bh.Offset+bh.Length])
 	if err != nil {
 		return nil, err
 	}
 	decompressed = make([]byte, decodedLen)
-	if err := block.DecompressInto(typ, data[int(bh.Offset):bh.Offset+bh.Length], decompressed); err != nil {
+	if err := block.DecompressInto(typ, nil /* dict */, data[int(bh.Offset):bh.Offset+bh.Length], decompressed); err != nil {
 		return nil, err
 	}
 	return decompressed, nil
@@ -849,6 +862,40 @@ func (w *layoutWriter) WriteFilterBlock(f filterWriter) (bh block.Handle, err er
 	return w.writeNamedBlock(b, block.NoCompression, f.metaName())
 }
 
+// WritePartitionedFilter finishes the provided partitioned filter and writes
+// its partitions, followed by the partition index block, to the writer. It
+// automatically adds the partition index block to the file's meta index when
+// the writer is finished. It returns the total length of the written blocks,
+// which is zero if the filter is empty.
+func (w *layoutWriter) WritePartitionedFilter(f *partitionedFilterWriter) (size uint64, err error) {
+	partitions := f.finishPartitions()
+	if len(partitions) == 0 {
+		return 0, nil
+	}
+	var index colblk.IndexBlockWriter
+	index.Init()
+	for _, p := range partitions {
+		bh, err := w.writeBlock(p.data, block.NoCompression, &w.buf)
+		if err != nil {
+			return 0, err
+		}
+		index.AddBlockHandle(p.separator, bh, nil)
+		size += bh.Length
+	}
+	bh, err := w.writeNamedBlock(index.Finish(index.Rows()), block.NoCompression, f.metaName())
+	if err != nil {
+		return 0, err
+	}
+	return size + bh.Length, nil
+}
+
+// WriteRangeFilterBlock writes the provided range filter block, uncompressed.
+// It automatically adds the block to the file's meta index when the writer is
+// finished.
+func (w *layoutWriter) WriteRangeFilterBlock(b []byte) (block.Handle, error) {
+	return w.writeNamedBlock(b, block.NoCompression, metaRangeFilterName)
+}
+
 // WritePropertiesBlock constructs a trailer for the provided properties block
 // and writes the block and trailer to the writer. It automatically adds the
 // properties block to the file's meta index when the writer is finished.
@@ -863,6 +910,13 @@ func (w *layoutWriter) WritePropertiesBlock(b []byte) (block.Handle, error) {
 	return w.writeNamedBlock(b, block.NoCompression, metaPropertiesName)
 }
 
+// WriteCompressionDictBlock writes the compression dictionary of the table,
+// uncompressed. It automatically adds the block to the file's meta index when
+// the writer is finished.
+func (w *layoutWriter) WriteCompressionDictBlock(b []byte) (block.Handle, error) {
+	return w.writeNamedBlock(b, block.NoCompression, metaCompressionDictName)
+}
+
 // WriteRangeKeyBlock constructs a trailer for the provided range key block and
 // writes the block and trailer to the writer. It automatically adds the range
 // key block to the file's meta index when the writer is finished.
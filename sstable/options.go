@@ -174,6 +174,18 @@ type WriterOptions struct {
 	// The default value (DefaultCompression) uses snappy compression.
 	Compression block.Compression
 
+	// AdaptiveCompressionModel is the model choosing the compression of data
+	// blocks, when Compression is AdaptiveCompression. Sharing a model across
+	// the tables written to an LSM level lets it learn from all of them. If
+	// nil, the writer uses a new model with the default options.
+	AdaptiveCompressionModel *block.AdaptiveCompressionModel
+
+	// CompressionLevel is the compression level of data blocks, for
+	// compression algorithms that support levels (only Zstandard). Zero selects
+	// the default level of the algorithm. Only respected by columnar table
+	// formats.
+	CompressionLevel int
+
 	// FilterPolicy defines a filter algorithm (such as a Bloom filter) that can
 	// reduce disk reads for Get calls.
 	//
@@ -192,6 +204,25 @@ type WriterOptions struct {
 	// filters should be preferred except under constrained memory situations.
 	FilterType FilterType
 
+	// PartitionFilters partitions the table filter into several filter blocks,
+	// each covering the data blocks of one index block, indexed by a partition
+	// index block. A lookup reads and caches only the partition covering the
+	// lookup key rather than a filter for the entire table, which reduces the
+	// block cache footprint of filters for very large sstables. Partitioned
+	// filters are only written by columnar table formats (TableFormatPebblev5
+	// and later); other formats ignore this option.
+	PartitionFilters bool
+
+	// RangeFilterPrefixLength, if positive, enables writing a range filter
+	// containing the distinct key prefixes of the table truncated to this many
+	// bytes. Bounded iterators consult the range filter to skip tables that
+	// contain no keys within the iterator bounds. Longer truncation lengths
+	// make the filter more precise but larger. Range filters are only written
+	// by columnar table formats (TableFormatPebblev5 and later); other formats
+	// ignore this option. The filter orders prefixes bytewise, so it is also
+	// not written unless Comparer.BytewisePrefixes is set.
+	RangeFilterPrefixLength int
+
 	// IndexBlockSize is the target uncompressed size in bytes of each index
 	// block. When the index block size is larger than this target, two-level
 	// indexes are automatically enabled. Setting this option to a large value
@@ -351,7 +382,10 @@ func (o WriterOptions) ensureDefaults() WriterOptions {
 		o.KeySchema = &s
 	}
 	if o.Compression <= block.DefaultCompression || o.Compression >= block.NCompression ||
-		(o.Compression == block.MinLZCompression && o.TableFormat < TableFormatPebblev6) {
+		(o.Compression == block.MinLZCompression && o.TableFormat < TableFormatPebblev6) ||
+		(o.Compression == block.AdaptiveCompression && o.TableFormat < TableFormatPebblev6) ||
+		(o.Compression == block.LZ4Compression && o.TableFormat < TableFormatPebblev8) ||
+		(o.Compression == block.ZstdDictionaryCompression && o.TableFormat < TableFormatPebblev8) {
 		o.Compression = block.SnappyCompression
 	}
 	return o
@@ -145,6 +145,9 @@ type Properties struct {
 
 	// The name of the comparer used in this table.
 	ComparerName string `prop:"rocksdb.comparator"`
+	// The size of the compression dictionary of the table, if data blocks were
+	// compressed with a dictionary. Only serialized if > 0.
+	CompressionDictionarySize uint64 `prop:"pebble.compression.dictionary.size"`
 	// The total size of all data blocks.
 	DataSize uint64 `prop:"rocksdb.data.size"`
 	// The name of the filter policy used in this table. Empty if no filter
@@ -191,6 +194,14 @@ type Properties struct {
 	// The cumulative bytes of values in this table that were pinned by
 	// open snapshots. This value is comparable to RawValueSize.
 	SnapshotPinnedValueSize uint64 `prop:"pebble.raw.snapshot-pinned-values.size"`
+	// The cumulative bytes of values in this table that have an expiry time.
+	// Such values are never separated into blob files. This value is
+	// comparable to RawValueSize.
+	ExpiringValueSize uint64 `prop:"pebble.raw.expiring-values.size"`
+	// The earliest and latest expiry times, in Unix nanoseconds, of the values
+	// in this table that have an expiry time.
+	MinValueExpiry uint64 `prop:"pebble.value-expiry.min"`
+	MaxValueExpiry uint64 `prop:"pebble.value-expiry.max"`
 	// Size (uncompressed) of the top-level index if kTwoLevelIndexSearch is used.
 	TopLevelIndexSize uint64 `prop:"rocksdb.top-level.index.size"`
 	// User collected properties. Currently, we only use them to store block
@@ -401,6 +412,9 @@ func (p *Properties) accumulateProps(tblFormat TableFormat) ([]string, map[strin
 	if p.CompressionOptions != "" {
 		p.saveString(m, unsafe.Offsetof(p.CompressionOptions), p.CompressionOptions)
 	}
+	if p.CompressionDictionarySize > 0 {
+		p.saveUvarint(m, unsafe.Offsetof(p.CompressionDictionarySize), p.CompressionDictionarySize)
+	}
 	p.saveUvarint(m, unsafe.Offsetof(p.DataSize), p.DataSize)
 	if p.FilterPolicyName != "" {
 		p.saveString(m, unsafe.Offsetof(p.FilterPolicyName), p.FilterPolicyName)
@@ -463,6 +477,11 @@ func (p *Properties) accumulateProps(tblFormat TableFormat) ([]string, map[strin
 		p.saveUvarint(m, unsafe.Offsetof(p.SnapshotPinnedKeySize), p.SnapshotPinnedKeySize)
 		p.saveUvarint(m, unsafe.Offsetof(p.SnapshotPinnedValueSize), p.SnapshotPinnedValueSize)
 	}
+	if p.ExpiringValueSize > 0 {
+		p.saveUvarint(m, unsafe.Offsetof(p.ExpiringValueSize), p.ExpiringValueSize)
+		p.saveUvarint(m, unsafe.Offsetof(p.MinValueExpiry), p.MinValueExpiry)
+		p.saveUvarint(m, unsafe.Offsetof(p.MaxValueExpiry), p.MaxValueExpiry)
+	}
 	p.saveUvarint(m, unsafe.Offsetof(p.RawKeySize), p.RawKeySize)
 	p.saveUvarint(m, unsafe.Offsetof(p.RawValueSize), p.RawValueSize)
 	if p.ValueBlocksSize > 0 {
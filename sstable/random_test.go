@@ -301,6 +301,10 @@ func (cfg *randomTableConfig) randomize() {
 		}
 		if v := cfg.rng.IntN(11); v > 0 {
 			cfg.wopts.FilterPolicy = bloom.FilterPolicy(v)
+			cfg.wopts.PartitionFilters = cfg.rng.IntN(2) == 1
+		}
+		if cfg.rng.IntN(2) == 1 {
+			cfg.wopts.RangeFilterPrefixLength = 1 + cfg.rng.IntN(8)
 		}
 		if cfg.wopts.TableFormat >= TableFormatPebblev1 && cfg.rng.Float64() < 0.75 {
 			cfg.wopts.BlockPropertyCollectors = append(cfg.wopts.BlockPropertyCollectors, NewTestKeysBlockPropertyCollector)
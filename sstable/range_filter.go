@@ -0,0 +1,121 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package sstable
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/cockroachdb/crlib/crbytes"
+	"github.com/chris124567/pebble/sstable/colblk"
+)
+
+// A range filter is an optional per-table filter that answers whether a table
+// may contain any key within a range [lower, upper), allowing bounded
+// iterators to skip tables with no keys in their bounds without reading any
+// index or data blocks.
+//
+// The filter is a sorted set of the distinct key prefixes (as determined by
+// Comparer.Split) in the table, each truncated to a fixed length. It's encoded
+// as a columnar block with a single prefix-compressed column and a 4-byte
+// custom header holding the truncation length.
+//
+// Truncation trades precision for space: short truncation lengths produce
+// small filters, but a range is only excluded if no stored truncated prefix
+// falls between the truncated prefixes of its bounds. Because truncation
+// preserves the bytewise order of prefixes, and prefixes order keys
+// (Comparer.Compare(a, b) < 0 implies prefix(a) <= prefix(b) bytewise, which
+// the Comparer declares with Comparer.BytewisePrefixes), the filter never
+// produces false negatives. Range filters are not written for other
+// Comparers.
+//
+// Empty prefixes are never stored. A query whose lower bound has an empty
+// truncated prefix is always answered positively instead.
+
+const (
+	rangeFilterCustomHeaderSize = 4
+	rangeFilterBundleSize       = 16
+)
+
+// rangeFilterWriter accumulates the truncated key prefixes of a table and
+// encodes them into a range filter block.
+type rangeFilterWriter struct {
+	prefixLen int
+	prefixes  colblk.PrefixBytesBuilder
+	last      []byte
+	enc       colblk.BlockEncoder
+}
+
+func newRangeFilterWriter(prefixLen int) *rangeFilterWriter {
+	w := &rangeFilterWriter{prefixLen: prefixLen}
+	w.prefixes.Init(rangeFilterBundleSize)
+	return w
+}
+
+// addKey adds the prefix of a key to the filter. Prefixes must be added in
+// order.
+func (w *rangeFilterWriter) addKey(prefix []byte) {
+	if len(prefix) > w.prefixLen {
+		prefix = prefix[:w.prefixLen]
+	}
+	if len(prefix) == 0 || (w.prefixes.Rows() > 0 && bytes.Equal(prefix, w.last)) {
+		return
+	}
+	var shared int
+	if w.prefixes.Rows() > 0 {
+		shared = crbytes.CommonPrefix(w.last, prefix)
+	}
+	w.prefixes.Put(prefix, shared)
+	w.last = append(w.last[:0], prefix...)
+}
+
+// empty returns true if no prefixes have been added to the filter.
+func (w *rangeFilterWriter) empty() bool {
+	return w.prefixes.Rows() == 0
+}
+
+// finish encodes the range filter block. The returned slice is only valid
+// until the writer is reused.
+func (w *rangeFilterWriter) finish() []byte {
+	rows := w.prefixes.Rows()
+	size := w.prefixes.Size(rows, colblk.HeaderSize(1, rangeFilterCustomHeaderSize)) + 1
+	w.enc.Init(int(size), colblk.Header{
+		Version: colblk.Version1,
+		Columns: 1,
+		Rows:    uint32(rows),
+	}, rangeFilterCustomHeaderSize)
+	binary.LittleEndian.PutUint32(w.enc.Data()[:rangeFilterCustomHeaderSize], uint32(w.prefixLen))
+	w.enc.Encode(rows, &w.prefixes)
+	return w.enc.Finish()
+}
+
+// rangeFilterMayContain returns true if the table the range filter block data
+// was built from may contain a key with a prefix in [lowerPrefix,
+// upperPrefix].
+func rangeFilterMayContain(data, lowerPrefix, upperPrefix []byte) bool {
+	prefixLen := int(binary.LittleEndian.Uint32(data[:rangeFilterCustomHeaderSize]))
+	if len(lowerPrefix) > prefixLen {
+		lowerPrefix = lowerPrefix[:prefixLen]
+	}
+	if len(upperPrefix) > prefixLen {
+		upperPrefix = upperPrefix[:prefixLen]
+	}
+	if len(lowerPrefix) == 0 {
+		return true
+	}
+	d := colblk.DecodeBlock(data, rangeFilterCustomHeaderSize)
+	prefixes := d.PrefixBytes(0)
+	// Find the first stored prefix ≥ lowerPrefix, and check whether it's ≤
+	// upperPrefix.
+	lo, _ := prefixes.Search(lowerPrefix)
+	if lo == prefixes.Rows() {
+		return false
+	}
+	hi, eq := prefixes.Search(upperPrefix)
+	if eq {
+		hi++
+	}
+	return lo < hi
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/chris124567/pebble/internal/base"
 	"github.com/chris124567/pebble/internal/bytealloc"
+	"github.com/chris124567/pebble/internal/compression"
 	"github.com/chris124567/pebble/internal/invariants"
 	"github.com/chris124567/pebble/internal/keyspan"
 	"github.com/chris124567/pebble/internal/rangekey"
@@ -54,14 +55,16 @@ type Reader struct {
 
 	err error
 
-	indexBH      block.Handle
-	filterBH     block.Handle
-	rangeDelBH   block.Handle
-	rangeKeyBH   block.Handle
-	valueBIH     valblk.IndexHandle
-	propertiesBH block.Handle
-	metaindexBH  block.Handle
-	footerBH     block.Handle
+	indexBH           block.Handle
+	filterBH          block.Handle
+	rangeFilterBH     block.Handle
+	rangeDelBH        block.Handle
+	rangeKeyBH        block.Handle
+	compressionDictBH block.Handle
+	valueBIH          valblk.IndexHandle
+	propertiesBH      block.Handle
+	metaindexBH       block.Handle
+	footerBH          block.Handle
 
 	Properties  Properties
 	tableFormat TableFormat
@@ -387,6 +390,66 @@ func (r *Reader) readFilterBlock(
 	return r.blockReader.Read(ctx, env, readHandle, bh, noInitBlockMetadataFn)
 }
 
+// filterPartitionHandle returns the handle of the partition of the table's
+// partitioned filter covering the provided prefix. It returns false if the
+// prefix sorts after all the partitions, in which case the table contains no
+// key with the prefix.
+func (r *Reader) filterPartitionHandle(
+	ctx context.Context, env block.ReadEnv, readHandle objstorage.ReadHandle, prefix []byte,
+) (block.Handle, bool, error) {
+	indexH, err := r.readIndexBlock(ctx, env, readHandle, r.filterBH)
+	if err != nil {
+		return block.Handle{}, false, err
+	}
+	var iter colblk.IndexIter
+	_ = iter.InitHandle(r.Comparer, indexH, NoTransforms)
+	defer func() { _ = iter.Close() }()
+	// All keys with the prefix sort at or after the prefix itself, so the
+	// first partition with a separator ≥ the prefix is the first partition
+	// that may contain the prefix. Partitions also contain the prefix of the
+	// first key of the following partition, so it's the only partition that
+	// needs to be consulted.
+	if !iter.SeekGE(prefix) {
+		return block.Handle{}, false, nil
+	}
+	bhp, err := iter.BlockHandleWithProperties()
+	if err != nil {
+		return block.Handle{}, false, err
+	}
+	return bhp.Handle, true, nil
+}
+
+// readFilterPartitions reads all the partitions of the table's partitioned
+// filter. The returned partitions don't reference any block buffers.
+func (r *Reader) readFilterPartitions(
+	ctx context.Context, readHandle objstorage.ReadHandle,
+) ([]filterPartition, error) {
+	indexH, err := r.readIndexBlock(ctx, block.NoReadEnv, readHandle, r.filterBH)
+	if err != nil {
+		return nil, err
+	}
+	var iter colblk.IndexIter
+	_ = iter.InitHandle(r.Comparer, indexH, NoTransforms)
+	defer func() { _ = iter.Close() }()
+	var partitions []filterPartition
+	for valid := iter.First(); valid; valid = iter.Next() {
+		bhp, err := iter.BlockHandleWithProperties()
+		if err != nil {
+			return nil, err
+		}
+		h, err := r.readFilterBlock(ctx, block.NoReadEnv, readHandle, bhp.Handle)
+		if err != nil {
+			return nil, err
+		}
+		partitions = append(partitions, filterPartition{
+			separator: slices.Clone(iter.Separator()),
+			data:      slices.Clone(h.BlockData()),
+		})
+		h.Release()
+	}
+	return partitions, nil
+}
+
 func (r *Reader) readRangeDelBlock(
 	ctx context.Context, env block.ReadEnv, readHandle objstorage.ReadHandle, bh block.Handle,
 ) (block.BufferHandle, error) {
@@ -513,12 +576,37 @@ func (r *Reader) readMetaindex(
 		r.rangeKeyBH = bh
 	}
 
+	if bh, ok := meta[metaRangeFilterName]; ok {
+		r.rangeFilterBH = bh
+	}
+
+	if bh, ok := meta[metaCompressionDictName]; ok {
+		r.compressionDictBH = bh
+		b, err = r.blockReader.Read(ctx, metaEnv, readHandle, bh, noInitBlockMetadataFn)
+		if err != nil {
+			return err
+		}
+		// The dictionary outlives the buffer pool, so it must be copied.
+		dict, err := compression.LoadDictionary(slices.Clone(b.BlockData()))
+		b.Release()
+		if err != nil {
+			return err
+		}
+		r.blockReader.SetCompressionDictionary(dict)
+	}
+
 	for name, fp := range filters {
-		if bh, ok := meta["fullfilter."+name]; ok {
+		if bh, ok := meta[fullFilterMetaPrefix+name]; ok {
 			r.filterBH = bh
 			r.tableFilter = newTableFilterReader(fp, r.filterMetricsTracker)
 			break
 		}
+		if bh, ok := meta[partitionedFilterMetaPrefix+name]; ok {
+			r.filterBH = bh
+			r.tableFilter = newTableFilterReader(fp, r.filterMetricsTracker)
+			r.tableFilter.partitioned = true
+			break
+		}
 	}
 	return nil
 }
@@ -553,19 +641,40 @@ func (r *Reader) Layout() (*Layout, error) {
 	}
 
 	l := &Layout{
-		Data:       make([]block.HandleWithProperties, 0, r.Properties.NumDataBlocks),
-		RangeDel:   r.rangeDelBH,
-		RangeKey:   r.rangeKeyBH,
-		ValueIndex: r.valueBIH.Handle,
-		Properties: r.propertiesBH,
-		MetaIndex:  r.metaindexBH,
-		Footer:     r.footerBH,
-		Format:     r.tableFormat,
+		Data:            make([]block.HandleWithProperties, 0, r.Properties.NumDataBlocks),
+		RangeDel:        r.rangeDelBH,
+		RangeKey:        r.rangeKeyBH,
+		RangeFilter:     r.rangeFilterBH,
+		ValueIndex:      r.valueBIH.Handle,
+		CompressionDict: r.compressionDictBH,
+		Properties:      r.propertiesBH,
+		MetaIndex:       r.metaindexBH,
+		Footer:          r.footerBH,
+		Format:          r.tableFormat,
 	}
+	ctx := context.TODO()
 	if r.filterBH.Length > 0 {
-		l.Filter = []NamedBlockHandle{{Name: "fullfilter." + r.tableFilter.policy.Name(), Handle: r.filterBH}}
+		if r.tableFilter.partitioned {
+			l.Filter = []NamedBlockHandle{{Name: partitionedFilterMetaPrefix + r.tableFilter.policy.Name(), Handle: r.filterBH}}
+			indexH, err := r.readIndexBlock(ctx, block.NoReadEnv, noReadHandle, r.filterBH)
+			if err != nil {
+				return nil, err
+			}
+			var iter colblk.IndexIter
+			_ = iter.InitHandle(r.Comparer, indexH, NoTransforms)
+			for valid := iter.First(); valid; valid = iter.Next() {
+				bhp, err := iter.BlockHandleWithProperties()
+				if err != nil {
+					_ = iter.Close()
+					return nil, err
+				}
+				l.Filter = append(l.Filter, NamedBlockHandle{Name: "filter-partition", Handle: bhp.Handle})
+			}
+			_ = iter.Close()
+		} else {
+			l.Filter = []NamedBlockHandle{{Name: fullFilterMetaPrefix + r.tableFilter.policy.Name(), Handle: r.filterBH}}
+		}
 	}
-	ctx := context.TODO()
 
 	indexH, err := r.readTopLevelIndexBlock(ctx, block.NoReadEnv, noReadHandle)
 	if err != nil {
@@ -686,6 +795,10 @@ func (r *Reader) ValidateBlockChecksums() error {
 			readFn: r.readFilterBlock,
 		})
 	}
+	blocks = append(blocks, blk{
+		bh:     l.RangeFilter,
+		readFn: r.readFilterBlock,
+	})
 	blocks = append(blocks, blk{
 		bh:     l.RangeDel,
 		readFn: r.readRangeDelBlock,
@@ -697,6 +810,10 @@ func (r *Reader) ValidateBlockChecksums() error {
 	readNoInit := func(ctx context.Context, env block.ReadEnv, rh objstorage.ReadHandle, bh block.Handle) (block.BufferHandle, error) {
 		return r.blockReader.Read(ctx, env, rh, bh, noInitBlockMetadataFn)
 	}
+	blocks = append(blocks, blk{
+		bh:     l.CompressionDict,
+		readFn: readNoInit,
+	})
 	blocks = append(blocks, blk{
 		bh:     l.Properties,
 		readFn: readNoInit,
@@ -165,6 +165,13 @@ type singleLevelIterator[I any, PI indexBlockIterator[I], D any, PD dataBlockIte
 	useFilterBlock         bool
 	lastBloomFilterMatched bool
 
+	// rangeFilterChecked is set once the range filter, if any, has been
+	// consulted for the current bounds, in which case rangeFilterExcluded
+	// records whether the filter determined that the sstable contains no keys
+	// within the bounds. Both are reset by SetBounds.
+	rangeFilterChecked  bool
+	rangeFilterExcluded bool
+
 	transforms IterTransforms
 
 	// All fields above this field are cleared when resetting the iterator for reuse.
@@ -439,6 +446,8 @@ func (i *singleLevelIterator[I, PI, P, PD]) SetBounds(lower, upper []byte) {
 	i.upper = upper
 	i.blockLower = nil
 	i.blockUpper = nil
+	i.rangeFilterChecked = false
+	i.rangeFilterExcluded = false
 }
 
 func (i *singleLevelIterator[I, PI, P, PD]) SetContext(ctx context.Context) {
@@ -671,6 +680,10 @@ func (i *singleLevelIterator[I, PI, D, PD]) SeekGE(
 
 	i.exhaustedBounds = 0
 	i.err = nil // clear cached iteration error
+	if i.rangeFilterExcludesBounds() {
+		PD(&i.data).Invalidate()
+		return nil
+	}
 	boundsCmp := i.boundsCmp
 	// Seek optimization only applies until iterator is first positioned after SetBounds.
 	i.boundsCmp = 0
@@ -832,6 +845,10 @@ func (i *singleLevelIterator[I, PI, D, PD]) seekPrefixGE(
 		}
 		i.lastBloomFilterMatched = true
 	}
+	if i.rangeFilterExcludesBounds() {
+		PD(&i.data).Invalidate()
+		return nil
+	}
 	if flags.TrySeekUsingNext() {
 		// The i.exhaustedBounds comparison indicates that the upper bound was
 		// reached. The i.data.isDataInvalidated() indicates that the sstable was
@@ -861,7 +878,8 @@ func (i *singleLevelIterator[I, PI, D, PD]) seekPrefixGE(
 }
 
 // shouldUseFilterBlock returns whether we should use the filter block, based on
-// its length and the size limit.
+// its length and the size limit. For a partitioned filter, the length of the
+// partition index block is compared against the limit.
 func shouldUseFilterBlock(reader *Reader, filterBlockSizeLimit FilterBlockSizeLimit) bool {
 	return reader.tableFilter != nil && reader.filterBH.Length <= uint64(filterBlockSizeLimit)
 }
@@ -879,7 +897,16 @@ func (i *singleLevelIterator[I, PI, D, PD]) bloomFilterMayContain(prefix []byte)
 		}
 	}
 
-	dataH, err := i.reader.readFilterBlock(i.ctx, i.readEnv.Block, i.indexFilterRH, i.reader.filterBH)
+	filterBH := i.reader.filterBH
+	if i.reader.tableFilter.partitioned {
+		var ok bool
+		var err error
+		filterBH, ok, err = i.reader.filterPartitionHandle(i.ctx, i.readEnv.Block, i.indexFilterRH, prefixToCheck)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	dataH, err := i.reader.readFilterBlock(i.ctx, i.readEnv.Block, i.indexFilterRH, filterBH)
 	if err != nil {
 		return false, err
 	}
@@ -887,6 +914,53 @@ func (i *singleLevelIterator[I, PI, D, PD]) bloomFilterMayContain(prefix []byte)
 	return i.reader.tableFilter.mayContain(dataH.BlockData(), prefixToCheck), nil
 }
 
+// rangeFilterExcludesBounds returns true if the sstable's range filter
+// determines that the sstable contains no keys within the iterator bounds. The
+// filter is only consulted when both bounds are set, and only once per
+// SetBounds. If reading the filter fails, i.err is set and true is returned.
+func (i *singleLevelIterator[I, PI, D, PD]) rangeFilterExcludesBounds() bool {
+	if i.rangeFilterChecked {
+		return i.rangeFilterExcluded
+	}
+	i.rangeFilterChecked = true
+	if i.lower == nil || i.upper == nil || i.reader.rangeFilterBH.Length == 0 {
+		return false
+	}
+	lower, upper := i.lower, i.upper
+	if i.transforms.HasSyntheticPrefix() {
+		// We have to remove the synthetic prefix. Bounds that don't carry
+		// the prefix are not narrower than the sstable, so we don't bother
+		// consulting the filter.
+		var lowerOk, upperOk bool
+		lower, lowerOk = bytes.CutPrefix(lower, i.transforms.SyntheticPrefix())
+		upper, upperOk = bytes.CutPrefix(upper, i.transforms.SyntheticPrefix())
+		if !lowerOk || !upperOk {
+			return false
+		}
+	}
+	split := i.reader.Comparer.Split
+	lower, upper = lower[:split(lower)], upper[:split(upper)]
+
+	dataH, err := i.reader.readFilterBlock(i.ctx, i.readEnv.Block, i.indexFilterRH, i.reader.rangeFilterBH)
+	if err != nil {
+		// Consult the filter again on the next positioning call.
+		i.rangeFilterChecked = false
+		i.err = err
+		return true
+	}
+	defer dataH.Release()
+	mayContain := rangeFilterMayContain(dataH.BlockData(), lower, upper)
+	if m := i.reader.filterMetricsTracker; m != nil {
+		if mayContain {
+			m.rangeFilterMisses.Add(1)
+		} else {
+			m.rangeFilterHits.Add(1)
+		}
+	}
+	i.rangeFilterExcluded = !mayContain
+	return i.rangeFilterExcluded
+}
+
 // virtualLast should only be called if i.readBlockEnv.Virtual != nil
 func (i *singleLevelIterator[I, PI, D, PD]) virtualLast() *base.InternalKV {
 	if i.readEnv.Virtual == nil {
@@ -917,6 +991,10 @@ func (i *singleLevelIterator[I, PI, D, PD]) virtualLastSeekLE() *base.InternalKV
 
 	i.exhaustedBounds = 0
 	i.err = nil // clear cached iteration error
+	if i.rangeFilterExcludesBounds() {
+		PD(&i.data).Invalidate()
+		return nil
+	}
 	// Seek optimization only applies until iterator is first positioned with a
 	// SeekGE or SeekLT after SetBounds.
 	i.boundsCmp = 0
@@ -1010,6 +1088,10 @@ func (i *singleLevelIterator[I, PI, D, PD]) SeekLT(
 
 	i.exhaustedBounds = 0
 	i.err = nil // clear cached iteration error
+	if i.rangeFilterExcludesBounds() {
+		PD(&i.data).Invalidate()
+		return nil
+	}
 	boundsCmp := i.boundsCmp
 	// Seek optimization only applies until iterator is first positioned after SetBounds.
 	i.boundsCmp = 0
@@ -1118,6 +1200,10 @@ func (i *singleLevelIterator[I, PI, D, PD]) First() *base.InternalKV {
 func (i *singleLevelIterator[I, PI, D, PD]) firstInternal() *base.InternalKV {
 	i.exhaustedBounds = 0
 	i.err = nil // clear cached iteration error
+	if i.rangeFilterExcludesBounds() {
+		PD(&i.data).Invalidate()
+		return nil
+	}
 	// Seek optimization only applies until iterator is first positioned after SetBounds.
 	i.boundsCmp = 0
 
@@ -1182,6 +1268,10 @@ func (i *singleLevelIterator[I, PI, D, PD]) Last() *base.InternalKV {
 func (i *singleLevelIterator[I, PI, D, PD]) lastInternal() *base.InternalKV {
 	i.exhaustedBounds = 0
 	i.err = nil // clear cached iteration error
+	if i.rangeFilterExcludesBounds() {
+		PD(&i.data).Invalidate()
+		return nil
+	}
 	// Seek optimization only applies until iterator is first positioned after SetBounds.
 	i.boundsCmp = 0
 
@@ -1343,6 +1433,12 @@ func (i *singleLevelIterator[I, PI, D, PD]) Prev() *base.InternalKV {
 }
 
 func (i *singleLevelIterator[I, PI, D, PD]) skipForward() *base.InternalKV {
+	if i.rangeFilterExcluded {
+		// The range filter excluded the bounds, so the iterator was never
+		// positioned. Stepping must not expose keys outside the bounds.
+		PD(&i.data).Invalidate()
+		return nil
+	}
 	for {
 		if !PI(&i.index).Next() {
 			PD(&i.data).Invalidate()
@@ -1421,6 +1517,10 @@ func (i *singleLevelIterator[I, PI, D, PD]) skipForward() *base.InternalKV {
 }
 
 func (i *singleLevelIterator[I, PI, D, PD]) skipBackward() *base.InternalKV {
+	if i.rangeFilterExcluded {
+		PD(&i.data).Invalidate()
+		return nil
+	}
 	for {
 		if !PI(&i.index).Prev() {
 			PD(&i.data).Invalidate()
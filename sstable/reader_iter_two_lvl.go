@@ -274,6 +274,10 @@ func (i *twoLevelIterator[I, PI, D, PD]) SeekGE(
 
 	err := i.secondLevel.err
 	i.secondLevel.err = nil // clear cached iteration error
+	if i.secondLevel.rangeFilterExcludesBounds() {
+		PD(&i.secondLevel.data).Invalidate()
+		return nil
+	}
 
 	// The twoLevelIterator could be already exhausted. Utilize that when
 	// trySeekUsingNext is true. See the comment about data-exhausted, PGDE, and
@@ -448,6 +452,10 @@ func (i *twoLevelIterator[I, PI, D, PD]) SeekPrefixGE(
 		}
 		i.lastBloomFilterMatched = true
 	}
+	if i.secondLevel.rangeFilterExcludesBounds() {
+		PD(&i.secondLevel.data).Invalidate()
+		return nil
+	}
 
 	// Bloom filter matches.
 
@@ -638,6 +646,10 @@ func (i *twoLevelIterator[I, PI, D, PD]) SeekLT(
 
 	i.secondLevel.exhaustedBounds = 0
 	i.secondLevel.err = nil // clear cached iteration error
+	if i.secondLevel.rangeFilterExcludesBounds() {
+		PD(&i.secondLevel.data).Invalidate()
+		return nil
+	}
 	// Seek optimization only applies until iterator is first positioned after SetBounds.
 	i.secondLevel.boundsCmp = 0
 
@@ -872,6 +884,12 @@ func (i *twoLevelIterator[I, PI, D, PD]) Prev() *base.InternalKV {
 }
 
 func (i *twoLevelIterator[I, PI, D, PD]) skipForward() *base.InternalKV {
+	if i.secondLevel.rangeFilterExcluded {
+		// The range filter excluded the bounds, so the iterator was never
+		// positioned. Stepping must not expose keys outside the bounds.
+		PD(&i.secondLevel.data).Invalidate()
+		return nil
+	}
 	for {
 		if i.secondLevel.err != nil || i.secondLevel.exhaustedBounds > 0 {
 			return nil
@@ -950,6 +968,10 @@ func (i *twoLevelIterator[I, PI, D, PD]) skipForward() *base.InternalKV {
 }
 
 func (i *twoLevelIterator[I, PI, D, PD]) skipBackward() *base.InternalKV {
+	if i.secondLevel.rangeFilterExcluded {
+		PD(&i.secondLevel.data).Invalidate()
+		return nil
+	}
 	for {
 		if i.secondLevel.err != nil || i.secondLevel.exhaustedBounds < 0 {
 			return nil
@@ -16,6 +16,7 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/chris124567/pebble/internal/base"
 	"github.com/chris124567/pebble/internal/bytealloc"
+	"github.com/chris124567/pebble/internal/compression"
 	"github.com/chris124567/pebble/internal/invariants"
 	"github.com/chris124567/pebble/internal/keyspan"
 	"github.com/chris124567/pebble/internal/rangedel"
@@ -1876,7 +1877,7 @@ func (w *RawRowWriter) rewriteSuffixes(
 	// already have ensured this is valid if it exists).
 	if w.filter != nil {
 		if filterBlockBH, ok := l.FilterByName(w.filter.metaName()); ok {
-			filterBlock, _, err := readBlockBuf(sst, filterBlockBH, r.blockReader.ChecksumType(), nil)
+			filterBlock, _, err := readBlockBuf(sst, filterBlockBH, r.blockReader.ChecksumType(), nil /* dict */, nil)
 			if err != nil {
 				return errors.Wrap(err, "reading filter")
 			}
@@ -1960,6 +1961,18 @@ func (w *RawRowWriter) copyProperties(props Properties) {
 	w.props.IndexType = 0
 }
 
+// copyCompressionDict implements RawWriter.
+func (w *RawRowWriter) copyCompressionDict(dict *compression.Dictionary) {
+	// Compression dictionaries are only used with columnar table formats.
+	panic(errors.AssertionFailedf("pebble: %s tables have no compression dictionary", w.tableFormat))
+}
+
+// copyPartitionedFilter implements RawWriter.
+func (w *RawRowWriter) copyPartitionedFilter(partitions []filterPartition, filterName string) error {
+	// Partitioned filters are only written with columnar table formats.
+	return errors.AssertionFailedf("pebble: %s tables have no partitioned filters", w.tableFormat)
+}
+
 // copyFilter implements RawWriter.
 func (w *RawRowWriter) copyFilter(filter []byte, filterName string) error {
 	if w.filter != nil && filterName != w.filter.policyName() {
@@ -1980,3 +1993,11 @@ func (w *RawRowWriter) SetSnapshotPinnedProperties(
 	w.props.SnapshotPinnedKeySize = pinnedKeySize
 	w.props.SnapshotPinnedValueSize = pinnedValueSize
 }
+
+// SetValueExpiryProperties sets the properties for values with an expiry time.
+// Should only be used internally by Pebble.
+func (w *RawRowWriter) SetValueExpiryProperties(valueSize, minExpiry, maxExpiry uint64) {
+	w.props.ExpiringValueSize = valueSize
+	w.props.MinValueExpiry = minExpiry
+	w.props.MaxValueExpiry = maxExpiry
+}
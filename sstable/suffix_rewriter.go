@@ -16,6 +16,7 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/chris124567/pebble/internal/base"
 	"github.com/chris124567/pebble/internal/bytealloc"
+	"github.com/chris124567/pebble/internal/compression"
 	"github.com/chris124567/pebble/internal/invariants"
 	"github.com/chris124567/pebble/objstorage"
 	"github.com/chris124567/pebble/sstable/block"
@@ -168,7 +169,8 @@ func rewriteDataBlocksInParallel(
 				for i := worker; i < len(input); i += concurrency {
 					bh := input[i]
 					var err error
-					inputBlock, inputBlockBuf, err = readBlockBuf(sstBytes, bh.Handle, r.blockReader.ChecksumType(), inputBlockBuf)
+					inputBlock, inputBlockBuf, err = readBlockBuf(sstBytes, bh.Handle,
+						r.blockReader.ChecksumType(), r.blockReader.CompressionDictionary(), inputBlockBuf)
 					if err != nil {
 						return err
 					}
@@ -373,9 +375,13 @@ func NewMemReader(sst []byte, o ReaderOptions) (*Reader, error) {
 // readBlockBuf may return a byte slice that points directly into sstBytes. If
 // the caller is going to expect that sstBytes remain stable, it should copy the
 // returned slice before writing it out to a objstorage.Writable which may
-// mangle it.
+// mangle it. The dictionary is the compression dictionary of the table, if any.
 func readBlockBuf(
-	sstBytes []byte, bh block.Handle, checksumType block.ChecksumType, buf []byte,
+	sstBytes []byte,
+	bh block.Handle,
+	checksumType block.ChecksumType,
+	dict *compression.Dictionary,
+	buf []byte,
 ) ([]byte, []byte, error) {
 	raw := sstBytes[bh.Offset : bh.Offset+bh.Length+block.TrailerLen]
 	if err := block.ValidateChecksum(checksumType, raw, bh); err != nil {
@@ -391,7 +397,7 @@ func readBlockBuf(
 		}
 	}
 
-	decompressedLen, err := block.DecompressedLen(algo, raw)
+	decompressedLen, err := block.DecompressedLen(algo, dict, raw)
 	if err != nil {
 		return nil, buf, err
 	}
@@ -403,7 +409,7 @@ func readBlockBuf(
 		}
 	}
 	dst := buf[:decompressedLen]
-	err = block.DecompressInto(algo, raw, dst)
+	err = block.DecompressInto(algo, dict, raw, dst)
 	return dst, buf, err
 }
 
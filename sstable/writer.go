@@ -9,6 +9,7 @@ import (
 
 	"github.com/cockroachdb/errors"
 	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/internal/compression"
 	"github.com/chris124567/pebble/internal/keyspan"
 	"github.com/chris124567/pebble/objstorage"
 	"github.com/chris124567/pebble/sstable/blob"
@@ -348,6 +349,9 @@ type RawWriter interface {
 	// SetSnapshotPinnedProperties sets the properties for pinned keys. Should only
 	// be used internally by Pebble.
 	SetSnapshotPinnedProperties(keyCount, keySize, valueSize uint64)
+	// SetValueExpiryProperties sets the properties for values with an expiry
+	// time. Should only be used internally by Pebble.
+	SetValueExpiryProperties(valueSize, minExpiry, maxExpiry uint64)
 	// Close finishes writing the table and closes the underlying file that the
 	// table was written to.
 	Close() error
@@ -376,11 +380,22 @@ type RawWriter interface {
 	// using CopySpan().
 	copyFilter(filter []byte, filterName string) error
 
+	// copyPartitionedFilter copies the specified partitioned filter to the
+	// table. It's specifically used by the sstable copier that can copy parts
+	// of an sstable to a new sstable, using CopySpan().
+	copyPartitionedFilter(partitions []filterPartition, filterName string) error
+
 	// copyProperties copies properties from the specified props, and resets others
 	// to prepare for copying data blocks from another sstable. It's specifically
 	// used by the sstable copier that can copy parts of an sstable to a new sstable,
 	// using CopySpan().
 	copyProperties(props Properties)
+
+	// copyCompressionDict sets the compression dictionary of the table to the
+	// compression dictionary of the table data blocks are copied from. It's
+	// specifically used by the sstable copier that can copy parts of an sstable
+	// to a new sstable, using CopySpan().
+	copyCompressionDict(dict *compression.Dictionary)
 }
 
 // WriterMetadata holds info about a finished sstable.
@@ -401,6 +416,10 @@ type WriterMetadata struct {
 	SmallestSeqNum   base.SeqNum
 	LargestSeqNum    base.SeqNum
 	Properties       Properties
+	// CompressionStats tallies the data blocks of the table by the compression
+	// setting chosen for them, if the table was written with
+	// AdaptiveCompression.
+	CompressionStats block.CompressionStats
 }
 
 // SetSmallestPointKey sets the smallest point key to the given key.
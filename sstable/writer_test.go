@@ -10,6 +10,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 	"math/rand/v2"
 	"strconv"
 	"strings"
@@ -1293,3 +1294,332 @@ func runWriterBench(b *testing.B, keys [][]byte, comparer *base.Comparer, format
 		})
 	}
 }
+
+func TestWriterLZ4AndZstdDictionaryCompression(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	blockCache := cache.New(16 << 20)
+	defer blockCache.Unref()
+	cacheHandle := blockCache.NewHandle()
+	defer cacheHandle.Close()
+
+	// Small blocks of structured values, that compress poorly on their own.
+	const n = 20000
+	key := func(i int) []byte { return []byte(fmt.Sprintf("key%06d", i)) }
+	value := func(i int) []byte {
+		return []byte(fmt.Sprintf(`{"id":%d,"name":"user-%d","region":"us-east-1","status":"active"}`, i, i*7919%10007))
+	}
+	build := func(t *testing.T, c block.Compression, format TableFormat) []byte {
+		f := &objstorage.MemObj{}
+		w := NewWriter(f, WriterOptions{
+			BlockSize:   1 << 10,
+			Compression: c,
+			TableFormat: format,
+		})
+		for i := 0; i < n; i++ {
+			require.NoError(t, w.Set(key(i), value(i)))
+		}
+		require.NoError(t, w.Close())
+		return f.Data()
+	}
+	var fileNum base.DiskFileNum
+	open := func(t *testing.T, data []byte) *Reader {
+		fileNum++
+		r, err := NewMemReader(data, ReaderOptions{
+			ReaderOptions: block.ReaderOptions{
+				CacheOpts: sstableinternal.CacheOptions{CacheHandle: cacheHandle, FileNum: fileNum},
+			},
+		})
+		require.NoError(t, err)
+		return r
+	}
+	check := func(t *testing.T, r *Reader, from, to int) {
+		require.NoError(t, r.ValidateBlockChecksums())
+		it, err := r.NewIter(NoTransforms, nil, nil, AssertNoBlobHandles)
+		require.NoError(t, err)
+		i := from
+		for kv := it.First(); kv != nil; kv = it.Next() {
+			require.Equal(t, string(key(i)), string(kv.K.UserKey))
+			v, _, err := kv.Value(nil)
+			require.NoError(t, err)
+			require.Equal(t, string(value(i)), string(v))
+			i++
+		}
+		require.NoError(t, it.Close())
+		require.Equal(t, to, i)
+	}
+
+	var zstdSize int
+	{
+		data := build(t, block.ZstdCompression, TableFormatPebblev8)
+		zstdSize = len(data)
+	}
+	for _, c := range []block.Compression{block.LZ4Compression, block.ZstdDictionaryCompression} {
+		t.Run(c.String(), func(t *testing.T) {
+			data := build(t, c, TableFormatPebblev8)
+			r := open(t, data)
+			defer r.Close()
+			require.Equal(t, c.String(), r.Properties.CompressionName)
+			check(t, r, 0, n)
+
+			l, err := r.Layout()
+			require.NoError(t, err)
+			lastBH := l.Data[len(l.Data)-1].Handle
+			ci := block.CompressionIndicator(data[lastBH.Offset+lastBH.Length])
+			if c == block.ZstdDictionaryCompression {
+				require.NotZero(t, l.CompressionDict.Length)
+				require.Equal(t, l.CompressionDict.Length, r.Properties.CompressionDictionarySize)
+				require.Equal(t, block.ZstdDictCompressionIndicator, ci)
+				require.Less(t, len(data), zstdSize)
+			} else {
+				require.Zero(t, l.CompressionDict.Length)
+				require.Equal(t, block.Lz4CompressionIndicator, ci)
+			}
+
+			// Copy a span of the table, with a cold cache so that the data
+			// blocks are copied as is.
+			r2 := open(t, data)
+			defer r2.Close()
+			out := &objstorage.MemObj{}
+			_, err = CopySpan(context.Background(), newMemReader(data), r2, ReaderOptions{},
+				out, WriterOptions{Compression: c}, base.MakeSearchKey(key(n/2)), base.MakeSearchKey(key(n)))
+			require.NoError(t, err)
+			r3 := open(t, out.Data())
+			defer r3.Close()
+			require.Equal(t, r.Properties.CompressionDictionarySize, r3.Properties.CompressionDictionarySize)
+			require.NoError(t, r3.ValidateBlockChecksums())
+			it, err := r3.NewIter(NoTransforms, nil, nil, AssertNoBlobHandles)
+			require.NoError(t, err)
+			kv := it.Last()
+			require.Equal(t, string(key(n-1)), string(kv.K.UserKey))
+			v, _, err := kv.Value(nil)
+			require.NoError(t, err)
+			require.Equal(t, string(value(n-1)), string(v))
+			require.NoError(t, it.Close())
+		})
+	}
+
+	// Older table formats fall back to snappy.
+	for _, c := range []block.Compression{block.LZ4Compression, block.ZstdDictionaryCompression} {
+		r := open(t, build(t, c, TableFormatPebblev7))
+		require.Equal(t, block.SnappyCompression.String(), r.Properties.CompressionName)
+		check(t, r, 0, n)
+		require.NoError(t, r.Close())
+	}
+}
+
+func TestWriterPartitionedFilters(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// Even prefixes are present in the table, with several versions each so
+	// that prefixes straddle data block and filter partition boundaries. Odd
+	// prefixes are absent.
+	const n = 20000
+	prefix := func(i int) []byte { return []byte(fmt.Sprintf("k%06d", i)) }
+	build := func(t *testing.T, suffixes ...int) []byte {
+		f := &objstorage.MemObj{}
+		w := NewWriter(f, WriterOptions{
+			Comparer:           testkeys.Comparer,
+			KeySchema:          &testkeysSchema,
+			TableFormat:        TableFormatPebblev7,
+			BlockSize:          256,
+			IndexBlockSize:     512,
+			FilterPolicy:       bloom.FilterPolicy(10),
+			PartitionFilters:   true,
+			DisableValueBlocks: true,
+		})
+		for i := 0; i < n; i += 2 {
+			for _, s := range suffixes {
+				require.NoError(t, w.Set(fmt.Appendf(prefix(i), "@%d", s), []byte("value")))
+			}
+		}
+		require.NoError(t, w.Close())
+		return f.Data()
+	}
+	blockCache := cache.New(16 << 20)
+	defer blockCache.Unref()
+	cacheHandle := blockCache.NewHandle()
+	defer cacheHandle.Close()
+	var metrics FilterMetricsTracker
+	rOpts := ReaderOptions{
+		ReaderOptions: block.ReaderOptions{
+			CacheOpts: sstableinternal.CacheOptions{CacheHandle: cacheHandle, FileNum: 1},
+		},
+		Comparer:             testkeys.Comparer,
+		KeySchemas:           KeySchemas{testkeysSchema.Name: &testkeysSchema},
+		Filters:              map[string]FilterPolicy{bloom.FilterPolicy(10).Name(): bloom.FilterPolicy(10)},
+		FilterMetricsTracker: &metrics,
+	}
+	var fileNum base.DiskFileNum
+	open := func(t *testing.T, data []byte) *Reader {
+		fileNum++
+		rOpts := rOpts
+		rOpts.CacheOpts.FileNum = fileNum
+		r, err := NewMemReader(data, rOpts)
+		require.NoError(t, err)
+		return r
+	}
+	// check verifies lookups of prefixes in [from, to). Tables produced by
+	// copying or rewriting keep the filter partitions of the original table,
+	// which are only aligned with the index blocks of the original table.
+	check := func(t *testing.T, data []byte, from, to int, aligned bool) {
+		r := open(t, data)
+		defer r.Close()
+		require.NoError(t, r.ValidateBlockChecksums())
+
+		l, err := r.Layout()
+		require.NoError(t, err)
+		_, ok := l.FilterByName("partitionedfilter." + bloom.FilterPolicy(10).Name())
+		require.True(t, ok)
+		require.Greater(t, len(l.Filter), 10)
+		if aligned {
+			// One partition per index block, following the partition index.
+			require.Equal(t, int(r.Properties.IndexPartitions)+1, len(l.Filter))
+		}
+
+		it, err := r.NewIter(NoTransforms, nil, nil, AssertNoBlobHandles)
+		require.NoError(t, err)
+		defer it.Close()
+		before := metrics.Load()
+		for i := from; i < to; i++ {
+			kv := it.SeekPrefixGE(prefix(i), prefix(i), base.SeekGEFlagsNone)
+			if i%2 == 0 {
+				// The filter must not exclude present prefixes.
+				require.NotNil(t, kv, "prefix %s", prefix(i))
+				require.Equal(t, string(prefix(i)), string(kv.K.UserKey[:testkeys.Comparer.Split(kv.K.UserKey)]))
+			}
+		}
+		require.NoError(t, it.Error())
+		// Most absent prefixes should be excluded by the filter.
+		require.Greater(t, metrics.Load().Hits-before.Hits, int64(to-from)/4)
+	}
+
+	data := build(t, 3, 2, 1)
+	check(t, data, 0, n, true /* aligned */)
+
+	t.Run("copy", func(t *testing.T) {
+		r := open(t, data)
+		defer r.Close()
+		out := &objstorage.MemObj{}
+		_, err := CopySpan(context.Background(), newMemReader(data), r, rOpts, out, WriterOptions{
+			Comparer:     testkeys.Comparer,
+			KeySchema:    &testkeysSchema,
+			FilterPolicy: bloom.FilterPolicy(10),
+		}, base.MakeSearchKey(prefix(n/2)), base.MakeSearchKey(prefix(n)))
+		require.NoError(t, err)
+		// Only data blocks entirely within the span are copied.
+		check(t, out.Data(), n/2+100, n, false /* aligned */)
+	})
+
+	t.Run("rewrite", func(t *testing.T) {
+		rOpts := rOpts
+		rOpts.CacheOpts.FileNum = 100
+		out := &objstorage.MemObj{}
+		_, _, err := RewriteKeySuffixesAndReturnFormat(build(t, 1), rOpts, out, WriterOptions{
+			Comparer:     testkeys.Comparer,
+			KeySchema:    &testkeysSchema,
+			FilterPolicy: bloom.FilterPolicy(10),
+		}, []byte("@1"), []byte("@5"), 4)
+		require.NoError(t, err)
+		check(t, out.Data(), 0, n, false /* aligned */)
+	})
+}
+
+func TestWriterRangeFilter(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// Prefixes are present in runs of 100, separated by gaps of 100 absent
+	// prefixes.
+	const n = 10000
+	prefix := func(i int) []byte { return []byte(fmt.Sprintf("k%06d", i)) }
+	present := func(i int) bool { return (i/100)%2 == 0 }
+	for _, indexBlockSize := range []int{math.MaxInt32, 512} {
+		t.Run(fmt.Sprintf("index-block-size=%d", indexBlockSize), func(t *testing.T) {
+			f := &objstorage.MemObj{}
+			w := NewWriter(f, WriterOptions{
+				Comparer:                testkeys.Comparer,
+				KeySchema:               &testkeysSchema,
+				TableFormat:             TableFormatPebblev7,
+				BlockSize:               256,
+				IndexBlockSize:          indexBlockSize,
+				RangeFilterPrefixLength: 6,
+			})
+			for i := 0; i < n; i++ {
+				if present(i) {
+					for _, s := range []int{3, 1} {
+						require.NoError(t, w.Set(fmt.Appendf(prefix(i), "@%d", s), []byte("value")))
+					}
+				}
+			}
+			require.NoError(t, w.Close())
+
+			var metrics FilterMetricsTracker
+			r, err := NewMemReader(f.Data(), ReaderOptions{
+				Comparer:             testkeys.Comparer,
+				KeySchemas:           KeySchemas{testkeysSchema.Name: &testkeysSchema},
+				FilterMetricsTracker: &metrics,
+			})
+			require.NoError(t, err)
+			defer r.Close()
+			require.NoError(t, r.ValidateBlockChecksums())
+			l, err := r.Layout()
+			require.NoError(t, err)
+			require.NotZero(t, l.RangeFilter.Length)
+			require.Equal(t, indexBlockSize != math.MaxInt32, r.Attributes.Has(AttributeTwoLevelIndex))
+
+			it, err := r.NewIter(NoTransforms, nil, nil, AssertNoBlobHandles)
+			require.NoError(t, err)
+			defer it.Close()
+			rng := rand.New(rand.NewPCG(0, uint64(indexBlockSize)))
+			for k := 0; k < 1000; k++ {
+				lo := rng.IntN(n)
+				hi := lo + 1 + rng.IntN(50)
+				first, last := -1, -1
+				for i := lo; i < hi && i < n; i++ {
+					if present(i) {
+						if first < 0 {
+							first = i
+						}
+						last = i
+					}
+				}
+				it.SetBounds(prefix(lo), prefix(hi))
+				before := metrics.Load()
+				kv := it.SeekGE(prefix(lo), base.SeekGEFlagsNone)
+				if first < 0 {
+					require.Nil(t, kv)
+					require.NoError(t, it.Error())
+					// The filter holds the first 6 of 7 bytes of each prefix, so
+					// it always excludes a range that ends before the next run of
+					// present prefixes.
+					if hi/100 == lo/100 {
+						require.Equal(t, before.RangeFilterHits+1, metrics.Load().RangeFilterHits)
+					}
+					// Range filter checks are not counted as bloom filter checks.
+					require.Equal(t, before.Hits+before.Misses, metrics.Load().Hits+metrics.Load().Misses)
+					// Stepping after the iterator was exhausted must not expose
+					// keys outside the bounds.
+					require.Nil(t, it.Prev())
+					require.Nil(t, it.SeekLT(prefix(hi), base.SeekLTFlagsNone))
+					require.Nil(t, it.Next())
+					continue
+				}
+				// The filter must not exclude ranges containing keys.
+				require.NotNil(t, kv, "[%d, %d)", lo, hi)
+				require.Equal(t, string(fmt.Appendf(prefix(first), "@3")), string(kv.K.UserKey))
+				kv = it.SeekLT(prefix(hi), base.SeekLTFlagsNone)
+				require.NotNil(t, kv, "[%d, %d)", lo, hi)
+				require.Equal(t, string(fmt.Appendf(prefix(last), "@1")), string(kv.K.UserKey))
+				require.Equal(t, before.RangeFilterHits, metrics.Load().RangeFilterHits)
+			}
+
+			// Widening the bounds of an iterator excluded by the filter must make
+			// the keys visible again.
+			it.SetBounds(prefix(150), prefix(160))
+			require.Nil(t, it.SeekGE(prefix(150), base.SeekGEFlagsNone))
+			it.SetBounds(prefix(150), prefix(210))
+			kv := it.SeekGE(prefix(150), base.SeekGEFlagsNone)
+			require.NotNil(t, kv)
+			require.Equal(t, "k000200@3", string(kv.K.UserKey))
+		})
+	}
+}
@@ -322,6 +322,7 @@ func (d *DB) loadTableStats(
 			if props.NumDataBlocks > 0 {
 				stats.TombstoneDenseBlocksRatio = float64(props.NumTombstoneDenseBlocks) / float64(props.NumDataBlocks)
 			}
+			setValueExpiryStats(&stats, &r.Properties, meta.Size+meta.EstimatedReferenceSize())
 
 			if props.NumPointDeletions() > 0 {
 				if err = d.loadTablePointKeyStats(&props, v, level, meta, &stats); err != nil {
@@ -698,9 +699,28 @@ func sanityCheckStats(meta *tableMetadata, logger Logger, info string) {
 	}
 }
 
+// setValueExpiryStats sets the statistics of values with an expiry (see
+// Options.TTL) from the properties of a table of the given size, which
+// includes the estimated size of the values it references in blob files. The
+// properties count the full length of separated values, so the estimate covers
+// both the values stored in the table and those in blob files.
+func setValueExpiryStats(stats *manifest.TableStats, props *sstable.Properties, size uint64) {
+	if props.ExpiringValueSize == 0 || props.RawKeySize+props.RawValueSize == 0 {
+		return
+	}
+	// Scale the raw size of the expiring values to the table's size on disk.
+	// For virtual tables, this also scales the estimate to the virtual table's
+	// share of its backing table.
+	ratio := min(1, float64(props.ExpiringValueSize)/float64(props.RawKeySize+props.RawValueSize))
+	stats.ExpiringValueSize = uint64(ratio * float64(size))
+	stats.MinValueExpiry = props.MinValueExpiry
+	stats.MaxValueExpiry = props.MaxValueExpiry
+}
+
 func maybeSetStatsFromProperties(
-	meta *tableMetadata, props *sstable.CommonProperties, logger Logger,
+	meta *tableMetadata, properties *sstable.Properties, logger Logger,
 ) bool {
+	props := &properties.CommonProperties
 	// If a table contains range deletions or range key deletions, we defer the
 	// stats collection. There are two main reasons for this:
 	//
@@ -743,6 +763,7 @@ func maybeSetStatsFromProperties(
 	meta.Stats.RangeDeletionsBytesEstimate = 0
 	meta.Stats.ValueBlocksSize = props.ValueBlocksSize
 	meta.Stats.CompressionType = block.CompressionFromString(props.CompressionName)
+	setValueExpiryStats(&meta.Stats, properties, meta.Size+meta.EstimatedReferenceSize())
 	meta.StatsMarkValid()
 	sanityCheckStats(meta, logger, "stats from properties")
 	return true
@@ -1115,7 +1136,7 @@ var compressionTypeAnnotator = manifest.Annotator[compressionTypes]{
 type compressionTypeAggregator struct{}
 
 type compressionTypes struct {
-	snappy, zstd, minlz, none, unknown uint64
+	snappy, zstd, minlz, lz4, zstdDict, adaptive, none, unknown uint64
 }
 
 func (a compressionTypeAggregator) Zero(dst *compressionTypes) *compressionTypes {
@@ -1136,6 +1157,12 @@ func (a compressionTypeAggregator) Accumulate(
 		dst.zstd++
 	case MinLZCompression:
 		dst.minlz++
+	case LZ4Compression:
+		dst.lz4++
+	case ZstdDictionaryCompression:
+		dst.zstdDict++
+	case AdaptiveCompression:
+		dst.adaptive++
 	case NoCompression:
 		dst.none++
 	default:
@@ -1150,6 +1177,9 @@ func (a compressionTypeAggregator) Merge(
 	dst.snappy += src.snappy
 	dst.zstd += src.zstd
 	dst.minlz += src.minlz
+	dst.lz4 += src.lz4
+	dst.zstdDict += src.zstdDict
+	dst.adaptive += src.adaptive
 	dst.none += src.none
 	dst.unknown += src.unknown
 	return dst
@@ -0,0 +1,269 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/objstorage"
+	"github.com/chris124567/pebble/objstorage/remote"
+)
+
+// TieringOptions configures the background migration of cold local sstables
+// to remote storage. See Options.Experimental.Tiering.
+//
+// A table is moved by a tiering compaction, which copies the table
+// byte-for-byte to remote storage and replaces it in the MANIFEST with the
+// copy, in the same level. Only physical tables without blob references are
+// moved. Tables moved by tiering can be brought back to local storage with
+// DB.Download (see DownloadSpan.RecallTiered).
+//
+// The DB periodically scans the tables of the levels [MinLevel, L6] for cold
+// tables (see CheckInterval). Tiering compactions are only scheduled when
+// automatic compactions are enabled, and have a lower priority than all other
+// compactions. Tables whose span policy is TableStorageLocal (see
+// Options.Experimental.SpanPolicyFunc) are never moved.
+type TieringOptions struct {
+	// Locator identifies the remote storage to which tables are moved. It must
+	// be recognized by Options.Experimental.RemoteStorage. As with
+	// CreateOnShared, DB.SetCreatorID must be called before tables can be
+	// moved.
+	Locator remote.Locator
+
+	// MinLevel is the shallowest level whose tables are moved. Tables in levels
+	// above it are never moved. It must be at least 1: L0 tables are
+	// short-lived. The default is 5.
+	MinLevel int
+
+	// MinAge is the minimum age of the tables that are moved, as measured from
+	// the time they were created on local storage. A table that is recalled to
+	// local storage by DB.Download is considered created at that time. The
+	// default is 24 hours. A negative value moves tables regardless of their
+	// age.
+	MinAge time.Duration
+
+	// MaxCachedFraction is the largest fraction of a table's size that may be
+	// resident in the block cache for the table to be considered cold. Hotter
+	// tables are not moved. The default is 0.1. A negative value disables the
+	// check.
+	MaxCachedFraction float64
+
+	// CheckInterval is the interval at which the DB scans for tables to move.
+	// Each scan examines a bounded number of tables, resuming where the
+	// previous scan stopped, so a full pass over a large LSM spans several
+	// intervals. The default is one minute.
+	CheckInterval time.Duration
+
+	// Now returns the current time, against which the age of tables is
+	// evaluated. The default is time.Now.
+	Now func() time.Time
+}
+
+// EnsureDefaults ensures that the default values for all of the options have
+// been initialized.
+func (o *TieringOptions) EnsureDefaults() {
+	if o.MinLevel == 0 {
+		o.MinLevel = numLevels - 2
+	}
+	if o.MinAge == 0 {
+		o.MinAge = 24 * time.Hour
+	}
+	if o.MaxCachedFraction == 0 {
+		o.MaxCachedFraction = 0.1
+	}
+	if o.CheckInterval <= 0 {
+		o.CheckInterval = time.Minute
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+}
+
+// usesSharedObjects returns true if the options allow the creation of objects
+// on shared storage.
+func (o *Options) usesSharedObjects() bool {
+	return o.Experimental.CreateOnShared != remote.CreateOnSharedNone || o.Experimental.Tiering != nil
+}
+
+// tieringScanBatch is the maximum number of tables examined by each scan for
+// cold tables.
+const tieringScanBatch = 1000
+
+// tieringScan is the state of the periodic scan for cold local tables. The
+// scan walks the levels [MinLevel, L6] from the deepest, since they tend to
+// hold the coldest data, and examines at most tieringScanBatch tables at a
+// time.
+type tieringScan struct {
+	// level and key are the position of the scan: it resumes at the first
+	// table of the level whose largest key is >= key, skipping the table
+	// last if it is the last table examined by the previous scan.
+	level int
+	key   []byte
+	last  base.TableNum
+	// candidates are the cold tables found by the scans that have yet to be
+	// picked for a tiering compaction.
+	candidates []tieringCandidate
+}
+
+type tieringCandidate struct {
+	level int
+	file  *tableMetadata
+}
+
+// scanTieringCandidates examines the next batch of at most n tables of the
+// tiering scan, and queues the cold local tables as candidates for tiering
+// compactions. The tables of the current version are examined without holding
+// d.mu, as examining a table consults the block cache and the span policies.
+//
+// REQUIRES: d.mu is not held.
+func (d *DB) scanTieringCandidates(n int) {
+	t := d.opts.Experimental.Tiering
+	d.mu.Lock()
+	vers := d.mu.versions.currentVersion()
+	vers.Ref()
+	// The scan is only run by one goroutine at a time, so the position is
+	// copied and written back once the tables have been examined.
+	s := d.mu.compact.tiering
+	d.mu.Unlock()
+	defer vers.Unref()
+
+	if s.level < t.MinLevel {
+		s.level, s.key, s.last = numLevels-1, nil, 0
+	}
+	// Candidates of the previous scan that were not picked, for lack of
+	// compaction concurrency, are found again by the next pass.
+	var candidates []tieringCandidate
+	createdBefore := t.Now().Add(-max(t.MinAge, 0)).Unix()
+	for n > 0 && s.level >= t.MinLevel {
+		iter := vers.Levels[s.level].Iter()
+		f := iter.First()
+		if s.key != nil {
+			f = iter.SeekGE(d.cmp, s.key)
+		}
+		for ; f != nil && n > 0; f = iter.Next() {
+			if f.TableNum == s.last {
+				continue
+			}
+			n--
+			s.key, s.last = f.Largest().UserKey, f.TableNum
+			if d.isColdLocalTable(f, createdBefore) {
+				candidates = append(candidates, tieringCandidate{level: s.level, file: f})
+			}
+		}
+		if f == nil {
+			// The level has been examined; continue with the level above.
+			s.level, s.key, s.last = s.level-1, nil, 0
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s.candidates = candidates
+	d.mu.compact.tiering = s
+}
+
+// pickTieringCompaction picks a tiering compaction of one of the cold local
+// tables found by the last scan (see scanTieringCandidates), if any. The
+// candidates may belong to an older version.
+//
+// REQUIRES: d.mu and d.mu.versions.logLock are held.
+func (d *DB) pickTieringCompaction(env compactionEnv) *pickedCompaction {
+	if d.opts.Experimental.Tiering == nil {
+		return nil
+	}
+	vers := d.mu.versions.currentVersion()
+	s := &d.mu.compact.tiering
+	for len(s.candidates) > 0 {
+		c := s.candidates[0]
+		s.candidates = s.candidates[1:]
+		// The table may have been compacted since it was found. Tables that are
+		// compacting now are found again by a later scan.
+		if c.file.IsCompacting() || !objstorage.IsLocalTable(d.objProvider, c.file.FileBacking.DiskFileNum) {
+			continue
+		}
+		if found := vers.Levels[c.level].Find(d.cmp, c.file); found.Empty() {
+			continue
+		}
+		pc := pickDownloadCompaction(vers, d.mu.versions.l0Organizer, d.opts, env,
+			d.mu.versions.picker.getBaseLevel(), compactionKindTiering, c.level, c.file)
+		if pc != nil {
+			return pc
+		}
+	}
+	return nil
+}
+
+// isColdLocalTable returns true if the table can be moved to remote storage by
+// a tiering compaction: it is a local physical table without blob references,
+// created no later than createdBefore (in seconds since the Unix epoch), whose
+// span policies don't require local storage, and with a small enough fraction
+// of its size resident in the block cache. Whether the table is compacting is
+// not checked, as it requires d.mu: pickTieringCompaction checks it.
+func (d *DB) isColdLocalTable(f *tableMetadata, createdBefore int64) bool {
+	if f.Virtual || len(f.BlobReferences) > 0 || f.CreationTime > createdBefore {
+		return false
+	}
+	// Most of the tables that are not local were moved by tiering; the lookup
+	// skips them before the more expensive checks below.
+	if !objstorage.IsLocalTable(d.objProvider, f.FileBacking.DiskFileNum) {
+		return false
+	}
+	local := false
+	if err := d.opts.forEachSpanPolicy(f.UserKeyBounds(), func(policy *SpanPolicy) bool {
+		local = policy.TableStoragePolicy == TableStorageLocal
+		return !local
+	}); err != nil || local {
+		return false
+	}
+	if maxFraction := d.opts.Experimental.Tiering.MaxCachedFraction; maxFraction >= 0 {
+		cached := d.cacheHandle.FileSize(f.FileBacking.DiskFileNum)
+		if float64(cached) > maxFraction*float64(f.Size) {
+			return false
+		}
+	}
+	return true
+}
+
+// isTieredTable returns true if the table in the given level was moved to
+// remote storage by tiering, and can be recalled to local storage by
+// DB.Download. Tables that CreateOnShared and the span policies place on
+// shared storage are not recalled.
+func (d *DB) isTieredTable(f *tableMetadata, level int) bool {
+	t := d.opts.Experimental.Tiering
+	if t == nil {
+		return false
+	}
+	if preferShared, _, err := d.opts.spanPreferSharedStorage(f.UserKeyBounds(), level); err != nil || preferShared {
+		return false
+	}
+	meta, err := d.objProvider.Lookup(base.FileTypeTable, f.FileBacking.DiskFileNum)
+	return err == nil && meta.IsShared() && meta.Remote.Locator == t.Locator
+}
+
+// copyRemoteTableToLocal copies the remote table srcNum to a new local object
+// dstNum. On error, the new object is removed.
+func (d *DB) copyRemoteTableToLocal(srcNum, dstNum base.DiskFileNum) error {
+	ctx := context.TODO()
+	src, err := d.objProvider.OpenForReading(ctx, base.FileTypeTable, srcNum, objstorage.OpenOptions{})
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	w, _, err := d.objProvider.Create(ctx, base.FileTypeTable, dstNum, objstorage.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	rh := src.NewReadHandle(objstorage.NoReadBefore)
+	err = objstorage.Copy(ctx, rh, w, 0, uint64(src.Size()))
+	err = errors.CombineErrors(err, rh.Close())
+	if err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Finish()
+}
@@ -0,0 +1,207 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chris124567/pebble/objstorage"
+	"github.com/chris124567/pebble/objstorage/remote"
+	"github.com/chris124567/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTiering(t *testing.T) {
+	var now atomic.Int64
+	// Tables are created at the wall time; tiering evaluates their age against
+	// a clock that starts at the wall time and is advanced manually.
+	now.Store(time.Now().Unix())
+	advance := func(d time.Duration) { now.Add(int64(d / time.Second)) }
+
+	tier := remote.NewInMem()
+	opts := &Options{
+		FS:     vfs.NewMem(),
+		Logger: testLogger{t},
+	}
+	opts.Experimental.RemoteStorage = remote.MakeSimpleFactory(map[remote.Locator]remote.Storage{
+		"tier": tier,
+	})
+	opts.Experimental.Tiering = &TieringOptions{
+		Locator:       "tier",
+		MinLevel:      numLevels - 1,
+		MinAge:        time.Hour,
+		CheckInterval: time.Millisecond,
+		Now:           func() time.Time { return time.Unix(now.Load(), 0) },
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	require.NoError(t, d.SetCreatorID(1))
+
+	value := bytes.Repeat([]byte("v"), 100)
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("key%04d", i)), value, nil))
+	}
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Compact(context.Background(), []byte("a"), []byte("z"), false))
+	readAll := func() {
+		iter, err := d.NewIter(nil)
+		require.NoError(t, err)
+		n := 0
+		for valid := iter.First(); valid; valid = iter.Next() {
+			require.Equal(t, fmt.Sprintf("key%04d", n), string(iter.Key()))
+			require.Equal(t, value, iter.Value())
+			n++
+		}
+		require.NoError(t, iter.Close())
+		require.Equal(t, 1000, n)
+	}
+	// tables returns the tables in the last level, and whether they are all
+	// local or all remote.
+	tables := func() (_ []*tableMetadata, allLocal, allRemote bool) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		allLocal, allRemote = true, true
+		var res []*tableMetadata
+		for f := range d.mu.versions.currentVersion().Levels[numLevels-1].All() {
+			res = append(res, f)
+			isLocal := objstorage.IsLocalTable(d.objProvider, f.FileBacking.DiskFileNum)
+			allLocal = allLocal && isLocal
+			allRemote = allRemote && !isLocal
+		}
+		return res, allLocal, allRemote
+	}
+	ts, local, _ := tables()
+	require.NotEmpty(t, ts)
+	require.True(t, local)
+
+	// The tables are hot once they are read.
+	readAll()
+	advance(2 * time.Hour)
+	createdBefore := opts.Experimental.Tiering.Now().Add(-time.Hour).Unix()
+	for _, f := range ts {
+		require.False(t, d.isColdLocalTable(f, createdBefore))
+	}
+	time.Sleep(10 * time.Millisecond)
+	_, local, _ = tables()
+	require.True(t, local)
+
+	// Once evicted from the block cache, the tables are moved to the remote
+	// tier without being rewritten.
+	for _, f := range ts {
+		d.cacheHandle.EvictFile(f.FileBacking.DiskFileNum)
+	}
+	require.Eventually(t, func() bool {
+		_, _, allRemote := tables()
+		return allRemote
+	}, 10*time.Second, time.Millisecond)
+	moved, _, _ := tables()
+	require.Len(t, moved, len(ts))
+	for i := range ts {
+		require.Equal(t, ts[i].Size, moved[i].Size)
+		require.Equal(t, ts[i].CreationTime, moved[i].CreationTime)
+		require.Equal(t, ts[i].LargestSeqNum, moved[i].LargestSeqNum)
+	}
+	require.Equal(t, int64(len(ts)), d.Metrics().Compact.TieringCount)
+	objs, err := tier.List("", "")
+	require.NoError(t, err)
+	require.NotEmpty(t, objs)
+	readAll()
+
+	// Download only recalls tiered tables when asked to.
+	span := DownloadSpan{StartKey: []byte("a"), EndKey: []byte("z")}
+	require.NoError(t, d.Download(context.Background(), []DownloadSpan{span}))
+	_, _, allRemote := tables()
+	require.True(t, allRemote)
+	span.RecallTiered = true
+	require.NoError(t, d.Download(context.Background(), []DownloadSpan{span}))
+	recalled, local, _ := tables()
+	require.True(t, local)
+	for _, f := range recalled {
+		require.Equal(t, now.Load(), f.CreationTime)
+	}
+	readAll()
+	for _, f := range recalled {
+		d.cacheHandle.EvictFile(f.FileBacking.DiskFileNum)
+	}
+
+	// The recalled tables are moved again once they are old enough.
+	time.Sleep(10 * time.Millisecond)
+	_, local, _ = tables()
+	require.True(t, local)
+	advance(2 * time.Hour)
+	require.Eventually(t, func() bool {
+		_, _, allRemote := tables()
+		return allRemote
+	}, 10*time.Second, time.Millisecond)
+	require.NoError(t, d.Close())
+
+	// The moves are recorded in the MANIFEST.
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	_, _, allRemote = tables()
+	require.True(t, allRemote)
+	readAll()
+}
+
+func TestTieringScan(t *testing.T) {
+	opts := &Options{
+		FS:     vfs.NewMem(),
+		Logger: testLogger{t},
+	}
+	opts.Experimental.RemoteStorage = remote.MakeSimpleFactory(map[remote.Locator]remote.Storage{
+		"tier": remote.NewInMem(),
+	})
+	// The scans are driven by the test.
+	opts.Experimental.Tiering = &TieringOptions{
+		Locator:           "tier",
+		MinAge:            -1,
+		MaxCachedFraction: -1,
+		CheckInterval:     time.Hour,
+	}
+	opts.Experimental.SpanPolicyFunc = MakeStaticSpanPolicyFunc(
+		DefaultComparer.Compare, KeyRange{Start: []byte("b"), End: []byte("c")}, SpanPolicy{
+			TableStoragePolicy: TableStorageLocal,
+		})
+	opts.EnsureDefaults()
+	require.Equal(t, numLevels-2, opts.Experimental.Tiering.MinLevel)
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// Write a table for each of the prefixes into L6; outputs are split at
+	// the boundaries of the span policy.
+	for _, prefix := range []string{"a", "b", "c"} {
+		require.NoError(t, d.Set([]byte(prefix), nil, nil))
+	}
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Compact(context.Background(), []byte("a"), []byte("z"), false))
+
+	d.mu.Lock()
+	require.Equal(t, 3, d.mu.versions.currentVersion().Levels[numLevels-1].Len())
+	d.mu.Unlock()
+	scan := func(n int) string {
+		d.scanTieringCandidates(n)
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		var buf bytes.Buffer
+		for _, c := range d.mu.compact.tiering.candidates {
+			fmt.Fprintf(&buf, "L%d:%s ", c.level, c.file.Smallest().UserKey)
+		}
+		return buf.String()
+	}
+	// The scan resumes where the previous one stopped, skips the tables that
+	// must stay local, and starts over once L5 has been examined.
+	require.Equal(t, "L6:a ", scan(1))
+	require.Equal(t, "", scan(1))
+	require.Equal(t, "L6:c ", scan(1))
+	require.Equal(t, "", scan(1))
+	require.Equal(t, "L6:a L6:c ", scan(10))
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/chris124567/pebble"
 	"github.com/chris124567/pebble/bloom"
+	"github.com/chris124567/pebble/fusefilter"
 	"github.com/chris124567/pebble/internal/base"
 	"github.com/chris124567/pebble/objstorage"
 	"github.com/chris124567/pebble/objstorage/objstorageprovider"
@@ -164,7 +165,7 @@ func New(opts ...Option) *T {
 
 	opts = append(opts,
 		Comparers(base.DefaultComparer),
-		Filters(bloom.FilterPolicy(10)),
+		Filters(bloom.FilterPolicy(10), fusefilter.FilterPolicy(8)),
 		Mergers(base.DefaultMerger))
 
 	for _, opt := range opts {
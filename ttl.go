@@ -0,0 +1,502 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"encoding/binary"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/chris124567/pebble/internal/base"
+)
+
+// TTLOptions configures values with a time-to-live. See Options.TTL.
+type TTLOptions struct {
+	// Now returns the current time, against which the expiry of values is
+	// evaluated. The default is time.Now.
+	Now func() time.Time
+
+	// CompactionThreshold is the estimated fraction of a table's bytes that
+	// must belong to expired values for the table to be compacted in order to
+	// reclaim them. The default is 0.5. A negative value disables these
+	// compactions; expired values are then only dropped by compactions
+	// scheduled for other reasons.
+	CompactionThreshold float64
+}
+
+// EnsureDefaults ensures that the default values for all of the options have
+// been initialized.
+func (o *TTLOptions) EnsureDefaults() {
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	if o.CompactionThreshold == 0 {
+		o.CompactionThreshold = 0.5
+	}
+}
+
+var errTTLDisabled = errors.New("pebble: TTL is not enabled; see Options.TTL")
+
+// When TTL is enabled, the values of SET, SETWITHDEL and MERGE keys are stored
+// with a header recording their expiry: either a single ttlNoExpiry byte, or a
+// ttlExpiry byte followed by the expiry time in nanoseconds since the Unix
+// epoch, as a little-endian uint64.
+const (
+	ttlNoExpiry byte = 0
+	ttlExpiry   byte = 1
+)
+
+func ttlHeaderLen(expiry uint64) int {
+	if expiry == 0 {
+		return 1
+	}
+	return 9
+}
+
+// putTTLHeader encodes the header for the given expiry into buf, which must be
+// ttlHeaderLen(expiry) bytes long.
+func putTTLHeader(buf []byte, expiry uint64) {
+	if expiry == 0 {
+		buf[0] = ttlNoExpiry
+		return
+	}
+	buf[0] = ttlExpiry
+	binary.LittleEndian.PutUint64(buf[1:], expiry)
+}
+
+// decodeTTLValue splits a stored value into its expiry, which is zero if the
+// value never expires, and the value written by the user.
+func decodeTTLValue(v []byte) (expiry uint64, value []byte, _ error) {
+	if len(v) > 0 {
+		switch v[0] {
+		case ttlNoExpiry:
+			return 0, v[1:], nil
+		case ttlExpiry:
+			if len(v) >= 9 {
+				return binary.LittleEndian.Uint64(v[1:]), v[9:], nil
+			}
+		}
+	}
+	return 0, nil, base.CorruptionErrorf("pebble: invalid TTL value header")
+}
+
+// ttlValueHasExpiry returns true if the stored value v has an expiry. Values
+// with an expiry are never separated into blob files, so that compactions can
+// read their expiry without fetching them.
+func ttlValueHasExpiry(v []byte) bool {
+	return len(v) > 0 && v[0] == ttlExpiry
+}
+
+// ttlValueExpiry returns the expiry of the stored value of kv. It is used as
+// compact.IterConfig.ValueExpiry.
+func ttlValueExpiry(kv *base.InternalKV) (uint64, error) {
+	if kv.V.IsBlobValueHandle() {
+		// Values separated into blob files never expire; see ttlValueHasExpiry.
+		return 0, nil
+	}
+	v, _, err := kv.Value(nil)
+	if err != nil {
+		return 0, err
+	}
+	expiry, _, err := decodeTTLValue(v)
+	return expiry, err
+}
+
+// checkUnownedBatchTTL returns an error if a batch that was not created by a
+// DB holds sets or merges, whose values lack the TTL header. REQUIRES:
+// d.opts.TTL != nil.
+func checkUnownedBatchTTL(b *Batch) error {
+	for r := b.Reader(); ; {
+		kind, _, _, ok, err := r.Next()
+		if !ok || err != nil {
+			return err
+		}
+		switch kind {
+		case InternalKeyKindSet, InternalKeyKindSetWithDelete, InternalKeyKindMerge:
+			return errors.New("pebble: batch with sets or merges not created by a DB with TTL enabled")
+		}
+	}
+}
+
+// ttlNow returns the current time against which the expiry of values is
+// evaluated. REQUIRES: d.opts.TTL != nil.
+func (d *DB) ttlNow() uint64 {
+	return uint64(d.opts.TTL.Now().UnixNano())
+}
+
+// storedMerge returns the Merge used to merge values as they are stored, with
+// their TTL headers if TTL is enabled. It is used by compactions, which retain
+// the expiry of the base of a merge in the merged value.
+func (d *DB) storedMerge() Merge {
+	if d.opts.TTL == nil {
+		return d.merge
+	}
+	merge := d.merge
+	return func(key, value []byte) (ValueMerger, error) {
+		expiry, value, err := decodeTTLValue(value)
+		if err != nil {
+			return nil, err
+		}
+		inner, err := merge(key, value)
+		if err != nil {
+			return nil, err
+		}
+		return &storedTTLValueMerger{inner: inner, expiry: expiry}, nil
+	}
+}
+
+// storedTTLValueMerger merges stored values with the user's merge operator,
+// encoding the expiry of the base of the merge into the merged value.
+type storedTTLValueMerger struct {
+	inner  ValueMerger
+	expiry uint64
+}
+
+var _ DeletableValueMerger = (*storedTTLValueMerger)(nil)
+
+func (m *storedTTLValueMerger) decode(value []byte) ([]byte, error) {
+	expiry, value, err := decodeTTLValue(value)
+	if expiry != 0 {
+		m.expiry = expiry
+	}
+	return value, err
+}
+
+// MergeNewer implements base.ValueMerger.
+func (m *storedTTLValueMerger) MergeNewer(value []byte) error {
+	value, err := m.decode(value)
+	if err != nil {
+		return err
+	}
+	return m.inner.MergeNewer(value)
+}
+
+// MergeOlder implements base.ValueMerger.
+func (m *storedTTLValueMerger) MergeOlder(value []byte) error {
+	value, err := m.decode(value)
+	if err != nil {
+		return err
+	}
+	return m.inner.MergeOlder(value)
+}
+
+// Finish implements base.ValueMerger.
+func (m *storedTTLValueMerger) Finish(includesBase bool) ([]byte, io.Closer, error) {
+	value, _, closer, err := m.DeletableFinish(includesBase)
+	return value, closer, err
+}
+
+// DeletableFinish implements base.DeletableValueMerger.
+func (m *storedTTLValueMerger) DeletableFinish(
+	includesBase bool,
+) (_ []byte, needDelete bool, _ io.Closer, _ error) {
+	value, needDelete, closer, err := finishValueMerger(m.inner, includesBase)
+	if err != nil || needDelete {
+		if closer != nil {
+			err = firstError(err, closer.Close())
+		}
+		return nil, needDelete, nil, err
+	}
+	n := ttlHeaderLen(m.expiry)
+	res := make([]byte, n+len(value))
+	putTTLHeader(res, m.expiry)
+	copy(res[n:], value)
+	if closer != nil {
+		if err := closer.Close(); err != nil {
+			return nil, false, nil, err
+		}
+	}
+	return res, false, nil, nil
+}
+
+// initTTL wraps the Iterator's point iterator and merge operator to expire
+// values as of now. See ttlIter.
+func (i *Iterator) initTTL(t *ttlIter, now uint64) {
+	*t = ttlIter{
+		equal:    i.comparer.Equal,
+		merge:    i.merge,
+		now:      now,
+		buf:      t.buf[:0],
+		keyBuf:   t.keyBuf[:0],
+		mergeKey: t.mergeKey[:0],
+		setKey:   t.setKey[:0],
+	}
+	i.ttl = t
+	i.merge = t.newValueMerger
+}
+
+// ttlIter wraps the point iterator of an Iterator when TTL is enabled. It
+// strips the TTL header from the values of SET, SETWITHDEL and MERGE keys, and
+// surfaces SET and SETWITHDEL keys whose values have expired as DELs at the
+// same sequence number.
+//
+// An expired key that is the base of a merge is surfaced unchanged, so that the
+// merged value expires along with it, as it does once compacted. In the forward
+// direction, the base is the SET immediately following a MERGE of the same
+// user key. In the reverse direction, it is the SET immediately preceding one,
+// which ttlIter determines by stepping back and forth. The expiry of the base
+// is passed to the merge operator out of band: ttlIter retains the expiry of
+// the last SET it surfaced, which the ttlValueMerger reads when it is handed
+// the base value.
+type ttlIter struct {
+	topLevelIterator
+	equal base.Equal
+	merge Merge
+	now   uint64
+	kv    base.InternalKV
+	buf   []byte
+	// keyBuf holds the user key of an expired SET while stepping back to find
+	// out whether it is the base of a merge.
+	keyBuf []byte
+	err    error
+	// mergeKey holds the user key of the last key surfaced while iterating
+	// forward, if it was a MERGE.
+	mergeKey   []byte
+	afterMerge bool
+	// setKey and setExpiry hold the user key and expiry of the last expiring
+	// SET or SETWITHDEL surfaced, if no keys other than MERGEs have been
+	// surfaced since. setExpiry is zero otherwise.
+	setKey    []byte
+	setExpiry uint64
+}
+
+var _ topLevelIterator = (*ttlIter)(nil)
+
+// init sets the iterator wrapped by t.
+func (t *ttlIter) init(iter topLevelIterator) {
+	t.topLevelIterator = iter
+	t.reset()
+}
+
+func (t *ttlIter) reset() {
+	t.err = nil
+	t.afterMerge = false
+	t.setExpiry = 0
+}
+
+// value returns the value of kv with its TTL header stripped.
+func (t *ttlIter) value(kv *base.InternalKV) (expiry uint64, value []byte, ok bool) {
+	v, callerOwned, err := kv.Value(t.buf)
+	if err != nil {
+		t.err = err
+		return 0, nil, false
+	}
+	if callerOwned {
+		t.buf = v[:0]
+	}
+	expiry, value, err = decodeTTLValue(v)
+	if err != nil {
+		t.err = err
+		return 0, nil, false
+	}
+	return expiry, value, true
+}
+
+// surface transforms the key the wrapped iterator is positioned at.
+func (t *ttlIter) surface(kv *base.InternalKV, reverse bool) *base.InternalKV {
+	if kv == nil {
+		t.afterMerge = false
+		t.setExpiry = 0
+		return nil
+	}
+	afterMerge := t.afterMerge && t.equal(t.mergeKey, kv.K.UserKey)
+	t.afterMerge = false
+	switch kv.Kind() {
+	case InternalKeyKindSet, InternalKeyKindSetWithDelete:
+	case InternalKeyKindMerge:
+		_, value, ok := t.value(kv)
+		if !ok {
+			return nil
+		}
+		if !reverse {
+			t.mergeKey = append(t.mergeKey[:0], kv.K.UserKey...)
+			t.afterMerge = true
+		}
+		t.kv = base.InternalKV{K: kv.K, V: base.MakeInPlaceValue(value)}
+		return &t.kv
+	default:
+		t.setExpiry = 0
+		return kv
+	}
+
+	t.setExpiry = 0
+	expiry, value, ok := t.value(kv)
+	if !ok {
+		return nil
+	}
+	if expiry != 0 && expiry <= t.now {
+		isBase := afterMerge
+		if reverse {
+			if kv, isBase = t.prevIsMerge(kv); kv == nil {
+				return nil
+			}
+			if isBase {
+				if _, value, ok = t.value(kv); !ok {
+					return nil
+				}
+			}
+		}
+		if !isBase {
+			t.kv = base.InternalKV{
+				K: base.MakeInternalKey(kv.K.UserKey, kv.SeqNum(), InternalKeyKindDelete),
+			}
+			return &t.kv
+		}
+	}
+	if expiry != 0 {
+		t.setKey = append(t.setKey[:0], kv.K.UserKey...)
+		t.setExpiry = expiry
+	}
+	t.kv = base.InternalKV{K: kv.K, V: base.MakeInPlaceValue(value)}
+	return &t.kv
+}
+
+// prevIsMerge steps the wrapped iterator back from kv and returns whether the
+// preceding key is a MERGE of the same user key. The wrapped iterator is
+// returned to kv, which is returned again, or nil if stepping failed.
+func (t *ttlIter) prevIsMerge(kv *base.InternalKV) (*base.InternalKV, bool) {
+	t.keyBuf = append(t.keyBuf[:0], kv.K.UserKey...)
+	prev := t.topLevelIterator.Prev()
+	if prev == nil && t.topLevelIterator.Error() != nil {
+		return nil, false
+	}
+	isMerge := prev != nil && prev.Kind() == InternalKeyKindMerge && t.equal(prev.K.UserKey, t.keyBuf)
+	return t.topLevelIterator.Next(), isMerge
+}
+
+// baseExpiry returns the expiry of the last SET surfaced, if it was a SET of
+// the given user key.
+func (t *ttlIter) baseExpiry(key []byte) uint64 {
+	if t.setExpiry != 0 && t.equal(t.setKey, key) {
+		return t.setExpiry
+	}
+	return 0
+}
+
+// SeekGE implements base.InternalIterator.
+func (t *ttlIter) SeekGE(key []byte, flags base.SeekGEFlags) *base.InternalKV {
+	t.reset()
+	return t.surface(t.topLevelIterator.SeekGE(key, flags), false)
+}
+
+// SeekPrefixGE implements base.InternalIterator.
+func (t *ttlIter) SeekPrefixGE(prefix, key []byte, flags base.SeekGEFlags) *base.InternalKV {
+	t.reset()
+	return t.surface(t.topLevelIterator.SeekPrefixGE(prefix, key, flags), false)
+}
+
+// SeekPrefixGEStrict implements topLevelIterator.
+func (t *ttlIter) SeekPrefixGEStrict(prefix, key []byte, flags base.SeekGEFlags) *base.InternalKV {
+	t.reset()
+	return t.surface(t.topLevelIterator.SeekPrefixGEStrict(prefix, key, flags), false)
+}
+
+// SeekLT implements base.InternalIterator.
+func (t *ttlIter) SeekLT(key []byte, flags base.SeekLTFlags) *base.InternalKV {
+	t.reset()
+	return t.surface(t.topLevelIterator.SeekLT(key, flags), true)
+}
+
+// First implements base.InternalIterator.
+func (t *ttlIter) First() *base.InternalKV {
+	t.reset()
+	return t.surface(t.topLevelIterator.First(), false)
+}
+
+// Last implements base.InternalIterator.
+func (t *ttlIter) Last() *base.InternalKV {
+	t.reset()
+	return t.surface(t.topLevelIterator.Last(), true)
+}
+
+// Next implements base.InternalIterator.
+func (t *ttlIter) Next() *base.InternalKV {
+	return t.surface(t.topLevelIterator.Next(), false)
+}
+
+// NextPrefix implements base.InternalIterator.
+func (t *ttlIter) NextPrefix(succKey []byte) *base.InternalKV {
+	t.afterMerge = false
+	return t.surface(t.topLevelIterator.NextPrefix(succKey), false)
+}
+
+// Prev implements base.InternalIterator.
+func (t *ttlIter) Prev() *base.InternalKV {
+	t.afterMerge = false
+	return t.surface(t.topLevelIterator.Prev(), true)
+}
+
+// Error implements base.InternalIterator.
+func (t *ttlIter) Error() error {
+	if t.err != nil {
+		return t.err
+	}
+	return t.topLevelIterator.Error()
+}
+
+// newValueMerger is the Merge of an Iterator with TTL enabled. The values it
+// is handed have had their TTL headers stripped by the ttlIter.
+func (t *ttlIter) newValueMerger(key, value []byte) (ValueMerger, error) {
+	inner, err := t.merge(key, value)
+	if err != nil {
+		return nil, err
+	}
+	// When iterating in reverse, the first value is the base of the merge, if
+	// there is one.
+	return &ttlValueMerger{
+		inner:  inner,
+		iter:   t,
+		key:    slices.Clone(key),
+		expiry: t.baseExpiry(key),
+	}, nil
+}
+
+// ttlValueMerger merges values read through a ttlIter with the user's merge
+// operator, deleting the merged value if its base has expired.
+type ttlValueMerger struct {
+	inner  ValueMerger
+	iter   *ttlIter
+	key    []byte
+	expiry uint64
+}
+
+var _ DeletableValueMerger = (*ttlValueMerger)(nil)
+
+// MergeNewer implements base.ValueMerger.
+func (m *ttlValueMerger) MergeNewer(value []byte) error {
+	return m.inner.MergeNewer(value)
+}
+
+// MergeOlder implements base.ValueMerger.
+func (m *ttlValueMerger) MergeOlder(value []byte) error {
+	// When iterating forward, the last value is the base of the merge, if there
+	// is one.
+	if expiry := m.iter.baseExpiry(m.key); expiry != 0 {
+		m.expiry = expiry
+	}
+	return m.inner.MergeOlder(value)
+}
+
+// Finish implements base.ValueMerger.
+func (m *ttlValueMerger) Finish(includesBase bool) ([]byte, io.Closer, error) {
+	value, _, closer, err := m.DeletableFinish(includesBase)
+	return value, closer, err
+}
+
+// DeletableFinish implements base.DeletableValueMerger.
+func (m *ttlValueMerger) DeletableFinish(
+	includesBase bool,
+) (_ []byte, needDelete bool, _ io.Closer, _ error) {
+	value, needDelete, closer, err := finishValueMerger(m.inner, includesBase)
+	if err == nil && !needDelete && m.expiry != 0 && m.expiry <= m.iter.now {
+		if closer != nil {
+			err = closer.Close()
+		}
+		return nil, true, nil, err
+	}
+	return value, needDelete, closer, err
+}
@@ -0,0 +1,253 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chris124567/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+// ttlTestClock is a manually advanced clock for TTLOptions.Now.
+type ttlTestClock struct {
+	nanos atomic.Int64
+}
+
+func (c *ttlTestClock) now() time.Time { return time.Unix(0, c.nanos.Load()) }
+
+func (c *ttlTestClock) advance(d time.Duration) { c.nanos.Add(int64(d)) }
+
+// scanTTLTestDB returns the DB's keys and values, iterating forward or in
+// reverse.
+func scanTTLTestDB(t *testing.T, d *DB, reverse bool) string {
+	iter, err := d.NewIter(nil)
+	require.NoError(t, err)
+	var res []string
+	if reverse {
+		for valid := iter.Last(); valid; valid = iter.Prev() {
+			res = append([]string{fmt.Sprintf("%s=%s", iter.Key(), iter.Value())}, res...)
+		}
+	} else {
+		for valid := iter.First(); valid; valid = iter.Next() {
+			res = append(res, fmt.Sprintf("%s=%s", iter.Key(), iter.Value()))
+		}
+	}
+	require.NoError(t, iter.Close())
+	return strings.Join(res, " ")
+}
+
+func TestTTL(t *testing.T) {
+	clock := &ttlTestClock{}
+	clock.nanos.Store(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano())
+	mem := vfs.NewMem()
+	opts := &Options{
+		FS:                          mem,
+		TTL:                         &TTLOptions{Now: clock.now},
+		DisableAutomaticCompactions: true,
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+
+	hour := &WriteOptions{TTL: time.Hour}
+	require.NoError(t, d.Set([]byte("a"), []byte("old"), nil))
+	require.NoError(t, d.Set([]byte("a"), []byte("1"), hour))
+	require.NoError(t, d.Set([]byte("b"), []byte("2"), nil))
+	b := d.NewBatch()
+	require.NoError(t, b.SetWithTTL([]byte("c"), []byte("3"), 2*time.Hour, nil))
+	// A merge onto an expiring value expires along with it.
+	require.NoError(t, b.SetWithTTL([]byte("d"), []byte("4"), time.Hour, nil))
+	require.NoError(t, b.Merge([]byte("d"), []byte("5"), nil))
+	require.NoError(t, b.Merge([]byte("e"), []byte("6"), nil))
+	require.Error(t, b.SetWithTTL([]byte("f"), nil, 0, nil))
+	require.NoError(t, b.Commit(nil))
+
+	expectGet := func(key, expected string) {
+		t.Helper()
+		v, closer, err := d.Get([]byte(key))
+		if expected == "" {
+			require.ErrorIs(t, err, ErrNotFound)
+			return
+		}
+		require.NoError(t, err)
+		require.Equal(t, expected, string(v))
+		require.NoError(t, closer.Close())
+	}
+	expectScan := func(expected string) {
+		t.Helper()
+		require.Equal(t, expected, scanTTLTestDB(t, d, false))
+		require.Equal(t, expected, scanTTLTestDB(t, d, true))
+	}
+
+	expectScan("a=1 b=2 c=3 d=45 e=6")
+	expectGet("a", "1")
+	expectGet("d", "45")
+
+	// Iterators expire values as of their creation.
+	iter, err := d.NewIter(nil)
+	require.NoError(t, err)
+	clock.advance(time.Hour)
+	require.True(t, iter.SeekGE([]byte("a")))
+	require.Equal(t, "1", string(iter.Value()))
+	clone, err := iter.Clone(CloneOptions{})
+	require.NoError(t, err)
+	require.True(t, clone.SeekLT([]byte("b")))
+	require.Equal(t, "1", string(iter.Value()))
+	require.NoError(t, clone.Close())
+	require.NoError(t, iter.Close())
+
+	// The expired value of a shadows the older value, and the merge onto d
+	// expires along with its base.
+	expectScan("b=2 c=3 e=6")
+	expectGet("a", "")
+	expectGet("d", "")
+	expectGet("e", "6")
+
+	// Compactions drop expired values.
+	require.NoError(t, d.Flush())
+	d.mu.Lock()
+	d.waitTableStats()
+	d.mu.Unlock()
+	m := d.Metrics()
+	require.Equal(t, int64(1), m.Levels[0].TablesCount)
+	require.NoError(t, d.Compact(context.Background(), []byte("a"), []byte("z"), false))
+	expectScan("b=2 c=3 e=6")
+	expectGet("a", "")
+	expectGet("d", "")
+	require.NoError(t, d.Close())
+
+	// TTL cannot be disabled once enabled.
+	_, err = Open("", &Options{FS: mem})
+	require.Error(t, err)
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	clock.advance(time.Hour)
+	expectScan("b=2 e=6")
+	require.NoError(t, d.Close())
+
+	// Nor can it be enabled on an existing DB.
+	d, err = Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	require.ErrorIs(t, d.Set([]byte("a"), nil, hour), errTTLDisabled)
+	require.NoError(t, d.Close())
+	_, err = Open("", &Options{FS: d.opts.FS, TTL: &TTLOptions{}})
+	require.Error(t, err)
+}
+
+// TestTTLExpiredCompaction verifies that a table whose values have largely
+// expired is compacted to drop them.
+func TestTTLExpiredCompaction(t *testing.T) {
+	clock := &ttlTestClock{}
+	clock.nanos.Store(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano())
+	d, err := Open("", &Options{
+		FS:  vfs.NewMem(),
+		TTL: &TTLOptions{Now: clock.now},
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	b := d.NewBatch()
+	for i := 0; i < 100; i++ {
+		ttl := time.Duration(i+1) * time.Minute
+		require.NoError(t, b.SetWithTTL([]byte(fmt.Sprintf("%03d", i)), make([]byte, 100), ttl, nil))
+	}
+	require.NoError(t, b.Commit(nil))
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Compact(context.Background(), []byte("000"), []byte("100"), false))
+	d.mu.Lock()
+	d.waitTableStats()
+	d.mu.Unlock()
+
+	tables, err := d.SSTables(WithProperties())
+	require.NoError(t, err)
+	require.Len(t, tables[numLevels-1], 1)
+	props := tables[numLevels-1][0].Properties
+	require.Equal(t, uint64(100*(100+9)), props.ExpiringValueSize)
+	start := uint64(clock.now().UnixNano())
+	require.Equal(t, start+uint64(time.Minute), props.MinValueExpiry)
+	require.Equal(t, start+uint64(100*time.Minute), props.MaxValueExpiry)
+
+	// Once most values have expired, an expired compaction drops them.
+	clock.advance(80 * time.Minute)
+	d.mu.Lock()
+	d.maybeScheduleCompaction()
+	d.mu.Unlock()
+	require.Eventually(t, func() bool {
+		return d.Metrics().Compact.ExpiredCount == 1
+	}, 10*time.Second, time.Millisecond)
+	tables, err = d.SSTables(WithProperties())
+	require.NoError(t, err)
+	require.Len(t, tables[numLevels-1], 1)
+	require.Equal(t, uint64(20*(100+9)), tables[numLevels-1][0].Properties.ExpiringValueSize)
+}
+
+// TestTTLValueSeparation verifies that values with an expiry are kept in
+// sstables when value separation is enabled, so that compactions can expire
+// them without fetching values from blob files.
+func TestTTLValueSeparation(t *testing.T) {
+	clock := &ttlTestClock{}
+	clock.nanos.Store(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano())
+	opts := &Options{
+		FS:                          vfs.NewMem(),
+		FormatMajorVersion:          internalFormatNewest,
+		TTL:                         &TTLOptions{Now: clock.now, CompactionThreshold: -1},
+		DisableAutomaticCompactions: true,
+	}
+	opts.Experimental.ValueSeparationPolicy = func() ValueSeparationPolicy {
+		return ValueSeparationPolicy{Enabled: true, MinimumSize: 50, MaxBlobReferenceDepth: 10}
+	}
+	opts.Experimental.EnableColumnarBlocks = func() bool { return true }
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// Half of the values expire; the other half never do.
+	b := d.NewBatch()
+	for i := 0; i < 100; i++ {
+		key := []byte(fmt.Sprintf("%03d", i))
+		if i%2 == 0 {
+			require.NoError(t, b.SetWithTTL(key, make([]byte, 100), time.Hour, nil))
+		} else {
+			require.NoError(t, b.Set(key, make([]byte, 100), nil))
+		}
+	}
+	require.NoError(t, b.Commit(nil))
+	require.NoError(t, d.Flush())
+	tables, err := d.SSTables(WithProperties())
+	require.NoError(t, err)
+	require.Len(t, tables[0], 1)
+	require.Equal(t, uint64(50*(100+9)), tables[0][0].Properties.ExpiringValueSize)
+	require.Equal(t, uint64(50*(100+1)), d.Metrics().BlobFiles.ReferencedValueSize)
+
+	// Flush an overlapping table, so that the compaction rewrites the first.
+	require.NoError(t, d.Set([]byte("000a"), make([]byte, 100), nil))
+	require.NoError(t, d.Flush())
+	clock.advance(2 * time.Hour)
+	require.NoError(t, d.Compact(context.Background(), []byte("000"), []byte("100"), false))
+	require.Zero(t, d.Metrics().Compact.MoveCount)
+	require.Equal(t, uint64(50), d.Metrics().Keys.ExpiredValuesCount)
+	iter, err := d.NewIter(nil)
+	require.NoError(t, err)
+	n := 0
+	for valid := iter.First(); valid; valid = iter.Next() {
+		require.Len(t, iter.Value(), 100)
+		n++
+	}
+	require.NoError(t, iter.Close())
+	require.Equal(t, 51, n)
+
+	// A batch built without a DB lacks TTL headers and is rejected.
+	var unowned Batch
+	require.NoError(t, unowned.Set([]byte("x"), []byte("1"), nil))
+	require.Error(t, d.Apply(&unowned, nil))
+	unowned.Reset()
+	require.NoError(t, unowned.Delete([]byte("x"), nil))
+	require.NoError(t, d.Apply(&unowned, nil))
+}
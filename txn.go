@@ -0,0 +1,332 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+
+	"github.com/cockroachdb/errors"
+	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/internal/rangekey"
+)
+
+// ErrTxnConflict is returned by Txn.Commit when a key or span read by the
+// transaction was written by another commit after the transaction's snapshot
+// was taken. The transaction's writes were not applied and the caller may
+// retry the transaction.
+type ErrTxnConflict struct {
+	// Key is the start key of the conflicting write. It is nil if the conflict
+	// was caused by an operation whose key range is not tracked (such as an
+	// sstable ingestion).
+	Key []byte
+	// SeqNum is the sequence number of the conflicting write.
+	SeqNum base.SeqNum
+}
+
+// Error implements error.
+func (e *ErrTxnConflict) Error() string {
+	if e.Key == nil {
+		return fmt.Sprintf("pebble: transaction conflict with ingestion at seqnum %s", e.SeqNum)
+	}
+	return fmt.Sprintf("pebble: transaction conflict on key %q at seqnum %s", e.Key, e.SeqNum)
+}
+
+// errTxnClosed is returned when a method is called on a committed or closed
+// transaction.
+var errTxnClosed = errors.New("pebble: transaction already committed or closed")
+
+// Txn is an optimistic transaction. Reads observe a consistent snapshot of the
+// DB overlaid with the transaction's own writes, which are buffered in an
+// indexed Batch. The transaction records the keys and spans it reads, and
+// Commit atomically validates that none of them were written by another
+// commit after the transaction's snapshot before applying the writes through
+// the commit pipeline.
+//
+// Blind writes never conflict; only the read set is validated. Iterators
+// record their full [LowerBound, UpperBound) span, regardless of how much of
+// it is actually traversed.
+//
+// A Txn is not safe for concurrent use.
+type Txn struct {
+	db    *DB
+	batch *Batch
+	snap  *Snapshot
+	// trackFrom is the sequence number from which the DB records writes on
+	// behalf of this transaction. trackFrom <= snap.seqNum.
+	trackFrom base.SeqNum
+	// readKeys and readSpans make up the read set.
+	readKeys  [][]byte
+	readSpans []KeyRange
+	done      bool
+}
+
+// NewTxn begins a new optimistic transaction. The transaction must be
+// finished by calling Commit or Close.
+func (d *DB) NewTxn() *Txn {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	t := &Txn{db: d, batch: d.NewIndexedBatch()}
+
+	// Start tracking writes before determining the snapshot sequence number, so
+	// that every write not visible to the snapshot is tracked. Batches that
+	// were assigned sequence numbers before tracking began must be published
+	// before we take the snapshot.
+	d.commit.mu.Lock()
+	t.trackFrom = d.mu.versions.logSeqNum.Load()
+	d.txns.register(t)
+	d.commit.mu.Unlock()
+	for d.mu.versions.visibleSeqNum.Load() < t.trackFrom {
+		runtime.Gosched()
+	}
+	t.snap = d.NewSnapshot()
+	return t
+}
+
+// SnapshotSeqNum returns the sequence number of the snapshot the transaction
+// reads from.
+func (t *Txn) SnapshotSeqNum() base.SeqNum {
+	return t.snap.seqNum
+}
+
+// Get gets the value for the given key, observing the transaction's own
+// writes. The key is added to the transaction's read set. See Reader.Get.
+func (t *Txn) Get(key []byte) ([]byte, io.Closer, error) {
+	if t.done {
+		return nil, nil, errTxnClosed
+	}
+	t.readKeys = append(t.readKeys, append([]byte(nil), key...))
+	return t.db.getInternal(key, t.batch, t.snap)
+}
+
+// NewIter returns an iterator observing the transaction's own writes. The
+// iterator's [LowerBound, UpperBound) span is added to the transaction's read
+// set; a nil bound is unbounded. Bounds later set through SetBounds,
+// SetOptions or Clone are added to the read set too. See Reader.NewIter.
+func (t *Txn) NewIter(o *IterOptions) (*Iterator, error) {
+	return t.NewIterWithContext(context.Background(), o)
+}
+
+// NewIterWithContext is like NewIter, and additionally accepts a context for
+// tracing.
+func (t *Txn) NewIterWithContext(ctx context.Context, o *IterOptions) (*Iterator, error) {
+	if t.done {
+		return nil, errTxnClosed
+	}
+	if o != nil {
+		t.recordSpan(o.LowerBound, o.UpperBound)
+	} else {
+		t.recordSpan(nil, nil)
+	}
+	iter := t.db.newIter(ctx, t.batch, newIterOpts{
+		snapshot: snapshotIterOpts{seqNum: t.snap.seqNum},
+	}, o)
+	iter.txn = t
+	return iter, nil
+}
+
+// recordSpan adds the span [lower, upper) to the read set. A nil bound is
+// unbounded.
+func (t *Txn) recordSpan(lower, upper []byte) {
+	var span KeyRange
+	if lower != nil {
+		span.Start = append([]byte(nil), lower...)
+	}
+	if upper != nil {
+		span.End = append([]byte(nil), upper...)
+	}
+	t.readSpans = append(t.readSpans, span)
+}
+
+// Set sets the value for the given key. See Writer.Set.
+func (t *Txn) Set(key, value []byte, opts *WriteOptions) error {
+	if t.done {
+		return errTxnClosed
+	}
+	return t.batch.Set(key, value, opts)
+}
+
+// Delete deletes the value for the given key. See Writer.Delete.
+func (t *Txn) Delete(key []byte, opts *WriteOptions) error {
+	if t.done {
+		return errTxnClosed
+	}
+	return t.batch.Delete(key, opts)
+}
+
+// DeleteRange deletes all of the point keys in the range [start, end). See
+// Writer.DeleteRange.
+func (t *Txn) DeleteRange(start, end []byte, opts *WriteOptions) error {
+	if t.done {
+		return errTxnClosed
+	}
+	return t.batch.DeleteRange(start, end, opts)
+}
+
+// Merge merges the value at key with the new value. See Writer.Merge.
+func (t *Txn) Merge(key, value []byte, opts *WriteOptions) error {
+	if t.done {
+		return errTxnClosed
+	}
+	return t.batch.Merge(key, value, opts)
+}
+
+// Commit validates the transaction's read set and, if no conflicting write
+// was committed since the transaction's snapshot, applies the transaction's
+// writes. On conflict, Commit returns an *ErrTxnConflict and none of the
+// writes are applied. In either case the transaction is finished and its
+// resources are released.
+func (t *Txn) Commit(opts *WriteOptions) error {
+	if t.done {
+		return errTxnClosed
+	}
+	defer func() { _ = t.Close() }()
+	if t.batch.Empty() {
+		// A read-only transaction has nothing to apply, but validating its read
+		// set still tells the caller whether its reads were serializable.
+		t.db.commit.mu.Lock()
+		defer t.db.commit.mu.Unlock()
+		return t.db.txns.validate(t)
+	}
+	t.batch.txn = t
+	return t.db.Apply(t.batch, opts)
+}
+
+// Close finishes the transaction, discarding any writes if it was not
+// committed. Close is a no-op on a finished transaction.
+func (t *Txn) Close() error {
+	if t.done {
+		return nil
+	}
+	t.done = true
+	t.db.commit.mu.Lock()
+	t.db.txns.unregister(t)
+	t.db.commit.mu.Unlock()
+	return errors.CombineErrors(t.snap.Close(), t.batch.Close())
+}
+
+// trackedWrite is a write committed while at least one transaction was open.
+type trackedWrite struct {
+	// start is the written key or the start of the written span.
+	start []byte
+	// end is the exclusive end of the written span, or nil for point writes.
+	end []byte
+	// all is set for writes whose key range is not tracked; they conflict with
+	// every read.
+	all    bool
+	seqNum base.SeqNum
+}
+
+// txnTracker records the writes committed while transactions are open, so
+// that transactions can be validated at commit time. All fields are
+// protected by commitPipeline.mu.
+type txnTracker struct {
+	cmp Compare
+	// open maps each open transaction to its trackFrom sequence number.
+	open map[*Txn]base.SeqNum
+	// writes is ordered by sequence number, because it is only appended to
+	// while assigning sequence numbers.
+	writes []trackedWrite
+}
+
+func (tt *txnTracker) init(cmp Compare) {
+	tt.cmp = cmp
+	tt.open = make(map[*Txn]base.SeqNum)
+}
+
+func (tt *txnTracker) register(t *Txn) {
+	tt.open[t] = t.trackFrom
+}
+
+func (tt *txnTracker) unregister(t *Txn) {
+	delete(tt.open, t)
+	if len(tt.open) == 0 {
+		tt.writes = tt.writes[:0]
+		return
+	}
+	// Discard writes that no open transaction can conflict with.
+	minSeqNum := base.SeqNumMax
+	for _, s := range tt.open {
+		minSeqNum = min(minSeqNum, s)
+	}
+	i := sort.Search(len(tt.writes), func(i int) bool { return tt.writes[i].seqNum >= minSeqNum })
+	tt.writes = append(tt.writes[:0], tt.writes[i:]...)
+}
+
+// trackBatch records the writes of a batch that has been assigned a sequence
+// number. If opaque is true, the batch allocated sequence numbers for an
+// operation whose keys cannot be determined from the batch (ingestion).
+func (tt *txnTracker) trackBatch(b *Batch, opaque bool) {
+	if len(tt.open) == 0 {
+		return
+	}
+	seqNum := b.SeqNum()
+	if opaque {
+		tt.writes = append(tt.writes, trackedWrite{all: true, seqNum: seqNum})
+		return
+	}
+	for r := b.Reader(); ; {
+		kind, ukey, value, ok, err := r.Next()
+		if !ok || err != nil {
+			// The batch was validated before being committed.
+			break
+		}
+		w := trackedWrite{start: append([]byte(nil), ukey...), seqNum: seqNum}
+		switch kind {
+		case InternalKeyKindRangeDelete:
+			w.end = append([]byte(nil), value...)
+		case InternalKeyKindRangeKeySet, InternalKeyKindRangeKeyUnset, InternalKeyKindRangeKeyDelete:
+			end, _, err := rangekey.DecodeEndKey(kind, value)
+			if err != nil {
+				w.all = true
+			}
+			w.end = append([]byte(nil), end...)
+		case InternalKeyKindLogData:
+			continue
+		case InternalKeyKindIngestSST, InternalKeyKindExcise:
+			w.all = true
+		}
+		tt.writes = append(tt.writes, w)
+	}
+}
+
+// validate returns an *ErrTxnConflict if any write committed at or after the
+// transaction's snapshot overlaps the transaction's read set.
+func (tt *txnTracker) validate(t *Txn) error {
+	snapSeqNum := t.snap.seqNum
+	i := sort.Search(len(tt.writes), func(i int) bool { return tt.writes[i].seqNum >= snapSeqNum })
+	for _, w := range tt.writes[i:] {
+		if w.all || tt.overlapsReadSet(t, &w) {
+			return &ErrTxnConflict{Key: w.start, SeqNum: w.seqNum}
+		}
+	}
+	return nil
+}
+
+func (tt *txnTracker) overlapsReadSet(t *Txn, w *trackedWrite) bool {
+	for _, k := range t.readKeys {
+		if w.end == nil {
+			if tt.cmp(k, w.start) == 0 {
+				return true
+			}
+		} else if tt.cmp(w.start, k) <= 0 && tt.cmp(k, w.end) < 0 {
+			return true
+		}
+	}
+	for _, s := range t.readSpans {
+		if s.Start != nil && tt.cmp(w.start, s.Start) < 0 && (w.end == nil || tt.cmp(w.end, s.Start) <= 0) {
+			continue
+		}
+		if s.End != nil && tt.cmp(w.start, s.End) >= 0 {
+			continue
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/chris124567/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxn(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	require.NoError(t, d.Set([]byte("a"), []byte("1"), nil))
+
+	expectGet := func(txn *Txn, key, expected string) {
+		v, closer, err := txn.Get([]byte(key))
+		if expected == "" {
+			require.ErrorIs(t, err, ErrNotFound)
+			return
+		}
+		require.NoError(t, err)
+		require.Equal(t, expected, string(v))
+		require.NoError(t, closer.Close())
+	}
+	expectConflict := func(err error, key string) {
+		var conflict *ErrTxnConflict
+		require.True(t, errors.As(err, &conflict), "expected conflict, got %v", err)
+		require.Equal(t, key, string(conflict.Key))
+	}
+
+	t.Run("read-your-writes", func(t *testing.T) {
+		txn := d.NewTxn()
+		require.NoError(t, txn.Set([]byte("b"), []byte("2"), nil))
+		expectGet(txn, "b", "2")
+		// The write is not visible outside the transaction until commit.
+		_, _, err := d.Get([]byte("b"))
+		require.ErrorIs(t, err, ErrNotFound)
+		require.NoError(t, txn.Commit(nil))
+		require.NoError(t, txn.Close())
+		require.ErrorIs(t, txn.Set([]byte("c"), nil, nil), errTxnClosed)
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		txn := d.NewTxn()
+		defer txn.Close()
+		require.NoError(t, d.Set([]byte("a"), []byte("10"), nil))
+		expectGet(txn, "a", "1")
+	})
+
+	t.Run("point-conflict", func(t *testing.T) {
+		txn := d.NewTxn()
+		expectGet(txn, "a", "10")
+		require.NoError(t, txn.Set([]byte("a"), []byte("11"), nil))
+		require.NoError(t, d.Set([]byte("a"), []byte("12"), nil))
+		expectConflict(txn.Commit(nil), "a")
+		// The transaction's write was not applied.
+		v, closer, err := d.Get([]byte("a"))
+		require.NoError(t, err)
+		require.Equal(t, "12", string(v))
+		require.NoError(t, closer.Close())
+	})
+
+	t.Run("blind-write", func(t *testing.T) {
+		txn := d.NewTxn()
+		require.NoError(t, txn.Set([]byte("a"), []byte("13"), nil))
+		require.NoError(t, d.Set([]byte("a"), []byte("14"), nil))
+		require.NoError(t, txn.Commit(nil))
+	})
+
+	t.Run("span-conflict", func(t *testing.T) {
+		txn := d.NewTxn()
+		iter, err := txn.NewIter(&IterOptions{LowerBound: []byte("m"), UpperBound: []byte("p")})
+		require.NoError(t, err)
+		require.False(t, iter.First())
+		require.NoError(t, iter.Close())
+		require.NoError(t, txn.Set([]byte("x"), []byte("1"), nil))
+		// A write outside the scanned span does not conflict, but a write into
+		// it does.
+		require.NoError(t, d.Set([]byte("p"), []byte("1"), nil))
+		require.NoError(t, d.Set([]byte("n"), []byte("1"), nil))
+		expectConflict(txn.Commit(nil), "n")
+	})
+
+	t.Run("rebound-conflict", func(t *testing.T) {
+		for _, rebound := range []func(*Iterator) (*Iterator, error){
+			func(iter *Iterator) (*Iterator, error) {
+				iter.SetBounds([]byte("r"), []byte("t"))
+				return iter, nil
+			},
+			func(iter *Iterator) (*Iterator, error) {
+				iter.SetOptions(&IterOptions{LowerBound: []byte("r"), UpperBound: []byte("t")})
+				return iter, nil
+			},
+			func(iter *Iterator) (*Iterator, error) {
+				defer iter.Close()
+				return iter.Clone(CloneOptions{
+					IterOptions: &IterOptions{LowerBound: []byte("r"), UpperBound: []byte("t")},
+				})
+			},
+		} {
+			txn := d.NewTxn()
+			iter, err := txn.NewIter(&IterOptions{LowerBound: []byte("m"), UpperBound: []byte("n")})
+			require.NoError(t, err)
+			iter, err = rebound(iter)
+			require.NoError(t, err)
+			require.False(t, iter.First())
+			require.NoError(t, iter.Close())
+			require.NoError(t, txn.Set([]byte("x"), []byte("1"), nil))
+			// The write falls outside the iterator's original bounds, but into
+			// the span it was re-bounded to.
+			require.NoError(t, d.Set([]byte("s"), []byte("1"), nil))
+			expectConflict(txn.Commit(nil), "s")
+			require.NoError(t, d.Delete([]byte("s"), nil))
+		}
+	})
+
+	t.Run("range-delete-conflict", func(t *testing.T) {
+		txn := d.NewTxn()
+		expectGet(txn, "b", "2")
+		require.NoError(t, txn.Set([]byte("y"), []byte("1"), nil))
+		require.NoError(t, d.DeleteRange([]byte("a"), []byte("c"), nil))
+		expectConflict(txn.Commit(nil), "a")
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		txn := d.NewTxn()
+		expectGet(txn, "q", "")
+		require.NoError(t, txn.Commit(nil))
+		txn = d.NewTxn()
+		expectGet(txn, "q", "")
+		require.NoError(t, d.Set([]byte("q"), []byte("1"), nil))
+		expectConflict(txn.Commit(nil), "q")
+	})
+}
+
+// TestTxnConcurrentIncrements runs concurrent read-modify-write transactions
+// incrementing a counter, retrying on conflict, and verifies that no
+// increment is lost.
+func TestTxnConcurrentIncrements(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	const workers, increments = 4, 50
+	key := []byte("counter")
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < increments; {
+				txn := d.NewTxn()
+				n := 0
+				v, closer, err := txn.Get(key)
+				if err == nil {
+					n, err = strconv.Atoi(string(v))
+					require.NoError(t, err)
+					require.NoError(t, closer.Close())
+				} else {
+					require.ErrorIs(t, err, ErrNotFound)
+				}
+				require.NoError(t, txn.Set(key, []byte(fmt.Sprint(n+1)), nil))
+				err = txn.Commit(nil)
+				var conflict *ErrTxnConflict
+				if errors.As(err, &conflict) {
+					continue
+				}
+				require.NoError(t, err)
+				i++
+			}
+		}()
+	}
+	wg.Wait()
+
+	v, closer, err := d.Get(key)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprint(workers*increments), string(v))
+	require.NoError(t, closer.Close())
+	require.Empty(t, d.txns.writes)
+}
@@ -49,6 +49,10 @@ func (d *DB) determineCompactionValueSeparation(
 		}
 	}
 
+	var keepInPlace func([]byte) bool
+	if d.opts.TTL != nil {
+		keepInPlace = ttlValueHasExpiry
+	}
 	// This compaction should write values to new blob files.
 	return &writeNewBlobFiles{
 		comparer: d.opts.Comparer,
@@ -58,6 +62,7 @@ func (d *DB) determineCompactionValueSeparation(
 		shortAttrExtractor: d.opts.Experimental.ShortAttributeExtractor,
 		writerOpts:         d.opts.MakeBlobWriterOptions(c.outputLevel.level),
 		minimumSize:        policy.MinimumSize,
+		keepInPlace:        keepInPlace,
 	}
 }
 
@@ -73,6 +78,12 @@ func shouldWriteBlobFiles(
 	if c.kind == compactionKindFlush {
 		return true, 0
 	}
+	// Rewrite compactions rewrite their inputs in place, for example to
+	// re-encrypt them with a new key, and should rewrite the inputs' values
+	// too.
+	if c.kind == compactionKindRewrite {
+		return true, 0
+	}
 	inputReferenceDepth := compactionBlobReferenceDepth(c.inputs)
 	if inputReferenceDepth == 0 {
 		// None of the input sstables reference blob files. It may be the case
@@ -164,6 +175,10 @@ type writeNewBlobFiles struct {
 	// to the sstable (but may still be written to a value block within the
 	// sstable).
 	minimumSize int
+	// keepInPlace, if set, is called with values that would otherwise be
+	// separated and returns true for values that must be written to the
+	// sstable instead.
+	keepInPlace func(value []byte) bool
 
 	// Current blob writer state
 	writer  *blob.FileWriter
@@ -210,7 +225,7 @@ func (vs *writeNewBlobFiles) Add(
 	}
 
 	// Values that are too small are never separated.
-	if len(v) < vs.minimumSize {
+	if len(v) < vs.minimumSize || (vs.keepInPlace != nil && vs.keepInPlace(v)) {
 		return tw.Add(kv.K, v, forceObsolete)
 	}
 	// Merge and deletesized keys are never separated.
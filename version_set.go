@@ -12,6 +12,7 @@ import (
 
 	"github.com/cockroachdb/errors"
 	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/internal/encryption"
 	"github.com/chris124567/pebble/internal/invariants"
 	"github.com/chris124567/pebble/internal/manifest"
 	"github.com/chris124567/pebble/objstorage"
@@ -181,8 +182,7 @@ func (vs *versionSet) create(
 	vs.append(emptyVersion)
 	vs.blobFiles.Init(nil)
 
-	vs.setCompactionPicker(
-		newCompactionPickerByScore(emptyVersion, vs.l0Organizer, &vs.virtualBackings, vs.opts, nil))
+	vs.setCompactionPicker(vs.newCompactionPicker(emptyVersion, nil))
 	// Note that a "snapshot" version edit is written to the manifest when it is
 	// created.
 	vs.manifestFileNum = vs.getNextDiskFileNum()
@@ -241,7 +241,12 @@ func (vs *versionSet) load(
 			errors.Safe(manifestFilename), dirname)
 	}
 	defer manifestFile.Close()
-	rr := record.NewReader(manifestFile, 0 /* logNum */)
+	manifestReader, err := encryption.OpenFile(manifestFile, opts.keyProvider())
+	if err != nil {
+		return errors.Wrapf(err, "pebble: could not open manifest file %q for DB %q",
+			errors.Safe(manifestFilename), dirname)
+	}
+	rr := record.NewReader(manifestReader, 0 /* logNum */)
 	for {
 		r, err := rr.Next()
 		if err == io.EOF || record.IsInvalidRecord(err) {
@@ -374,8 +379,7 @@ func (vs *versionSet) load(
 		}
 	})
 
-	vs.setCompactionPicker(
-		newCompactionPickerByScore(newVersion, vs.l0Organizer, &vs.virtualBackings, vs.opts, nil))
+	vs.setCompactionPicker(vs.newCompactionPicker(newVersion, nil))
 	return nil
 }
 
@@ -777,15 +781,35 @@ func (vs *versionSet) UpdateVersionLocked(updateFn func() (versionUpdate, error)
 	vs.metrics.BlobFiles.Local.LiveSize = uint64(int64(vs.metrics.BlobFiles.Local.LiveSize) + localBlobLiveDelta.size)
 	vs.metrics.BlobFiles.Local.LiveCount = uint64(int64(vs.metrics.BlobFiles.Local.LiveCount) + localBlobLiveDelta.count)
 
-	vs.setCompactionPicker(
-		newCompactionPickerByScore(newVersion, vs.l0Organizer, &vs.virtualBackings, vs.opts, inProgress))
+	vs.setCompactionPicker(vs.newCompactionPicker(newVersion, inProgress))
 	if !vs.dynamicBaseLevel {
 		vs.picker.forceBaseLevel1()
 	}
 	return nil
 }
 
-func (vs *versionSet) setCompactionPicker(picker *compactionPickerByScore) {
+// newCompactionPicker creates the compaction picker of the configured
+// compaction style for the given version, wrapped by the configured
+// CompactionPolicy if any.
+func (vs *versionSet) newCompactionPicker(
+	v *version, inProgress []compactionInfo,
+) compactionPicker {
+	var picker compactionPicker
+	switch vs.opts.Experimental.CompactionStyle {
+	case CompactionStyleUniversal:
+		picker = newCompactionPickerUniversal(v, vs.l0Organizer, &vs.virtualBackings, vs.opts, inProgress)
+	case CompactionStyleFIFO:
+		picker = newCompactionPickerFIFO(v, vs.l0Organizer, &vs.virtualBackings, vs.opts, inProgress)
+	default:
+		picker = newCompactionPickerByScore(v, vs.l0Organizer, &vs.virtualBackings, vs.opts, inProgress)
+	}
+	if vs.opts.Experimental.CompactionPolicy != nil {
+		picker = newCompactionPickerPolicy(picker, v, vs.l0Organizer, vs.opts)
+	}
+	return picker
+}
+
+func (vs *versionSet) setCompactionPicker(picker compactionPicker) {
 	vs.picker = picker
 	vs.curCompactionConcurrency.Store(int32(picker.getCompactionConcurrency()))
 }
@@ -982,12 +1006,27 @@ func (vs *versionSet) incrementCompactions(
 	case compactionKindTombstoneDensity:
 		vs.metrics.Compact.TombstoneDensityCount++
 
+	case compactionKindExpired:
+		vs.metrics.Compact.ExpiredCount++
+
 	case compactionKindRewrite:
 		vs.metrics.Compact.RewriteCount++
 
 	case compactionKindCopy:
 		vs.metrics.Compact.CopyCount++
 
+	case compactionKindTiering:
+		vs.metrics.Compact.TieringCount++
+
+	case compactionKindUniversal:
+		vs.metrics.Compact.UniversalCount++
+
+	case compactionKindFIFO:
+		vs.metrics.Compact.FIFOCount++
+
+	case compactionKindPolicy:
+		vs.metrics.Compact.PolicyCount++
+
 	default:
 		if invariants.Enabled {
 			panic("unhandled compaction kind")
@@ -1029,6 +1068,9 @@ func (vs *versionSet) createManifest(
 	if err != nil {
 		return err
 	}
+	if manifestFile, err = createEncryptedFile(manifestFile, vs.opts.keyProvider()); err != nil {
+		return err
+	}
 	manifestWriter = record.NewWriter(manifestFile)
 
 	snapshot := manifest.VersionEdit{
@@ -1104,6 +1146,15 @@ func (vs *versionSet) getNextDiskFileNum() base.DiskFileNum {
 	return base.DiskFileNum(x)
 }
 
+// reserveDiskFileNums reserves n consecutive file numbers and returns the
+// first one.
+//
+// Can be called without the versionSet's mutex being held.
+func (vs *versionSet) reserveDiskFileNums(n uint64) base.DiskFileNum {
+	x := vs.nextFileNum.Add(n) - n
+	return base.DiskFileNum(x)
+}
+
 func (vs *versionSet) append(v *version) {
 	if v.Refs() != 0 {
 		panic("pebble: version should be unreferenced")
@@ -0,0 +1,733 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+// Package encryptedfs provides a vfs.FS that encrypts the contents of the
+// files of an underlying vfs.FS.
+//
+// Every file begins with a plaintext header holding a random salt, from which
+// the file's keys are derived from the FS's key. The contents of the file
+// follow the header in fixed-size chunks, each encrypted independently with
+// AES-256-CTR under a random IV and authenticated with HMAC-SHA256 along with
+// its index and length, so that any range of the file can be read by
+// decrypting only the chunks overlapping it.
+//
+// A chunk has two trailers, each recording a length of the chunk's contents
+// and their MAC. Data appended to a chunk is encrypted under the chunk's IV and
+// written past its existing contents, and the new length and MAC are written
+// to the trailer that does not hold a version of the chunk that may have been
+// synced. Appending to a file, as the WAL and MANIFEST are written, therefore
+// never overwrites synced bytes, and a torn write during a crash cannot make
+// previously synced contents unreadable. A write that overwrites existing
+// contents re-encrypts the entire chunk under a new IV, in place.
+//
+// Encryption hides and authenticates the contents of individual chunks, but
+// not the names or sizes of files, and it cannot detect the truncation of a
+// file at a chunk boundary or the replacement of a chunk by an older version
+// of itself.
+package encryptedfs
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"math/bits"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/chris124567/pebble/vfs"
+)
+
+// KeySize is the size of the key of an encrypted FS.
+const KeySize = 32
+
+// DefaultChunkSize is the default size of the plaintext of a chunk.
+const DefaultChunkSize = 4096
+
+// Options configures an encrypted FS.
+type Options struct {
+	// Key is the key from which the keys of individual files are derived. It
+	// must be KeySize bytes long.
+	Key []byte
+	// ChunkSize is the size of the plaintext of the chunks of new files. The
+	// chunk size of existing files is recorded in their headers, but FS.Stat
+	// computes the size of a file from ChunkSize without reading its header,
+	// so it must not change across uses of the same files for FS.Stat to
+	// report the exact size of their plaintext. Defaults to DefaultChunkSize.
+	ChunkSize int
+}
+
+// New returns an FS that encrypts the contents of the files of fs. Every file
+// opened through the returned FS must have been created through an encrypted
+// FS with the same key. Directories and lock files are not encrypted.
+func New(fs vfs.FS, opts Options) (vfs.FS, error) {
+	if len(opts.Key) != KeySize {
+		return nil, errors.Newf("encryptedfs: key must be %d bytes, not %d", KeySize, len(opts.Key))
+	}
+	if opts.ChunkSize == 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+	if opts.ChunkSize < 0 || opts.ChunkSize > maxChunkSize {
+		return nil, errors.Newf("encryptedfs: invalid chunk size %d", opts.ChunkSize)
+	}
+	return &FS{
+		FS:        fs,
+		key:       bytes.Clone(opts.Key),
+		chunkSize: opts.ChunkSize,
+	}, nil
+}
+
+// FS is an encrypted vfs.FS. See New.
+type FS struct {
+	vfs.FS
+	key       []byte
+	chunkSize int
+}
+
+var _ vfs.FS = (*FS)(nil)
+
+// The header of a file is the headerMagic, a version byte, three reserved
+// bytes, the 4-byte little-endian chunk size and the salt.
+var headerMagic = [8]byte{0xf0, 'p', 'e', 'b', 'b', 'l', 'e', 0xfe}
+
+const (
+	headerVersion = 2
+	saltLen       = 16
+	headerLen     = len(headerMagic) + 4 + 4 + saltLen
+
+	// Each chunk is its IV, followed by its two trailers and its ciphertext. A
+	// trailer is the 4-byte little-endian length of the chunk's contents,
+	// followed by their MAC.
+	ivLen         = aes.BlockSize
+	macLen        = 16
+	trailerLen    = 4 + macLen
+	chunkOverhead = ivLen + 2*trailerLen
+
+	maxChunkSize = 1 << 20
+)
+
+// Create implements vfs.FS.
+func (fs *FS) Create(name string, category vfs.DiskWriteCategory) (vfs.File, error) {
+	f, err := fs.FS.Create(name, category)
+	if err != nil {
+		return nil, err
+	}
+	return fs.initFile(f)
+}
+
+// Open implements vfs.FS. The header of the file is only read once its
+// contents or size are needed, and a file without a valid header is reported
+// by the operations that need it.
+func (fs *FS) Open(name string, opts ...vfs.OpenOption) (vfs.File, error) {
+	f, err := fs.FS.Open(name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &file{File: f, lazy: &lazyHeader{fs: fs, name: name}}, nil
+}
+
+// OpenReadWrite implements vfs.FS.
+func (fs *FS) OpenReadWrite(
+	name string, category vfs.DiskWriteCategory, opts ...vfs.OpenOption,
+) (vfs.File, error) {
+	f, err := fs.FS.OpenReadWrite(name, category, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return fs.openOrInitFile(name, f)
+}
+
+// ReuseForWrite implements vfs.FS. Overwriting a reused file would require
+// reading back the chunks it partially overwrites, which the handle returned by
+// the underlying FS may not support, so oldname is removed and newname created
+// instead.
+func (fs *FS) ReuseForWrite(
+	oldname, newname string, category vfs.DiskWriteCategory,
+) (vfs.File, error) {
+	if err := fs.FS.Remove(oldname); err != nil {
+		return nil, err
+	}
+	return fs.Create(newname, category)
+}
+
+// Stat implements vfs.FS. The size of a file is the size of its plaintext,
+// computed from the configured chunk size without reading the file's header
+// (see Options.ChunkSize). The size of a file without a header, such as a lock
+// file, is meaningless, but Stat does not fail.
+func (fs *FS) Stat(name string) (vfs.FileInfo, error) {
+	fi, err := fs.FS.Stat(name)
+	if err != nil || fi.IsDir() {
+		return fi, err
+	}
+	return fileInfo{FileInfo: fi, chunkSize: fs.chunkSize}, nil
+}
+
+// Unwrap implements vfs.FS.
+func (fs *FS) Unwrap() vfs.FS {
+	return fs.FS
+}
+
+// header is the decoded header of a file.
+type header struct {
+	chunkSize int
+	salt      [saltLen]byte
+}
+
+func (h *header) encode() []byte {
+	buf := make([]byte, headerLen)
+	copy(buf, headerMagic[:])
+	buf[len(headerMagic)] = headerVersion
+	binary.LittleEndian.PutUint32(buf[len(headerMagic)+4:], uint32(h.chunkSize))
+	copy(buf[len(headerMagic)+8:], h.salt[:])
+	return buf
+}
+
+// readHeader reads the header of f. A file shorter than a header has a zero
+// chunk size: nothing can have been durably written to a file before its
+// header.
+func readHeader(f vfs.File) (header, error) {
+	var buf [headerLen]byte
+	n, err := f.ReadAt(buf[:], 0)
+	if n < headerLen {
+		if err != nil && err != io.EOF {
+			return header{}, err
+		}
+		return header{}, nil
+	}
+	if !bytes.Equal(buf[:len(headerMagic)], headerMagic[:]) {
+		return header{}, errors.New("file is not encrypted")
+	}
+	if v := buf[len(headerMagic)]; v != headerVersion {
+		return header{}, errors.Newf("unsupported header version %d", v)
+	}
+	h := header{chunkSize: int(binary.LittleEndian.Uint32(buf[len(headerMagic)+4:]))}
+	if h.chunkSize <= 0 || h.chunkSize > maxChunkSize {
+		return header{}, errors.Newf("invalid chunk size %d", h.chunkSize)
+	}
+	copy(h.salt[:], buf[len(headerMagic)+8:])
+	return h, nil
+}
+
+// initFile writes a new header to f.
+func (fs *FS) initFile(f vfs.File) (vfs.File, error) {
+	h := header{chunkSize: fs.chunkSize}
+	_, err := rand.Read(h.salt[:])
+	var ef *file
+	if err == nil {
+		ef, err = fs.newFile(f, h)
+	}
+	if err == nil {
+		_, err = f.WriteAt(h.encode(), 0)
+	}
+	if err != nil {
+		return nil, errors.CombineErrors(err, f.Close())
+	}
+	return ef, nil
+}
+
+// openOrInitFile reads the header of f, writing a new one if f has none.
+func (fs *FS) openOrInitFile(name string, f vfs.File) (vfs.File, error) {
+	h, err := readHeader(f)
+	if err != nil {
+		return nil, errors.CombineErrors(errors.Wrapf(err, "encryptedfs: %s", name), f.Close())
+	}
+	if h.chunkSize == 0 {
+		return fs.initFile(f)
+	}
+	ef, err := fs.newFile(f, h)
+	if err != nil {
+		return nil, errors.CombineErrors(err, f.Close())
+	}
+	return ef, nil
+}
+
+// newFile returns a file encrypting its chunks with the keys derived from the
+// salt of h.
+func (fs *FS) newFile(f vfs.File, h header) (*file, error) {
+	keys, err := fs.fileKeys(h)
+	if err != nil {
+		return nil, err
+	}
+	return &file{File: f, keys: keys, chunkSize: h.chunkSize}, nil
+}
+
+// fileKeys are the keys encrypting and authenticating the chunks of a file.
+type fileKeys struct {
+	block  cipher.Block
+	macKey []byte
+}
+
+// fileKeys derives the keys of a file from the salt of h.
+func (fs *FS) fileKeys(h header) (fileKeys, error) {
+	derive := func(label string) []byte {
+		mac := hmac.New(sha256.New, fs.key)
+		mac.Write([]byte(label))
+		mac.Write(h.salt[:])
+		return mac.Sum(nil)
+	}
+	block, err := aes.NewCipher(derive("pebble encryptedfs file key"))
+	if err != nil {
+		return fileKeys{}, err
+	}
+	return fileKeys{block: block, macKey: derive("pebble encryptedfs file MAC key")}, nil
+}
+
+// file is an encrypted vfs.File. Offsets passed to its methods are offsets of
+// the plaintext.
+type file struct {
+	vfs.File
+	keys      fileKeys
+	chunkSize int
+	// empty is set for a file without a header.
+	empty bool
+	// lazy is set for a file opened by FS.Open, whose header is read by
+	// load the first time it is needed.
+	lazy *lazyHeader
+
+	mu struct {
+		sync.Mutex
+		readOff  int64
+		writeOff int64
+		// syncs counts the syncs of the file, including those in progress.
+		syncs uint64
+		// tail caches the last partial chunk written through the file. It is
+		// unset if its ciphertext is nil.
+		tail chunk
+	}
+}
+
+var _ vfs.File = (*file)(nil)
+
+// lazyHeader is the state of a file whose header is read on first use.
+type lazyHeader struct {
+	fs   *FS
+	name string
+	once sync.Once
+	err  error
+}
+
+// load reads the header of a file opened by FS.Open, if it hasn't been read
+// yet. A file with no header, which may only be read, is empty.
+func (f *file) load() error {
+	if f.lazy == nil {
+		return nil
+	}
+	f.lazy.once.Do(func() {
+		h, err := readHeader(f.File)
+		if err == nil && h.chunkSize == 0 {
+			f.empty = true
+			return
+		}
+		if err == nil {
+			f.keys, err = f.lazy.fs.fileKeys(h)
+			f.chunkSize = h.chunkSize
+		}
+		if err != nil {
+			f.lazy.err = errors.Wrapf(err, "encryptedfs: %s", f.lazy.name)
+		}
+	})
+	return f.lazy.err
+}
+
+// physicalChunkSize returns the size of a full chunk in the underlying file.
+func (f *file) physicalChunkSize() int64 {
+	return int64(f.chunkSize + chunkOverhead)
+}
+
+// chunkOffset returns the offset of the chunk with the given index in the
+// underlying file.
+func (f *file) chunkOffset(idx int64) int64 {
+	return int64(headerLen) + idx*f.physicalChunkSize()
+}
+
+// physicalSize returns the size of the underlying file holding size bytes of
+// plaintext.
+func (f *file) physicalSize(size int64) int64 {
+	return physicalSize(size, f.chunkSize)
+}
+
+func physicalSize(size int64, chunkSize int) int64 {
+	n := int64(headerLen) + size/int64(chunkSize)*int64(chunkSize+chunkOverhead)
+	if rem := size % int64(chunkSize); rem > 0 {
+		n += rem + chunkOverhead
+	}
+	return n
+}
+
+// logicalSize returns the size of the plaintext held by an underlying file of
+// the given size.
+func logicalSize(size int64, chunkSize int) int64 {
+	if chunkSize == 0 || size <= int64(headerLen) {
+		return 0
+	}
+	size -= int64(headerLen)
+	physicalChunkSize := int64(chunkSize + chunkOverhead)
+	n := size / physicalChunkSize * int64(chunkSize)
+	if rem := size % physicalChunkSize; rem > chunkOverhead {
+		n += rem - chunkOverhead
+	}
+	return n
+}
+
+// chunk is the state of a chunk of a file.
+type chunk struct {
+	idx        int64
+	iv         [ivLen]byte
+	ciphertext []byte
+	// trailer is the index of the trailer recording the current length of the
+	// chunk. It was written after the file had been synced syncs times.
+	trailer int
+	syncs   uint64
+	// torn is set if the underlying file holds bytes of the chunk past its
+	// current length, left by a torn write.
+	torn bool
+}
+
+// xorKeyStreamAt encrypts or decrypts src into dst with the key stream of the
+// chunk's IV, starting at offset off of the chunk's contents.
+func (f *file) xorKeyStreamAt(c *chunk, dst, src []byte, off int) {
+	if len(src) == 0 {
+		return
+	}
+	// The counter of the block containing off is the IV plus the block's
+	// index, as a 128-bit big-endian integer.
+	var ctr [ivLen]byte
+	hi := binary.BigEndian.Uint64(c.iv[:8])
+	lo, carry := bits.Add64(binary.BigEndian.Uint64(c.iv[8:]), uint64(off/aes.BlockSize), 0)
+	binary.BigEndian.PutUint64(ctr[:8], hi+carry)
+	binary.BigEndian.PutUint64(ctr[8:], lo)
+	stream := cipher.NewCTR(f.keys.block, ctr[:])
+	if skip := off % aes.BlockSize; skip != 0 {
+		var pad [aes.BlockSize]byte
+		stream.XORKeyStream(pad[:skip], pad[:skip])
+	}
+	stream.XORKeyStream(dst, src)
+}
+
+// mac returns the MAC of the given ciphertext of the chunk with the given
+// index and IV.
+func (f *file) mac(idx int64, iv *[ivLen]byte, ciphertext []byte) []byte {
+	var buf [12]byte
+	binary.LittleEndian.PutUint64(buf[:8], uint64(idx))
+	binary.LittleEndian.PutUint32(buf[8:], uint32(len(ciphertext)))
+	mac := hmac.New(sha256.New, f.keys.macKey)
+	mac.Write(buf[:])
+	mac.Write(iv[:])
+	mac.Write(ciphertext)
+	return mac.Sum(nil)[:macLen]
+}
+
+// appendTrailer appends the trailer recording the current length of c to dst.
+func (f *file) appendTrailer(dst []byte, c *chunk) []byte {
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(len(c.ciphertext)))
+	return append(dst, f.mac(c.idx, &c.iv, c.ciphertext)...)
+}
+
+// decodeChunk decodes the chunk with the given index from its bytes in the
+// underlying file. Its length is the longest of the lengths recorded by its
+// trailers whose MAC is intact. The chunk's ciphertext aliases buf.
+func (f *file) decodeChunk(buf []byte, idx int64) (chunk, error) {
+	if len(buf) <= chunkOverhead {
+		return chunk{}, errors.Newf("encryptedfs: chunk %d is truncated", idx)
+	}
+	c := chunk{idx: idx}
+	copy(c.iv[:], buf)
+	contents := buf[chunkOverhead:]
+	trailer := func(i int) []byte { return buf[ivLen+i*trailerLen : ivLen+(i+1)*trailerLen] }
+	first := 0
+	if binary.LittleEndian.Uint32(trailer(1)) > binary.LittleEndian.Uint32(trailer(0)) {
+		first = 1
+	}
+	for _, i := range [2]int{first, 1 - first} {
+		t := trailer(i)
+		n := int64(binary.LittleEndian.Uint32(t))
+		if n == 0 || n > int64(len(contents)) || n > int64(f.chunkSize) {
+			continue
+		}
+		if !hmac.Equal(t[4:], f.mac(idx, &c.iv, contents[:n])) {
+			continue
+		}
+		c.ciphertext = contents[:n]
+		c.trailer = i
+		c.torn = int(n) < len(contents)
+		return c, nil
+	}
+	return chunk{}, errors.Newf("encryptedfs: chunk %d failed authentication", idx)
+}
+
+// Read implements io.Reader.
+func (f *file) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, err := f.readAt(p, f.mu.readOff)
+	f.mu.readOff += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadAt implements io.ReaderAt.
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	return f.readAt(p, off)
+}
+
+func (f *file) readAt(p []byte, off int64) (int, error) {
+	if err := f.load(); err != nil {
+		return 0, err
+	}
+	if f.empty {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	first := off / int64(f.chunkSize)
+	last := (off + int64(len(p)) - 1) / int64(f.chunkSize)
+	buf := make([]byte, (last-first+1)*f.physicalChunkSize())
+	n, err := f.File.ReadAt(buf, f.chunkOffset(first))
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	buf = buf[:n]
+	var copied int
+	for idx := first; idx <= last && len(buf) > 0; idx++ {
+		raw := buf[:min(int64(len(buf)), f.physicalChunkSize())]
+		buf = buf[len(raw):]
+		c, err := f.decodeChunk(raw, idx)
+		if err != nil {
+			return copied, err
+		}
+		start := 0
+		if idx == first {
+			start = int(off % int64(f.chunkSize))
+		}
+		if start >= len(c.ciphertext) {
+			break
+		}
+		n := copy(p[copied:], c.ciphertext[start:])
+		f.xorKeyStreamAt(&c, p[copied:copied+n], p[copied:copied+n], start)
+		copied += n
+		if len(c.ciphertext) < f.chunkSize {
+			break
+		}
+	}
+	if copied < len(p) {
+		return copied, io.EOF
+	}
+	return copied, nil
+}
+
+// Write implements io.Writer.
+func (f *file) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, err := f.writeAtLocked(p, f.mu.writeOff)
+	f.mu.writeOff += int64(n)
+	return n, err
+}
+
+// WriteAt implements io.WriterAt.
+func (f *file) WriteAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.writeAtLocked(p, off)
+}
+
+// writeAtLocked encrypts the written range chunk by chunk. Data appended to a
+// chunk is encrypted under the chunk's IV and written past its existing
+// contents, along with one of its trailers; see the package documentation.
+// Other chunks overlapping the written range are encrypted under new IVs and
+// written in their entirety, after reading back the chunks that are only
+// partially overwritten. Since only the first chunk may be appended to, the
+// chunks' bytes are written at once, after the trailer if any.
+func (f *file) writeAtLocked(p []byte, off int64) (int, error) {
+	if err := f.load(); err != nil {
+		return 0, err
+	}
+	if f.empty {
+		return 0, errors.New("encryptedfs: file was not opened for writing")
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	first := off / int64(f.chunkSize)
+	var trailer []byte
+	var trailerOff int64
+	data := make([]byte, 0, (int64(len(p))/int64(f.chunkSize)+2)*f.physicalChunkSize())
+	dataOff := int64(-1)
+	var written int
+	for idx := first; written < len(p); idx++ {
+		start := 0
+		if idx == first {
+			start = int(off % int64(f.chunkSize))
+		}
+		n := min(len(p)-written, f.chunkSize-start)
+		src := p[written : written+n]
+		written += n
+		c, err := f.readChunkLocked(idx)
+		if err != nil {
+			f.mu.tail = chunk{}
+			return 0, err
+		}
+		// The key stream of a chunk past its length may have encrypted the
+		// bytes of a torn write, so a torn chunk is not appended to.
+		if oldLen := len(c.ciphertext); oldLen > 0 && start >= oldLen && !c.torn {
+			// Append to the chunk. Its current trailer is left intact if it
+			// may have been synced.
+			c.ciphertext = append(c.ciphertext, make([]byte, start+n-oldLen)...)
+			copy(c.ciphertext[start:], src)
+			f.xorKeyStreamAt(&c, c.ciphertext[oldLen:], c.ciphertext[oldLen:], oldLen)
+			if c.syncs != f.mu.syncs {
+				c.trailer = 1 - c.trailer
+			}
+			trailerOff = f.chunkOffset(idx) + int64(ivLen+c.trailer*trailerLen)
+			trailer = f.appendTrailer(nil, &c)
+			dataOff = f.chunkOffset(idx) + int64(chunkOverhead+oldLen)
+			data = append(data, c.ciphertext[oldLen:]...)
+		} else {
+			// Encrypt the entire chunk under a new IV.
+			plaintext := make([]byte, max(len(c.ciphertext), start+n))
+			f.xorKeyStreamAt(&c, plaintext, c.ciphertext, 0)
+			copy(plaintext[start:], src)
+			if _, err := rand.Read(c.iv[:]); err != nil {
+				f.mu.tail = chunk{}
+				return 0, err
+			}
+			c.ciphertext = plaintext
+			f.xorKeyStreamAt(&c, c.ciphertext, c.ciphertext, 0)
+			c.trailer, c.torn = 0, false
+			if dataOff < 0 {
+				dataOff = f.chunkOffset(idx)
+			}
+			data = append(data, c.iv[:]...)
+			data = f.appendTrailer(data, &c)
+			data = append(data, make([]byte, trailerLen)...)
+			data = append(data, c.ciphertext...)
+		}
+		c.syncs = f.mu.syncs
+		f.mu.tail = chunk{}
+		if len(c.ciphertext) < f.chunkSize {
+			f.mu.tail = c
+		}
+	}
+	if trailer != nil {
+		if _, err := f.File.WriteAt(trailer, trailerOff); err != nil {
+			f.mu.tail = chunk{}
+			return 0, err
+		}
+	}
+	if _, err := f.File.WriteAt(data, dataOff); err != nil {
+		f.mu.tail = chunk{}
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// readChunkLocked returns the chunk with the given index, which has no
+// ciphertext if the chunk does not exist. The returned chunk's ciphertext may
+// be appended to.
+func (f *file) readChunkLocked(idx int64) (chunk, error) {
+	if f.mu.tail.ciphertext != nil && f.mu.tail.idx == idx {
+		return f.mu.tail, nil
+	}
+	buf := make([]byte, f.physicalChunkSize())
+	n, err := f.File.ReadAt(buf, f.chunkOffset(idx))
+	if err != nil && err != io.EOF {
+		return chunk{}, err
+	}
+	if n == 0 {
+		return chunk{idx: idx}, nil
+	}
+	c, err := f.decodeChunk(buf[:n], idx)
+	if err != nil {
+		return chunk{}, err
+	}
+	c.ciphertext = bytes.Clone(c.ciphertext)
+	// A chunk read from the underlying file may have been synced.
+	c.syncs = f.mu.syncs - 1
+	return c, nil
+}
+
+// Preallocate implements vfs.File.
+func (f *file) Preallocate(offset, length int64) error {
+	if err := f.load(); err != nil {
+		return err
+	}
+	if f.empty {
+		return nil
+	}
+	start := f.chunkOffset(offset / int64(f.chunkSize))
+	return f.File.Preallocate(start, f.physicalSize(offset+length)-start)
+}
+
+// Stat implements vfs.File. The size of the file is the size of its plaintext.
+func (f *file) Stat() (vfs.FileInfo, error) {
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+	fi, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return fileInfo{FileInfo: fi, chunkSize: f.chunkSize}, nil
+}
+
+// noteSync records a sync of the file before it starts: appends made after it
+// leave the trailers of the chunks they append to intact.
+func (f *file) noteSync() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mu.syncs++
+}
+
+// Sync implements vfs.File.
+func (f *file) Sync() error {
+	f.noteSync()
+	return f.File.Sync()
+}
+
+// SyncData implements vfs.File.
+func (f *file) SyncData() error {
+	f.noteSync()
+	return f.File.SyncData()
+}
+
+// SyncTo implements vfs.File.
+func (f *file) SyncTo(length int64) (fullSync bool, err error) {
+	if err := f.load(); err != nil {
+		return false, err
+	}
+	f.noteSync()
+	if f.empty {
+		return f.File.SyncTo(length)
+	}
+	return f.File.SyncTo(f.physicalSize(length))
+}
+
+// Prefetch implements vfs.File.
+func (f *file) Prefetch(offset, length int64) error {
+	if err := f.load(); err != nil {
+		return err
+	}
+	if f.empty {
+		return nil
+	}
+	start := f.chunkOffset(offset / int64(f.chunkSize))
+	return f.File.Prefetch(start, f.physicalSize(offset+length)-start)
+}
+
+type fileInfo struct {
+	vfs.FileInfo
+	chunkSize int
+}
+
+// Size implements os.FileInfo.
+func (fi fileInfo) Size() int64 {
+	return logicalSize(fi.FileInfo.Size(), fi.chunkSize)
+}
@@ -0,0 +1,308 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package encryptedfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/chris124567/pebble"
+	"github.com/chris124567/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func TestFile(t *testing.T) {
+	for _, chunkSize := range []int{1, 7, 64, DefaultChunkSize} {
+		t.Run(fmt.Sprint(chunkSize), func(t *testing.T) {
+			mem := vfs.NewMem()
+			fs, err := New(mem, Options{Key: testKey(1), ChunkSize: chunkSize})
+			require.NoError(t, err)
+
+			// Apply random appends and overwrites to a file, checking its
+			// contents against a model.
+			f, err := fs.Create("file", vfs.WriteCategoryUnspecified)
+			require.NoError(t, err)
+			var model []byte
+			var writeOff int
+			writeModel := func(p []byte, off int) {
+				if len(model) < off+len(p) {
+					model = append(model, make([]byte, off+len(p)-len(model))...)
+				}
+				copy(model[off:], p)
+			}
+			checkRead := func(f vfs.File) {
+				for i := 0; i < 10; i++ {
+					off := rand.IntN(len(model) + 10)
+					p := make([]byte, rand.IntN(3*chunkSize+10))
+					n, err := f.ReadAt(p, int64(off))
+					if len(p) > 0 && off+len(p) > len(model) {
+						require.Equal(t, io.EOF, err)
+					} else {
+						require.NoError(t, err)
+					}
+					require.Equal(t, string(model[min(off, len(model)):min(off+len(p), len(model))]), string(p[:n]))
+				}
+				stat, err := f.Stat()
+				require.NoError(t, err)
+				require.Equal(t, int64(len(model)), stat.Size())
+			}
+			for i := 0; i < 200; i++ {
+				p := make([]byte, rand.IntN(3*chunkSize+10))
+				for j := range p {
+					p[j] = byte(rand.IntN(256))
+				}
+				if rand.IntN(4) == 0 && len(model) > 0 {
+					off := rand.IntN(len(model))
+					_, err := f.WriteAt(p, int64(off))
+					require.NoError(t, err)
+					writeModel(p, off)
+				} else {
+					_, err := f.Write(bytes.Clone(p))
+					require.NoError(t, err)
+					writeModel(p, writeOff)
+					writeOff += len(p)
+				}
+				checkRead(f)
+			}
+			require.NoError(t, f.Sync())
+			require.NoError(t, f.Close())
+
+			f, err = fs.Open("file")
+			require.NoError(t, err)
+			checkRead(f)
+			data, err := io.ReadAll(f)
+			require.NoError(t, err)
+			require.Equal(t, model, data)
+			require.NoError(t, f.Close())
+			stat, err := fs.Stat("file")
+			require.NoError(t, err)
+			require.Equal(t, int64(len(model)), stat.Size())
+
+			// The file cannot be read with another key.
+			otherFS, err := New(mem, Options{Key: testKey(2)})
+			require.NoError(t, err)
+			f, err = otherFS.Open("file")
+			require.NoError(t, err)
+			_, err = io.ReadAll(f)
+			require.Error(t, err)
+			require.NoError(t, f.Close())
+
+			// Tampering with a chunk is detected.
+			raw, err := mem.OpenReadWrite("file", vfs.WriteCategoryUnspecified)
+			require.NoError(t, err)
+			b := make([]byte, 1)
+			_, err = raw.ReadAt(b, int64(headerLen+chunkOverhead))
+			require.NoError(t, err)
+			b[0] ^= 1
+			_, err = raw.WriteAt(b, int64(headerLen+chunkOverhead))
+			require.NoError(t, err)
+			require.NoError(t, raw.Close())
+			f, err = fs.Open("file")
+			require.NoError(t, err)
+			_, err = f.ReadAt(make([]byte, 1), 0)
+			require.Error(t, err)
+			require.NoError(t, f.Close())
+		})
+	}
+}
+
+// recordingFS records the writes to the files it creates once record is set.
+type recordingFS struct {
+	vfs.FS
+	record bool
+	writes []recordedWrite
+}
+
+type recordedWrite struct {
+	off  int64
+	data []byte
+}
+
+func (fs *recordingFS) Create(name string, category vfs.DiskWriteCategory) (vfs.File, error) {
+	f, err := fs.FS.Create(name, category)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingFile{File: f, fs: fs}, nil
+}
+
+type recordingFile struct {
+	vfs.File
+	fs *recordingFS
+}
+
+func (f *recordingFile) WriteAt(p []byte, off int64) (int, error) {
+	if f.fs.record {
+		f.fs.writes = append(f.fs.writes, recordedWrite{off: off, data: bytes.Clone(p)})
+	}
+	return f.File.WriteAt(p, off)
+}
+
+// TestTornAppend checks that appends to a synced file cannot make its synced
+// contents unreadable, whichever of the appends' writes reach the underlying
+// file and wherever one of them is torn.
+func TestTornAppend(t *testing.T) {
+	for _, chunkSize := range []int{4, 7, 64} {
+		t.Run(fmt.Sprint(chunkSize), func(t *testing.T) {
+			rec := &recordingFS{FS: vfs.NewMem()}
+			fs, err := New(rec, Options{Key: testKey(1), ChunkSize: chunkSize})
+			require.NoError(t, err)
+			f, err := fs.Create("file", vfs.WriteCategoryUnspecified)
+			require.NoError(t, err)
+			_, err = f.Write([]byte("hello"))
+			require.NoError(t, err)
+			require.NoError(t, f.Sync())
+			raw, err := rec.FS.Open("file")
+			require.NoError(t, err)
+			synced, err := io.ReadAll(raw)
+			require.NoError(t, err)
+			require.NoError(t, raw.Close())
+
+			rec.record = true
+			for _, s := range []string{" world", "!!", "foo bar baz"} {
+				_, err = f.Write([]byte(s))
+				require.NoError(t, err)
+			}
+			require.NoError(t, f.Close())
+			require.LessOrEqual(t, len(rec.writes), 6)
+
+			for mask := 0; mask < 1<<len(rec.writes); mask++ {
+				for torn := range rec.writes {
+					if mask&(1<<torn) == 0 {
+						continue
+					}
+					for cut := 0; cut < len(rec.writes[torn].data); cut++ {
+						mem := vfs.NewMem()
+						raw, err := mem.Create("file", vfs.WriteCategoryUnspecified)
+						require.NoError(t, err)
+						_, err = raw.Write(synced)
+						require.NoError(t, err)
+						for i, w := range rec.writes {
+							if mask&(1<<i) == 0 {
+								continue
+							}
+							data := w.data
+							if i == torn {
+								data = data[:cut]
+							}
+							_, err = raw.WriteAt(data, w.off)
+							require.NoError(t, err)
+						}
+						require.NoError(t, raw.Close())
+
+						fs, err := New(mem, Options{Key: testKey(1), ChunkSize: chunkSize})
+						require.NoError(t, err)
+						f, err := fs.Open("file")
+						require.NoError(t, err)
+						p := make([]byte, len("hello"))
+						_, err = f.ReadAt(p, 0)
+						require.NoError(t, err, "mask %b, write %d torn at %d", mask, torn, cut)
+						require.Equal(t, "hello", string(p))
+						require.NoError(t, f.Close())
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestUnencryptedFile(t *testing.T) {
+	mem := vfs.NewMem()
+	fs, err := New(mem, Options{Key: testKey(1)})
+	require.NoError(t, err)
+	f, err := mem.Create("plaintext", vfs.WriteCategoryUnspecified)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("this file was written without encryption"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	// Stat doesn't read the header, and the missing header is reported once
+	// the contents are read.
+	_, err = fs.Stat("plaintext")
+	require.NoError(t, err)
+	f, err = fs.Open("plaintext")
+	require.NoError(t, err)
+	_, err = f.ReadAt(make([]byte, 1), 0)
+	require.Error(t, err)
+	_, err = f.Stat()
+	require.Error(t, err)
+	require.NoError(t, f.Close())
+
+	// A file without a header is empty.
+	f, err = mem.Create("empty", vfs.WriteCategoryUnspecified)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	stat, err := fs.Stat("empty")
+	require.NoError(t, err)
+	require.Zero(t, stat.Size())
+	f, err = fs.Open("empty")
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.Empty(t, data)
+	require.NoError(t, f.Close())
+	f, err = fs.OpenReadWrite("empty", vfs.WriteCategoryUnspecified)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	stat, err = fs.Stat("empty")
+	require.NoError(t, err)
+	require.Equal(t, int64(len("hello")), stat.Size())
+}
+
+func TestDB(t *testing.T) {
+	mem := vfs.NewMem()
+	fs, err := New(mem, Options{Key: testKey(1)})
+	require.NoError(t, err)
+	opts := &pebble.Options{FS: fs}
+	d, err := pebble.Open("db", opts)
+	require.NoError(t, err)
+	const n = 1000
+	for i := 0; i < n; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("key%04d", i)), []byte(fmt.Sprintf("secret-value-%04d", i)), nil))
+		if i%300 == 0 {
+			require.NoError(t, d.Flush())
+		}
+	}
+	require.NoError(t, d.Compact(context.Background(), []byte("key"), []byte("key9"), true))
+	require.NoError(t, d.Checkpoint("checkpoint"))
+	require.NoError(t, d.Close())
+
+	// No file written by the DB holds plaintext.
+	for _, dir := range []string{"db", "checkpoint"} {
+		ls, err := mem.List(dir)
+		require.NoError(t, err)
+		for _, name := range ls {
+			f, err := mem.Open(mem.PathJoin(dir, name))
+			require.NoError(t, err)
+			data, err := io.ReadAll(f)
+			require.NoError(t, err)
+			require.NoError(t, f.Close())
+			require.False(t, bytes.Contains(data, []byte("secret-value")), "%s contains plaintext", name)
+			require.False(t, strings.HasPrefix(name, "OPTIONS") && bytes.Contains(data, []byte("[Version]")))
+		}
+	}
+
+	for _, dir := range []string{"db", "checkpoint"} {
+		d, err := pebble.Open(dir, &pebble.Options{FS: fs})
+		require.NoError(t, err)
+		for i := 0; i < n; i++ {
+			v, closer, err := d.Get([]byte(fmt.Sprintf("key%04d", i)))
+			require.NoError(t, err)
+			require.Equal(t, fmt.Sprintf("secret-value-%04d", i), string(v))
+			require.NoError(t, closer.Close())
+		}
+		require.NoError(t, d.Close())
+	}
+}
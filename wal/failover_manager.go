@@ -14,6 +14,7 @@ import (
 
 	"github.com/cockroachdb/errors"
 	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/internal/encryption"
 	"github.com/chris124567/pebble/internal/invariants"
 	"github.com/chris124567/pebble/vfs"
 )
@@ -737,6 +738,19 @@ func (wm *failoverManager) logCreator(
 			wm.opts.EventListener.LogCreated(createInfo)
 		}
 	}()
+	if wm.opts.KeyProvider != nil {
+		defer func() {
+			if err == nil {
+				ef, encErr := encryption.CreateFile(logFile, wm.opts.KeyProvider)
+				if encErr != nil {
+					err = firstError(encErr, logFile.Close())
+					logFile = nil
+					return
+				}
+				logFile = ef
+			}
+		}()
+	}
 	if considerRecycle {
 		// Try to use a recycled log file. Recycling log files is an important
 		// performance optimization as it is faster to sync a file that has
@@ -13,6 +13,7 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/chris124567/pebble/batchrepr"
 	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/internal/encryption"
 	"github.com/chris124567/pebble/record"
 	"github.com/chris124567/pebble/vfs"
 	"github.com/cockroachdb/redact"
@@ -70,9 +71,17 @@ func (ll LogicalLog) PhysicalSize() (uint64, error) {
 	return size, nil
 }
 
-// OpenForRead a logical WAL for reading.
+// OpenForRead a logical WAL for reading. Reading an encrypted segment file
+// returns an error wrapping encryption.ErrNoKeyProvider; see
+// OpenEncryptedForRead.
 func (ll LogicalLog) OpenForRead() Reader {
-	return newVirtualWALReader(ll)
+	return newVirtualWALReader(ll, nil /* keyProvider */)
+}
+
+// OpenEncryptedForRead opens a logical WAL for reading, decrypting encrypted
+// segment files with keys from the provider.
+func (ll LogicalLog) OpenEncryptedForRead(keyProvider encryption.KeyProvider) Reader {
+	return newVirtualWALReader(ll, keyProvider)
 }
 
 // String implements fmt.Stringer.
@@ -202,10 +211,11 @@ func (l Logs) Get(num NumWAL) (LogicalLog, bool) {
 	return l[i], true
 }
 
-func newVirtualWALReader(wal LogicalLog) *virtualWALReader {
+func newVirtualWALReader(wal LogicalLog, keyProvider encryption.KeyProvider) *virtualWALReader {
 	return &virtualWALReader{
-		LogicalLog: wal,
-		currIndex:  -1,
+		LogicalLog:  wal,
+		keyProvider: keyProvider,
+		currIndex:   -1,
 	}
 }
 
@@ -217,6 +227,8 @@ func newVirtualWALReader(wal LogicalLog) *virtualWALReader {
 type virtualWALReader struct {
 	// VirtualWAL metadata.
 	LogicalLog
+	// keyProvider provides the keys of encrypted segment files, or is nil.
+	keyProvider encryption.KeyProvider
 
 	// State pertaining to the current position of the reader within the virtual
 	// WAL and its constituent physical files.
@@ -373,6 +385,11 @@ func (r *virtualWALReader) nextFile() error {
 	if r.currFile, err = fs.Open(path); err != nil {
 		return errors.Wrapf(err, "opening WAL file segment %q", path)
 	}
+	f, err := encryption.OpenFile(r.currFile, r.keyProvider)
+	if err != nil {
+		return errors.Wrapf(err, "opening WAL file segment %q", path)
+	}
+	r.currFile = f
 	r.currReader = record.NewReader(r.currFile, base.DiskFileNum(r.Num))
 	return nil
 }
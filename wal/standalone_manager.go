@@ -9,6 +9,7 @@ import (
 	"sync"
 
 	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/internal/encryption"
 	"github.com/chris124567/pebble/record"
 	"github.com/chris124567/pebble/vfs"
 )
@@ -188,6 +189,14 @@ func (m *StandaloneManager) Create(wn NumWAL, jobID int) (Writer, error) {
 		err = firstError(err, newLogFile.Close())
 		return nil, err
 	}
+	if m.o.KeyProvider != nil {
+		var ef vfs.File
+		if ef, err = encryption.CreateFile(newLogFile, m.o.KeyProvider); err != nil {
+			err = firstError(err, newLogFile.Close())
+			return nil, err
+		}
+		newLogFile = ef
+	}
 	newLogFile = vfs.NewSyncingFile(newLogFile, vfs.SyncingFileOptions{
 		NoSyncOnClose:   m.o.NoSyncOnClose,
 		BytesPerSync:    m.o.BytesPerSync,
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/chris124567/pebble/internal/base"
+	"github.com/chris124567/pebble/internal/encryption"
 	"github.com/chris124567/pebble/record"
 	"github.com/chris124567/pebble/vfs"
 	"github.com/cockroachdb/redact"
@@ -111,6 +112,10 @@ type Options struct {
 	// recycling.
 	MaxNumRecyclableLogs int
 
+	// KeyProvider, if set, provides the key with which new WAL files are
+	// encrypted.
+	KeyProvider encryption.KeyProvider
+
 	// Configuration for calling vfs.NewSyncingFile.
 
 	// NoSyncOnClose is documented in SyncingFileOptions.